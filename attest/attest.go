@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attest provides a mechanism-independent way to request attestation evidence, so
+// that applications can attest on platforms with a TSM report subsystem, a TPM, or both,
+// without hardcoding which one the platform provides.
+package attest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+const (
+	// FormatTsmReportPrefix prefixes the provider name in the Format of TSM report
+	// evidence, e.g., "tsm-report/sev_guest".
+	FormatTsmReportPrefix = "tsm-report/"
+	// FormatTpmQuote is the Format of TPM quote evidence.
+	FormatTpmQuote = "tpm-quote"
+	// maxTsmNonceSize is the largest nonce a TSM report can bind, the inblob size limit.
+	maxTsmNonceSize = 64
+)
+
+// ErrNoAttester is returned by Detect when the platform provides no attestation mechanism.
+var ErrNoAttester = errors.New("no attestation mechanism is available")
+
+// Evidence is attestation evidence that binds a nonce.
+type Evidence struct {
+	// Format identifies the mechanism and encoding of Data, e.g., "tsm-report/tdx_guest"
+	// or "tpm-quote".
+	Format string
+	// Nonce is the nonce that the evidence binds.
+	Nonce []byte
+	// Data is the primary evidence, e.g., a report outblob or a TPM quote's attestation
+	// structure.
+	Data []byte
+	// Auxiliary is supporting data for verifying Data, e.g., a report auxblob with
+	// certificates or a TPM quote's signature.
+	Auxiliary []byte
+}
+
+// Attester produces attestation evidence.
+type Attester interface {
+	// Name names the mechanism, e.g., "tsm-report" or "tpm".
+	Name() string
+	// Attest returns evidence that binds the nonce.
+	Attest(nonce []byte) (*Evidence, error)
+}
+
+// ReportAttester is an Attester backed by the TSM report subsystem.
+type ReportAttester struct {
+	client configfsi.Client
+	opts   []report.Option
+}
+
+// NewReportAttester returns an Attester that gets reports from client with the given
+// options, e.g., report.WithAuxBlob() to include certificates as auxiliary evidence.
+func NewReportAttester(client configfsi.Client, opts ...report.Option) *ReportAttester {
+	return &ReportAttester{client: client, opts: opts}
+}
+
+// Name returns "tsm-report".
+func (a *ReportAttester) Name() string { return "tsm-report" }
+
+// Attest returns a report whose inblob is the nonce, which can be at most 64 bytes.
+func (a *ReportAttester) Attest(nonce []byte) (*Evidence, error) {
+	if len(nonce) > maxTsmNonceSize {
+		return nil, fmt.Errorf("nonce is %d bytes, but a report binds at most %d", len(nonce), maxTsmNonceSize)
+	}
+	resp, err := report.Get(a.client, report.NewRequest(nonce, a.opts...))
+	if err != nil {
+		return nil, err
+	}
+	return &Evidence{
+		Format:    FormatTsmReportPrefix + strings.TrimRight(resp.Provider, "\n"),
+		Nonce:     nonce,
+		Data:      resp.OutBlob,
+		Auxiliary: resp.AuxBlob,
+	}, nil
+}
+
+// TpmQuoteFunc returns a TPM quote over the nonce as the quote's attestation structure
+// (TPMS_ATTEST) and signature. Applications implement it with their TPM library, e.g.,
+// by quoting with an attestation key over the PCRs their verifier expects.
+type TpmQuoteFunc func(nonce []byte) (attestation, signature []byte, err error)
+
+// TpmAttester is an Attester backed by TPM quotes.
+type TpmAttester struct {
+	quote TpmQuoteFunc
+}
+
+// NewTpmAttester returns an Attester that produces TPM quotes with quote.
+func NewTpmAttester(quote TpmQuoteFunc) *TpmAttester {
+	return &TpmAttester{quote: quote}
+}
+
+// Name returns "tpm".
+func (a *TpmAttester) Name() string { return "tpm" }
+
+// Attest returns a TPM quote over the nonce.
+func (a *TpmAttester) Attest(nonce []byte) (*Evidence, error) {
+	attestation, signature, err := a.quote(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("could not get tpm quote: %w", err)
+	}
+	return &Evidence{Format: FormatTpmQuote, Nonce: nonce, Data: attestation, Auxiliary: signature}, nil
+}
+
+// Detect returns a ReportAttester if client has a usable report subsystem, and otherwise
+// tpm if it is not nil. It returns ErrNoAttester if neither is available.
+func Detect(client configfsi.Client, tpm Attester, opts ...report.Option) (Attester, error) {
+	if client != nil && configfsi.Supports(client, "report") {
+		return NewReportAttester(client, opts...), nil
+	}
+	if tpm != nil {
+		return tpm, nil
+	}
+	return nil, ErrNoAttester
+}
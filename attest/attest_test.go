@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+)
+
+func tpmQuote(nonce []byte) ([]byte, []byte, error) {
+	return append([]byte("quote:"), nonce...), []byte("signature"), nil
+}
+
+func TestDetectReport(t *testing.T) {
+	client := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportSnp(0, nil)}}
+	a, err := Detect(client, NewTpmAttester(tpmQuote))
+	if err != nil {
+		t.Fatalf("Detect() = _, %v. Want nil", err)
+	}
+	if a.Name() != "tsm-report" {
+		t.Fatalf("Detect() = %q. Want the tsm-report attester", a.Name())
+	}
+	nonce := bytes.Repeat([]byte{7}, 64)
+	ev, err := a.Attest(nonce)
+	if err != nil {
+		t.Fatalf("Attest() = _, %v. Want nil", err)
+	}
+	if ev.Format != "tsm-report/sev_guest" || !bytes.Equal(ev.Nonce, nonce) || len(ev.Data) == 0 {
+		t.Errorf("Attest() = %+v. Want sev_guest evidence for the nonce", ev)
+	}
+	if _, err := a.Attest(make([]byte, 65)); err == nil {
+		t.Error("Attest(65-byte nonce) = _, nil. Want an error")
+	}
+}
+
+func TestDetectTpm(t *testing.T) {
+	client := &faketsm.Client{Subsystems: map[string]configfsi.Client{}}
+	a, err := Detect(client, NewTpmAttester(tpmQuote))
+	if err != nil {
+		t.Fatalf("Detect() = _, %v. Want nil", err)
+	}
+	ev, err := a.Attest([]byte("nonce"))
+	if err != nil {
+		t.Fatalf("Attest() = _, %v. Want nil", err)
+	}
+	if ev.Format != FormatTpmQuote || string(ev.Data) != "quote:nonce" || string(ev.Auxiliary) != "signature" {
+		t.Errorf("Attest() = %+v. Want a TPM quote", ev)
+	}
+	if _, err := Detect(client, nil); !errors.Is(err, ErrNoAttester) {
+		t.Errorf("Detect(no mechanisms) = _, %v. Want ErrNoAttester", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tsmbrokerd is a configfs broker that lets the users given by -allow_uids request
+// TSM reports without write access to /sys/kernel/config. Clients connect with
+// broker.Dial. Peers are authorized by their SO_PEERCRED credentials, so the socket is
+// accessible to all users.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+)
+
+var (
+	socket     = flag.String("socket", "/run/tsmbroker.sock", "Unix socket to listen on")
+	allowUIDs  = flag.String("allow_uids", "", "comma-separated user IDs allowed to use the broker besides root")
+	maxEntries = flag.Int("max_entries", broker.DefaultMaxEntries, "report entries each connection may own at once")
+)
+
+func parseUIDs(s string) ([]uint32, error) {
+	var uids []uint32
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		uid, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -allow_uids: %w", err)
+		}
+		uids = append(uids, uint32(uid))
+	}
+	return uids, nil
+}
+
+func run(ctx context.Context) error {
+	uids, err := parseUIDs(*allowUIDs)
+	if err != nil {
+		return err
+	}
+	if *maxEntries <= 0 {
+		return errors.New("-max_entries must be positive")
+	}
+	client, err := linuxtsm.MakeClient()
+	if err != nil {
+		return err
+	}
+	// Remove a stale socket from a previous run, but nothing else at the path.
+	if info, err := os.Lstat(*socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(*socket)
+	}
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: *socket, Net: "unix"})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	if err := os.Chmod(*socket, 0666); err != nil {
+		return err
+	}
+	s := broker.NewServer(client, broker.AllowUIDs(uids...))
+	s.MaxEntries = *maxEntries
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	err = s.Serve(l)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func main() {
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "tsmbrokerd: %v\n", err)
+		os.Exit(1)
+	}
+}
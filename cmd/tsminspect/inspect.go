@@ -0,0 +1,201 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/hex"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// State is the state of the tsm tree as seen by the process.
+type State struct {
+	// Root is the tsm directory, if found.
+	Root string `json:"root,omitempty"`
+	// RootEntries lists the tsm directory if the client could not be made.
+	RootEntries []string `json:"root_entries,omitempty"`
+	// ClientError is why a client for the tsm tree could not be made, if it could not.
+	ClientError string `json:"client_error,omitempty"`
+	// AccessError is why the process cannot request reports, if it cannot.
+	AccessError string      `json:"access_error,omitempty"`
+	Features    []Feature   `json:"features,omitempty"`
+	Subsystems  []Subsystem `json:"subsystems,omitempty"`
+}
+
+// Feature is whether a configfs-tsm feature is expected to work.
+type Feature struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Subsystem is the state of a TSM subsystem.
+type Subsystem struct {
+	Name    string  `json:"name"`
+	Present bool    `json:"present"`
+	Usable  bool    `json:"usable"`
+	Error   string  `json:"error,omitempty"`
+	Entries []Entry `json:"entries,omitempty"`
+}
+
+// Entry is the state of a subsystem entry.
+type Entry struct {
+	Name string `json:"name"`
+	// Probe is true for the temporary entry that tsminspect created.
+	Probe      bool        `json:"probe,omitempty"`
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Attribute is the state of an entry attribute.
+type Attribute struct {
+	Name string `json:"name"`
+	// Access is "ro", "wo", or "rw" as the schema describes the attribute, or "?" if it is
+	// not known.
+	Access string `json:"access"`
+	// Read is true if the attribute was read successfully.
+	Read bool `json:"read"`
+	// Value is the value read, as text or hex.
+	Value string `json:"value,omitempty"`
+	// Skipped explains why a readable attribute was not read.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// probePattern is the name pattern of the report entry that inspect creates.
+const probePattern = "tsminspect"
+
+// generatingAttributes are attributes whose read asks the TSM for a report, which
+// inspecting should not do.
+var generatingAttributes = map[string]bool{"outblob": true, "auxblob": true, "manifestblob": true}
+
+// binaryAttributes are attributes whose values are shown in hex.
+var binaryAttributes = map[string]bool{"digest": true}
+
+func accessName(a *configfsi.AttributeSchema) string {
+	if a == nil {
+		return "?"
+	}
+	switch a.Access {
+	case configfsi.ReadOnly:
+		return "ro"
+	case configfsi.WriteOnly:
+		return "wo"
+	case configfsi.ReadWrite:
+		return "rw"
+	}
+	return "?"
+}
+
+// inspectAttribute reads an attribute unless it is write-only or reading it would generate a
+// report.
+func inspectAttribute(client configfsi.Client, schema *configfsi.SubsystemSchema, dir, name string) Attribute {
+	var a *configfsi.AttributeSchema
+	if schema != nil {
+		a = schema.Attribute(name)
+	}
+	attr := Attribute{Name: name, Access: accessName(a)}
+	switch {
+	case a != nil && !a.Access.Readable():
+		return attr
+	case generatingAttributes[name]:
+		attr.Skipped = "reading generates a report"
+		return attr
+	}
+	data, err := client.ReadFile(path.Join(dir, name))
+	if err != nil {
+		attr.Error = err.Error()
+		return attr
+	}
+	attr.Read = true
+	if binaryAttributes[name] {
+		attr.Value = hex.EncodeToString(data)
+	} else {
+		attr.Value = strings.TrimRight(string(data), "\n")
+	}
+	return attr
+}
+
+// inspectEntry returns the state of the entry's attributes.
+func inspectEntry(client configfsi.Client, subsystem, entry string) Entry {
+	dir := (&configfsi.TsmPath{Subsystem: subsystem, Entry: entry}).String()
+	result := Entry{Name: entry}
+	schema := configfsi.Schema(subsystem)
+	var names []string
+	if files, err := client.ReadDir(dir); err == nil {
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+	} else if schema != nil {
+		// Fall back to the attributes the entry should have.
+		for _, a := range schema.Attributes {
+			names = append(names, a.Name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Attributes = append(result.Attributes, inspectAttribute(client, schema, dir, name))
+	}
+	return result
+}
+
+// inspectSubsystem returns the state of a subsystem and its entries. If probe is true and
+// the subsystem is report, a temporary entry is created and inspected so that attributes
+// such as provider are shown even without other entries.
+func inspectSubsystem(client configfsi.Client, status configfsi.SubsystemStatus, probe bool) Subsystem {
+	result := Subsystem{Name: status.Name, Present: status.Present, Usable: status.Usable}
+	if status.Err != nil {
+		result.Error = status.Err.Error()
+	}
+	if !status.Usable {
+		return result
+	}
+	dir := (&configfsi.TsmPath{Subsystem: status.Name}).String()
+	files, err := client.ReadDir(dir)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			result.Entries = append(result.Entries, inspectEntry(client, status.Name, f.Name()))
+		}
+	}
+	sort.Slice(result.Entries, func(i, j int) bool { return result.Entries[i].Name < result.Entries[j].Name })
+	if !probe || status.Name != "report" {
+		return result
+	}
+	entry, err := client.MkdirTemp(dir, probePattern)
+	if err != nil {
+		result.Error = "could not create a probe entry: " + err.Error()
+		return result
+	}
+	defer client.RemoveAll(entry)
+	probed := inspectEntry(client, status.Name, path.Base(entry))
+	probed.Probe = true
+	result.Entries = append(result.Entries, probed)
+	return result
+}
+
+// inspect returns the state of the subsystems that client reaches.
+func inspect(client configfsi.Client, probe bool) []Subsystem {
+	var result []Subsystem
+	for _, status := range configfsi.Probe(client).Subsystems {
+		result = append(result, inspectSubsystem(client, status, probe))
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+func findAttribute(e *Entry, name string) *Attribute {
+	for i := range e.Attributes {
+		if e.Attributes[i].Name == name {
+			return &e.Attributes[i]
+		}
+	}
+	return nil
+}
+
+func TestInspect(t *testing.T) {
+	client := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.Report611(1),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	if err := rtmr.ExtendDigest(client, 2, bytes.Repeat([]byte{1}, 48)); err != nil {
+		t.Fatal(err)
+	}
+	subsystems := inspect(client, true)
+	if len(subsystems) != 2 || subsystems[0].Name != "report" || subsystems[1].Name != "rtmrs" {
+		t.Fatalf("inspect() = %+v, want report and rtmrs", subsystems)
+	}
+
+	report := subsystems[0]
+	if len(report.Entries) != 1 || !report.Entries[0].Probe {
+		t.Fatalf("report entries = %+v, want only the probe entry", report.Entries)
+	}
+	probe := &report.Entries[0]
+	if a := findAttribute(probe, "provider"); a == nil || !a.Read || a.Value != "fake" {
+		t.Errorf("provider = %+v, want a read of \"fake\"", a)
+	}
+	if a := findAttribute(probe, "privlevel_floor"); a == nil || a.Value != "1" || a.Access != "ro" {
+		t.Errorf("privlevel_floor = %+v, want read-only 1", a)
+	}
+	if a := findAttribute(probe, "outblob"); a == nil || a.Read || a.Skipped == "" {
+		t.Errorf("outblob = %+v, want it skipped", a)
+	}
+	if a := findAttribute(probe, "inblob"); a == nil || a.Read || a.Error != "" || a.Access != "wo" {
+		t.Errorf("inblob = %+v, want write-only and not read", a)
+	}
+	entries, err := client.ReadDir(configfsi.TsmPrefix + "/report")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("report entries after inspect = %v, %v, want the probe entry removed", entries, err)
+	}
+
+	rtmrs := subsystems[1]
+	if len(rtmrs.Entries) != 1 {
+		t.Fatalf("rtmrs entries = %+v, want one", rtmrs.Entries)
+	}
+	if a := findAttribute(&rtmrs.Entries[0], "index"); a == nil || a.Value != "2" {
+		t.Errorf("index = %+v, want 2", a)
+	}
+	if a := findAttribute(&rtmrs.Entries[0], "digest"); a == nil || len(a.Value) != 96 {
+		t.Errorf("digest = %+v, want 48 bytes of hex", a)
+	}
+
+	var out strings.Builder
+	printState(&out, &State{Root: "/sys/kernel/config/tsm", Subsystems: subsystems})
+	if !strings.Contains(out.String(), "not read: reading generates a report") {
+		t.Errorf("printState() = %s, want the skipped outblob", out.String())
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tsminspect prints the state of the configfs-tsm tree as the running process sees
+// it: the tsm directory, whether reports can be requested, the expected features, and each
+// subsystem's entries with the readability and value of their attributes. Attach its
+// output, e.g., from -json, to reports of permission or provider problems.
+//
+// It does not read outblob, auxblob, or manifestblob, since reading them generates a
+// report. With -probe, the default, it creates and removes a temporary report entry.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+)
+
+var (
+	jsonOutput = flag.Bool("json", false, "print the state as JSON")
+	probe      = flag.Bool("probe", true, "create a temporary report entry to inspect")
+)
+
+// collect returns the state of the tsm tree.
+func collect() *State {
+	state := &State{}
+	root, err := linuxtsm.FindTsmRoot()
+	if err == nil {
+		state.Root = root
+	}
+	for _, f := range linuxtsm.Features() {
+		state.Features = append(state.Features, Feature{Name: f.Name, Available: f.Available, Reason: f.Reason})
+	}
+	client, err := linuxtsm.MakeClient()
+	if err != nil {
+		state.ClientError = err.Error()
+		if root != "" {
+			if entries, err := os.ReadDir(root); err == nil {
+				for _, e := range entries {
+					state.RootEntries = append(state.RootEntries, e.Name())
+				}
+			}
+		}
+		return state
+	}
+	if err := linuxtsm.CheckAccess(); err != nil {
+		state.AccessError = err.Error()
+	}
+	state.Subsystems = inspect(client, *probe)
+	return state
+}
+
+// printState prints the state for people.
+func printState(w io.Writer, state *State) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+	root := state.Root
+	if root == "" {
+		root = "(not found)"
+	}
+	fmt.Fprintf(tw, "tsm root:\t%s\n", root)
+	if state.ClientError != "" {
+		fmt.Fprintf(tw, "client error:\t%s\n", state.ClientError)
+	}
+	if len(state.RootEntries) != 0 {
+		fmt.Fprintf(tw, "root entries:\t%s\n", strings.Join(state.RootEntries, " "))
+	}
+	if state.AccessError != "" {
+		fmt.Fprintf(tw, "access error:\t%s\n", state.AccessError)
+	}
+	fmt.Fprintln(tw, "\nfeatures:")
+	for _, f := range state.Features {
+		available := "yes"
+		if !f.Available {
+			available = "no"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.Name, available, f.Reason)
+	}
+	for _, s := range state.Subsystems {
+		fmt.Fprintf(tw, "\nsubsystem %s:\tpresent=%t usable=%t\t%s\n", s.Name, s.Present, s.Usable, s.Error)
+		for _, e := range s.Entries {
+			name := e.Name
+			if e.Probe {
+				name += " (probe)"
+			}
+			fmt.Fprintf(tw, "  entry %s:\n", name)
+			for _, a := range e.Attributes {
+				var detail string
+				switch {
+				case a.Error != "":
+					detail = "error: " + a.Error
+				case a.Skipped != "":
+					detail = "not read: " + a.Skipped
+				case a.Read:
+					detail = a.Value
+				}
+				fmt.Fprintf(tw, "    %s\t%s\t%s\n", a.Name, a.Access, detail)
+			}
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	state := collect()
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(state); err != nil {
+			fmt.Fprintf(os.Stderr, "tsminspect: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printState(os.Stdout, state)
+}
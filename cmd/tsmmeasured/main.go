@@ -0,0 +1,168 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tsmmeasured measures files into an RTMR as they are executed or opened, like
+// IMA, and writes the event log that a verifier needs to replay the register. It requires
+// CAP_SYS_ADMIN for fanotify.
+//
+// An existing -eventlog is continued after checking that it replays to the registers it
+// extends. Without one, the register must still be at its reset value, since the log
+// would otherwise lack earlier extends.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+	"github.com/google/go-configfs-tsm/filemeasure"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+var (
+	rtmrIndex = flag.Int("rtmr", filemeasure.DefaultRtmr, "RTMR to extend")
+	include   = flag.String("include", "/", "comma-separated directories whose files are measured")
+	exclude   = flag.String("exclude", "", "comma-separated directories within -include whose files are not measured")
+	onExec    = flag.Bool("exec", true, "measure files when they are executed")
+	onOpen    = flag.Bool("open", false, "measure files when they are opened")
+	eventLog  = flag.String("eventlog", "", "path of the JSON event log to continue and write after each measurement")
+)
+
+func split(s string) []string {
+	var dirs []string
+	for _, dir := range strings.Split(s, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// writeLog replaces the event log file so that readers never see a partial log.
+func writeLog(path string, log *rtmr.EventLog) error {
+	data, err := log.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadLog returns an event log that continues the log at path, if any, and checks that the
+// register that index names has no extends missing from it.
+func loadLog(client configfsi.Client, path string, index int) (*rtmr.EventLog, error) {
+	var events []rtmr.Event
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, fmt.Errorf("could not parse event log %s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	log, err := rtmr.ContinueEventLog(client, events)
+	if err != nil {
+		return nil, fmt.Errorf("cannot continue event log %s: %w", path, err)
+	}
+	// ContinueEventLog checks only the registers that the log extends.
+	resp, err := rtmr.GetDigest(client, index)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := rtmr.Replay(events, crypto.SHA384)[index]
+	if !ok {
+		want = make([]byte, len(resp.Digest))
+	}
+	if !bytes.Equal(resp.Digest, want) {
+		return nil, fmt.Errorf("rtmr%d has extends that event log %s lacks", index, path)
+	}
+	return log, nil
+}
+
+func run(ctx context.Context) error {
+	client, err := linuxtsm.MakeClient()
+	if err != nil {
+		return err
+	}
+	var events filemeasure.Events
+	if *onExec {
+		events |= filemeasure.OnExec
+	}
+	if *onOpen {
+		events |= filemeasure.OnOpen
+	}
+	log := rtmr.NewEventLog()
+	opts := &filemeasure.Options{
+		Rtmr:    rtmrIndex,
+		Include: split(*include),
+		Exclude: split(*exclude),
+	}
+	if *eventLog != "" {
+		if log, err = loadLog(client, *eventLog, *rtmrIndex); err != nil {
+			return err
+		}
+		opts.OnMeasure = func(*filemeasure.Record) {
+			if err := writeLog(*eventLog, log); err != nil {
+				fmt.Fprintf(os.Stderr, "tsmmeasured: could not write event log: %v\n", err)
+			}
+		}
+	}
+	m := filemeasure.NewMeasurer(client, log, opts)
+	// Watch the mounts of the included directories; Matches filters the rest.
+	mounts := opts.Include
+	if len(mounts) == 0 {
+		mounts = []string{"/"}
+	}
+	err = filemeasure.Watch(ctx, m, mounts, events, func(err error) {
+		fmt.Fprintf(os.Stderr, "tsmmeasured: %v\n", err)
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func main() {
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "tsmmeasured: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+func TestLoadLog(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	path := filepath.Join(t.TempDir(), "eventlog.json")
+	log, err := loadLog(client, path, 3)
+	if err != nil {
+		t.Fatalf("loadLog(no log, reset rtmr3) = _, %v. Want nil", err)
+	}
+	if err := log.ExtendDigest(client, 3, bytes.Repeat([]byte{1}, 48), "test", nil); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v. Want nil", err)
+	}
+	if err := writeLog(path, log); err != nil {
+		t.Fatalf("writeLog() = %v. Want nil", err)
+	}
+	// A restart continues the log.
+	log, err = loadLog(client, path, 3)
+	if err != nil {
+		t.Fatalf("loadLog(log) = _, %v. Want nil", err)
+	}
+	if events := log.Events(); len(events) != 1 {
+		t.Errorf("loadLog(log) has %d events. Want 1", len(events))
+	}
+	// Without the log, the earlier extend would be missing from the new one.
+	if _, err := loadLog(client, filepath.Join(t.TempDir(), "missing.json"), 3); err == nil {
+		t.Error("loadLog(no log, extended rtmr3) = _, nil. Want an error")
+	}
+	// Nor may the log miss extends made without it.
+	if err := rtmr.ExtendDigest(client, 3, bytes.Repeat([]byte{2}, 48)); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v. Want nil", err)
+	}
+	if _, err := loadLog(client, path, 3); err == nil {
+		t.Error("loadLog(stale log) = _, nil. Want an error")
+	}
+}
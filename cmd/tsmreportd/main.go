@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tsmreportd serves TSM report requests to unprivileged processes as a systemd
+// socket-activated service, so that only it needs access to configfs. It does not serve
+// RTMR extends. See tsmreportd.socket and tsmreportd.service, whose socket admits the
+// members of the tsm group. Without socket activation, it listens on the Unix socket given
+// by -socket.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+	"github.com/google/go-configfs-tsm/server"
+)
+
+var (
+	socket         = flag.String("socket", "", "Unix socket to listen on if not socket-activated")
+	maxRequests    = flag.Int("max_requests", 16, "requests served per connection before it is closed, or 0 for no limit")
+	maxConns       = flag.Int("max_conns", 64, "connections served at once, or 0 for no limit")
+	maxConnsPerUID = flag.Int("max_conns_per_uid", 4, "connections served at once for each user, or 0 for no limit")
+	allowUIDs      = flag.String("allow_uids", "", "comma-separated user IDs allowed to get reports besides root; if empty, all users that can open the socket are")
+)
+
+// policy returns the policy for -allow_uids.
+func policy() (server.Policy, error) {
+	if *allowUIDs == "" {
+		// The socket's permissions admit peers.
+		return func(*broker.Cred, string) error { return nil }, nil
+	}
+	var uids []uint32
+	for _, s := range strings.Split(*allowUIDs, ",") {
+		uid, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -allow_uids: %w", err)
+		}
+		uids = append(uids, uint32(uid))
+	}
+	return server.AllowUIDs(uids, nil), nil
+}
+
+func run(ctx context.Context) error {
+	client, err := linuxtsm.MakeClient()
+	if err != nil {
+		return err
+	}
+	p, err := policy()
+	if err != nil {
+		return err
+	}
+	opts := &server.ServeOptions{
+		MaxRequests:    *maxRequests,
+		MaxConns:       *maxConns,
+		MaxConnsPerUID: *maxConnsPerUID,
+		Policy:         p,
+		ReportOnly:     true,
+	}
+	err = server.ServeActivated(ctx, client, opts)
+	if !errors.Is(err, server.ErrNotActivated) {
+		return err
+	}
+	if *socket == "" {
+		return fmt.Errorf("%w and -socket is not set", err)
+	}
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(*socket, 0660); err != nil {
+		l.Close()
+		return err
+	}
+	return server.ServeListeners(ctx, []net.Listener{l}, client, opts)
+}
+
+func main() {
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "tsmreportd: %v\n", err)
+		os.Exit(1)
+	}
+}
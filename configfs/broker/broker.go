@@ -0,0 +1,319 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package broker forwards configfs operations from unprivileged processes to a privileged
+// broker over a Unix socket. The broker checks each peer's credentials with SO_PEERCRED
+// and only lets a connection use the report entries that it created, which the broker
+// removes when the connection closes. Other subsystems, e.g., rtmrs, are not brokered.
+//
+// The broker's net/rpc service is named "Configfs". Client implements configfsi.Client, so
+// it can be used with the report and rtmr packages unchanged.
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/rpc"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// serviceName is the net/rpc service name of the broker's methods.
+const serviceName = "Configfs"
+
+// subsystem is the only TSM subsystem that the broker serves.
+const subsystem = "report"
+
+// DefaultMaxEntries is the number of entries that a connection may own at once if the
+// server does not set MaxEntries.
+const DefaultMaxEntries = 4
+
+// Cred is the credentials of a peer process.
+type Cred struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// Policy decides whether a peer may use the broker.
+type Policy func(cred *Cred) error
+
+// AllowUIDs returns a Policy that admits root and the given user IDs.
+func AllowUIDs(uids ...uint32) Policy {
+	return func(cred *Cred) error {
+		if cred.UID == 0 {
+			return nil
+		}
+		for _, uid := range uids {
+			if cred.UID == uid {
+				return nil
+			}
+		}
+		return fmt.Errorf("uid %d is not allowed to use the configfs broker", cred.UID)
+	}
+}
+
+// Request is the argument to every broker RPC.
+type Request struct {
+	Name     string
+	Pattern  string
+	Contents []byte
+}
+
+// DirEntry is a serializable os.DirEntry.
+type DirEntry struct {
+	EntryName string
+	EntryMode fs.FileMode
+}
+
+// Reply is the result of every broker RPC. Errors are part of the reply rather than the
+// RPC error so that the errno survives the trip.
+type Reply struct {
+	Data    []byte
+	Path    string
+	Entries []DirEntry
+	// Op and Errno describe an *os.PathError, if Errno is non-zero.
+	Op    string
+	Errno syscall.Errno
+	// Err is the error message, if any.
+	Err string
+}
+
+// setErr records err in the reply.
+func (r *Reply) setErr(err error) {
+	if err == nil {
+		return
+	}
+	r.Err = err.Error()
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		r.Errno = errno
+		r.Op = "configfs"
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			r.Op = pathErr.Op
+		}
+	}
+}
+
+// Server is a broker that serves configfs operations backed by a client.
+type Server struct {
+	client configfsi.Client
+	policy Policy
+	// MaxEntries is the number of entries that a connection may own at once, so that one
+	// peer cannot exhaust configfs entries. Zero means DefaultMaxEntries.
+	MaxEntries int
+}
+
+// NewServer returns a broker that serves operations on client to peers that policy admits.
+// A nil policy admits only root.
+func NewServer(client configfsi.Client, policy Policy) *Server {
+	if policy == nil {
+		policy = AllowUIDs()
+	}
+	return &Server{client: client, policy: policy}
+}
+
+// Serve accepts connections on l until it is closed. Each connection is served in its own
+// goroutine after its peer is admitted.
+func (s *Server) Serve(l *net.UnixListener) error {
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection and removes the entries it created when it closes.
+func (s *Server) ServeConn(conn *net.UnixConn) {
+	defer conn.Close()
+	cred, err := PeerCred(conn)
+	if err != nil || s.policy(cred) != nil {
+		return
+	}
+	s.serve(conn, cred)
+}
+
+// ServeAdmitted serves a single connection whose peer the caller has admitted by other
+// means than SO_PEERCRED, e.g., by its vsock context ID, and removes the entries it created
+// when it closes. The server's policy does not apply.
+func (s *Server) ServeAdmitted(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	s.serve(conn, nil)
+}
+
+// serve serves the broker RPCs on conn for a peer with the given credentials, if known.
+func (s *Server) serve(conn io.ReadWriteCloser, cred *Cred) {
+	maxEntries := s.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	session := &session{client: s.client, cred: cred, maxEntries: maxEntries, owned: map[string]bool{}}
+	defer session.cleanup()
+	rs := rpc.NewServer()
+	if err := rs.RegisterName(serviceName, session); err != nil {
+		return
+	}
+	rs.ServeConn(conn)
+}
+
+// session is the broker state for one connection.
+type session struct {
+	client configfsi.Client
+	// cred is the peer's credentials, or nil if it was admitted by other means.
+	cred       *Cred
+	maxEntries int
+	mu         sync.Mutex
+	// owned is the set of entry paths that the connection created.
+	owned map[string]bool
+}
+
+// checkOwned returns the parsed name and the normalized path of its entry, or an error
+// unless name is in an entry that the session created.
+func (s *session) checkOwned(name string) (*configfsi.TsmPath, string, error) {
+	p, err := configfsi.ParseTsmPath(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, "", err
+	}
+	entry := p.WithEntry(p.Entry).String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Entry == "" || !s.owned[entry] {
+		return nil, "", &os.PathError{Op: "broker", Path: name, Err: syscall.EACCES}
+	}
+	return p, entry, nil
+}
+
+// checkSubsystem returns an error unless p is in the brokered subsystem.
+func checkSubsystem(name string, p *configfsi.TsmPath) error {
+	if p.Subsystem != subsystem {
+		return &os.PathError{Op: "broker", Path: name, Err: syscall.EACCES}
+	}
+	return nil
+}
+
+// MkdirTemp is the RPC for configfsi.Client.MkdirTemp. Entries can only be created in the
+// report subsystem, up to the session's limit.
+func (s *session) MkdirTemp(req *Request, reply *Reply) error {
+	p, err := configfsi.ParseTsmPath(req.Name)
+	if err == nil && (p.Entry != "" || p.Validate() != nil) {
+		err = &os.PathError{Op: "broker", Path: req.Name, Err: syscall.EACCES}
+	}
+	if err == nil {
+		err = checkSubsystem(req.Name, p)
+	}
+	if err == nil {
+		// Hold the lock so that concurrent calls cannot exceed the limit.
+		s.mu.Lock()
+		if len(s.owned) >= s.maxEntries {
+			err = &os.PathError{Op: "broker", Path: req.Name, Err: syscall.EMFILE}
+		} else if reply.Path, err = s.client.MkdirTemp(req.Name, req.Pattern); err == nil {
+			s.owned[reply.Path] = true
+		}
+		s.mu.Unlock()
+	}
+	reply.setErr(err)
+	return nil
+}
+
+// ReadFile is the RPC for configfsi.Client.ReadFile.
+func (s *session) ReadFile(req *Request, reply *Reply) error {
+	_, _, err := s.checkOwned(req.Name)
+	if err == nil {
+		reply.Data, err = s.client.ReadFile(req.Name)
+	}
+	reply.setErr(err)
+	return nil
+}
+
+// WriteFile is the RPC for configfsi.Client.WriteFile.
+func (s *session) WriteFile(req *Request, reply *Reply) error {
+	_, _, err := s.checkOwned(req.Name)
+	if err == nil {
+		err = s.client.WriteFile(req.Name, req.Contents)
+	}
+	reply.setErr(err)
+	return nil
+}
+
+// ReadDir is the RPC for configfsi.Client.ReadDir. Only the connection's own entries are
+// listed in a subsystem directory.
+func (s *session) ReadDir(req *Request, reply *Reply) error {
+	p, err := configfsi.ParseTsmPath(req.Name)
+	if err == nil && p.Entry != "" {
+		_, _, err = s.checkOwned(req.Name)
+	} else if err == nil {
+		err = checkSubsystem(req.Name, p)
+	}
+	var entries []os.DirEntry
+	if err == nil {
+		entries, err = s.client.ReadDir(req.Name)
+	}
+	for _, e := range entries {
+		if p.Entry == "" && e.IsDir() {
+			s.mu.Lock()
+			owned := s.owned[p.WithEntry(e.Name()).String()]
+			s.mu.Unlock()
+			if !owned {
+				continue
+			}
+		}
+		mode := e.Type()
+		if info, ierr := e.Info(); ierr == nil {
+			mode = info.Mode()
+		}
+		reply.Entries = append(reply.Entries, DirEntry{EntryName: e.Name(), EntryMode: mode})
+	}
+	reply.setErr(err)
+	return nil
+}
+
+// RemoveAll is the RPC for configfsi.Client.RemoveAll. Only whole entries can be removed.
+func (s *session) RemoveAll(req *Request, reply *Reply) error {
+	p, entry, err := s.checkOwned(req.Name)
+	if err == nil && p.Attribute != "" {
+		err = &os.PathError{Op: "broker", Path: req.Name, Err: syscall.EACCES}
+	}
+	if err == nil {
+		err = s.client.RemoveAll(entry)
+	}
+	if err == nil {
+		s.mu.Lock()
+		delete(s.owned, entry)
+		s.mu.Unlock()
+	}
+	reply.setErr(err)
+	return nil
+}
+
+// cleanup removes the entries that the connection left behind.
+func (s *session) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for entry := range s.owned {
+		s.client.RemoveAll(entry)
+	}
+	s.owned = nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func serve(t *testing.T, policy broker.Policy) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "broker.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	client := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.Report611(0),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	go broker.NewServer(client, policy).Serve(l)
+	return socket
+}
+
+func TestGetReport(t *testing.T) {
+	socket := serve(t, broker.AllowUIDs(uint32(os.Getuid())))
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	inblob := bytes.Repeat([]byte{1}, 64)
+	resp, err := report.Get(client, &report.Request{InBlob: inblob})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	if !bytes.Contains(resp.OutBlob, []byte(hex.EncodeToString(inblob))) {
+		t.Errorf("OutBlob = %v. Want it to contain the inblob", resp.OutBlob)
+	}
+}
+
+func TestOtherEntriesDenied(t *testing.T) {
+	socket := serve(t, broker.AllowUIDs(uint32(os.Getuid())))
+	owner, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer owner.Close()
+	other, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	entry, err := owner.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ReadFile(entry + "/provider"); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("ReadFile(%q) = _, %v. Want EACCES", entry, err)
+	}
+	if err := other.RemoveAll(entry); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("RemoveAll(%q) = %v. Want EACCES", entry, err)
+	}
+	entries, err := other.ReadDir("/sys/kernel/config/tsm/report")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir() = %v, %v. Want no entries", entries, err)
+	}
+	if _, err := owner.ReadFile(entry + "/provider"); err != nil {
+		t.Errorf("ReadFile(%q) = _, %v. Want nil", entry, err)
+	}
+}
+
+func TestPolicyDenied(t *testing.T) {
+	socket := serve(t, func(*broker.Cred) error { return errors.New("denied") })
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry"); err == nil {
+		t.Error("MkdirTemp() = _, nil. Want an error for a denied peer")
+	}
+}
+
+func TestOtherSubsystemsDenied(t *testing.T) {
+	socket := serve(t, broker.AllowUIDs(uint32(os.Getuid())))
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.MkdirTemp("/sys/kernel/config/tsm/rtmrs", "rtmr"); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("MkdirTemp(rtmrs) = _, %v. Want EACCES", err)
+	}
+	if _, err := client.ReadDir("/sys/kernel/config/tsm/rtmrs"); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("ReadDir(rtmrs) = _, %v. Want EACCES", err)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	socket := serve(t, broker.AllowUIDs(uint32(os.Getuid())))
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var entries []string
+	for i := 0; i < broker.DefaultMaxEntries; i++ {
+		entry, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+		if err != nil {
+			t.Fatalf("MkdirTemp() %d = _, %v. Want nil", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry"); !errors.Is(err, syscall.EMFILE) {
+		t.Errorf("MkdirTemp() past the limit = _, %v. Want EMFILE", err)
+	}
+	if err := client.RemoveAll(entries[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry"); err != nil {
+		t.Errorf("MkdirTemp() after RemoveAll = _, %v. Want nil", err)
+	}
+}
+
+func TestRemoveAllNormalizesEntry(t *testing.T) {
+	socket := serve(t, broker.AllowUIDs(uint32(os.Getuid())))
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var entries []string
+	for i := 0; i < broker.DefaultMaxEntries; i++ {
+		entry, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+		if err != nil {
+			t.Fatalf("MkdirTemp() %d = _, %v. Want nil", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := client.RemoveAll(entries[0] + "/provider"); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("RemoveAll(%q) = %v. Want EACCES", entries[0]+"/provider", err)
+	}
+	if err := client.RemoveAll(entries[0] + "/"); err != nil {
+		t.Fatalf("RemoveAll(%q) = %v. Want nil", entries[0]+"/", err)
+	}
+	// The entry's ownership record is gone, so its slot is free again.
+	if _, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry"); err != nil {
+		t.Errorf("MkdirTemp() after RemoveAll with a trailing slash = _, %v. Want nil", err)
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	socket := serve(t, nil)
+	client, err := broker.Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_, err = client.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+	if root := os.Getuid() == 0; (err == nil) != root {
+		t.Errorf("MkdirTemp() with a nil policy as uid %d = _, %v. Want success only for root", os.Getuid(), err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/rpc"
+	"os"
+	"time"
+)
+
+func (d *DirEntry) Name() string               { return d.EntryName }
+func (d *DirEntry) IsDir() bool                { return d.EntryMode.IsDir() }
+func (d *DirEntry) Type() fs.FileMode          { return d.EntryMode.Type() }
+func (d *DirEntry) Info() (fs.FileInfo, error) { return dirInfo{d}, nil }
+
+// dirInfo is the fs.FileInfo of a DirEntry.
+type dirInfo struct{ *DirEntry }
+
+func (i dirInfo) Size() int64        { return 4096 }
+func (i dirInfo) Mode() fs.FileMode  { return i.EntryMode }
+func (i dirInfo) ModTime() time.Time { return time.Time{} }
+func (i dirInfo) Sys() any           { return nil }
+
+// Client is a configfsi.Client that forwards operations to a broker.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// NewClient returns a Client that uses an established connection to a broker.
+func NewClient(conn net.Conn) *Client {
+	return &Client{rpc: rpc.NewClient(conn)}
+}
+
+// Dial connects to the broker listening on the Unix socket at socketPath.
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Close closes the connection. The broker removes the entries that the client created.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// call calls the named broker method and returns its reply and error.
+func (c *Client) call(method string, req *Request) (*Reply, error) {
+	reply := &Reply{}
+	if err := c.rpc.Call(serviceName+"."+method, req, reply); err != nil {
+		return nil, err
+	}
+	switch {
+	case reply.Errno != 0:
+		return reply, &os.PathError{Op: reply.Op, Path: req.Name, Err: reply.Errno}
+	case reply.Err != "":
+		return reply, errors.New(reply.Err)
+	}
+	return reply, nil
+}
+
+// MkdirTemp implements configfsi.Client.
+func (c *Client) MkdirTemp(dir, pattern string) (string, error) {
+	reply, err := c.call("MkdirTemp", &Request{Name: dir, Pattern: pattern})
+	if err != nil {
+		return "", err
+	}
+	return reply.Path, nil
+}
+
+// ReadFile implements configfsi.Client.
+func (c *Client) ReadFile(name string) ([]byte, error) {
+	reply, err := c.call("ReadFile", &Request{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Data, nil
+}
+
+// ReadDir implements configfsi.Client.
+func (c *Client) ReadDir(dirname string) ([]os.DirEntry, error) {
+	reply, err := c.call("ReadDir", &Request{Name: dirname})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]os.DirEntry, len(reply.Entries))
+	for i := range reply.Entries {
+		result[i] = &reply.Entries[i]
+	}
+	return result, nil
+}
+
+// WriteFile implements configfsi.Client.
+func (c *Client) WriteFile(name string, contents []byte) error {
+	_, err := c.call("WriteFile", &Request{Name: name, Contents: contents})
+	return err
+}
+
+// RemoveAll implements configfsi.Client.
+func (c *Client) RemoveAll(path string) error {
+	_, err := c.call("RemoveAll", &Request{Name: path})
+	return err
+}
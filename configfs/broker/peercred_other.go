@@ -0,0 +1,27 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package broker
+
+import (
+	"errors"
+	"net"
+)
+
+// PeerCred returns an error, since SO_PEERCRED is specific to Linux.
+func PeerCred(*net.UnixConn) (*Cred, error) {
+	return nil, errors.New("peer credentials are only supported on Linux")
+}
@@ -16,7 +16,12 @@
 package configfsi
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 )
 
 // Client abstracts the filesystem operations for interacting with configfs files.
@@ -34,3 +39,71 @@ type Client interface {
 	// RemoveAll removes path and any children it contains.
 	RemoveAll(path string) error
 }
+
+// Logger receives debug-level diagnostics about configfs operations as a message and
+// alternating key-value pairs. A *slog.Logger satisfies this interface.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// Opener is an optional interface for Clients that can stream attribute contents rather
+// than reading them in full.
+type Opener interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+}
+
+// Open opens the named file for reading with client's Open method if it implements Opener,
+// or otherwise returns a reader over the result of ReadFile.
+func Open(client Client, name string) (io.ReadCloser, error) {
+	if o, ok := client.(Opener); ok {
+		return o.Open(name)
+	}
+	data, err := client.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Mkdirer is an optional interface for Clients that can create directories with a chosen
+// name rather than a random one.
+type Mkdirer interface {
+	// Mkdir creates the named directory.
+	Mkdir(name string) error
+}
+
+// Mkdir creates the named directory with client's Mkdir method, or returns an error if
+// client does not implement Mkdirer.
+func Mkdir(client Client, name string) error {
+	if m, ok := client.(Mkdirer); ok {
+		return m.Mkdir(name)
+	}
+	return fmt.Errorf("client does not support creating %q by name", name)
+}
+
+// Stater is an optional interface for Clients that can describe a file directly.
+type Stater interface {
+	// Stat returns a FileInfo describing the named file.
+	Stat(name string) (os.FileInfo, error)
+}
+
+// Stat returns a FileInfo describing the named file with client's Stat method if it
+// implements Stater, or otherwise from the entry for name in its parent's ReadDir. A
+// missing file is reported as an error wrapping fs.ErrNotExist.
+func Stat(client Client, name string) (os.FileInfo, error) {
+	if s, ok := client.(Stater); ok {
+		return s.Stat(name)
+	}
+	entries, err := client.ReadDir(path.Dir(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	base := path.Base(name)
+	for _, entry := range entries {
+		if entry.Name() == base {
+			return entry.Info()
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
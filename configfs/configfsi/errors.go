@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"errors"
+	"syscall"
+)
+
+// The Is* helpers classify errors from Clients and from the report and rtmr packages,
+// which wrap the errno that configfs returned. They accept raw syscall.Errno values as
+// well as errors that wrap them.
+
+// IsBusy returns whether err is EBUSY, e.g., because a report entry is being read
+// concurrently or an rtmr index is already bound to another entry.
+func IsBusy(err error) bool {
+	return errors.Is(err, syscall.EBUSY)
+}
+
+// IsWouldBlock returns whether err is EAGAIN (EWOULDBLOCK), i.e., the operation may
+// succeed if tried again.
+func IsWouldBlock(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EWOULDBLOCK)
+}
+
+// IsInterrupted returns whether err is EINTR.
+func IsInterrupted(err error) bool {
+	return errors.Is(err, syscall.EINTR)
+}
+
+// IsNotSupported returns whether err means the kernel or TSM provider does not support the
+// operation, e.g., EOPNOTSUPP for an attribute the provider does not implement, or ENXIO
+// when no TSM provider is registered.
+func IsNotSupported(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EOPNOTSUPP, syscall.ENOTSUP, syscall.ENOSYS,
+		syscall.ENOTTY, syscall.ENXIO, syscall.ENODEV} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient returns whether err is an errno that configfs returns for conditions that
+// may clear on their own: EBUSY, EAGAIN, or EINTR.
+func IsTransient(err error) bool {
+	return IsBusy(err) || IsWouldBlock(err) || IsInterrupted(err)
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrnoHelpers(t *testing.T) {
+	wrap := func(errno syscall.Errno) error {
+		return fmt.Errorf("could not write: %w",
+			&os.PathError{Op: "write", Path: "/sys/kernel/config/tsm/report/r/inblob", Err: errno})
+	}
+	tcs := []struct {
+		name string
+		is   func(error) bool
+		yes  []error
+		no   []error
+	}{
+		{
+			name: "IsBusy",
+			is:   IsBusy,
+			yes:  []error{syscall.EBUSY, wrap(syscall.EBUSY)},
+			no:   []error{nil, syscall.EAGAIN, errors.New("busy")},
+		},
+		{
+			name: "IsWouldBlock",
+			is:   IsWouldBlock,
+			yes:  []error{syscall.EAGAIN, syscall.EWOULDBLOCK, wrap(syscall.EAGAIN)},
+			no:   []error{nil, syscall.EBUSY},
+		},
+		{
+			name: "IsNotSupported",
+			is:   IsNotSupported,
+			yes:  []error{syscall.EOPNOTSUPP, wrap(syscall.ENXIO), wrap(syscall.ENOTTY)},
+			no:   []error{nil, syscall.EINVAL, os.ErrNotExist},
+		},
+		{
+			name: "IsTransient",
+			is:   IsTransient,
+			yes:  []error{wrap(syscall.EBUSY), wrap(syscall.EAGAIN), wrap(syscall.EINTR)},
+			no:   []error{nil, wrap(syscall.EINVAL), wrap(syscall.EPERM)},
+		},
+	}
+	for _, tc := range tcs {
+		for _, err := range tc.yes {
+			if !tc.is(err) {
+				t.Errorf("%s(%v) = false, want true", tc.name, err)
+			}
+		}
+		for _, err := range tc.no {
+			if tc.is(err) {
+				t.Errorf("%s(%v) = true, want false", tc.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// FS returns a read-only io/fs.FS view of the tsm tree rooted at TsmPrefix, e.g., the name
+// "report/entry/outblob" refers to TsmPrefix + "/report/entry/outblob". Files are read
+// with client's ReadFile on their first Read, since reading a configfs attribute may have
+// side effects such as generating a report.
+func FS(client Client) fs.FS {
+	return &clientFS{client: client}
+}
+
+type clientFS struct {
+	client Client
+}
+
+func (f *clientFS) fullPath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(TsmPrefix, name), nil
+}
+
+// Open implements fs.FS.
+func (f *clientFS) Open(name string) (fs.File, error) {
+	info, err := f.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	full, _ := f.fullPath("open", name)
+	return &clientFile{fsys: f, name: name, full: full, info: info}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *clientFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := f.fullPath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "." {
+		return rootInfo{}, nil
+	}
+	info, err := Stat(f.client, full)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: unwrapPathError(err)}
+	}
+	return info, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *clientFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := f.fullPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.client.ReadDir(full)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: unwrapPathError(err)}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (f *clientFS) ReadFile(name string) ([]byte, error) {
+	full, err := f.fullPath("read", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.client.ReadFile(full)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: unwrapPathError(err)}
+	}
+	return data, nil
+}
+
+// unwrapPathError returns the cause of a *fs.PathError so that it can be rewrapped with
+// the name relative to the FS root.
+func unwrapPathError(err error) error {
+	if perr, ok := err.(*fs.PathError); ok {
+		return perr.Err
+	}
+	return err
+}
+
+// clientFile is an fs.File for a configfs attribute or directory.
+type clientFile struct {
+	fsys   *clientFS
+	name   string
+	full   string
+	info   fs.FileInfo
+	reader io.Reader
+	// dirents holds the remaining entries for ReadDir calls with n > 0.
+	dirents []fs.DirEntry
+	dirRead bool
+}
+
+func (c *clientFile) Stat() (fs.FileInfo, error) { return c.info, nil }
+
+func (c *clientFile) Close() error { return nil }
+
+func (c *clientFile) Read(p []byte) (int, error) {
+	if c.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: c.name, Err: fs.ErrInvalid}
+	}
+	if c.reader == nil {
+		data, err := c.fsys.client.ReadFile(c.full)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: c.name, Err: unwrapPathError(err)}
+		}
+		c.reader = bytes.NewReader(data)
+	}
+	return c.reader.Read(p)
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (c *clientFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !c.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: c.name, Err: fs.ErrInvalid}
+	}
+	if !c.dirRead {
+		entries, err := c.fsys.ReadDir(c.name)
+		if err != nil {
+			return nil, err
+		}
+		c.dirents = entries
+		c.dirRead = true
+	}
+	if n <= 0 {
+		result := c.dirents
+		c.dirents = nil
+		return result, nil
+	}
+	if len(c.dirents) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(c.dirents) {
+		n = len(c.dirents)
+	}
+	result := c.dirents[:n]
+	c.dirents = c.dirents[n:]
+	return result, nil
+}
+
+// rootInfo describes the root of the tsm tree, which need not be listed by its parent.
+type rootInfo struct{}
+
+func (rootInfo) Name() string       { return "." }
+func (rootInfo) Size() int64        { return 0 }
+func (rootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (rootInfo) ModTime() time.Time { return time.Time{} }
+func (rootInfo) IsDir() bool        { return true }
+func (rootInfo) Sys() any           { return nil }
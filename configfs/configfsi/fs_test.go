@@ -0,0 +1,71 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi_test
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+)
+
+func TestFS(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	entryPath, err := c.MkdirTemp(configfsi.TsmPrefix+"/report", "entry")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v, want nil", err)
+	}
+	if err := c.WriteFile(path.Join(entryPath, "inblob"), []byte("nonce")); err != nil {
+		t.Fatalf("WriteFile(inblob) = %v, want nil", err)
+	}
+	entry := path.Join("report", path.Base(entryPath))
+	fsys := configfsi.FS(c)
+
+	var walked []string
+	if err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		walked = append(walked, p)
+		return err
+	}); err != nil {
+		t.Fatalf("WalkDir() = %v, want nil", err)
+	}
+	for _, want := range []string{".", "report", entry, path.Join(entry, "outblob")} {
+		found := false
+		for _, p := range walked {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("WalkDir() visited %v, missing %q", walked, want)
+		}
+	}
+
+	out, err := fs.ReadFile(fsys, path.Join(entry, "outblob"))
+	if err != nil || !strings.Contains(string(out), "inblob: 6e6f6e6365") {
+		t.Errorf("ReadFile(outblob) = %q, %v, want the fake report", out, err)
+	}
+	info, err := fs.Stat(fsys, entry)
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat(%q) = %v, %v, want a directory", entry, info, err)
+	}
+	if _, err := fsys.Open("../report"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open(../report) = _, %v, want fs.ErrInvalid", err)
+	}
+	if _, err := fsys.Open(path.Join(entry, "nonexistent")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(nonexistent) = _, %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// Interceptor runs call, which performs the Client operation op on the named path, and
+// returns its error. It may act before and after call, call it more than once, or not at all.
+type Interceptor func(op, name string, call func() error) error
+
+// interceptedClient is a Client whose operations all go through an Interceptor.
+type interceptedClient struct {
+	client Client
+	around Interceptor
+}
+
+// Wrap returns a Client that performs every operation of client, including the optional
+// Opener, Mkdirer, Stater, and Watcher operations, through around. Wrapped clients compose,
+// e.g., LoggingClient(RetryClient(client, 3, time.Millisecond, nil), logger).
+func Wrap(client Client, around Interceptor) Client {
+	return &interceptedClient{client: client, around: around}
+}
+
+// MkdirTemp implements Client.
+func (c *interceptedClient) MkdirTemp(dir, pattern string) (string, error) {
+	var result string
+	err := c.around("MkdirTemp", dir, func() (err error) {
+		result, err = c.client.MkdirTemp(dir, pattern)
+		return err
+	})
+	return result, err
+}
+
+// ReadFile implements Client.
+func (c *interceptedClient) ReadFile(name string) ([]byte, error) {
+	var result []byte
+	err := c.around("ReadFile", name, func() (err error) {
+		result, err = c.client.ReadFile(name)
+		return err
+	})
+	return result, err
+}
+
+// ReadDir implements Client.
+func (c *interceptedClient) ReadDir(dirname string) ([]os.DirEntry, error) {
+	var result []os.DirEntry
+	err := c.around("ReadDir", dirname, func() (err error) {
+		result, err = c.client.ReadDir(dirname)
+		return err
+	})
+	return result, err
+}
+
+// WriteFile implements Client.
+func (c *interceptedClient) WriteFile(name string, contents []byte) error {
+	return c.around("WriteFile", name, func() error {
+		return c.client.WriteFile(name, contents)
+	})
+}
+
+// RemoveAll implements Client.
+func (c *interceptedClient) RemoveAll(path string) error {
+	return c.around("RemoveAll", path, func() error {
+		return c.client.RemoveAll(path)
+	})
+}
+
+// Mkdir implements Mkdirer.
+func (c *interceptedClient) Mkdir(name string) error {
+	return c.around("Mkdir", name, func() error {
+		return Mkdir(c.client, name)
+	})
+}
+
+// Open implements Opener.
+func (c *interceptedClient) Open(name string) (io.ReadCloser, error) {
+	var result io.ReadCloser
+	err := c.around("Open", name, func() (err error) {
+		result, err = Open(c.client, name)
+		return err
+	})
+	return result, err
+}
+
+// Stat implements Stater.
+func (c *interceptedClient) Stat(name string) (os.FileInfo, error) {
+	var result os.FileInfo
+	err := c.around("Stat", name, func() (err error) {
+		result, err = Stat(c.client, name)
+		return err
+	})
+	return result, err
+}
+
+// Watch implements Watcher.
+func (c *interceptedClient) Watch(ctx context.Context, entry string) (<-chan Event, error) {
+	var result <-chan Event
+	err := c.around("Watch", entry, func() (err error) {
+		result, err = Watch(ctx, c.client, entry)
+		return err
+	})
+	return result, err
+}
+
+// LoggingClient returns a Client that logs every operation of client, its duration, and
+// its error at debug level.
+func LoggingClient(client Client, logger Logger) Client {
+	return Wrap(client, func(op, name string, call func() error) error {
+		start := time.Now()
+		err := call()
+		logger.Debug("configfs "+op, "path", name, "duration", time.Since(start), "err", err)
+		return err
+	})
+}
+
+// RetryClient returns a Client that retries an operation of client up to retries more
+// times while retryable reports its error as retryable, waiting backoff before the first
+// retry and doubling the wait after each. If retryable is nil, IsTransient is used.
+func RetryClient(client Client, retries int, backoff time.Duration, retryable func(error) bool) Client {
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	return Wrap(client, func(op, name string, call func() error) error {
+		wait := backoff
+		err := call()
+		for attempt := 0; err != nil && attempt < retries && retryable(err); attempt++ {
+			time.Sleep(wait)
+			wait *= 2
+			err = call()
+		}
+		return err
+	})
+}
+
+// Tracer starts a span for a Client operation. The returned function ends the span with
+// the operation's error.
+type Tracer interface {
+	Start(op, name string) (end func(err error))
+}
+
+// TracingClient returns a Client that traces every operation of client with tracer.
+func TracingClient(client Client, tracer Tracer) Client {
+	return Wrap(client, func(op, name string, call func() error) error {
+		end := tracer.Start(op, name)
+		err := call()
+		end(err)
+		return err
+	})
+}
+
+// OpMetrics receives the latency and error of each Client operation.
+type OpMetrics interface {
+	ObserveOp(op string, latency time.Duration, err error)
+}
+
+// MetricsClient returns a Client that reports the latency and error of every operation of
+// client to metrics.
+func MetricsClient(client Client, metrics OpMetrics) Client {
+	return Wrap(client, func(op, name string, call func() error) error {
+		start := time.Now()
+		err := call()
+		metrics.ObserveOp(op, time.Since(start), err)
+		return err
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi_test
+
+import (
+	"context"
+	"errors"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/configfsitest"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+)
+
+// flakyClient fails the first failures WriteFile calls with EBUSY.
+type flakyClient struct {
+	configfsi.Client
+	failures int
+}
+
+func (c *flakyClient) WriteFile(name string, contents []byte) error {
+	if c.failures > 0 {
+		c.failures--
+		return syscall.EBUSY
+	}
+	return c.Client.WriteFile(name, contents)
+}
+
+type opRecorder struct {
+	ops []string
+}
+
+func (r *opRecorder) ObserveOp(op string, _ time.Duration, err error) {
+	if err != nil {
+		op += " failed"
+	}
+	r.ops = append(r.ops, op)
+}
+
+func TestMiddleware(t *testing.T) {
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	flaky := &flakyClient{Client: fake, failures: 3}
+	recorder := &opRecorder{}
+	// Metrics observe each attempt that the retry layer makes.
+	c := configfsi.RetryClient(configfsi.MetricsClient(flaky, recorder), 2, time.Microsecond, nil)
+
+	entry, err := c.MkdirTemp(path.Join(configfsi.TsmPrefix, "report"), "entry")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v, want nil", err)
+	}
+	inblob := path.Join(entry, "inblob")
+	if err := c.WriteFile(inblob, []byte("nonce")); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("WriteFile() = %v, want EBUSY after 2 retries", err)
+	}
+	if err := c.WriteFile(inblob, []byte("nonce")); err != nil {
+		t.Errorf("WriteFile() = %v, want nil", err)
+	}
+	if _, err := configfsi.Stat(c, inblob); err != nil {
+		t.Errorf("Stat(%q) = _, %v, want nil", inblob, err)
+	}
+	want := []string{"MkdirTemp", "WriteFile failed", "WriteFile failed", "WriteFile failed",
+		"WriteFile", "Stat"}
+	if len(recorder.ops) != len(want) {
+		t.Fatalf("observed ops %v, want %v", recorder.ops, want)
+	}
+	for i := range want {
+		if recorder.ops[i] != want[i] {
+			t.Errorf("observed ops %v, want %v", recorder.ops, want)
+			break
+		}
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	tracer := &configfsitest.Tracer{}
+	ctx, parent := tracer.Start(context.Background(), "caller")
+	stub := configfsitest.NewStub(t,
+		configfsitest.MkdirTemp("/sys/kernel/config/tsm/report", "/sys/kernel/config/tsm/report/entry", nil),
+		configfsitest.RemoveAll("/sys/kernel/config/tsm/report/entry", syscall.EBUSY))
+	c, span := configfsi.StartSpan(ctx, tracer, stub, "op", "key", 1)
+	entry, err := c.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeErr := c.RemoveAll(entry)
+	span.End(removeErr)
+	parent.End(nil)
+
+	spans := tracer.Spans()
+	if got := tracer.Names(0); len(got) != 1 || got[0] != "op" || spans[1].Attributes["key"] != 1 {
+		t.Fatalf("children of caller = %v, want op with key=1", got)
+	}
+	want := []string{"configfs.MkdirTemp", "configfs.RemoveAll"}
+	if got := tracer.Names(1); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("children of op = %v, want %v", got, want)
+	}
+	if s := spans[3]; !s.Ended || !errors.Is(s.Err, syscall.EBUSY) || s.Attributes["path"] != entry {
+		t.Errorf("RemoveAll span = %+v, want it ended with EBUSY and path %q", s, entry)
+	}
+}
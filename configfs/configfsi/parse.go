@@ -15,12 +15,167 @@
 package configfsi
 
 import (
+	"math"
 	"strconv"
-	"strings"
+	"syscall"
 )
 
-// Kstrtouint returns the unsigned integer represented in data, following the same grammar
-// allowances as Linux, i.e., data may have a single trailing newline.
+// The kstrto* functions follow the grammar of the Linux kernel's lib/kstrtox.c, which
+// parses the attributes that this package writes:
+//   - base 0 selects base 16 for a "0x" or "0X" prefix followed by a hex digit, base 8 for
+//     another leading "0", and base 10 otherwise. Base 16 also allows a "0x" prefix.
+//   - unsigned numbers may have a leading "+", signed numbers a leading "+" or "-".
+//   - the digits may be followed by a single newline and nothing else.
+// Errors are *strconv.NumError values that wrap syscall.EINVAL or syscall.ERANGE, the
+// errnos the kernel would return.
+
+// fixupRadix returns the base and the remaining digits as _parse_integer_fixup_radix does.
+func fixupRadix(s string, base int) (string, int) {
+	lower := func(i int) byte {
+		if i >= len(s) {
+			return 0
+		}
+		return s[i] | 0x20
+	}
+	if base == 0 {
+		if len(s) > 0 && s[0] == '0' {
+			if lower(1) == 'x' && len(s) > 2 && digitValue(s[2]) < 16 {
+				base = 16
+			} else {
+				base = 8
+			}
+		} else {
+			base = 10
+		}
+	}
+	if base == 16 && len(s) > 1 && s[0] == '0' && lower(1) == 'x' {
+		s = s[2:]
+	}
+	return s, base
+}
+
+// digitValue returns the value of a digit character in bases up to 16, or 16 if c is not
+// a digit.
+func digitValue(c byte) uint64 {
+	switch {
+	case '0' <= c && c <= '9':
+		return uint64(c - '0')
+	case 'a' <= c|0x20 && c|0x20 <= 'f':
+		return uint64(c|0x20-'a') + 10
+	}
+	return 16
+}
+
+// kstrtoull parses an unsigned number without a sign as _kstrtoull does.
+func kstrtoull(fn, s string, base int) (uint64, error) {
+	numErr := func(err error) error {
+		return &strconv.NumError{Func: fn, Num: s, Err: err}
+	}
+	if base != 0 && (base < 2 || base > 16) {
+		return 0, numErr(syscall.EINVAL)
+	}
+	rest, base := fixupRadix(s, base)
+	var result uint64
+	var overflow bool
+	n := 0
+	for ; n < len(rest); n++ {
+		v := digitValue(rest[n])
+		if v >= uint64(base) {
+			break
+		}
+		if result > (math.MaxUint64-v)/uint64(base) {
+			overflow = true
+		}
+		result = result*uint64(base) + v
+	}
+	if overflow {
+		return 0, numErr(syscall.ERANGE)
+	}
+	if n == 0 {
+		return 0, numErr(syscall.EINVAL)
+	}
+	rest = rest[n:]
+	if len(rest) > 0 && rest[0] == '\n' {
+		rest = rest[1:]
+	}
+	if rest != "" {
+		return 0, numErr(syscall.EINVAL)
+	}
+	return result, nil
+}
+
+// Kstrtouint returns the unsigned integer represented in data as the kernel's kstrtouint
+// family would parse it for an integer of the given bit size.
 func Kstrtouint(data []byte, base, bits int) (uint64, error) {
-	return strconv.ParseUint(strings.TrimRight(string(data), "\n"), base, bits)
+	s := string(data)
+	digits := s
+	if len(digits) > 0 && digits[0] == '+' {
+		digits = digits[1:]
+	}
+	v, err := kstrtoull("kstrtouint", digits, base)
+	if err != nil {
+		err.(*strconv.NumError).Num = s
+		return 0, err
+	}
+	if bits < 64 && v>>uint(bits) != 0 {
+		return 0, &strconv.NumError{Func: "kstrtouint", Num: s, Err: syscall.ERANGE}
+	}
+	return v, nil
+}
+
+// Kstrtoull returns the unsigned 64-bit integer represented in data as the kernel's
+// kstrtoull would parse it.
+func Kstrtoull(data []byte, base int) (uint64, error) {
+	return Kstrtouint(data, base, 64)
+}
+
+// Kstrtoint returns the signed integer represented in data as the kernel's kstrtoint
+// family would parse it for an integer of the given bit size.
+func Kstrtoint(data []byte, base, bits int) (int64, error) {
+	s := string(data)
+	negative := len(s) > 0 && s[0] == '-'
+	digits := s
+	if negative || (len(s) > 0 && s[0] == '+') {
+		digits = s[1:]
+	}
+	v, err := kstrtoull("kstrtoint", digits, base)
+	if err != nil {
+		err.(*strconv.NumError).Num = s
+		return 0, err
+	}
+	limit := uint64(1) << uint(bits-1)
+	if negative {
+		if v > limit {
+			return 0, &strconv.NumError{Func: "kstrtoint", Num: s, Err: syscall.ERANGE}
+		}
+		return -int64(v-1) - 1, nil
+	}
+	if v >= limit {
+		return 0, &strconv.NumError{Func: "kstrtoint", Num: s, Err: syscall.ERANGE}
+	}
+	return int64(v), nil
+}
+
+// Kstrtobool returns the boolean represented in data as the kernel's kstrtobool would
+// parse it. Only the first one or two characters are significant: "y", "Y", "1", "on" are
+// true, and "n", "N", "0", "off" are false, case-insensitively for "on" and "off".
+func Kstrtobool(data []byte) (bool, error) {
+	if len(data) > 0 {
+		switch data[0] {
+		case 'y', 'Y', '1':
+			return true, nil
+		case 'n', 'N', '0':
+			return false, nil
+		case 'o', 'O':
+			if len(data) > 1 {
+				switch data[1] {
+				case 'n', 'N':
+					return true, nil
+				case 'f', 'F':
+					return false, nil
+				}
+			}
+		}
+	}
+	return false, &strconv.NumError{Func: "kstrtobool", Num: string(data), Err: syscall.EINVAL}
 }
@@ -0,0 +1,131 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestKstrtouint(t *testing.T) {
+	tcs := []struct {
+		input   string
+		base    int
+		bits    int
+		want    uint64
+		wantErr error
+	}{
+		{input: "0", base: 10, bits: 64, want: 0},
+		{input: "42", base: 10, bits: 64, want: 42},
+		{input: "42\n", base: 10, bits: 64, want: 42},
+		{input: "+42", base: 10, bits: 64, want: 42},
+		{input: "42\n\n", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "42 ", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: " 42", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "-1", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "\n", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "+", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "0x10", base: 0, bits: 64, want: 16},
+		{input: "0X1f", base: 0, bits: 64, want: 31},
+		{input: "010", base: 0, bits: 64, want: 8},
+		{input: "10", base: 0, bits: 64, want: 10},
+		{input: "0", base: 0, bits: 64, want: 0},
+		{input: "0x", base: 0, bits: 64, wantErr: syscall.EINVAL},
+		{input: "08", base: 0, bits: 64, wantErr: syscall.EINVAL},
+		{input: "0x10", base: 16, bits: 64, want: 16},
+		{input: "ff", base: 16, bits: 64, want: 255},
+		{input: "0x10", base: 10, bits: 64, wantErr: syscall.EINVAL},
+		{input: "12", base: 8, bits: 64, want: 10},
+		{input: "101", base: 2, bits: 64, want: 5},
+		{input: "1", base: 17, bits: 64, wantErr: syscall.EINVAL},
+		{input: "18446744073709551615", base: 10, bits: 64, want: 18446744073709551615},
+		{input: "18446744073709551616", base: 10, bits: 64, wantErr: syscall.ERANGE},
+		{input: "99999999999999999999x", base: 10, bits: 64, wantErr: syscall.ERANGE},
+		{input: "4294967295", base: 10, bits: 32, want: 4294967295},
+		{input: "4294967296", base: 10, bits: 32, wantErr: syscall.ERANGE},
+		{input: "3", base: 10, bits: 2, want: 3},
+		{input: "4", base: 10, bits: 2, wantErr: syscall.ERANGE},
+	}
+	for _, tc := range tcs {
+		got, err := Kstrtouint([]byte(tc.input), tc.base, tc.bits)
+		if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) || got != tc.want {
+			t.Errorf("Kstrtouint(%q, %d, %d) = %d, %v, want %d, %v",
+				tc.input, tc.base, tc.bits, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestKstrtoint(t *testing.T) {
+	tcs := []struct {
+		input   string
+		bits    int
+		want    int64
+		wantErr error
+	}{
+		{input: "-1", bits: 64, want: -1},
+		{input: "+1\n", bits: 64, want: 1},
+		{input: "--1", bits: 64, wantErr: syscall.EINVAL},
+		{input: "-", bits: 64, wantErr: syscall.EINVAL},
+		{input: "-0x10", bits: 64, want: -16},
+		{input: "2147483647", bits: 32, want: 2147483647},
+		{input: "2147483648", bits: 32, wantErr: syscall.ERANGE},
+		{input: "-2147483648", bits: 32, want: -2147483648},
+		{input: "-2147483649", bits: 32, wantErr: syscall.ERANGE},
+		{input: "-9223372036854775808", bits: 64, want: -9223372036854775808},
+		{input: "9223372036854775808", bits: 64, wantErr: syscall.ERANGE},
+	}
+	for _, tc := range tcs {
+		got, err := Kstrtoint([]byte(tc.input), 0, tc.bits)
+		if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) || got != tc.want {
+			t.Errorf("Kstrtoint(%q, 0, %d) = %d, %v, want %d, %v",
+				tc.input, tc.bits, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestKstrtobool(t *testing.T) {
+	tcs := []struct {
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{input: "y", want: true},
+		{input: "Yes", want: true},
+		{input: "1\n", want: true},
+		{input: "on", want: true},
+		{input: "ON", want: true},
+		{input: "n"},
+		{input: "No"},
+		{input: "0"},
+		{input: "off"},
+		{input: "oFf"},
+		{input: "o", wantErr: true},
+		{input: "ox", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "true", wantErr: true},
+		{input: "2", wantErr: true},
+	}
+	for _, tc := range tcs {
+		got, err := Kstrtobool([]byte(tc.input))
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("Kstrtobool(%q) = %v, %v, want %v, error %v", tc.input, got, err, tc.want, tc.wantErr)
+		}
+		if err != nil && !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("Kstrtobool(%q) = _, %v, want EINVAL", tc.input, err)
+		}
+	}
+}
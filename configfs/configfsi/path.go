@@ -15,10 +15,13 @@
 package configfsi
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"strings"
+	"sync"
 )
 
 const (
@@ -27,6 +30,8 @@ const (
 
 	// How many random characters to use when replacing * in a temporary path pattern.
 	randomPathSize = 10
+	// How many names MakeTemp tries before giving up, as os.MkdirTemp does.
+	maxTempAttempts = 10000
 )
 
 // TsmPath represents a configfs file path decomposed into the components
@@ -47,6 +52,53 @@ func (p *TsmPath) String() string {
 	return path.Join(TsmPrefix, p.Subsystem, p.Entry, p.Attribute)
 }
 
+// WithEntry returns a copy of the path with the given entry and no attribute.
+func (p *TsmPath) WithEntry(entry string) *TsmPath {
+	return &TsmPath{Subsystem: p.Subsystem, Entry: entry}
+}
+
+// WithAttribute returns a copy of the path with the given attribute.
+func (p *TsmPath) WithAttribute(attribute string) *TsmPath {
+	return &TsmPath{Subsystem: p.Subsystem, Entry: p.Entry, Attribute: attribute}
+}
+
+// validComponent returns an error if a path component could refer outside of its
+// intended directory.
+func validComponent(kind, name string) error {
+	if strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%s %q contains a path separator or NUL", kind, name)
+	}
+	if name == "." || strings.Contains(name, "..") {
+		return fmt.Errorf("%s %q contains a relative path element", kind, name)
+	}
+	return nil
+}
+
+// Validate returns an error if the path does not name a subsystem, entry, or attribute
+// that String maps to exactly, e.g., because a component is missing or contains "/" or "..".
+func (p *TsmPath) Validate() error {
+	if p.Subsystem == "" {
+		return fmt.Errorf("tsm path %v has an empty subsystem", p)
+	}
+	if p.Entry == "" && p.Attribute != "" {
+		return fmt.Errorf("tsm path %v has an attribute without an entry", p)
+	}
+	if err := validComponent("subsystem", p.Subsystem); err != nil {
+		return err
+	}
+	if p.Entry != "" {
+		if err := validComponent("entry", p.Entry); err != nil {
+			return err
+		}
+	}
+	if p.Attribute != "" {
+		if err := validComponent("attribute", p.Attribute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ParseTsmPath decomposes a configfs path to TSM into its expected format, or returns
 // an error.
 func ParseTsmPath(filepath string) (*TsmPath, error) {
@@ -101,3 +153,54 @@ func TempName(rand io.Reader, pattern string) string {
 	}
 	return pattern[0:lastAsterisk] + randString + pattern[lastAsterisk+1:]
 }
+
+// ErrPatternHasSeparator is returned for a temporary path pattern that contains a path
+// separator, as os.MkdirTemp does.
+var ErrPatternHasSeparator = errors.New("pattern contains path separator")
+
+// MakeTemp calls create with names from TempName(rand, pattern) until it succeeds, and
+// returns the name it succeeded with. Names that create reports as already existing with
+// an error wrapping os.ErrExist are retried up to a limit, like os.MkdirTemp. Client
+// implementations use MakeTemp for consistent MkdirTemp semantics.
+func MakeTemp(rand io.Reader, pattern string, create func(name string) error) (string, error) {
+	return MakeTempWith(func(pattern string) string { return TempName(rand, pattern) }, pattern, create)
+}
+
+// MakeTempWith is MakeTemp with names from next(pattern) instead of random names, e.g.,
+// from SequentialNames for fakes with stable entry names.
+func MakeTempWith(next func(pattern string) string, pattern string, create func(name string) error) (string, error) {
+	if strings.ContainsRune(pattern, '/') {
+		return "", &os.PathError{Op: "mkdirtemp", Path: pattern, Err: ErrPatternHasSeparator}
+	}
+	for try := 0; try < maxTempAttempts; try++ {
+		name := next(pattern)
+		err := create(name)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+	}
+	return "", &os.PathError{Op: "mkdirtemp", Path: pattern, Err: os.ErrExist}
+}
+
+// SequentialNames returns a name generator for MakeTempWith that replaces the last "*" in
+// the pattern, or appends to the pattern, a counter that starts at 0 and is shared by all
+// patterns. It is safe for concurrent use.
+func SequentialNames() func(pattern string) string {
+	var mu sync.Mutex
+	counter := 0
+	return func(pattern string) string {
+		mu.Lock()
+		n := counter
+		counter++
+		mu.Unlock()
+		suffix := fmt.Sprintf("%d", n)
+		lastAsterisk := strings.LastIndex(pattern, "*")
+		if lastAsterisk == -1 {
+			return pattern + suffix
+		}
+		return pattern[0:lastAsterisk] + suffix + pattern[lastAsterisk+1:]
+	}
+}
@@ -15,7 +15,10 @@
 package configfsi
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
+	"os"
 	"strings"
 	"testing"
 )
@@ -44,6 +47,30 @@ func TestTsmPathString(t *testing.T) {
 	}
 }
 
+func TestTsmPathValidate(t *testing.T) {
+	report := &TsmPath{Subsystem: "report"}
+	tcs := []struct {
+		input   *TsmPath
+		wantErr string
+	}{
+		{input: report},
+		{input: report.WithEntry("r")},
+		{input: report.WithEntry("r").WithAttribute("inblob")},
+		{input: &TsmPath{}, wantErr: "empty subsystem"},
+		{input: report.WithAttribute("inblob"), wantErr: "attribute without an entry"},
+		{input: report.WithEntry("a/b"), wantErr: `entry "a/b" contains a path separator`},
+		{input: report.WithEntry(".."), wantErr: `entry ".." contains a relative path element`},
+		{input: report.WithEntry("r").WithAttribute("x..y"), wantErr: "relative path element"},
+		{input: &TsmPath{Subsystem: "."}, wantErr: `subsystem "." contains a relative path element`},
+		{input: report.WithEntry("r").WithAttribute("in\x00blob"), wantErr: "NUL"},
+	}
+	for _, tc := range tcs {
+		if err := tc.input.Validate(); !match(err, tc.wantErr) {
+			t.Errorf("%v.Validate() = %v, want %q", tc.input, err, tc.wantErr)
+		}
+	}
+}
+
 func match(err error, want string) bool {
 	if err == nil && want == "" {
 		return true
@@ -137,3 +164,51 @@ func TestTempName(t *testing.T) {
 	}
 
 }
+
+func TestMakeTemp(t *testing.T) {
+	existing := map[string]bool{}
+	create := func(name string) error {
+		if existing[name] {
+			return os.ErrExist
+		}
+		existing[name] = true
+		return nil
+	}
+	// A zero random source always produces the same name, so the second call collides on
+	// every attempt.
+	zeros := bytes.NewReader(make([]byte, randomPathSize*(maxTempAttempts+1)))
+	first, err := MakeTemp(zeros, "entry", create)
+	if err != nil || first != "entry0000000000" {
+		t.Fatalf("MakeTemp() = %q, %v, want %q", first, err, "entry0000000000")
+	}
+	if _, err := MakeTemp(zeros, "entry", create); !errors.Is(err, os.ErrExist) {
+		t.Errorf("MakeTemp(colliding) = _, %v, want os.ErrExist", err)
+	}
+	if _, err := MakeTemp(rand.Reader, "entry", create); err != nil {
+		t.Errorf("MakeTemp(rand.Reader) = _, %v, want nil", err)
+	}
+	if _, err := MakeTemp(rand.Reader, "a/b*", create); !errors.Is(err, ErrPatternHasSeparator) {
+		t.Errorf("MakeTemp(_, \"a/b*\") = _, %v, want ErrPatternHasSeparator", err)
+	}
+}
+
+func TestSequentialNames(t *testing.T) {
+	next := SequentialNames()
+	existing := map[string]bool{"entry1": true}
+	create := func(name string) error {
+		if existing[name] {
+			return os.ErrExist
+		}
+		existing[name] = true
+		return nil
+	}
+	for _, want := range []string{"entry0", "entry2", "a3.b"} {
+		pattern := "entry"
+		if want == "a3.b" {
+			pattern = "a*.b"
+		}
+		if got, err := MakeTempWith(next, pattern, create); err != nil || got != want {
+			t.Errorf("MakeTempWith(%q) = %q, %v, want %q", pattern, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// KnownSubsystems are the TSM subsystems that this module has packages for.
+var KnownSubsystems = []string{"report", "rtmrs"}
+
+// SubsystemStatus describes whether a TSM subsystem is available.
+type SubsystemStatus struct {
+	// Name is the subsystem's directory name under TsmPrefix, e.g., "report".
+	Name string
+	// Present is true if the subsystem's directory exists.
+	Present bool
+	// Usable is true if the subsystem's entries can be listed, which also requires
+	// permission to use the subsystem.
+	Usable bool
+	// Err is the reason the subsystem is not usable, if any.
+	Err error
+}
+
+// ProbeResult describes the TSM subsystems of a client.
+type ProbeResult struct {
+	// Subsystems holds the status of each known subsystem and each other subsystem that the
+	// tsm directory lists, sorted by name.
+	Subsystems []SubsystemStatus
+}
+
+// Subsystem returns the status of the named subsystem, or nil if it was not probed.
+func (r *ProbeResult) Subsystem(name string) *SubsystemStatus {
+	for i := range r.Subsystems {
+		if r.Subsystems[i].Name == name {
+			return &r.Subsystems[i]
+		}
+	}
+	return nil
+}
+
+// probeSubsystem returns the status of the named subsystem.
+func probeSubsystem(client Client, name string) SubsystemStatus {
+	status := SubsystemStatus{Name: name}
+	p := &TsmPath{Subsystem: name}
+	if _, status.Err = client.ReadDir(p.String()); status.Err == nil {
+		status.Present = true
+		status.Usable = true
+		return status
+	}
+	// The directory may exist but not be listable, e.g., without permission.
+	status.Present = !errors.Is(status.Err, fs.ErrNotExist)
+	return status
+}
+
+// Supports returns whether client has a usable TSM subsystem of the given name, e.g.,
+// "report" or "rtmrs", so that applications can degrade gracefully on older kernels.
+func Supports(client Client, subsystem string) bool {
+	return probeSubsystem(client, subsystem).Usable
+}
+
+// Probe returns the status of the known TSM subsystems and of any others that the tsm
+// directory lists. Probe does not create entries.
+func Probe(client Client) *ProbeResult {
+	names := map[string]bool{}
+	for _, name := range KnownSubsystems {
+		names[name] = true
+	}
+	if entries, err := client.ReadDir(TsmPrefix); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				names[e.Name()] = true
+			}
+		}
+	}
+	result := &ProbeResult{}
+	for name := range names {
+		result.Subsystems = append(result.Subsystems, probeSubsystem(client, name))
+	}
+	sort.Slice(result.Subsystems, func(i, j int) bool {
+		return result.Subsystems[i].Name < result.Subsystems[j].Name
+	})
+	return result
+}
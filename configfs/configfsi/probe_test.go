@@ -0,0 +1,59 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi_test
+
+import (
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+)
+
+func TestProbe(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.ReportV7(0),
+		"future": faketsm.ReportV7(0),
+	}}
+	if !configfsi.Supports(c, "report") {
+		t.Errorf("Supports(report) = false, want true")
+	}
+	if configfsi.Supports(c, "rtmrs") {
+		t.Errorf("Supports(rtmrs) = true, want false")
+	}
+	result := configfsi.Probe(c)
+	want := []configfsi.SubsystemStatus{
+		{Name: "future", Present: true, Usable: true},
+		{Name: "report", Present: true, Usable: true},
+		{Name: "rtmrs"},
+	}
+	if len(result.Subsystems) != len(want) {
+		t.Fatalf("Probe() = %+v, want %+v", result.Subsystems, want)
+	}
+	for i, got := range result.Subsystems {
+		got.Err = nil
+		if got != want[i] {
+			t.Errorf("Probe() subsystem %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if result.Subsystem("rtmrs").Err == nil {
+		t.Errorf("Probe() rtmrs error = nil, want the reason it is missing")
+	}
+
+	c.Subsystems["rtmrs"] = fakertmr.CreateRtmrSubsystem(t.TempDir())
+	if s := configfsi.Probe(c).Subsystem("rtmrs"); s == nil || !s.Usable {
+		t.Errorf("Probe() rtmrs = %+v, want usable", s)
+	}
+}
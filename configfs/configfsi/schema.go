@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+// Revision identifies a revision of the configfs-tsm ABI. Later revisions compare greater.
+type Revision int
+
+const (
+	// RevisionV7 is the report subsystem as specified in the configfs-tsm patch v7 series.
+	RevisionV7 Revision = iota + 1
+	// Revision611 adds the report service attributes of Linux 6.11.
+	Revision611
+	// RevisionRtmr adds the rtmrs subsystem.
+	RevisionRtmr
+)
+
+// String returns a short name for the revision.
+func (r Revision) String() string {
+	switch r {
+	case RevisionV7:
+		return "v7"
+	case Revision611:
+		return "6.11"
+	case RevisionRtmr:
+		return "rtmrs"
+	}
+	return "unknown"
+}
+
+// Access describes whether an attribute can be read, written, or both.
+type Access int
+
+const (
+	// ReadOnly attributes can only be read.
+	ReadOnly Access = iota + 1
+	// WriteOnly attributes can only be written.
+	WriteOnly
+	// ReadWrite attributes can be read and written.
+	ReadWrite
+)
+
+// Readable returns whether the attribute can be read.
+func (a Access) Readable() bool { return a == ReadOnly || a == ReadWrite }
+
+// Writable returns whether the attribute can be written.
+func (a Access) Writable() bool { return a == WriteOnly || a == ReadWrite }
+
+// AttributeSchema describes a known attribute of a subsystem entry.
+type AttributeSchema struct {
+	Name   string
+	Access Access
+	// Since is the first revision with the attribute.
+	Since Revision
+}
+
+// SubsystemSchema describes the attributes of a subsystem's entries.
+type SubsystemSchema struct {
+	Name       string
+	Since      Revision
+	Attributes []AttributeSchema
+}
+
+// Attribute returns the named attribute's schema, or nil if it is not known.
+func (s *SubsystemSchema) Attribute(name string) *AttributeSchema {
+	for i := range s.Attributes {
+		if s.Attributes[i].Name == name {
+			return &s.Attributes[i]
+		}
+	}
+	return nil
+}
+
+// AttributesAt returns the attributes that entries have as of the given revision.
+func (s *SubsystemSchema) AttributesAt(rev Revision) []AttributeSchema {
+	var result []AttributeSchema
+	for _, a := range s.Attributes {
+		if a.Since <= rev {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// Names returns the names of the attributes as of rev whose access satisfies match.
+func (s *SubsystemSchema) Names(rev Revision, match func(Access) bool) []string {
+	var result []string
+	for _, a := range s.AttributesAt(rev) {
+		if match(a.Access) {
+			result = append(result, a.Name)
+		}
+	}
+	return result
+}
+
+// schemas is the registry of known subsystems. Attributes are sorted by name.
+var schemas = []SubsystemSchema{
+	{
+		Name:  "report",
+		Since: RevisionV7,
+		Attributes: []AttributeSchema{
+			{Name: "auxblob", Access: ReadOnly, Since: RevisionV7},
+			{Name: "generation", Access: ReadOnly, Since: RevisionV7},
+			{Name: "inblob", Access: WriteOnly, Since: RevisionV7},
+			{Name: "manifestblob", Access: ReadOnly, Since: Revision611},
+			{Name: "outblob", Access: ReadOnly, Since: RevisionV7},
+			{Name: "privlevel", Access: WriteOnly, Since: RevisionV7},
+			{Name: "privlevel_floor", Access: ReadOnly, Since: RevisionV7},
+			{Name: "provider", Access: ReadOnly, Since: RevisionV7},
+			{Name: "service_guid", Access: WriteOnly, Since: Revision611},
+			{Name: "service_manifest_version", Access: WriteOnly, Since: Revision611},
+			{Name: "service_provider", Access: WriteOnly, Since: Revision611},
+		},
+	},
+	{
+		Name:  "rtmrs",
+		Since: RevisionRtmr,
+		Attributes: []AttributeSchema{
+			{Name: "digest", Access: ReadWrite, Since: RevisionRtmr},
+			{Name: "index", Access: ReadWrite, Since: RevisionRtmr},
+			{Name: "tcg_map", Access: ReadOnly, Since: RevisionRtmr},
+		},
+	},
+}
+
+// Schema returns the schema of the named subsystem, e.g., "report" or "rtmrs", or nil if
+// it is not known.
+func Schema(subsystem string) *SubsystemSchema {
+	for i := range schemas {
+		if schemas[i].Name == subsystem {
+			return &schemas[i]
+		}
+	}
+	return nil
+}
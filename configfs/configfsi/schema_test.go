@@ -0,0 +1,54 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	for _, s := range schemas {
+		if !sort.SliceIsSorted(s.Attributes, func(i, j int) bool { return s.Attributes[i].Name < s.Attributes[j].Name }) {
+			t.Errorf("%s attributes are not sorted by name", s.Name)
+		}
+		for _, a := range s.Attributes {
+			if a.Since < s.Since {
+				t.Errorf("%s/%s since %v predates its subsystem since %v", s.Name, a.Name, a.Since, s.Since)
+			}
+		}
+	}
+	report := Schema("report")
+	if report == nil {
+		t.Fatal("Schema(report) = nil")
+	}
+	writable := func(a Access) bool { return a.Writable() }
+	if got := strings.Join(report.Names(RevisionV7, writable), ","); got != "inblob,privlevel" {
+		t.Errorf("report writable attributes at v7 = %q, want %q", got, "inblob,privlevel")
+	}
+	if got := len(report.AttributesAt(Revision611)); got != 11 {
+		t.Errorf("report has %d attributes at 6.11, want 11", got)
+	}
+	if a := report.Attribute("manifestblob"); a == nil || a.Since != Revision611 || !a.Access.Readable() {
+		t.Errorf("report manifestblob = %+v, want readable since 6.11", a)
+	}
+	if a := Schema("rtmrs").Attribute("tcg_map"); a == nil || a.Access.Writable() {
+		t.Errorf("rtmrs tcg_map = %+v, want read-only", a)
+	}
+	if Schema("unknown") != nil {
+		t.Errorf("Schema(unknown) != nil, want nil")
+	}
+}
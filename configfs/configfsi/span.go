@@ -0,0 +1,54 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import "context"
+
+// Span is a span of a ContextTracer.
+type Span interface {
+	// SetAttributes sets attributes of the span from alternating key-value pairs.
+	SetAttributes(attrs ...any)
+	// End ends the span with the error of its operation, if any.
+	End(err error)
+}
+
+// ContextTracer starts spans as children of the span in a context, so that operations
+// join the distributed trace of their caller. An OpenTelemetry trace.Tracer adapts to it
+// by starting a span with the name and converting the pairs to attribute.KeyValues, and
+// by recording a non-nil error and setting an error status in End, as the tsmotel module
+// does.
+type ContextTracer interface {
+	// Start starts a span with the name and alternating key-value attributes as a child of
+	// any span in ctx, and returns ctx with the new span.
+	Start(ctx context.Context, name string, attrs ...any) (context.Context, Span)
+}
+
+// StartSpan starts a span with tracer and returns it along with a Client whose operations
+// on client are traced as its children. Child spans are named "configfs." and the
+// operation, e.g., "configfs.MkdirTemp" for entry creation, and have a "path" attribute.
+// A nil ctx is context.Background().
+func StartSpan(ctx context.Context, tracer ContextTracer, client Client, name string, attrs ...any) (Client, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	traced := Wrap(client, func(op, path string, call func() error) error {
+		_, child := tracer.Start(ctx, "configfs."+op, "path", path)
+		err := call()
+		child.End(err)
+		return err
+	})
+	return traced, span
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsi
+
+import (
+	"context"
+	"fmt"
+)
+
+// Event describes a change to a watched configfs entry.
+type Event struct {
+	// Name is the path of the written attribute, or of the entry itself if it was removed.
+	Name string
+	// Removed is true if the entry was removed. No further events follow.
+	Removed bool
+}
+
+// Watcher is an optional interface for Clients that can notify about changes to an entry,
+// e.g., to detect interference from another process writing the same report entry.
+type Watcher interface {
+	// Watch returns a channel that receives an Event whenever an attribute of the named
+	// entry directory is written. The channel is closed when ctx is done or after the
+	// entry is removed. Events may be dropped if the receiver falls behind.
+	Watch(ctx context.Context, entry string) (<-chan Event, error)
+}
+
+// Watch watches the named entry with client's Watch method, or returns an error if client
+// does not implement Watcher.
+func Watch(ctx context.Context, client Client, entry string) (<-chan Event, error) {
+	if w, ok := client.(Watcher); ok {
+		return w.Watch(ctx, entry)
+	}
+	return nil, fmt.Errorf("client does not support watching %q", entry)
+}
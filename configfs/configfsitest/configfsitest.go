@@ -0,0 +1,234 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configfsitest provides a scriptable configfsi.Client and assertion helpers for
+// unit tests that don't need the full behavior of faketsm.
+package configfsitest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// ErrUnexpectedCall is returned by a Stub for a call that does not match the next expected
+// call.
+var ErrUnexpectedCall = errors.New("unexpected configfs call")
+
+// Call is an expected Client operation and its canned result.
+type Call struct {
+	// Op is the Client method name, e.g., "WriteFile".
+	Op string
+	// Name is the path argument. MkdirTemp's is its dir argument.
+	Name string
+	// Contents, if non-nil, is the data WriteFile is expected to write.
+	Contents []byte
+	// Data is the result of ReadFile.
+	Data []byte
+	// Entries is the result of ReadDir.
+	Entries []os.DirEntry
+	// Path is the result of MkdirTemp.
+	Path string
+	// Err is the error the call returns.
+	Err error
+}
+
+// ReadFile returns an expected ReadFile call.
+func ReadFile(name string, data []byte, err error) Call {
+	return Call{Op: "ReadFile", Name: name, Data: data, Err: err}
+}
+
+// WriteFile returns an expected WriteFile call. If contents is nil, any data matches.
+func WriteFile(name string, contents []byte, err error) Call {
+	return Call{Op: "WriteFile", Name: name, Contents: contents, Err: err}
+}
+
+// MkdirTemp returns an expected MkdirTemp call in dir that creates path.
+func MkdirTemp(dir, path string, err error) Call {
+	return Call{Op: "MkdirTemp", Name: dir, Path: path, Err: err}
+}
+
+// ReadDir returns an expected ReadDir call that lists entries.
+func ReadDir(dir string, entries []os.DirEntry, err error) Call {
+	return Call{Op: "ReadDir", Name: dir, Entries: entries, Err: err}
+}
+
+// RemoveAll returns an expected RemoveAll call.
+func RemoveAll(path string, err error) Call {
+	return Call{Op: "RemoveAll", Name: path, Err: err}
+}
+
+// Stub is a configfsi.Client that expects a script of calls in order and returns their
+// canned results. Calls that don't match the script fail the test and return an error
+// wrapping ErrUnexpectedCall.
+type Stub struct {
+	t       testing.TB
+	mu      sync.Mutex
+	calls   []Call
+	next    int
+	written map[string][]byte
+}
+
+// NewStub returns a Stub that expects calls in order. It fails t at cleanup if any
+// expected calls were not made.
+func NewStub(t testing.TB, calls ...Call) *Stub {
+	s := &Stub{t: t, calls: calls, written: map[string][]byte{}}
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.next < len(s.calls) {
+			t.Errorf("configfsitest: %d expected calls not made, next %s(%q)",
+				len(s.calls)-s.next, s.calls[s.next].Op, s.calls[s.next].Name)
+		}
+	})
+	return s
+}
+
+// Expect appends calls to the script.
+func (s *Stub) Expect(calls ...Call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, calls...)
+}
+
+// Written returns the last data written to name and whether it was written.
+func (s *Stub) Written(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.written[name]
+	return data, ok
+}
+
+// call returns the next expected call if it matches op and name.
+func (s *Stub) call(op, name string, contents []byte) (Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= len(s.calls) {
+		s.t.Errorf("configfsitest: unexpected %s(%q) after the script ended", op, name)
+		return Call{}, fmt.Errorf("%s(%q): %w", op, name, ErrUnexpectedCall)
+	}
+	want := s.calls[s.next]
+	if want.Op != op || want.Name != name || (op == "WriteFile" && want.Contents != nil && !bytes.Equal(want.Contents, contents)) {
+		s.t.Errorf("configfsitest: call %d is %s(%q, %q), want %s(%q, %q)",
+			s.next, op, name, contents, want.Op, want.Name, want.Contents)
+		return Call{}, fmt.Errorf("%s(%q): %w", op, name, ErrUnexpectedCall)
+	}
+	s.next++
+	if op == "WriteFile" && want.Err == nil {
+		s.written[name] = bytes.Clone(contents)
+	}
+	return want, nil
+}
+
+// MkdirTemp implements configfsi.Client.
+func (s *Stub) MkdirTemp(dir, _ string) (string, error) {
+	c, err := s.call("MkdirTemp", dir, nil)
+	if err != nil {
+		return "", err
+	}
+	return c.Path, c.Err
+}
+
+// ReadFile implements configfsi.Client.
+func (s *Stub) ReadFile(name string) ([]byte, error) {
+	c, err := s.call("ReadFile", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(c.Data), c.Err
+}
+
+// ReadDir implements configfsi.Client.
+func (s *Stub) ReadDir(dirname string) ([]os.DirEntry, error) {
+	c, err := s.call("ReadDir", dirname, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Entries, c.Err
+}
+
+// WriteFile implements configfsi.Client.
+func (s *Stub) WriteFile(name string, contents []byte) error {
+	c, err := s.call("WriteFile", name, contents)
+	if err != nil {
+		return err
+	}
+	return c.Err
+}
+
+// RemoveAll implements configfsi.Client.
+func (s *Stub) RemoveAll(path string) error {
+	c, err := s.call("RemoveAll", path, nil)
+	if err != nil {
+		return err
+	}
+	return c.Err
+}
+
+// dirEntry is an os.DirEntry and fs.FileInfo returned by DirEntries.
+type dirEntry struct {
+	name string
+	mode fs.FileMode
+}
+
+func (d *dirEntry) Name() string               { return d.name }
+func (d *dirEntry) IsDir() bool                { return d.mode.IsDir() }
+func (d *dirEntry) Type() fs.FileMode          { return d.mode.Type() }
+func (d *dirEntry) Info() (fs.FileInfo, error) { return d, nil }
+func (d *dirEntry) Size() int64                { return 4096 }
+func (d *dirEntry) Mode() fs.FileMode          { return d.mode }
+func (d *dirEntry) ModTime() time.Time         { return time.Time{} }
+func (d *dirEntry) Sys() any                   { return nil }
+
+// DirEntries returns directory entries with the given names for ReadDir results. Names
+// that end in "/" are directories, and others are files.
+func DirEntries(names ...string) []os.DirEntry {
+	var result []os.DirEntry
+	for _, name := range names {
+		if strings.HasSuffix(name, "/") {
+			result = append(result, &dirEntry{name: strings.TrimSuffix(name, "/"), mode: fs.ModeDir | 0755})
+		} else {
+			result = append(result, &dirEntry{name: name, mode: 0644})
+		}
+	}
+	return result
+}
+
+// AssertErrno fails t unless err wraps want.
+func AssertErrno(t testing.TB, err error, want syscall.Errno) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("error %v does not wrap errno %v (%d)", err, want, want)
+	}
+}
+
+// AssertWritten fails t unless the last data written to name through s is want.
+func AssertWritten(t testing.TB, s *Stub, name string, want []byte) {
+	t.Helper()
+	got, ok := s.Written(name)
+	if !ok {
+		t.Errorf("%s was not written, want %q", name, want)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s was written %q, want %q", name, got, want)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsitest_test
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsitest"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+const entry = "/sys/kernel/config/tsm/report/entry0"
+
+func TestStubInjectsErrno(t *testing.T) {
+	stub := configfsitest.NewStub(t,
+		configfsitest.MkdirTemp("/sys/kernel/config/tsm/report", entry, nil),
+		configfsitest.ReadFile(entry+"/generation", []byte("0\n"), nil),
+		configfsitest.WriteFile(entry+"/inblob", []byte("nonce"), syscall.EBUSY),
+		configfsitest.RemoveAll(entry, nil),
+	)
+	_, err := report.Get(stub, &report.Request{InBlob: []byte("nonce")})
+	configfsitest.AssertErrno(t, err, syscall.EBUSY)
+	if _, ok := stub.Written(entry + "/inblob"); ok {
+		t.Errorf("Written(inblob) = _, true, want false for a failed write")
+	}
+}
+
+func TestStubRecordsWrites(t *testing.T) {
+	stub := configfsitest.NewStub(t,
+		configfsitest.WriteFile(entry+"/inblob", nil, nil),
+		configfsitest.ReadDir(entry, configfsitest.DirEntries("inblob", "sub/"), nil),
+	)
+	if err := stub.WriteFile(entry+"/inblob", []byte("any")); err != nil {
+		t.Fatalf("WriteFile() = %v, want nil", err)
+	}
+	configfsitest.AssertWritten(t, stub, entry+"/inblob", []byte("any"))
+	entries, err := stub.ReadDir(entry)
+	if err != nil || len(entries) != 2 || entries[0].IsDir() || !entries[1].IsDir() {
+		t.Errorf("ReadDir() = %v, %v, want a file and a directory", entries, err)
+	}
+}
+
+// recordingTB records test failures instead of reporting them.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(func()) {}
+
+func TestStubUnexpectedCall(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	stub := configfsitest.NewStub(tb, configfsitest.ReadFile(entry+"/outblob", nil, nil))
+	if _, err := stub.ReadFile(entry + "/auxblob"); !errors.Is(err, configfsitest.ErrUnexpectedCall) {
+		t.Errorf("ReadFile(auxblob) = _, %v, want ErrUnexpectedCall", err)
+	}
+	if len(tb.failures) != 1 {
+		t.Errorf("unexpected call reported failures %v, want 1", tb.failures)
+	}
+}
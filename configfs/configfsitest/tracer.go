@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configfsitest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// RecordedSpan is a span that a Tracer started.
+type RecordedSpan struct {
+	Name string
+	// Parent is the index of the parent span in Tracer.Spans, or -1 for a root span.
+	Parent int
+	// Attributes are the span's attributes from Start and SetAttributes.
+	Attributes map[any]any
+	Ended      bool
+	Err        error
+}
+
+// Tracer is a configfsi.ContextTracer that records its spans.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+type spanKey struct{}
+
+// recordingSpan is the configfsi.Span of a RecordedSpan.
+type recordingSpan struct {
+	tracer *Tracer
+	span   *RecordedSpan
+}
+
+func setAttributes(span *RecordedSpan, attrs []any) {
+	for i := 0; i+1 < len(attrs); i += 2 {
+		span.Attributes[attrs[i]] = attrs[i+1]
+	}
+}
+
+// Start records a span whose parent is the span in ctx, if any.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, configfsi.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent := -1
+	if i, ok := ctx.Value(spanKey{}).(int); ok {
+		parent = i
+	}
+	span := &RecordedSpan{Name: name, Parent: parent, Attributes: map[any]any{}}
+	setAttributes(span, attrs)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, len(t.spans)-1), &recordingSpan{tracer: t, span: span}
+}
+
+// SetAttributes records attributes of the span.
+func (s *recordingSpan) SetAttributes(attrs ...any) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	setAttributes(s.span, attrs)
+}
+
+// End records that the span ended with err.
+func (s *recordingSpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.Ended = true
+	s.span.Err = err
+}
+
+// Spans returns copies of the recorded spans in the order they started.
+func (t *Tracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]RecordedSpan, len(t.spans))
+	for i, s := range t.spans {
+		result[i] = *s
+	}
+	return result
+}
+
+// Names returns the names of the children of the span at index parent, or of the root
+// spans if parent is -1, in the order they started.
+func (t *Tracer) Names(parent int) []string {
+	var names []string
+	for _, s := range t.Spans() {
+		if s.Parent == parent {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
@@ -13,13 +13,11 @@
 // limitations under the License.
 
 // Package fakertmr defines a configfsi.Client for faking TSM behavior.
-// The current implementation only supports TDX.
+// The registers follow TDX's by default, or those of another architecture's Profile.
 package fakertmr
 
 import (
-	"crypto"
 	"crypto/rand"
-	"crypto/sha512"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +25,8 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
@@ -41,91 +41,184 @@ const (
 
 // RtmrSubsystem represents a fake configfs-tsm rtmr subsystem.
 type RtmrSubsystem struct {
-	// WriteAttr called on any WriteFile to an attribute.
+	// WriteAttr called on any WriteFile to an attribute while holding the entry's lock, and
+	// for the index attribute also the subsystem's lock that guards indexMap.
 	WriteAttr func(dirname string, attr string, contents []byte, indexMap map[int]bool) error
-	// ReadAttr is called on any non-InAddr key.
+	// ReadAttr is called on any non-InAddr key while holding the entry's lock for reading.
 	ReadAttr func(dirname string, attr string) ([]byte, error)
 	// Random is the source of randomness to use for MkdirTemp
 	Random io.Reader
+	// TempName, if not nil, returns the entry names for MkdirTemp instead of Random, e.g.,
+	// configfsi.SequentialNames() for entry names that are stable across test runs.
+	TempName func(pattern string) string
 	// We use a temp folder to store the rtmr entries.
 	// The path to the fake rtmr subsystem.
 	Path string
+	// profile describes the registers of the subsystem.
+	profile *Profile
+	// mu guards rtmrIndexMap, entryLocks, and the creation of entries. Index writes hold
+	// it since they claim an index.
+	mu sync.Mutex
 	// rtmrIndexMap contains set of rtmr indexes that have been initialized.
 	// If true, the rtmr index is initialized.
 	rtmrIndexMap map[int]bool
+	// entryLocks serialize the attribute accesses of each entry by name, e.g., so that
+	// concurrent extends of one register fold every digest.
+	entryLocks map[string]*sync.RWMutex
+	latencies  latencies
+	history    history
 }
 
-// RemoveAll implements configfsi.Client.
-func (r *RtmrSubsystem) RemoveAll(path string) error {
-	return errors.New("rtmr subsystem does not support RemoveAll")
+// entryLock returns the lock of the named entry.
+func (r *RtmrSubsystem) entryLock(entry string) *sync.RWMutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entryLocks == nil {
+		r.entryLocks = make(map[string]*sync.RWMutex)
+	}
+	l, ok := r.entryLocks[entry]
+	if !ok {
+		l = &sync.RWMutex{}
+		r.entryLocks[entry] = l
+	}
+	return l
+}
+
+// RemoveAll removes the named rtmr entry and releases its index, like rmdir of a configfs
+// item. Like configfs, it fails with EBUSY while another operation uses the entry, and
+// with EPERM for the subsystem directory itself.
+func (r *RtmrSubsystem) RemoveAll(name string) error {
+	r.delay("RemoveAll", "")
+	p, err := configfsi.ParseTsmPath(name)
+	if err != nil {
+		return fmt.Errorf("RemoveAll: %v", err)
+	}
+	if p.Entry == "" {
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.EPERM}
+	}
+	if p.Attribute != "" {
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	l := r.entryLock(p.Entry)
+	if !l.TryLock() {
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.EBUSY}
+	}
+	defer l.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := path.Join(r.Path, p.Entry)
+	if _, err := os.Stat(entry); err != nil {
+		delete(r.entryLocks, p.Entry)
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.ENOENT}
+	}
+	if index, ok := entryIndex(entry); ok {
+		delete(r.rtmrIndexMap, index)
+	}
+	if err := os.RemoveAll(entry); err != nil {
+		return err
+	}
+	delete(r.entryLocks, p.Entry)
+	return nil
+}
+
+// rtmrSchema describes the attributes of an rtmr entry.
+var rtmrSchema = configfsi.Schema("rtmrs")
+
+// checkAccess returns the error that configfs returns for opening the named attribute for
+// reading or writing, like EACCES for writing tcg_map, or nil if it may be opened.
+func checkAccess(name, attr string, write bool) error {
+	if attr == "" {
+		return nil
+	}
+	a := rtmrSchema.Attribute(attr)
+	if a == nil {
+		return &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+	if (write && !a.Access.Writable()) || (!write && !a.Access.Readable()) {
+		return &os.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	}
+	return nil
+}
+
+// parseIndex returns the register index that an index attribute write of content binds,
+// with the errno that the kernel returns for invalid content: it parses content as
+// kstrtou32 with base 0 does, so it accepts one trailing newline and fails with ERANGE for
+// values beyond 32 bits, and fails with EINVAL for registers the profile does not have.
+func parseIndex(content []byte, profile *Profile) (int, error) {
+	index, err := configfsi.Kstrtouint(content, 0, 32)
+	if err != nil {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			return 0, numErr.Err
+		}
+		return 0, err
+	}
+	if index >= uint64(profile.Registers) {
+		return 0, syscall.EINVAL
+	}
+	return int(index), nil
+}
+
+// entryIndex returns the register index that the entry directory is bound to, if any.
+func entryIndex(entry string) (int, bool) {
+	data, err := os.ReadFile(path.Join(entry, tsmPathIndex))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return index, err == nil
 }
 
 func readTdx(entry string, attr string) ([]byte, error) {
 	return os.ReadFile(path.Join(entry, attr))
 }
 
-func makeWriteTdx(root string) func(entry string, attr string, content []byte, indexMap map[int]bool) error {
+func makeWrite(profile *Profile) func(entry string, attr string, content []byte, indexMap map[int]bool) error {
 	return func(entry string, attr string, content []byte, indexMap map[int]bool) error {
 		switch attr {
 		case tsmRtmrDigest:
-			// Check if the content is a valid SHA384 hash.
-			if len(content) != crypto.SHA384.Size() {
+			// Check if the content is a valid digest of the profile's hash algorithm.
+			if len(content) != profile.Hash.Size() {
 				return syscall.EINVAL
 			}
 			// Check if the entry is initialized.
-			indexFile, err := os.ReadFile(filepath.Join(entry, tsmPathIndex))
-			if err != nil {
-				return err
-			}
-			rtmrIndex, err := strconv.Atoi(string(indexFile))
-			if err != nil {
-				return err
+			rtmrIndex, ok := entryIndex(entry)
+			if !ok {
+				return syscall.EINVAL
 			}
-			if rtmrIndex != 2 && rtmrIndex != 3 {
+			if !profile.extendable(rtmrIndex) {
 				return os.ErrPermission
 			}
 			oldDigest, err := os.ReadFile(filepath.Join(entry, tsmRtmrDigest))
 			if err != nil {
 				return err
 			}
-			newDigest := sha512.Sum384(append(oldDigest[:], content...))
-			if err := os.WriteFile(filepath.Join(entry, tsmRtmrDigest), newDigest[:], 0666); err != nil {
+			h := profile.Hash.New()
+			h.Write(oldDigest)
+			h.Write(content)
+			if err := os.WriteFile(filepath.Join(entry, tsmRtmrDigest), h.Sum(nil), 0666); err != nil {
 				return err
 			}
 		case tsmPathIndex:
-			rtmrIndex, e := strconv.Atoi(string(content))
-			if e != nil {
-				return fmt.Errorf("WriteTdx: %v", e)
-			}
-			if rtmrIndex < 0 || rtmrIndex > 3 {
-				return fmt.Errorf("WriteTdx: invalid rtmr index %d. Index can only be a non-negative number", rtmrIndex)
+			rtmrIndex, err := parseIndex(content, profile)
+			if err != nil {
+				return err
 			}
-			if indexMap[rtmrIndex] {
+			// An entry stays bound to its register, and a register to one entry.
+			if _, ok := entryIndex(entry); ok || indexMap[rtmrIndex] {
 				return syscall.EBUSY
 			}
 			indexMap[rtmrIndex] = true
-			if err := os.WriteFile(filepath.Join(entry, tsmPathIndex), content, 0666); err != nil {
+			if err := os.WriteFile(filepath.Join(entry, tsmPathIndex), []byte(fmt.Sprintf("%d\n", rtmrIndex)), 0666); err != nil {
 				return err
 			}
-			var rtmrPcrMaps = map[int]string{
-				0: "1,7\n",
-				1: "2-6\n",
-				2: "8-15\n",
-				3: "\n",
-			}
-			// Write the tcgmap into a temp file and rename it to keep the read-only permission.
-			tempTsmPathTcgMap := filepath.Join(root, tsmPathTcgMap)
-			if err := os.WriteFile(tempTsmPathTcgMap, []byte(rtmrPcrMaps[rtmrIndex]), 0400); err != nil {
-				return err
-			}
-			if err := os.Rename(tempTsmPathTcgMap, filepath.Join(entry, tsmPathTcgMap)); err != nil {
+			// The attribute is read-only to callers by checkAccess, not by its file mode.
+			if err := os.WriteFile(filepath.Join(entry, tsmPathTcgMap), []byte(profile.tcgMap(rtmrIndex)), 0600); err != nil {
 				return err
 			}
 			// Initialize the digest file to all zeros.
-			// SHA-384 produces a 48-byte hash.
-			const sha384Size = 48
-			digest := [sha384Size]byte{}
-			if err := os.WriteFile(filepath.Join(entry, tsmRtmrDigest), digest[:], 0666); err != nil {
+			digest := make([]byte, profile.Hash.Size())
+			if err := os.WriteFile(filepath.Join(entry, tsmRtmrDigest), digest, 0666); err != nil {
 				return err
 			}
 
@@ -141,6 +234,7 @@ func makeWriteTdx(root string) func(entry string, attr string, content []byte, i
 // ReadDir reads the directory named by dirname
 // and returns a list of directory entries sorted by filename.
 func (r *RtmrSubsystem) ReadDir(dirname string) ([]os.DirEntry, error) {
+	r.delay("ReadDir", "")
 	p, err := configfsi.ParseTsmPath(dirname)
 	if err != nil {
 		return nil, fmt.Errorf("ReadDir: %v", err)
@@ -148,11 +242,19 @@ func (r *RtmrSubsystem) ReadDir(dirname string) ([]os.DirEntry, error) {
 	if p.Entry != "" {
 		return nil, fmt.Errorf("ReadDir: rtmr tsm %q cannot have subdirectories", dirname)
 	}
-	return os.ReadDir(r.Path)
+	r.mu.Lock()
+	entries, err := os.ReadDir(r.Path)
+	r.mu.Unlock()
+	if os.IsNotExist(err) {
+		// The backing directory is created with the first entry.
+		return nil, nil
+	}
+	return entries, err
 }
 
 // MkdirTemp creates a new temporary directory in the rtmr subsystem.
 func (r *RtmrSubsystem) MkdirTemp(dir, pattern string) (string, error) {
+	r.delay("MkdirTemp", "")
 	p, err := configfsi.ParseTsmPath(dir)
 	if err != nil {
 		return "", fmt.Errorf("MkdirTemp: Error %v", err)
@@ -160,26 +262,54 @@ func (r *RtmrSubsystem) MkdirTemp(dir, pattern string) (string, error) {
 	if p.Entry != "" {
 		return "", fmt.Errorf("MkdirTemp: rtmr entry %q cannot have subdirectories", dir)
 	}
-	if err = os.MkdirAll(r.Path, 0755); err != nil {
-		return "", fmt.Errorf("MkdirTemp: %v", err)
+	r.mu.Lock()
+	name, err := r.makeTemp(pattern, r.makeEntry)
+	r.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("MkdirTemp: %w", err)
+	}
+	return path.Join(dir, name), nil
+}
+
+// Mkdir creates the named rtmr entry.
+func (r *RtmrSubsystem) Mkdir(name string) error {
+	r.delay("Mkdir", "")
+	p, err := configfsi.ParseTsmPath(name)
+	if err != nil {
+		return fmt.Errorf("Mkdir: %v", err)
+	}
+	if p.Entry == "" || p.Attribute != "" {
+		return fmt.Errorf("Mkdir: %q is not an rtmr entry path", name)
+	}
+	r.mu.Lock()
+	err = r.makeEntry(p.Entry)
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("Mkdir: %w", err)
+	}
+	return nil
+}
+
+// makeEntry creates an entry directory with empty attributes. Called while holding mu.
+func (r *RtmrSubsystem) makeEntry(name string) error {
+	if err := os.MkdirAll(r.Path, 0755); err != nil {
+		return err
 	}
-	name := configfsi.TempName(r.Random, pattern)
 	fakeRtmrPath := path.Join(r.Path, name)
-	if err = os.Mkdir(fakeRtmrPath, 0755); err != nil {
-		return "", fmt.Errorf("MkdirTemp: %v", err)
+	if err := os.Mkdir(fakeRtmrPath, 0755); err != nil {
+		return err
 	}
-	// Create empty index, digest and tcg_map files.
-	perms := []int{os.O_RDWR, os.O_RDWR, os.O_RDONLY}
-	modes := []os.FileMode{0600, 0600, 0400}
-	for i, attr := range []string{tsmPathIndex, tsmRtmrDigest, tsmPathTcgMap} {
+	// Create empty index, digest and tcg_map files. Attribute permissions are enforced by
+	// checkAccess rather than file modes, which root and some filesystems ignore.
+	for _, attr := range []string{tsmPathIndex, tsmRtmrDigest, tsmPathTcgMap} {
 		p := filepath.Join(fakeRtmrPath, attr)
-		f, err := os.OpenFile(p, perms[i]|os.O_CREATE, modes[i])
+		f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0600)
 		if err != nil {
-			return "", fmt.Errorf("MkdirTemp: %v", err)
+			return err
 		}
 		f.Close()
 	}
-	return path.Join(dir, name), nil
+	return nil
 }
 
 // ReadFile reads the contents of a file in the rtmr subsystem.
@@ -188,6 +318,13 @@ func (r *RtmrSubsystem) ReadFile(name string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("ReadFile: Error %v", err)
 	}
+	r.delay("ReadFile", p.Attribute)
+	if err := checkAccess(name, p.Attribute, false); err != nil {
+		return nil, err
+	}
+	l := r.entryLock(p.Entry)
+	l.RLock()
+	defer l.RUnlock()
 	return r.ReadAttr(path.Join(r.Path, p.Entry), p.Attribute)
 }
 
@@ -200,17 +337,50 @@ func (r *RtmrSubsystem) WriteFile(name string, content []byte) error {
 	if p.Attribute == "" {
 		return fmt.Errorf("WriteFile: no attribute specified to %q", name)
 	}
-	return r.WriteAttr(path.Join(r.Path, p.Entry), p.Attribute, content, r.rtmrIndexMap)
+	r.delay("WriteFile", p.Attribute)
+	if err := checkAccess(name, p.Attribute, true); err != nil {
+		return err
+	}
+	l := r.entryLock(p.Entry)
+	l.Lock()
+	defer l.Unlock()
+	if p.Attribute == tsmPathIndex {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+	entry := path.Join(r.Path, p.Entry)
+	if err := r.WriteAttr(entry, p.Attribute, content, r.rtmrIndexMap); err != nil {
+		return err
+	}
+	if p.Attribute == tsmRtmrDigest {
+		if index, ok := entryIndex(entry); ok {
+			r.history.record(index, content)
+		}
+	}
+	return nil
 }
 
-// CreateRtmrSubsystem creates a new rtmr subsystem.
-// The current subsystem only supports TDX.
+// CreateRtmrSubsystem creates a new rtmr subsystem with TDX's registers.
 func CreateRtmrSubsystem(tempDir string) *RtmrSubsystem {
+	return CreateProfileSubsystem(tempDir, ProfileTdx)
+}
+
+// CreateProfileSubsystem creates a new rtmr subsystem with the registers of the profile.
+func CreateProfileSubsystem(tempDir string, profile *Profile) *RtmrSubsystem {
 	return &RtmrSubsystem{
 		Random:       rand.Reader,
-		WriteAttr:    makeWriteTdx(tempDir),
+		WriteAttr:    makeWrite(profile),
+		profile:      profile,
 		ReadAttr:     readTdx,
 		Path:         path.Join(tempDir, tsmRtmrSubsystem),
 		rtmrIndexMap: make(map[int]bool),
 	}
 }
+
+// makeTemp calls configfsi.MakeTempWith with names from TempName if set, or from Random.
+func (r *RtmrSubsystem) makeTemp(pattern string, create func(name string) error) (string, error) {
+	if r.TempName != nil {
+		return configfsi.MakeTempWith(r.TempName, pattern, create)
+	}
+	return configfsi.MakeTemp(r.Random, pattern, create)
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+const rtmrPath = configfsi.TsmPrefix + "/" + tsmRtmrSubsystem
+
+func TestConcurrentExtend(t *testing.T) {
+	t.Parallel()
+	r := CreateRtmrSubsystem(t.TempDir())
+	entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("2")); err != nil {
+		t.Fatalf("WriteFile(index) = %v. Want nil", err)
+	}
+	const extends = 32
+	digest := sha512.Sum384([]byte("event"))
+	var wg sync.WaitGroup
+	for i := 0; i < extends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.WriteFile(path.Join(entry, tsmRtmrDigest), digest[:]); err != nil {
+				t.Errorf("WriteFile(digest) = %v. Want nil", err)
+			}
+		}()
+	}
+	wg.Wait()
+	digests := make([][]byte, extends)
+	for i := range digests {
+		digests[i] = digest[:]
+	}
+	if err := r.CheckFolded(2, digests...); err != nil {
+		t.Errorf("CheckFolded(2) after %d concurrent extends = %v. Want nil", extends, err)
+	}
+}
+
+func TestConcurrentIndex(t *testing.T) {
+	t.Parallel()
+	r := CreateRtmrSubsystem(t.TempDir())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+			if err != nil {
+				t.Errorf("MkdirTemp() = _, %v. Want nil", err)
+				return
+			}
+			if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("3")); err == nil {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Errorf("%d entries claimed rtmr 3. Want 1", claimed)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	r := CreateRtmrSubsystem(t.TempDir())
+	entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("2")); err != nil {
+		t.Fatalf("WriteFile(index) = %v. Want nil", err)
+	}
+	l := r.entryLock(path.Base(entry))
+	l.RLock()
+	if err := r.RemoveAll(entry); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("RemoveAll(%q) while in use = %v. Want EBUSY", entry, err)
+	}
+	l.RUnlock()
+	if err := r.RemoveAll(entry); err != nil {
+		t.Fatalf("RemoveAll(%q) = %v. Want nil", entry, err)
+	}
+	if err := r.RemoveAll(entry); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("RemoveAll(%q) again = %v. Want ENOENT", entry, err)
+	}
+	if err := r.RemoveAll(rtmrPath); !errors.Is(err, syscall.EPERM) {
+		t.Errorf("RemoveAll(%q) = %v. Want EPERM", rtmrPath, err)
+	}
+	// The removed entry's index is free to claim again.
+	entry, err = r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("2")); err != nil {
+		t.Errorf("WriteFile(index) after removal = %v. Want nil", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	r := CreateRtmrSubsystem(t.TempDir())
+	entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("3")); err != nil {
+		t.Fatalf("WriteFile(index) = %v. Want nil", err)
+	}
+	first := sha512.Sum384([]byte("first"))
+	second := sha512.Sum384([]byte("second"))
+	for _, digest := range [][48]byte{first, second} {
+		if err := r.WriteFile(path.Join(entry, tsmRtmrDigest), digest[:]); err != nil {
+			t.Fatalf("WriteFile(digest) = %v. Want nil", err)
+		}
+	}
+	// Failed extends are not recorded.
+	if err := r.WriteFile(path.Join(entry, tsmRtmrDigest), []byte("short")); err == nil {
+		t.Fatal("WriteFile(short digest) = nil. Want an error")
+	}
+	got := r.History(3)
+	if len(got) != 2 || !bytes.Equal(got[0], first[:]) || !bytes.Equal(got[1], second[:]) {
+		t.Errorf("History(3) = %x. Want [%x %x]", got, first, second)
+	}
+	if got := r.History(2); len(got) != 0 {
+		t.Errorf("History(2) = %x. Want none", got)
+	}
+	r.ClearHistory()
+	if got := r.History(3); len(got) != 0 {
+		t.Errorf("History(3) after ClearHistory = %x. Want none", got)
+	}
+}
+
+func TestAttributeAccess(t *testing.T) {
+	dir := t.TempDir()
+	r := CreateRtmrSubsystem(dir)
+	entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathIndex), []byte("2")); err != nil {
+		t.Fatalf("WriteFile(index) = %v. Want nil", err)
+	}
+	// Permissions must not depend on file modes, which root and some filesystems ignore.
+	if err := os.Chmod(path.Join(r.Path, path.Base(entry), tsmPathTcgMap), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteFile(path.Join(entry, tsmPathTcgMap), []byte("1\n")); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("WriteFile(tcg_map) = %v. Want EACCES", err)
+	}
+	if got, err := r.ReadFile(path.Join(entry, tsmPathTcgMap)); err != nil || string(got) != "8-15\n" {
+		t.Errorf("ReadFile(tcg_map) = %q, %v. Want \"8-15\\n\"", got, err)
+	}
+	for _, write := range []bool{false, true} {
+		var err error
+		if write {
+			err = r.WriteFile(path.Join(entry, "bogus"), []byte("1"))
+		} else {
+			_, err = r.ReadFile(path.Join(entry, "bogus"))
+		}
+		if !errors.Is(err, syscall.ENOENT) {
+			t.Errorf("accessing bogus attribute (write %t) = %v. Want ENOENT", write, err)
+		}
+	}
+}
+
+func TestIndexParsing(t *testing.T) {
+	// The errnos follow kstrtou32(buf, 0, &index) and the register count of the profile.
+	tcs := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{input: "2", want: "2\n"},
+		{input: "2\n", want: "2\n"},
+		{input: "+2", want: "2\n"},
+		{input: "0x2", want: "2\n"},
+		{input: "02", want: "2\n"},
+		{input: "0", want: "0\n"},
+		{input: "", wantErr: syscall.EINVAL},
+		{input: "\n", wantErr: syscall.EINVAL},
+		{input: " 2", wantErr: syscall.EINVAL},
+		{input: "2 ", wantErr: syscall.EINVAL},
+		{input: "2\n\n", wantErr: syscall.EINVAL},
+		{input: "-1", wantErr: syscall.EINVAL},
+		{input: "08", wantErr: syscall.EINVAL},
+		{input: "two", wantErr: syscall.EINVAL},
+		{input: "4", wantErr: syscall.EINVAL},
+		{input: "4294967296", wantErr: syscall.ERANGE},
+	}
+	for _, tc := range tcs {
+		r := CreateRtmrSubsystem(t.TempDir())
+		entry, err := r.MkdirTemp(rtmrPath, "rtmr")
+		if err != nil {
+			t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+		}
+		err = r.WriteFile(path.Join(entry, tsmPathIndex), []byte(tc.input))
+		if tc.wantErr != nil {
+			if err != tc.wantErr {
+				t.Errorf("WriteFile(index, %q) = %v. Want %v", tc.input, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("WriteFile(index, %q) = %v. Want nil", tc.input, err)
+			continue
+		}
+		if got, err := r.ReadFile(path.Join(entry, tsmPathIndex)); err != nil || string(got) != tc.want {
+			t.Errorf("ReadFile(index) after writing %q = %q, %v. Want %q", tc.input, got, err, tc.want)
+		}
+	}
+}
+
+func TestIndexBusy(t *testing.T) {
+	r := CreateRtmrSubsystem(t.TempDir())
+	first, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	second, err := r.MkdirTemp(rtmrPath, "rtmr")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(first, tsmPathIndex), []byte("2")); err != nil {
+		t.Fatalf("WriteFile(index 2) = %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(second, tsmPathIndex), []byte("2")); err != syscall.EBUSY {
+		t.Errorf("WriteFile(index 2) of a second entry = %v. Want EBUSY", err)
+	}
+	if err := r.WriteFile(path.Join(first, tsmPathIndex), []byte("3")); err != syscall.EBUSY {
+		t.Errorf("WriteFile(index 3) of a bound entry = %v. Want EBUSY", err)
+	}
+	if err := r.WriteFile(path.Join(second, tsmRtmrDigest), make([]byte, 48)); err != syscall.EINVAL {
+		t.Errorf("WriteFile(digest) of an unbound entry = %v. Want EINVAL", err)
+	}
+}
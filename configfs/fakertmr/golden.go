@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+)
+
+// Fold returns the value of one of the profile's registers after extending its all-zero
+// initial value with the digests in order.
+func (p *Profile) Fold(digests ...[]byte) []byte {
+	value := make([]byte, p.Hash.Size())
+	for _, digest := range digests {
+		h := p.Hash.New()
+		h.Write(value)
+		h.Write(digest)
+		value = h.Sum(nil)
+	}
+	return value
+}
+
+// FoldSHA384 returns the value of a TDX RTMR after extending its all-zero initial value
+// with the digests in order.
+func FoldSHA384(digests ...[]byte) []byte {
+	return ProfileTdx.Fold(digests...)
+}
+
+// Digest returns the current value of the register at index, or an error wrapping
+// os.ErrNotExist if no entry is bound to it.
+func (r *RtmrSubsystem) Digest(index int) ([]byte, error) {
+	r.mu.Lock()
+	dirs, err := os.ReadDir(r.Path)
+	var name string
+	for _, d := range dirs {
+		if i, ok := entryIndex(path.Join(r.Path, d.Name())); ok && i == index {
+			name = d.Name()
+			break
+		}
+	}
+	r.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no entry is bound to register %d: %w", index, os.ErrNotExist)
+	}
+	l := r.entryLock(name)
+	l.RLock()
+	defer l.RUnlock()
+	return os.ReadFile(path.Join(r.Path, name, tsmRtmrDigest))
+}
+
+// CheckFolded returns an error if the register at index does not hold the value of
+// extending its initial value with the digests in order.
+func (r *RtmrSubsystem) CheckFolded(index int, digests ...[]byte) error {
+	got, err := r.Digest(index)
+	if err != nil {
+		return err
+	}
+	if want := r.profile.Fold(digests...); !bytes.Equal(got, want) {
+		return fmt.Errorf("register %d is %x after %d extends, want %x", index, got, len(digests), want)
+	}
+	return nil
+}
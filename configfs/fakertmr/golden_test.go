@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestFoldSHA384(t *testing.T) {
+	extend := make([]byte, 48)
+	extend[0] = 0x01
+	tcs := []struct {
+		name    string
+		digests [][]byte
+		want    string
+	}{
+		{
+			name: "no extends",
+			want: "000000000000000000000000000000000000000000000000" +
+				"000000000000000000000000000000000000000000000000",
+		},
+		{
+			// SHA-384(zeros || 0x01 || zeros).
+			name:    "one extend",
+			digests: [][]byte{extend},
+			want: "dcae87d56ea61215f323a6263060b6962802b2506f80be2a" +
+				"92cb0c4a1f0680f09c14eeaa6e82c9fa9aecf9524f65c59b",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := hex.DecodeString(tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := FoldSHA384(tc.digests...); !bytes.Equal(got, want) {
+				t.Errorf("FoldSHA384() = %x. Want %x", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"bytes"
+	"sync"
+)
+
+// history holds the digests extended into each register of an RtmrSubsystem.
+type history struct {
+	mu      sync.Mutex
+	digests map[int][][]byte
+}
+
+func (h *history) record(index int, digest []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.digests == nil {
+		h.digests = make(map[int][][]byte)
+	}
+	h.digests[index] = append(h.digests[index], bytes.Clone(digest))
+}
+
+// History returns the digests extended into the register at index, in the order they
+// were extended. Tests can use it to verify the exact measurement sequence that their code
+// produced rather than just the folded register value.
+func (r *RtmrSubsystem) History(index int) [][]byte {
+	r.history.mu.Lock()
+	defer r.history.mu.Unlock()
+	result := make([][]byte, len(r.history.digests[index]))
+	for i, digest := range r.history.digests[index] {
+		result[i] = bytes.Clone(digest)
+	}
+	return result
+}
+
+// ClearHistory forgets the digests extended so far. It does not change register values.
+func (r *RtmrSubsystem) ClearHistory() {
+	r.history.mu.Lock()
+	defer r.history.mu.Unlock()
+	r.history.digests = nil
+}
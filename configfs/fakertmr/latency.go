@@ -0,0 +1,62 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyKey identifies the calls that a latency applies to.
+type latencyKey struct {
+	op        string
+	attribute string
+}
+
+// latencies holds the latencies of an RtmrSubsystem.
+type latencies struct {
+	mu      sync.Mutex
+	latency map[latencyKey]time.Duration
+}
+
+// SetLatency makes calls of the named method, e.g., "WriteFile", sleep for d before they
+// take effect. If attribute is not empty, only ReadFile and WriteFile calls on that
+// attribute sleep, and that latency replaces the method's. Zero d removes the latency.
+func (r *RtmrSubsystem) SetLatency(op, attribute string, d time.Duration) {
+	r.latencies.mu.Lock()
+	defer r.latencies.mu.Unlock()
+	key := latencyKey{op: op, attribute: attribute}
+	if d == 0 {
+		delete(r.latencies.latency, key)
+		return
+	}
+	if r.latencies.latency == nil {
+		r.latencies.latency = make(map[latencyKey]time.Duration)
+	}
+	r.latencies.latency[key] = d
+}
+
+// delay sleeps for the latency of a call, if any.
+func (r *RtmrSubsystem) delay(op, attribute string) {
+	r.latencies.mu.Lock()
+	d, ok := r.latencies.latency[latencyKey{op: op, attribute: attribute}]
+	if !ok {
+		d = r.latencies.latency[latencyKey{op: op}]
+	}
+	r.latencies.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
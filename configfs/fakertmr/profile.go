@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"crypto"
+	// Register the hash algorithms of the profiles.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Profile describes the measurement registers of an architecture, which an rtmr entry
+// binds to by index.
+type Profile struct {
+	// Name is a human-readable name of the architecture.
+	Name string
+	// Registers is the number of registers, whose indexes are 0 to Registers-1.
+	Registers int
+	// Extendable lists the indexes of the registers that guests can extend.
+	Extendable []int
+	// TcgMaps are the tcg_map attribute values by index. Missing indexes map to no PCRs.
+	TcgMaps map[int]string
+	// Hash is the register hash algorithm, which determines the digest size and how
+	// extends fold.
+	Hash crypto.Hash
+}
+
+var (
+	// ProfileTdx models Intel TDX's RTMR0 to RTMR3, of which the guest can extend RTMR2
+	// and RTMR3.
+	ProfileTdx = &Profile{
+		Name:       "tdx",
+		Registers:  4,
+		Extendable: []int{2, 3},
+		TcgMaps: map[int]string{
+			0: "1,7\n",
+			1: "2-6\n",
+			2: "8-15\n",
+		},
+		Hash: crypto.SHA384,
+	}
+	// ProfileCca models an Arm CCA realm whose index 0 is the realm initial measurement
+	// (RIM) and whose indexes 1 to 4 are the extendable REM0 to REM3, with SHA-256.
+	ProfileCca = &Profile{
+		Name:       "cca",
+		Registers:  5,
+		Extendable: []int{1, 2, 3, 4},
+		Hash:       crypto.SHA256,
+	}
+	// ProfileCove models a RISC-V CoVE TVM whose index 0 is the static measurement and
+	// whose indexes 1 to 7 are extendable runtime measurements, with SHA-384.
+	ProfileCove = &Profile{
+		Name:       "cove",
+		Registers:  8,
+		Extendable: []int{1, 2, 3, 4, 5, 6, 7},
+		Hash:       crypto.SHA384,
+	}
+)
+
+// extendable returns whether the register at index is extendable.
+func (p *Profile) extendable(index int) bool {
+	for _, i := range p.Extendable {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+// tcgMap returns the tcg_map attribute value of the register at index.
+func (p *Profile) tcgMap(index int) string {
+	if m, ok := p.TcgMaps[index]; ok {
+		return m
+	}
+	return "\n"
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakertmr
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+func TestProfileCca(t *testing.T) {
+	r := CreateProfileSubsystem(t.TempDir(), ProfileCca)
+	bind := func(index string) (string, error) {
+		entry, err := r.MkdirTemp(rtmrPath, "rem")
+		if err != nil {
+			t.Fatalf("MkdirTemp() = _, %v. Want nil", err)
+		}
+		return entry, r.WriteFile(path.Join(entry, tsmPathIndex), []byte(index))
+	}
+	if _, err := bind("5"); err == nil {
+		t.Error("WriteFile(index 5) = nil. Want an error for a register beyond REM3")
+	}
+	rim, err := bind("0")
+	if err != nil {
+		t.Fatalf("WriteFile(index 0) = %v. Want nil", err)
+	}
+	digest := sha256.Sum256([]byte("event"))
+	if err := r.WriteFile(path.Join(rim, tsmRtmrDigest), digest[:]); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("extending the RIM = %v. Want a permission error", err)
+	}
+	rem, err := bind("1")
+	if err != nil {
+		t.Fatalf("WriteFile(index 1) = %v. Want nil", err)
+	}
+	if err := r.WriteFile(path.Join(rem, tsmRtmrDigest), make([]byte, 48)); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("extending REM0 with a SHA-384 digest = %v. Want EINVAL", err)
+	}
+	if err := r.WriteFile(path.Join(rem, tsmRtmrDigest), digest[:]); err != nil {
+		t.Fatalf("extending REM0 = %v. Want nil", err)
+	}
+	if err := r.CheckFolded(1, digest[:]); err != nil {
+		t.Errorf("CheckFolded(1) = %v. Want nil", err)
+	}
+}
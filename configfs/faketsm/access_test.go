@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+func TestAttributeAccess(t *testing.T) {
+	r := Report611(0)
+	entry, err := r.MkdirTemp(path.Join(configfsi.TsmPrefix, "report"), "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteFile(path.Join(entry, "inblob"), makeNonce(1)); err != nil {
+		t.Fatalf("WriteFile(inblob) = %v. Want nil", err)
+	}
+	for _, attr := range []string{"inblob", "privlevel", "service_provider", "service_guid", "service_manifest_version"} {
+		if _, err := r.ReadFile(path.Join(entry, attr)); !errors.Is(err, syscall.EACCES) {
+			t.Errorf("ReadFile(%s) = _, %v. Want EACCES", attr, err)
+		}
+	}
+	for _, attr := range []string{"outblob", "auxblob", "generation", "provider", "privlevel_floor", "manifestblob"} {
+		if err := r.WriteFile(path.Join(entry, attr), []byte("0")); !errors.Is(err, syscall.EACCES) {
+			t.Errorf("WriteFile(%s) = %v. Want EACCES", attr, err)
+		}
+	}
+	if err := r.WriteFile(path.Join(entry, "missing"), []byte("0")); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("WriteFile(missing) = %v. Want ENOENT", err)
+	}
+	if _, err := r.ReadFile(path.Join(entry, "missing")); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("ReadFile(missing) = _, %v. Want ENOENT", err)
+	}
+	got, err := r.ReadFile(path.Join(entry, "generation"))
+	if err != nil || string(got) != "1\n" {
+		t.Errorf("generation = %q, %v. Want 1 after one write", got, err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestRemoveAllBusy(t *testing.T) {
+	r := Report611(0)
+	readAttr := r.ReadAttr
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.ReadAttr = func(e *ReportEntry, attr string) ([]byte, error) {
+		if attr == "outblob" {
+			close(started)
+			<-release
+		}
+		return readAttr(e, attr)
+	}
+	req, err := report.Create(r, &report.Request{InBlob: makeNonce(1)})
+	if err != nil {
+		t.Fatalf("report.Create() = _, %v. Want nil", err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := req.Get()
+		done <- err
+	}()
+	<-started
+	if err := req.Destroy(); !configfsi.IsBusy(err) {
+		t.Errorf("Destroy() during Get() = %v. Want EBUSY", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Get() = _, %v. Want nil", err)
+	}
+	if err := req.Destroy(); err != nil {
+		t.Errorf("Destroy() after Get() = %v. Want nil", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"io/fs"
+	"time"
+)
+
+// dirEntry is an os.DirEntry and fs.FileInfo for fake configfs directories and attributes.
+type dirEntry struct {
+	name string
+	mode fs.FileMode
+}
+
+func (d *dirEntry) Name() string               { return d.name }
+func (d *dirEntry) IsDir() bool                { return d.mode.IsDir() }
+func (d *dirEntry) Type() fs.FileMode          { return d.mode.Type() }
+func (d *dirEntry) Info() (fs.FileInfo, error) { return d, nil }
+func (d *dirEntry) Size() int64                { return 4096 }
+func (d *dirEntry) Mode() fs.FileMode          { return d.mode }
+func (d *dirEntry) ModTime() time.Time         { return time.Time{} }
+func (d *dirEntry) Sys() any                   { return nil }
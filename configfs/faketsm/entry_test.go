@@ -0,0 +1,56 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"testing"
+
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestEntryAccessors(t *testing.T) {
+	r := Report611(0)
+	req, err := report.Create(r, &report.Request{
+		InBlob:    makeNonce(1),
+		Privilege: &report.Privilege{Level: 1},
+	})
+	if err != nil {
+		t.Fatalf("report.Create() = _, %v. Want nil", err)
+	}
+	resp, err := req.Get()
+	if err != nil {
+		t.Fatalf("Get() = _, %v. Want nil", err)
+	}
+	name := resp.Entry
+	e := r.Entry(name)
+	if e == nil {
+		t.Fatalf("Entry(%q) = nil. Want the entry", name)
+	}
+	if _, write := e.Generations(); write != 2 {
+		t.Errorf("Generations() = _, %d. Want 2 writes for inblob and privlevel", write)
+	}
+	if e.Destroyed() {
+		t.Error("Destroyed() = true before Destroy. Want false")
+	}
+	if err := req.Destroy(); err != nil {
+		t.Fatalf("Destroy() = %v. Want nil", err)
+	}
+	if !e.Destroyed() {
+		t.Error("Destroyed() = false after Destroy. Want true")
+	}
+	if got := r.Entry(name); got != nil {
+		t.Errorf("Entry(%q) after Destroy = %v. Want nil", name, got)
+	}
+}
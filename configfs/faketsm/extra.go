@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// ExtraAttribute is a report entry attribute that a fake adds beyond its revision's.
+type ExtraAttribute struct {
+	Name   string
+	Access configfsi.Access
+	// Default is the initial value of a writable attribute.
+	Default []byte
+	// Read returns the value of a read-only attribute. If nil, the value is empty.
+	Read func(e *ReportEntry) ([]byte, error)
+	// Check returns an error if contents cannot be written to a writable attribute. If
+	// nil, any contents can be written.
+	Check func(contents []byte) error
+}
+
+// addExtra adds the extra attributes to the subsystem's entries.
+func addExtra(r *ReportSubsystem, extra []ExtraAttribute) {
+	byName := make(map[string]ExtraAttribute)
+	for _, a := range extra {
+		byName[a.Name] = a
+		if a.Access == configfsi.ReadOnly {
+			r.ReadableAttrs = append(append([]string(nil), r.ReadableAttrs...), a.Name)
+		}
+	}
+	sort.Strings(r.ReadableAttrs)
+	makeEntry, readAttr, checkInAttr := r.MakeEntry, r.ReadAttr, r.CheckInAttr
+	r.MakeEntry = func() *ReportEntry {
+		e := makeEntry()
+		for _, a := range extra {
+			if a.Access.Writable() {
+				e.InAttrs[a.Name] = &ReportAttributeState{
+					Value:     bytes.Clone(a.Default),
+					ReadWrite: a.Access == configfsi.ReadWrite,
+				}
+			}
+		}
+		return e
+	}
+	r.ReadAttr = func(e *ReportEntry, attr string) ([]byte, error) {
+		a, ok := byName[attr]
+		if !ok {
+			return readAttr(e, attr)
+		}
+		if a.Read == nil {
+			return nil, nil
+		}
+		return a.Read(e)
+	}
+	r.CheckInAttr = func(e *ReportEntry, attr string, contents []byte) error {
+		a, ok := byName[attr]
+		if !ok {
+			return checkInAttr(e, attr, contents)
+		}
+		if a.Check == nil {
+			return nil
+		}
+		return a.Check(contents)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestExtraAttributes(t *testing.T) {
+	r := NewReport(configfsi.Revision611, &ReportOptions{Extra: []ExtraAttribute{
+		{Name: "format", Access: configfsi.WriteOnly, Default: []byte("default"),
+			Check: func(contents []byte) error {
+				if string(contents) != "default" && string(contents) != "extended" {
+					return syscall.EINVAL
+				}
+				return nil
+			}},
+		{Name: "evidence", Access: configfsi.ReadOnly, Read: func(e *ReportEntry) ([]byte, error) {
+			return append([]byte("evidence for "), e.InAttrs["format"].Value...), nil
+		}},
+	}})
+	open, err := report.CreateOpenReport(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Destroy()
+	attrs, err := open.Attributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, a := range attrs {
+		if a.Name == "format" || a.Name == "evidence" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("Attributes() = %v. Want format and evidence", attrs)
+	}
+	if err := open.WriteOption("format", []byte("bogus")); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("WriteOption(format, bogus) = %v. Want EINVAL", err)
+	}
+	if err := open.WriteOption("format", []byte("extended")); err != nil {
+		t.Fatalf("WriteOption(format) = %v. Want nil", err)
+	}
+	got, err := open.ReadOption("evidence")
+	if err != nil || string(got) != "evidence for extended" {
+		t.Errorf("ReadOption(evidence) = %q, %v. Want %q", got, err, "evidence for extended")
+	}
+	var entry string
+	for name := range r.Entries {
+		entry = path.Join(configfsi.TsmPrefix, "report", name)
+	}
+	if _, err := r.ReadFile(path.Join(entry, "format")); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("ReadFile(format) = _, %v. Want EACCES", err)
+	}
+}
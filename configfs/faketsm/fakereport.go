@@ -16,14 +16,18 @@ package faketsm
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"unicode/utf8"
 
@@ -48,8 +52,12 @@ type ReportAttributeState struct {
 
 // ReportEntry represents a report entry in the TSM report subsystem.
 type ReportEntry struct {
-	mu              sync.RWMutex
-	destroyed       bool
+	mu        sync.RWMutex
+	destroyed bool
+	// inFlight counts the ReadFile and WriteFile calls using the entry, which make
+	// RemoveAll fail with EBUSY like configfs. Changed with atomic operations while
+	// holding the client lock.
+	inFlight        int32
 	ReadGeneration  uint64
 	WriteGeneration uint64
 	// InAttrs represents the value of all WO attributes by name (relative to entry).
@@ -74,6 +82,41 @@ type ReportSubsystem struct {
 	Entries   map[string]*ReportEntry
 	// Random is the source of randomness to use for MkdirTemp
 	Random io.Reader
+	// TempName, if not nil, returns the entry names for MkdirTemp instead of Random, e.g.,
+	// configfsi.SequentialNames() for entry names that are stable across test runs.
+	TempName func(pattern string) string
+	// ReadableAttrs lists the read-only attributes of every entry for ReadDir.
+	ReadableAttrs []string
+	// watchers holds the channels of Watch calls by entry name. Guarded by mu.
+	watchers map[string][]chan configfsi.Event
+	faults   faults
+}
+
+// Generations returns the entry's read and write generations. The write generation is
+// the generation attribute's value, which advances on every attribute write.
+func (e *ReportEntry) Generations() (read, write uint64) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ReadGeneration, e.WriteGeneration
+}
+
+// Destroyed returns whether the entry has been removed.
+func (e *ReportEntry) Destroyed() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.destroyed
+}
+
+// Entry returns the named report entry, or nil if there is none. The entry is a report
+// entry path or just its name. The returned entry stays valid for assertions after it is
+// removed.
+func (r *ReportSubsystem) Entry(entry string) *ReportEntry {
+	if p, err := configfsi.ParseTsmPath(entry); err == nil {
+		entry = p.Entry
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Entries[entry]
 }
 
 // Called while mu is held
@@ -98,16 +141,24 @@ func (r *ReportSubsystem) MkdirTemp(dir, pattern string) (string, error) {
 	if p.Entry != "" {
 		return "", fmt.Errorf("report entry %q cannot have subdirectories", dir)
 	}
+	if err := r.before("MkdirTemp", ""); err != nil {
+		return "", err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.Entries == nil {
 		r.Entries = make(map[string]*ReportEntry)
 	}
-	name := configfsi.TempName(r.Random, pattern)
-	if _, ok := r.Entries[name]; ok {
-		return "", os.ErrExist
+	name, err := r.makeTemp(pattern, func(name string) error {
+		if _, ok := r.Entries[name]; ok {
+			return os.ErrExist
+		}
+		r.Entries[name] = r.MakeEntry()
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
-	r.Entries[name] = r.MakeEntry()
 	return path.Join(dir, name), nil
 }
 
@@ -127,7 +178,7 @@ func (e *ReportEntry) readCached(attr string) ([]byte, error) {
 	}
 	if a, ok := e.InAttrs[attr]; ok {
 		if !a.ReadWrite {
-			return nil, fmt.Errorf("%q is not readable", attr)
+			return nil, syscall.EACCES
 		}
 		return bytes.Clone(a.Value), nil
 	}
@@ -139,13 +190,67 @@ func (e *ReportEntry) readCached(attr string) ([]byte, error) {
 			return nil, nil
 		}
 	}
-	return nil, os.ErrNotExist
+	// Not yet read, so defer to ReadAttr.
+	return nil, syscall.EWOULDBLOCK
+}
+
+// readable returns false if attr is a write-only attribute of the entry.
+func (e *ReportEntry) readable(attr string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	a, ok := e.InAttrs[attr]
+	return !ok || a.ReadWrite
+}
 
+// isReadable returns whether attr is a read-only attribute of every entry.
+func (r *ReportSubsystem) isReadable(attr string) bool {
+	for _, name := range r.ReadableAttrs {
+		if name == attr {
+			return true
+		}
+	}
+	return false
 }
 
 // ReadDir reads the directory named by dirname and returns a list of directory entries sorted by filename.
 func (r *ReportSubsystem) ReadDir(dirname string) ([]os.DirEntry, error) {
-	return nil, errors.New("report subsystem does not support ReadDir")
+	p, err := configfsi.ParseTsmPath(dirname)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDir: %v", err)
+	}
+	if p.Attribute != "" {
+		return nil, syscall.ENOTDIR
+	}
+	if err := r.before("ReadDir", ""); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var result []os.DirEntry
+	if p.Entry == "" {
+		for name := range r.Entries {
+			result = append(result, &dirEntry{name: name, mode: fs.ModeDir | 0755})
+		}
+	} else {
+		e, ok := r.Entries[p.Entry]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		e.mu.RLock()
+		for name, a := range e.InAttrs {
+			mode := fs.FileMode(0200)
+			if a.ReadWrite {
+				mode = 0644
+			}
+			result = append(result, &dirEntry{name: name, mode: mode})
+		}
+		e.mu.RUnlock()
+		for _, name := range r.ReadableAttrs {
+			result = append(result, &dirEntry{name: name, mode: 0444})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
 }
 
 // ReadFile reads the named file and returns the contents.
@@ -157,16 +262,25 @@ func (r *ReportSubsystem) ReadFile(name string) ([]byte, error) {
 	if p.Attribute == "" {
 		return nil, fmt.Errorf("not an attribute: %q", name)
 	}
-	r.mu.RLock()
-	if r.Entries == nil {
-		return nil, os.ErrNotExist
+	if err := r.before("ReadFile", p.Attribute); err != nil {
+		return nil, err
+	}
+	if err := r.interfere("ReadFile", p); err != nil {
+		return nil, err
 	}
+	r.mu.RLock()
 	e, ok := r.Entries[p.Entry]
 	if !ok {
 		r.mu.RUnlock()
 		return nil, os.ErrNotExist
 	}
+	atomic.AddInt32(&e.inFlight, 1)
+	defer atomic.AddInt32(&e.inFlight, -1)
 	r.mu.RUnlock()
+	// Like configfs, refuse to open write-only attributes for reading.
+	if !e.readable(p.Attribute) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	}
 	if b, err := e.readCached(p.Attribute); (err == nil && len(b) != 0) || err != syscall.EWOULDBLOCK {
 		return b, err
 	}
@@ -182,7 +296,7 @@ func (r *ReportSubsystem) ReadFile(name string) ([]byte, error) {
 	e.ROAttrs[p.Attribute] = nil
 	b, err := r.ReadAttr(e, p.Attribute)
 	if err != nil {
-		return nil, fmt.Errorf("ReadAttr(_, %q): %v", p.Attribute, err)
+		return nil, fmt.Errorf("ReadAttr(_, %q): %w", p.Attribute, err)
 	}
 	e.ROAttrs[p.Attribute] = b
 	return b, nil
@@ -198,24 +312,41 @@ func (r *ReportSubsystem) WriteFile(name string, contents []byte) error {
 	if p.Attribute == "" {
 		return fmt.Errorf("cannot write to non-attribute: %q", name)
 	}
+	if err := r.before("WriteFile", p.Attribute); err != nil {
+		return err
+	}
+	if err := r.interfere("WriteFile", p); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	e, ok := r.Entries[p.Entry]
 	if !ok {
 		return os.ErrNotExist
 	}
+	atomic.AddInt32(&e.inFlight, 1)
+	defer atomic.AddInt32(&e.inFlight, -1)
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	if e.destroyed {
 		return os.ErrNotExist
 	}
+	// Like configfs, refuse to open read-only attributes for writing.
+	if _, ok := e.InAttrs[p.Attribute]; !ok {
+		errno := syscall.ENOENT
+		if r.isReadable(p.Attribute) {
+			errno = syscall.EACCES
+		}
+		return &os.PathError{Op: "open", Path: name, Err: errno}
+	}
 	if err := r.CheckInAttr(e, p.Attribute, contents); err != nil {
-		return fmt.Errorf("could not write %q: %v", name, err)
+		return fmt.Errorf("could not write %q: %w", name, err)
 	}
 	if err := e.tryAdvanceWriteGeneration(); err != nil {
 		return err
 	}
 	e.InAttrs[p.Attribute].Value = contents
+	r.notify(p.Entry, configfsi.Event{Name: name})
 	return nil
 }
 
@@ -228,6 +359,9 @@ func (r *ReportSubsystem) RemoveAll(name string) error {
 	if p.Attribute != "" || p.Entry == "" || p.Subsystem != subsystemName {
 		return fmt.Errorf("RemoveAll(%q) expected report subsystem entry path", name)
 	}
+	if err := r.before("RemoveAll", ""); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.Entries == nil {
@@ -237,14 +371,72 @@ func (r *ReportSubsystem) RemoveAll(name string) error {
 	if !ok {
 		return os.ErrNotExist
 	}
-	// Don't delete while another operation is using the entry.
+	// Like configfs, refuse to remove an entry while another operation is using it.
+	if atomic.LoadInt32(&e.inFlight) != 0 {
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.EBUSY}
+	}
 	e.mu.Lock()
 	e.destroyed = true
 	delete(r.Entries, p.Entry)
 	e.mu.Unlock()
+	r.notify(p.Entry, configfsi.Event{Name: name, Removed: true})
+	for _, ch := range r.watchers[p.Entry] {
+		close(ch)
+	}
+	delete(r.watchers, p.Entry)
 	return nil
 }
 
+// watchBuffer is the number of events a fake watcher holds before dropping events.
+const watchBuffer = 16
+
+// Watch returns a channel that receives an Event for each write to an attribute of the
+// named entry and for its removal.
+func (r *ReportSubsystem) Watch(ctx context.Context, entry string) (<-chan configfsi.Event, error) {
+	p, err := configfsi.ParseTsmPath(entry)
+	if err != nil {
+		return nil, fmt.Errorf("Watch: %v", err)
+	}
+	if p.Attribute != "" || p.Entry == "" {
+		return nil, fmt.Errorf("Watch(%q) expected report subsystem entry path", entry)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.Entries[p.Entry]; !ok {
+		return nil, os.ErrNotExist
+	}
+	if r.watchers == nil {
+		r.watchers = make(map[string][]chan configfsi.Event)
+	}
+	ch := make(chan configfsi.Event, watchBuffer)
+	r.watchers[p.Entry] = append(r.watchers[p.Entry], ch)
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// The channel is already closed if the entry was removed.
+		chans := r.watchers[p.Entry]
+		for i, c := range chans {
+			if c == ch {
+				r.watchers[p.Entry] = append(chans[:i], chans[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// notify sends ev to the entry's watchers without blocking. Called while holding mu.
+func (r *ReportSubsystem) notify(entry string, ev configfsi.Event) {
+	for _, ch := range r.watchers[entry] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
 func renderOutBlob(privlevel, inblob []byte) []byte {
 	// checkv7 already ensures this does not error
 	priv, _ := configfsi.Kstrtouint(privlevel, renderBase, 2)
@@ -253,27 +445,81 @@ func renderOutBlob(privlevel, inblob []byte) []byte {
 		hex.EncodeToString(inblob)))
 }
 
-func readV7(privlevelFloor uint) func(*ReportEntry, string) ([]byte, error) {
+// ReportOptions configures the provider that a fake report subsystem mirrors.
+type ReportOptions struct {
+	// Provider is the provider attribute value without a trailing newline. Empty means
+	// "fake".
+	Provider string
+	// PrivlevelFloor is the privlevel_floor attribute value.
+	PrivlevelFloor uint
+	// AuxBlob is the auxblob attribute value. Nil means "auxblob".
+	AuxBlob []byte
+	// InBlobMax is the largest accepted inblob size. Zero means 64.
+	InBlobMax int
+	// InBlobSize, if positive, is the exact inblob size the provider requires. Like the
+	// tdx_guest provider, reading outblob after writing an inblob of any other size fails
+	// with EINVAL.
+	InBlobSize int
+	// Extra are attributes beyond the revision's, e.g., ones that proposed configfs-tsm
+	// changes add, so that callers can develop against them before kernels ship them.
+	Extra []ExtraAttribute
+}
+
+func (o *ReportOptions) provider() string {
+	if o.Provider == "" {
+		return "fake"
+	}
+	return o.Provider
+}
+
+func (o *ReportOptions) auxBlob() []byte {
+	if o.AuxBlob == nil {
+		return []byte(`auxblob`)
+	}
+	return bytes.Clone(o.AuxBlob)
+}
+
+func (o *ReportOptions) inBlobMax() int {
+	if o.InBlobMax == 0 {
+		return tsmInBlobSize
+	}
+	return o.InBlobMax
+}
+
+// inBlob returns the entry's inblob for rendering an outblob, or EINVAL if it is unset or
+// not of the required size.
+func (o *ReportOptions) inBlob(e *ReportEntry) ([]byte, error) {
+	inblob, ok := e.InAttrs["inblob"]
+	if !ok || len(inblob.Value) == 0 {
+		return nil, syscall.EINVAL
+	}
+	if o.InBlobSize > 0 && len(inblob.Value) != o.InBlobSize {
+		return nil, syscall.EINVAL
+	}
+	return inblob.Value, nil
+}
+
+func readV7(opts *ReportOptions) func(*ReportEntry, string) ([]byte, error) {
 	return func(e *ReportEntry, attr string) ([]byte, error) {
 		switch attr {
 		case "provider":
-			return []byte("fake\n"), nil
+			return []byte(opts.provider() + "\n"), nil
 		case "auxblob":
-			return []byte(`auxblob`), nil
+			return opts.auxBlob(), nil
 		case "outblob":
 			privlevel := []byte("<missing>")
 			if a, ok := e.InAttrs["privlevel"]; ok && len(a.Value) > 0 {
 				privlevel = a.Value
 			}
-			inblob, ok := e.InAttrs["inblob"]
-			if !ok || len(inblob.Value) == 0 {
-				return nil, syscall.EINVAL
+			inblob, err := opts.inBlob(e)
+			if err != nil {
+				return nil, err
 			}
-			return renderOutBlob(privlevel, inblob.Value), nil
+			return renderOutBlob(privlevel, inblob), nil
 		case "privlevel_floor":
-			return []byte(fmt.Sprintf("%d\n", privlevelFloor)), nil
+			return []byte(fmt.Sprintf("%d\n", opts.PrivlevelFloor)), nil
 		}
-		return nil, os.ErrNotExist
+		return nil, syscall.ENOENT
 	}
 }
 
@@ -286,11 +532,11 @@ func makeV7() *ReportEntry {
 	}
 }
 
-func checkV7(privlevelFloor uint) func(*ReportEntry, string, []byte) error {
+func checkV7(opts *ReportOptions) func(*ReportEntry, string, []byte) error {
 	return func(e *ReportEntry, attr string, contents []byte) error {
 		switch attr {
 		case "inblob":
-			if len(contents) > tsmInBlobSize {
+			if len(contents) > opts.inBlobMax() {
 				return syscall.EINVAL
 			}
 		case "privlevel":
@@ -301,9 +547,9 @@ func checkV7(privlevelFloor uint) func(*ReportEntry, string, []byte) error {
 			if err != nil {
 				return ErrPrivLevelFormat
 			}
-			if uint(level) < privlevelFloor {
+			if uint(level) < opts.PrivlevelFloor {
 				return fmt.Errorf("privlevel %d cannot be less than %d",
-					level, privlevelFloor)
+					level, opts.PrivlevelFloor)
 			}
 		default:
 			return fmt.Errorf("unwritable attribute: %q", attr)
@@ -312,8 +558,8 @@ func checkV7(privlevelFloor uint) func(*ReportEntry, string, []byte) error {
 	}
 }
 
-func read611(privlevelFloor uint) func(*ReportEntry, string) ([]byte, error) {
-	fallback := readV7(privlevelFloor)
+func read611(opts *ReportOptions) func(*ReportEntry, string) ([]byte, error) {
+	fallback := readV7(opts)
 
 	return func(e *ReportEntry, attr string) ([]byte, error) {
 		switch attr {
@@ -326,8 +572,8 @@ func read611(privlevelFloor uint) func(*ReportEntry, string) ([]byte, error) {
 	}
 }
 
-func check611(privlevelFloor uint) func(*ReportEntry, string, []byte) error {
-	fallback := checkV7(privlevelFloor)
+func check611(opts *ReportOptions) func(*ReportEntry, string, []byte) error {
+	fallback := checkV7(opts)
 	return func(e *ReportEntry, attr string, contents []byte) error {
 		switch attr {
 		case "service_provider":
@@ -349,30 +595,68 @@ func check611(privlevelFloor uint) func(*ReportEntry, string, []byte) error {
 
 func make611() *ReportEntry {
 	res := makeV7()
-	res.InAttrs["service_provider"] = &ReportAttributeState{}
-	res.InAttrs["service_guid"] = &ReportAttributeState{}
-	res.InAttrs["service_manifest_version"] = &ReportAttributeState{}
+	for _, a := range reportSchema.AttributesAt(configfsi.Revision611) {
+		if a.Since == configfsi.Revision611 && a.Access.Writable() {
+			res.InAttrs[a.Name] = &ReportAttributeState{}
+		}
+	}
 	return res
 }
 
+var (
+	reportSchema = configfsi.Schema(subsystemName)
+	readOnly     = func(a configfsi.Access) bool { return a == configfsi.ReadOnly }
+	readableV7   = reportSchema.Names(configfsi.RevisionV7, readOnly)
+	readable611  = reportSchema.Names(configfsi.Revision611, readOnly)
+)
+
 // ReportV7 returns an empty report subsystem with attributes as specified in the configfs-tsm
 // Patch v7 series.
 func ReportV7(privlevelFloor uint) *ReportSubsystem {
-	return &ReportSubsystem{
-		MakeEntry:   makeV7,
-		ReadAttr:    readV7(privlevelFloor),
-		CheckInAttr: checkV7(privlevelFloor),
-		Random:      rand.Reader,
-	}
+	return NewReport(configfsi.RevisionV7, &ReportOptions{PrivlevelFloor: privlevelFloor})
 }
 
 // Report611 returns an empty report subsystem with attributes as specified in configfs-tsm
 // as of Linux 6.11.
 func Report611(privlevelFloor uint) *ReportSubsystem {
-	return &ReportSubsystem{
-		MakeEntry:   make611,
-		ReadAttr:    read611(privlevelFloor),
-		CheckInAttr: check611(privlevelFloor),
-		Random:      rand.Reader,
+	return NewReport(configfsi.Revision611, &ReportOptions{PrivlevelFloor: privlevelFloor})
+}
+
+// NewReport returns an empty report subsystem with the attributes of the given revision,
+// configfsi.RevisionV7 or later, for a provider as configured by opts. A nil opts is the
+// "fake" provider with a privlevel_floor of 0.
+func NewReport(rev configfsi.Revision, opts *ReportOptions) *ReportSubsystem {
+	if opts == nil {
+		opts = &ReportOptions{}
+	}
+	var r *ReportSubsystem
+	if rev < configfsi.Revision611 {
+		r = &ReportSubsystem{
+			MakeEntry:     makeV7,
+			ReadAttr:      readV7(opts),
+			CheckInAttr:   checkV7(opts),
+			Random:        rand.Reader,
+			ReadableAttrs: readableV7,
+		}
+	} else {
+		r = &ReportSubsystem{
+			MakeEntry:     make611,
+			ReadAttr:      read611(opts),
+			CheckInAttr:   check611(opts),
+			Random:        rand.Reader,
+			ReadableAttrs: readable611,
+		}
+	}
+	if len(opts.Extra) != 0 {
+		addExtra(r, opts.Extra)
+	}
+	return r
+}
+
+// makeTemp calls configfsi.MakeTempWith with names from TempName if set, or from Random.
+func (r *ReportSubsystem) makeTemp(pattern string, create func(name string) error) (string, error) {
+	if r.TempName != nil {
+		return configfsi.MakeTempWith(r.TempName, pattern, create)
 	}
+	return configfsi.MakeTemp(r.Random, pattern, create)
 }
@@ -16,6 +16,7 @@ package faketsm
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/big"
 	"path"
@@ -177,3 +178,33 @@ func BenchmarkReportGenerationInterference(b *testing.B) {
 func BenchmarkReportGenerationNoninterference(b *testing.B) {
 	noninterferenceByDesign(b, 20, b.N)
 }
+
+func TestWatch(t *testing.T) {
+	c := &Client{Subsystems: map[string]configfsi.Client{"report": ReportV7(0)}}
+	entry, err := c.MkdirTemp(path.Join(configfsi.TsmPrefix, "report"), "entry")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v, want nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := configfsi.Watch(ctx, c, entry)
+	if err != nil {
+		t.Fatalf("Watch(%q) = _, %v, want nil", entry, err)
+	}
+	inblob := path.Join(entry, "inblob")
+	if err := c.WriteFile(inblob, []byte("nonce")); err != nil {
+		t.Fatalf("WriteFile(inblob) = %v, want nil", err)
+	}
+	if ev := <-events; ev != (configfsi.Event{Name: inblob}) {
+		t.Errorf("first event = %+v, want write to %q", ev, inblob)
+	}
+	if err := c.RemoveAll(entry); err != nil {
+		t.Fatalf("RemoveAll(%q) = %v, want nil", entry, err)
+	}
+	if ev := <-events; ev != (configfsi.Event{Name: entry, Removed: true}) {
+		t.Errorf("second event = %+v, want removal of %q", ev, entry)
+	}
+	if ev, ok := <-events; ok {
+		t.Errorf("event after removal = %+v, want closed channel", ev)
+	}
+}
@@ -19,8 +19,13 @@
 package faketsm
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
+	"sort"
+	"sync"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
 )
@@ -28,7 +33,38 @@ import (
 // Client provides a "fake" provider for configfs to emulate the /sys/kernel/config/tsm behavior.
 // Dispatches to specialized subsystem Client interfaces.
 type Client struct {
+	// Subsystems are the subsystem implementations by name. Use Register to add one while
+	// the client is in use.
 	Subsystems map[string]configfsi.Client
+	// Recorder, if not nil, records every call to the client.
+	Recorder *Recorder
+	mu       sync.RWMutex
+}
+
+// Register adds sub, e.g., a third-party fake, as the named subsystem. Calls on paths in
+// the subsystem, as parsed by configfsi.ParseTsmPath, are routed to it. Register returns
+// an error wrapping os.ErrExist if the subsystem is already registered.
+func (c *Client) Register(name string, sub configfsi.Client) error {
+	if err := (&configfsi.TsmPath{Subsystem: name}).Validate(); err != nil {
+		return fmt.Errorf("faketsm: invalid subsystem name: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Subsystems[name]; ok {
+		return fmt.Errorf("faketsm: subsystem %q: %w", name, os.ErrExist)
+	}
+	if c.Subsystems == nil {
+		c.Subsystems = make(map[string]configfsi.Client)
+	}
+	c.Subsystems[name] = sub
+	return nil
+}
+
+// Unregister removes the named subsystem.
+func (c *Client) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.Subsystems, name)
 }
 
 func (c *Client) getSubsystem(name string) (configfsi.Client, error) {
@@ -39,18 +75,31 @@ func (c *Client) getSubsystem(name string) (configfsi.Client, error) {
 	if p.Subsystem == "" {
 		return nil, fmt.Errorf("faketsm: expected tsm subsystem in %q", name)
 	}
+	c.mu.RLock()
 	sub, ok := c.Subsystems[p.Subsystem]
+	c.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("faketsm: unsupported subsystem %q", p.Subsystem)
+		return nil, fmt.Errorf("faketsm: unsupported subsystem %q: %w", p.Subsystem, os.ErrNotExist)
 	}
 	return sub, nil
 }
 
 // ReadDir reads the directory named by dir and returns a list of directory entries.
-func (c *Client) ReadDir(dir string) ([]os.DirEntry, error) {
+func (c *Client) ReadDir(dir string) (entries []os.DirEntry, err error) {
+	defer func() { c.Recorder.record(Op{Method: "ReadDir", Path: dir, Err: err}) }()
 	if dir == "" {
 		return nil, fmt.Errorf("faketsm doesn't implement empty directory behavior")
 	}
+	if path.Clean(dir) == configfsi.TsmPrefix {
+		var result []os.DirEntry
+		c.mu.RLock()
+		for name := range c.Subsystems {
+			result = append(result, &dirEntry{name: name, mode: fs.ModeDir | 0755})
+		}
+		c.mu.RUnlock()
+		sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+		return result, nil
+	}
 	sub, err := c.getSubsystem(dir)
 	if err != nil {
 		return nil, err
@@ -60,7 +109,10 @@ func (c *Client) ReadDir(dir string) ([]os.DirEntry, error) {
 
 // MkdirTemp creates a new temporary directory in the directory dir and returns the pathname
 // of the new directory. Pattern semantics follow os.MkdirTemp.
-func (c *Client) MkdirTemp(dir, pattern string) (string, error) {
+func (c *Client) MkdirTemp(dir, pattern string) (created string, err error) {
+	defer func() {
+		c.Recorder.record(Op{Method: "MkdirTemp", Path: dir, Pattern: pattern, Created: created, Err: err})
+	}()
 	if dir == "" {
 		return "", fmt.Errorf("faketsm doesn't implement empty directory behavior")
 	}
@@ -71,8 +123,19 @@ func (c *Client) MkdirTemp(dir, pattern string) (string, error) {
 	return sub.MkdirTemp(dir, pattern)
 }
 
+// Mkdir creates the named directory if its subsystem supports it.
+func (c *Client) Mkdir(name string) (err error) {
+	defer func() { c.Recorder.record(Op{Method: "Mkdir", Path: name, Err: err}) }()
+	sub, err := c.getSubsystem(name)
+	if err != nil {
+		return err
+	}
+	return configfsi.Mkdir(sub, name)
+}
+
 // ReadFile reads the named file and returns the contents.
-func (c *Client) ReadFile(name string) ([]byte, error) {
+func (c *Client) ReadFile(name string) (data []byte, err error) {
+	defer func() { c.Recorder.record(Op{Method: "ReadFile", Path: name, Contents: data, Err: err}) }()
 	sub, err := c.getSubsystem(name)
 	if err != nil {
 		return nil, err
@@ -82,7 +145,8 @@ func (c *Client) ReadFile(name string) ([]byte, error) {
 
 // WriteFile writes data to the named file, creating it if necessary. The permissions
 // are implementation-defined.
-func (c *Client) WriteFile(name string, contents []byte) error {
+func (c *Client) WriteFile(name string, contents []byte) (err error) {
+	defer func() { c.Recorder.record(Op{Method: "WriteFile", Path: name, Contents: contents, Err: err}) }()
 	sub, err := c.getSubsystem(name)
 	if err != nil {
 		return err
@@ -91,10 +155,35 @@ func (c *Client) WriteFile(name string, contents []byte) error {
 }
 
 // RemoveAll removes path and any children it contains.
-func (c *Client) RemoveAll(name string) error {
+func (c *Client) RemoveAll(name string) (err error) {
+	defer func() { c.Recorder.record(Op{Method: "RemoveAll", Path: name, Err: err}) }()
 	sub, err := c.getSubsystem(name)
 	if err != nil {
 		return err
 	}
 	return sub.RemoveAll(name)
 }
+
+// Stat returns a FileInfo describing the named file in its subsystem.
+func (c *Client) Stat(name string) (os.FileInfo, error) {
+	if path.Clean(name) == configfsi.TsmPrefix {
+		return &dirEntry{name: path.Base(configfsi.TsmPrefix), mode: fs.ModeDir | 0755}, nil
+	}
+	sub, err := c.getSubsystem(name)
+	if err != nil {
+		return nil, err
+	}
+	if p, err := configfsi.ParseTsmPath(name); err == nil && p.Entry == "" {
+		return &dirEntry{name: p.Subsystem, mode: fs.ModeDir | 0755}, nil
+	}
+	return configfsi.Stat(sub, name)
+}
+
+// Watch watches the named entry if its subsystem supports it.
+func (c *Client) Watch(ctx context.Context, entry string) (<-chan configfsi.Event, error) {
+	sub, err := c.getSubsystem(entry)
+	if err != nil {
+		return nil, err
+	}
+	return configfsi.Watch(ctx, sub, entry)
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+)
+
+func TestRegister(t *testing.T) {
+	c := &Client{}
+	if err := c.Register("report", Report611(0)); err != nil {
+		t.Fatalf("Register(report) = %v. Want nil", err)
+	}
+	if err := c.Register("rtmr", fakertmr.CreateRtmrSubsystem(t.TempDir())); err != nil {
+		t.Fatalf("Register(rtmr) = %v. Want nil", err)
+	}
+	if err := c.Register("report", ReportV7(0)); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Register(report) again = %v. Want ErrExist", err)
+	}
+	if err := c.Register("a/b", ReportV7(0)); err == nil {
+		t.Error("Register(a/b) = nil. Want an error")
+	}
+	entries, err := c.ReadDir(configfsi.TsmPrefix)
+	if err != nil || len(entries) != 2 || entries[0].Name() != "report" || entries[1].Name() != "rtmr" {
+		t.Errorf("ReadDir(tsm) = %v, %v. Want report and rtmr", entries, err)
+	}
+	entry, err := c.MkdirTemp(path.Join(configfsi.TsmPrefix, "rtmr"), "entry")
+	if err != nil {
+		t.Fatalf("MkdirTemp(rtmr) = _, %v. Want nil", err)
+	}
+	if err := c.WriteFile(path.Join(entry, "index"), []byte("2")); err != nil {
+		t.Errorf("WriteFile(rtmr index) = %v. Want nil", err)
+	}
+	info, err := c.Stat(path.Join(configfsi.TsmPrefix, "report"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat(report) = %v, %v. Want a directory", info, err)
+	}
+	c.Unregister("rtmr")
+	if _, err := c.ReadFile(path.Join(entry, "index")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile after Unregister = _, %v. Want ErrNotExist", err)
+	}
+}
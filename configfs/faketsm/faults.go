@@ -0,0 +1,89 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"sync"
+	"time"
+)
+
+// Fault programs operations of a ReportSubsystem to fail, e.g., the second WriteFile to
+// inblob returns EFAULT, or the first outblob read returns EIO.
+type Fault struct {
+	// Op is the failing method: "MkdirTemp", "ReadDir", "ReadFile", "WriteFile", or
+	// "RemoveAll".
+	Op string
+	// Attribute limits the fault to ReadFile and WriteFile calls on the named attribute,
+	// if not empty.
+	Attribute string
+	// Nth is the 1-based index of the first matching call that fails. Zero means the first.
+	Nth int
+	// Times is how many matching calls fail, starting with the Nth. Zero means all of them.
+	Times int
+	// Err is the error that failing calls return.
+	Err error
+	// calls counts the matching calls so far.
+	calls int
+}
+
+// faults holds the injected faults, interference, and latency of a ReportSubsystem.
+type faults struct {
+	mu           sync.Mutex
+	faults       []*Fault
+	interference []*Interference
+	latency      map[latencyKey]time.Duration
+}
+
+// InjectFault adds a fault to the subsystem. Faults are checked before the operation
+// takes effect, so a failed write does not advance the generation.
+func (r *ReportSubsystem) InjectFault(f *Fault) {
+	r.faults.mu.Lock()
+	defer r.faults.mu.Unlock()
+	r.faults.faults = append(r.faults.faults, f)
+}
+
+// ClearFaults removes all injected faults and interference.
+func (r *ReportSubsystem) ClearFaults() {
+	r.faults.mu.Lock()
+	defer r.faults.mu.Unlock()
+	r.faults.faults = nil
+	r.faults.interference = nil
+}
+
+// fault returns the error of the first fault that fails the call, or nil.
+func (r *ReportSubsystem) fault(op, attribute string) error {
+	r.faults.mu.Lock()
+	defer r.faults.mu.Unlock()
+	var result error
+	for _, f := range r.faults.faults {
+		if f.Op != op || (f.Attribute != "" && f.Attribute != attribute) {
+			continue
+		}
+		if fires(f.Nth, f.Times, &f.calls) && result == nil {
+			result = f.Err
+		}
+	}
+	return result
+}
+
+// fires counts a matching call and returns whether it is one of the times calls, starting
+// with the 1-based nth, that should fire. Zero nth means the first and zero times means all.
+func fires(nth, times int, calls *int) bool {
+	*calls++
+	if nth == 0 {
+		nth = 1
+	}
+	return *calls >= nth && (times == 0 || *calls < nth+times)
+}
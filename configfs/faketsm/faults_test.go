@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestInjectFault(t *testing.T) {
+	r := Report611(0)
+	r.InjectFault(&Fault{Op: "WriteFile", Attribute: "inblob", Nth: 2, Err: syscall.EFAULT})
+	r.InjectFault(&Fault{Op: "ReadFile", Attribute: "outblob", Times: 1, Err: syscall.EIO})
+	req := &report.Request{InBlob: makeNonce(1)}
+
+	if _, err := report.Get(r, req); !errors.Is(err, syscall.EIO) {
+		t.Errorf("first Get() = _, %v. Want EIO", err)
+	}
+	if _, err := report.Get(r, req); !errors.Is(err, syscall.EFAULT) {
+		t.Errorf("second Get() = _, %v. Want EFAULT", err)
+	}
+	if _, err := report.Get(r, req); !errors.Is(err, syscall.EFAULT) {
+		t.Errorf("third Get() = _, %v. Want EFAULT", err)
+	}
+	r.ClearFaults()
+	if _, err := report.Get(r, req); err != nil {
+		t.Errorf("Get() after ClearFaults = _, %v. Want nil", err)
+	}
+}
+
+func TestInjectFaultTimes(t *testing.T) {
+	r := ReportV7(0)
+	dir := path.Join(configfsi.TsmPrefix, "report")
+	r.InjectFault(&Fault{Op: "MkdirTemp", Nth: 2, Times: 2, Err: syscall.ENOSPC})
+	for i, want := range []error{nil, syscall.ENOSPC, syscall.ENOSPC, nil} {
+		_, err := r.MkdirTemp(dir, "entry")
+		if !errors.Is(err, want) || (want == nil && err != nil) {
+			t.Errorf("MkdirTemp call %d = _, %v. Want %v", i+1, err, want)
+		}
+	}
+}
+
+func TestInterfere(t *testing.T) {
+	r := Report611(0)
+	r.Interfere(&Interference{Op: "ReadFile", Attribute: "outblob", Times: 1})
+	if _, err := report.Get(r, &report.Request{InBlob: makeNonce(1)}); report.GetGenerationErr(err) == nil {
+		t.Fatalf("Get() = _, %v. Want a generation error", err)
+	}
+	r.Interfere(&Interference{Op: "WriteFile", Attribute: "inblob", Nth: 2, Times: 1, Writes: 3})
+	resp, err := report.Get(r, &report.Request{InBlob: makeNonce(1), Retries: 1})
+	if err != nil {
+		t.Fatalf("Get() with a retry = _, %v. Want nil", err)
+	}
+	if resp.Retries != 0 {
+		t.Errorf("Retries = %d. Want 0 before the second inblob write", resp.Retries)
+	}
+	resp, err = report.Get(r, &report.Request{InBlob: makeNonce(1), Retries: 1})
+	if err != nil {
+		t.Fatalf("Get() with a retry = _, %v. Want nil", err)
+	}
+	if resp.Retries != 1 {
+		t.Errorf("Retries = %d. Want 1 after interference", resp.Retries)
+	}
+}
+
+func TestBumpGeneration(t *testing.T) {
+	r := ReportV7(0)
+	entry, err := r.MkdirTemp(path.Join(configfsi.TsmPrefix, "report"), "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.BumpGeneration(entry); err != nil {
+		t.Fatalf("BumpGeneration(%q) = %v. Want nil", entry, err)
+	}
+	got, err := r.ReadFile(entry + "/generation")
+	if err != nil || string(got) != "1\n" {
+		t.Errorf("generation = %q, %v. Want 1", got, err)
+	}
+	if err := r.BumpGeneration("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("BumpGeneration(missing) = %v. Want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Interference programs a ReportSubsystem to advance an entry's generation right before
+// matching calls, as if another process wrote one of its attributes. Tests can use it to
+// exercise report generation mismatch detection and recovery deterministically.
+type Interference struct {
+	// Op is the interfered method: "ReadFile" or "WriteFile".
+	Op string
+	// Attribute limits the interference to calls on the named attribute, if not empty.
+	Attribute string
+	// Nth is the 1-based index of the first matching call to interfere with. Zero means
+	// the first.
+	Nth int
+	// Times is how many matching calls to interfere with, starting with the Nth. Zero means
+	// all of them.
+	Times int
+	// Writes is how many outside writes to simulate each time. Zero means one.
+	Writes int
+	// calls counts the matching calls so far.
+	calls int
+}
+
+// Interfere adds an interference to the subsystem.
+func (r *ReportSubsystem) Interfere(i *Interference) {
+	r.faults.mu.Lock()
+	defer r.faults.mu.Unlock()
+	r.faults.interference = append(r.faults.interference, i)
+}
+
+// BumpGeneration advances the named entry's generation by one, as if another process
+// wrote one of its attributes. The entry is a report entry path or just its name.
+func (r *ReportSubsystem) BumpGeneration(entry string) error {
+	if p, err := configfsi.ParseTsmPath(entry); err == nil {
+		entry = p.Entry
+	}
+	return r.bumpGeneration(entry, 1)
+}
+
+func (r *ReportSubsystem) bumpGeneration(entry string, writes int) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.Entries[entry]
+	if !ok {
+		return fmt.Errorf("BumpGeneration(%q): %w", entry, os.ErrNotExist)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := 0; i < writes; i++ {
+		if err := e.tryAdvanceWriteGeneration(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interfere applies the interference that matches a call to entry's attribute.
+func (r *ReportSubsystem) interfere(op string, p *configfsi.TsmPath) error {
+	r.faults.mu.Lock()
+	writes := 0
+	for _, i := range r.faults.interference {
+		if i.Op != op || (i.Attribute != "" && i.Attribute != p.Attribute) {
+			continue
+		}
+		if fires(i.Nth, i.Times, &i.calls) {
+			if i.Writes == 0 {
+				writes++
+			} else {
+				writes += i.Writes
+			}
+		}
+	}
+	r.faults.mu.Unlock()
+	if writes == 0 {
+		return nil
+	}
+	return r.bumpGeneration(p.Entry, writes)
+}
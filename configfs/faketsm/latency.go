@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import "time"
+
+// latencyKey identifies the calls that a latency applies to.
+type latencyKey struct {
+	op        string
+	attribute string
+}
+
+// SetLatency makes calls of the named method, e.g., "ReadFile", sleep for d before they
+// take effect, to test callers' timeouts and concurrency under realistic slowness. If
+// attribute is not empty, only ReadFile and WriteFile calls on that attribute sleep, and
+// that latency replaces the method's. Zero d removes the latency.
+func (r *ReportSubsystem) SetLatency(op, attribute string, d time.Duration) {
+	r.faults.mu.Lock()
+	defer r.faults.mu.Unlock()
+	key := latencyKey{op: op, attribute: attribute}
+	if d == 0 {
+		delete(r.faults.latency, key)
+		return
+	}
+	if r.faults.latency == nil {
+		r.faults.latency = make(map[latencyKey]time.Duration)
+	}
+	r.faults.latency[key] = d
+}
+
+// delay sleeps for the latency of a call, if any.
+func (r *ReportSubsystem) delay(op, attribute string) {
+	r.faults.mu.Lock()
+	d, ok := r.faults.latency[latencyKey{op: op, attribute: attribute}]
+	if !ok {
+		d = r.faults.latency[latencyKey{op: op}]
+	}
+	r.faults.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// before delays a call and then returns its injected fault, if any.
+func (r *ReportSubsystem) before(op, attribute string) error {
+	r.delay(op, attribute)
+	return r.fault(op, attribute)
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestSetLatency(t *testing.T) {
+	r := Report611(0)
+	r.SetLatency("ReadFile", "outblob", 200*time.Millisecond)
+	_, err := report.Get(r, &report.Request{InBlob: makeNonce(1), Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("report.Get() with a slow outblob = _, %v. Want DeadlineExceeded", err)
+	}
+	r.SetLatency("ReadFile", "outblob", 0)
+	r.SetLatency("WriteFile", "", 5*time.Millisecond)
+	start := time.Now()
+	if _, err := report.Get(r, &report.Request{InBlob: makeNonce(1)}); err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("report.Get() took %v. Want at least the 5ms write latency", elapsed)
+	}
+}
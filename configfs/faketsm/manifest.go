@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/uuid"
+)
+
+// SvsmVtpmGUID is the service GUID of the SVSM vTPM service.
+const SvsmVtpmGUID = "c476f1eb-0123-45a5-9641-b4e7dde5bfe3"
+
+// ServiceManifest is the manifest of a service at a manifest version.
+type ServiceManifest struct {
+	// GUID is the service_guid that selects the service.
+	GUID string
+	// Version is the service_manifest_version that selects the manifest.
+	Version  uint32
+	Manifest []byte
+}
+
+// readManifest returns the manifest that the entry's service_guid and
+// service_manifest_version select, or EINVAL if there is none.
+func readManifest(e *ReportEntry, manifests []ServiceManifest) ([]byte, error) {
+	var guid uuid.UUID
+	if a := e.InAttrs["service_guid"]; a != nil && len(a.Value) != 0 {
+		var err error
+		if guid, err = uuid.Parse(string(a.Value)); err != nil {
+			return nil, syscall.EINVAL
+		}
+	}
+	var version uint64
+	if a := e.InAttrs["service_manifest_version"]; a != nil && len(a.Value) != 0 {
+		var err error
+		if version, err = configfsi.Kstrtouint(a.Value, renderBase, 32); err != nil {
+			return nil, syscall.EINVAL
+		}
+	}
+	for _, m := range manifests {
+		if want, err := uuid.Parse(m.GUID); err == nil && want == guid && uint64(m.Version) == version {
+			return m.Manifest, nil
+		}
+	}
+	return nil, syscall.EINVAL
+}
+
+// Report611WithManifests returns an empty report subsystem like Report611's whose
+// manifestblob is the manifest that the written service_guid and service_manifest_version
+// select. The GUID defaults to the nil UUID and the version to 0, as in the kernel. Reading
+// the manifestblob of an unknown service or version fails with EINVAL.
+func Report611WithManifests(privlevelFloor uint, manifests []ServiceManifest) *ReportSubsystem {
+	r := Report611(privlevelFloor)
+	fallback := r.ReadAttr
+	r.ReadAttr = func(e *ReportEntry, attr string) ([]byte, error) {
+		if attr == "manifestblob" {
+			return readManifest(e, manifests)
+		}
+		return fallback(e, attr)
+	}
+	return r
+}
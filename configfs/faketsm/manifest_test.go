@@ -0,0 +1,66 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestReport611WithManifests(t *testing.T) {
+	r := Report611WithManifests(0, []ServiceManifest{
+		{GUID: SvsmVtpmGUID, Manifest: []byte("vtpm v0")},
+		{GUID: SvsmVtpmGUID, Version: 1, Manifest: []byte("vtpm v1")},
+		{GUID: "00000000-0000-0000-0000-000000000000", Manifest: []byte("all services")},
+	})
+	tcs := []struct {
+		name    string
+		guid    string
+		version string
+		want    string
+		wantErr error
+	}{
+		{name: "default", want: "all services"},
+		{name: "vtpm", guid: SvsmVtpmGUID, want: "vtpm v0"},
+		{name: "vtpm v1", guid: "C476F1EB-0123-45A5-9641-B4E7DDE5BFE3", version: "1", want: "vtpm v1"},
+		{name: "unknown version", guid: SvsmVtpmGUID, version: "2", wantErr: syscall.EINVAL},
+		{name: "unknown service", guid: "8b1f8a3e-0000-4000-8000-000000000000", wantErr: syscall.EINVAL},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := report.Get(r, &report.Request{
+				InBlob:                 makeNonce(1),
+				ServiceProvider:        "svsm",
+				ServiceGuid:            tc.guid,
+				ServiceManifestVersion: tc.version,
+			})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("report.Get() = _, %v. Want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("report.Get() = _, %v. Want nil", err)
+			}
+			if string(resp.ManifestBlob) != tc.want {
+				t.Errorf("ManifestBlob = %q. Want %q", resp.ManifestBlob, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestNewReportOptions(t *testing.T) {
+	r := NewReport(configfsi.RevisionV7, &ReportOptions{
+		Provider:       "acme_guest",
+		PrivlevelFloor: 1,
+		AuxBlob:        []byte("certs"),
+		InBlobMax:      32,
+	})
+	if _, err := report.Get(r, &report.Request{InBlob: make([]byte, 33)}); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("report.Get(33-byte inblob) = _, %v. Want EINVAL", err)
+	}
+	resp, err := report.Get(r, &report.Request{
+		InBlob:     make([]byte, 32),
+		Privilege:  &report.Privilege{Level: 1},
+		GetAuxBlob: true,
+	})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	if resp.Provider != "acme_guest\n" {
+		t.Errorf("Provider = %q. Want %q", resp.Provider, "acme_guest\n")
+	}
+	if string(resp.AuxBlob) != "certs" {
+		t.Errorf("AuxBlob = %q. Want %q", resp.AuxBlob, "certs")
+	}
+	caps, err := report.Capabilities(r)
+	if err != nil {
+		t.Fatalf("report.Capabilities() = _, %v. Want nil", err)
+	}
+	if caps.Service {
+		t.Error("Capabilities().Service = true. Want false for the v7 attributes")
+	}
+}
+
+func TestInBlobSize(t *testing.T) {
+	r := NewReport(configfsi.Revision611, &ReportOptions{InBlobSize: 48})
+	for _, size := range []int{32, 64} {
+		if _, err := report.Get(r, &report.Request{InBlob: make([]byte, size)}); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("report.Get(%d-byte inblob) = _, %v. Want EINVAL", size, err)
+		}
+	}
+	if _, err := report.Get(r, &report.Request{InBlob: make([]byte, 48)}); err != nil {
+		t.Errorf("report.Get(48-byte inblob) = _, %v. Want nil", err)
+	}
+	if _, err := report.Get(ReportTdx(nil), &report.Request{InBlob: make([]byte, 32)}); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("report.Get(ReportTdx, 32-byte inblob) = _, %v. Want EINVAL", err)
+	}
+}
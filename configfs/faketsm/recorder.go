@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Op is a recorded call to a Client.
+type Op struct {
+	// Method is the called method, e.g., "WriteFile".
+	Method string
+	// Path is the directory or file argument.
+	Path string
+	// Pattern is the MkdirTemp pattern.
+	Pattern string
+	// Contents is the data that WriteFile wrote or ReadFile returned.
+	Contents []byte
+	// Created is the directory that MkdirTemp created.
+	Created string
+	Err     error
+}
+
+// Recorder records the calls to a Client. Its methods are safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	ops []Op
+}
+
+// record appends a call.
+func (r *Recorder) record(op Op) {
+	if r == nil {
+		return
+	}
+	op.Contents = bytes.Clone(op.Contents)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+// Ops returns the recorded calls in order.
+func (r *Recorder) Ops() []Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+// Count returns how many calls of the named method on path were recorded. An empty path
+// counts the method's calls on any path.
+func (r *Recorder) Count(method, path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, op := range r.ops {
+		if op.Method == method && (path == "" || op.Path == path) {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset discards the recorded calls.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := &Recorder{}
+	c := &Client{Subsystems: map[string]configfsi.Client{"report": Report611(0)}, Recorder: rec}
+	nonce := makeNonce(1)
+	resp, err := report.Get(c, &report.Request{InBlob: nonce})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	ops := rec.Ops()
+	if len(ops) == 0 || ops[0].Method != "MkdirTemp" || ops[0].Created == "" {
+		t.Fatalf("first op = %+v. Want a MkdirTemp", ops)
+	}
+	entry := ops[0].Created
+	if n := rec.Count("RemoveAll", entry); n != 1 {
+		t.Errorf("RemoveAll(%q) calls = %d. Want 1", entry, n)
+	}
+	if n := rec.Count("WriteFile", entry+"/inblob"); n != 1 {
+		t.Errorf("inblob writes = %d. Want 1", n)
+	}
+	for _, op := range ops {
+		if op.Method == "ReadFile" && op.Path == entry+"/outblob" && string(op.Contents) != string(resp.OutBlob) {
+			t.Errorf("recorded outblob = %q. Want %q", op.Contents, resp.OutBlob)
+		}
+	}
+	rec.Reset()
+	if err := c.WriteFile(entry+"/inblob", nonce); err == nil {
+		t.Error("WriteFile to a removed entry = nil. Want an error")
+	}
+	if ops := rec.Ops(); len(ops) != 1 || ops[0].Err == nil {
+		t.Errorf("ops after Reset = %+v. Want one failed WriteFile", ops)
+	}
+	if _, err := c.ReadFile("/sys/kernel/config/tsm/other/x"); err == nil {
+		t.Error("ReadFile(unknown subsystem) = _, nil. Want an error")
+	}
+	if n := rec.Count("ReadFile", ""); n != 1 {
+		t.Errorf("ReadFile calls = %d. Want 1", n)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/internal/snp"
+)
+
+const (
+	// snpSignatureAlgoEcdsaP384 is the SIGNATURE_ALGO of ECDSA P-384 with SHA-384.
+	snpSignatureAlgoEcdsaP384 = 1
+	// snpComponentSize is the size of each little-endian signature component.
+	snpComponentSize = 72
+)
+
+// SnpOptions configures the fields of fake SEV-SNP attestation reports.
+type SnpOptions struct {
+	// Version is the report version. Zero means 2.
+	Version     uint32
+	GuestSvn    uint32
+	Policy      uint64
+	FamilyID    [16]byte
+	ImageID     [16]byte
+	CurrentTcb  uint64
+	Measurement [48]byte
+	HostData    [32]byte
+	ReportedTcb uint64
+	ChipID      [64]byte
+	// Vcek signs the reports if not nil, e.g., a key from GenerateTestVcek. Otherwise the
+	// signature is zero.
+	Vcek *ecdsa.PrivateKey
+	// VcekCert is the DER certificate of Vcek that the auxblob certificate table holds.
+	// If empty, the auxblob is an empty certificate table.
+	VcekCert []byte
+}
+
+// GenerateTestVcek returns a new P-384 key and a self-signed DER certificate for it to use
+// as a test VCEK. The certificate is not issued by AMD, so verifiers must be configured to
+// trust it.
+func GenerateTestVcek() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "SEV-VCEK (test)"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+// render returns an ATTESTATION_REPORT for the report data and VMPL.
+func (o *SnpOptions) render(reportData []byte, vmpl uint32) ([]byte, error) {
+	r := make([]byte, snp.ReportSize)
+	le := binary.LittleEndian
+	version := o.Version
+	if version == 0 {
+		version = 2
+	}
+	le.PutUint32(r[0x00:], version)
+	le.PutUint32(r[0x04:], o.GuestSvn)
+	le.PutUint64(r[0x08:], o.Policy)
+	copy(r[0x10:0x20], o.FamilyID[:])
+	copy(r[0x20:0x30], o.ImageID[:])
+	le.PutUint32(r[0x30:], vmpl)
+	le.PutUint32(r[0x34:], snpSignatureAlgoEcdsaP384)
+	le.PutUint64(r[0x38:], o.CurrentTcb)
+	copy(r[0x50:0x90], reportData)
+	copy(r[0x90:0xC0], o.Measurement[:])
+	copy(r[0xC0:0xE0], o.HostData[:])
+	le.PutUint64(r[0x180:], o.ReportedTcb)
+	copy(r[0x1A0:0x1E0], o.ChipID[:])
+	if o.Vcek == nil {
+		return r, nil
+	}
+	digest := sha512.Sum384(r[:snp.SignedSize])
+	sigR, sigS, err := ecdsa.Sign(rand.Reader, o.Vcek, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	putLittleEndian(r[snp.SignedSize:snp.SignedSize+snpComponentSize], sigR)
+	putLittleEndian(r[snp.SignedSize+snpComponentSize:snp.SignedSize+2*snpComponentSize], sigS)
+	return r, nil
+}
+
+// putLittleEndian writes n to b in little-endian order.
+func putLittleEndian(b []byte, n *big.Int) {
+	be := n.Bytes()
+	for i := range be {
+		b[i] = be[len(be)-1-i]
+	}
+}
+
+// certTable returns an SEV-SNP certificate table with the VCEK certificate, if any.
+func (o *SnpOptions) certTable() []byte {
+	if len(o.VcekCert) == 0 {
+		return make([]byte, snp.CertTableEntrySize)
+	}
+	offset := 2 * snp.CertTableEntrySize
+	table := make([]byte, offset+len(o.VcekCert))
+	copy(table[0:16], snp.GUIDVcek[:])
+	binary.LittleEndian.PutUint32(table[16:], uint32(offset))
+	binary.LittleEndian.PutUint32(table[20:], uint32(len(o.VcekCert)))
+	copy(table[offset:], o.VcekCert)
+	return table
+}
+
+func readSnp(fallback func(*ReportEntry, string) ([]byte, error), reportOpts *ReportOptions, opts *SnpOptions) func(*ReportEntry, string) ([]byte, error) {
+	return func(e *ReportEntry, attr string) ([]byte, error) {
+		switch attr {
+		case "auxblob":
+			return opts.certTable(), nil
+		case "outblob":
+			inblob, err := reportOpts.inBlob(e)
+			if err != nil {
+				return nil, err
+			}
+			vmpl, err := readPrivlevel(e)
+			if err != nil {
+				return nil, err
+			}
+			return opts.render(inblob, vmpl)
+		}
+		return fallback(e, attr)
+	}
+}
+
+// readPrivlevel returns the entry's privlevel as written.
+func readPrivlevel(e *ReportEntry) (uint32, error) {
+	a, ok := e.InAttrs["privlevel"]
+	if !ok || len(a.Value) == 0 {
+		return 0, nil
+	}
+	level, err := configfsi.Kstrtouint(a.Value, renderBase, 32)
+	return uint32(level), err
+}
+
+// ReportSnp returns an empty report subsystem like Report611's whose provider is
+// "sev_guest" and whose outblob is a correctly sized SEV-SNP ATTESTATION_REPORT with the
+// inblob as REPORT_DATA and privlevel as VMPL. The auxblob is a certificate table. A nil
+// opts renders unsigned reports with zero fields.
+func ReportSnp(privlevelFloor uint, opts *SnpOptions) *ReportSubsystem {
+	if opts == nil {
+		opts = &SnpOptions{}
+	}
+	reportOpts := &ReportOptions{Provider: "sev_guest", PrivlevelFloor: privlevelFloor}
+	r := NewReport(configfsi.Revision611, reportOpts)
+	r.ReadAttr = readSnp(r.ReadAttr, reportOpts, opts)
+	return r
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// littleEndianInt returns the little-endian integer in b.
+func littleEndianInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func TestReportSnp(t *testing.T) {
+	key, cert, err := GenerateTestVcek()
+	if err != nil {
+		t.Fatalf("GenerateTestVcek() = _, _, %v. Want nil", err)
+	}
+	opts := &SnpOptions{Vcek: key, VcekCert: cert, Measurement: [48]byte{1, 2, 3}}
+	nonce := makeNonce(7)
+	resp, err := report.Get(ReportSnp(0, opts), &report.Request{
+		InBlob:     nonce,
+		Privilege:  &report.Privilege{Level: 2},
+		GetAuxBlob: true,
+	})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	r := resp.OutBlob
+	if len(r) != snp.ReportSize {
+		t.Fatalf("len(OutBlob) = %d. Want %d", len(r), snp.ReportSize)
+	}
+	if resp.Provider != "sev_guest\n" {
+		t.Errorf("Provider = %q. Want sev_guest", resp.Provider)
+	}
+	if got := binary.LittleEndian.Uint32(r[0x30:]); got != 2 {
+		t.Errorf("VMPL = %d. Want 2", got)
+	}
+	if !bytes.Equal(r[0x50:0x90], nonce) {
+		t.Errorf("REPORT_DATA = %v. Want %v", r[0x50:0x90], nonce)
+	}
+	if !bytes.Equal(r[0x90:0xC0], opts.Measurement[:]) {
+		t.Errorf("MEASUREMENT = %v. Want %v", r[0x90:0xC0], opts.Measurement)
+	}
+	digest := sha512.Sum384(r[:snp.SignedSize])
+	sigR := littleEndianInt(r[snp.SignedSize : snp.SignedSize+snpComponentSize])
+	sigS := littleEndianInt(r[snp.SignedSize+snpComponentSize : snp.SignedSize+2*snpComponentSize])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], sigR, sigS) {
+		t.Error("report signature does not verify with the VCEK")
+	}
+
+	table := resp.AuxBlob
+	offset := binary.LittleEndian.Uint32(table[16:])
+	length := binary.LittleEndian.Uint32(table[20:])
+	vcek, err := x509.ParseCertificate(table[offset : offset+length])
+	if err != nil {
+		t.Fatalf("could not parse the auxblob VCEK: %v", err)
+	}
+	if !vcek.PublicKey.(*ecdsa.PublicKey).Equal(&key.PublicKey) {
+		t.Error("auxblob VCEK does not match the signing key")
+	}
+}
+
+func TestReportSnpUnsigned(t *testing.T) {
+	resp, err := report.Get(ReportSnp(0, nil), &report.Request{InBlob: makeNonce(1)})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	if sig := resp.OutBlob[snp.SignedSize:]; !bytes.Equal(sig, make([]byte, len(sig))) {
+		t.Error("unsigned report has a non-zero signature")
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultStressGoroutines = 8
+	defaultStressIterations = 100
+	// stressRemoveRetries bounds how often the default operation retries a busy RemoveAll.
+	stressRemoveRetries = 1000
+)
+
+// StressOptions configures Stress.
+type StressOptions struct {
+	// Goroutines is the number of concurrent goroutines. Zero means 8.
+	Goroutines int
+	// Iterations is the number of operations each goroutine performs. Zero means 100.
+	Iterations int
+	// Seed seeds the random choices of the default operation.
+	Seed int64
+	// Op, if not nil, performs one operation with client, e.g., a call to a wrapper of
+	// report.Get. Its errors are counted but are not invariant violations. The default
+	// operation creates an entry, requests a report, removes the entry, and reads the
+	// generation of a random entry of another goroutine in between.
+	Op func(client configfsi.Client, rnd *rand.Rand) error
+}
+
+// StressResult summarizes a Stress run.
+type StressResult struct {
+	// Operations is the number of operations performed.
+	Operations int
+	// Failures is the number of operations that returned an error.
+	Failures int
+	// Err is the first error an operation returned, if any.
+	Err error
+}
+
+// InvariantError describes a violation of a report subsystem invariant that Stress observed.
+type InvariantError struct {
+	// Entry is the name of the report entry the violation concerns.
+	Entry string
+	// Invariant names the violated invariant.
+	Invariant string
+	// Detail describes the violation.
+	Detail string
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("report entry %q violates %s: %s", e.Entry, e.Invariant, e.Detail)
+}
+
+// Stress runs concurrent report operations against the subsystem and returns the operation
+// outcomes and an error combining an *InvariantError for each observed violation of these
+// invariants:
+//
+//   - "generation monotonicity": a read of an entry's generation is never less than one
+//     read before it began.
+//   - "no reads of destroyed entries": no read of an entry that began after the entry was
+//     removed succeeds.
+//   - "no leaked entries": every entry an operation creates is removed.
+//
+// Consumers can pass their own operation in opts to stress their wrappers of the
+// report package.
+func Stress(r *ReportSubsystem, opts *StressOptions) (*StressResult, error) {
+	if opts == nil {
+		opts = &StressOptions{}
+	}
+	goroutines := opts.Goroutines
+	if goroutines == 0 {
+		goroutines = defaultStressGoroutines
+	}
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = defaultStressIterations
+	}
+	c := &stressClient{r: r, entries: make(map[string]*stressEntry)}
+	op := opts.Op
+	if op == nil {
+		op = c.defaultOp
+	}
+	result := &StressResult{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(rnd *rand.Rand) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				err := op(c, rnd)
+				mu.Lock()
+				result.Operations++
+				if err != nil {
+					result.Failures++
+					if result.Err == nil {
+						result.Err = err
+					}
+				}
+				mu.Unlock()
+			}
+		}(rand.New(rand.NewSource(opts.Seed + int64(i))))
+	}
+	wg.Wait()
+	return result, c.finish()
+}
+
+// stressEntry is what a stressClient observed of an entry.
+type stressEntry struct {
+	// floor is the largest generation that a completed read returned.
+	floor uint64
+	// removedAt is the clock value when the entry's removal completed, or 0.
+	removedAt uint64
+}
+
+// stressClient forwards to a report subsystem and checks invariants of its results.
+type stressClient struct {
+	r *ReportSubsystem
+	// clock orders the start and end of operations.
+	clock      uint64
+	mu         sync.Mutex
+	entries    map[string]*stressEntry
+	violations []error
+}
+
+func (c *stressClient) tick() uint64 {
+	return atomic.AddUint64(&c.clock, 1)
+}
+
+// Called while mu is held.
+func (c *stressClient) violate(entry, invariant, format string, args ...any) {
+	c.violations = append(c.violations, &InvariantError{
+		Entry:     entry,
+		Invariant: invariant,
+		Detail:    fmt.Sprintf(format, args...),
+	})
+}
+
+// MkdirTemp forwards to the subsystem and starts tracking the new entry.
+func (c *stressClient) MkdirTemp(dir, pattern string) (string, error) {
+	name, err := c.r.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.entries[path.Base(name)] = &stressEntry{}
+	c.mu.Unlock()
+	return name, nil
+}
+
+// ReadDir forwards to the subsystem.
+func (c *stressClient) ReadDir(dirname string) ([]os.DirEntry, error) {
+	return c.r.ReadDir(dirname)
+}
+
+// WriteFile forwards to the subsystem.
+func (c *stressClient) WriteFile(name string, contents []byte) error {
+	return c.r.WriteFile(name, contents)
+}
+
+// ReadFile forwards to the subsystem and checks the result against earlier operations on
+// entries that MkdirTemp created.
+func (c *stressClient) ReadFile(name string) ([]byte, error) {
+	p, err := configfsi.ParseTsmPath(name)
+	if err != nil {
+		return c.r.ReadFile(name)
+	}
+	c.mu.Lock()
+	e, ok := c.entries[p.Entry]
+	var floor uint64
+	if ok {
+		floor = e.floor
+	}
+	c.mu.Unlock()
+	if !ok {
+		return c.r.ReadFile(name)
+	}
+	start := c.tick()
+	data, err := c.r.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.removedAt != 0 && start > e.removedAt {
+		c.violate(p.Entry, "no reads of destroyed entries", "read of %q succeeded after removal", p.Attribute)
+	}
+	if p.Attribute == "generation" {
+		generation, err := configfsi.Kstrtouint(data, renderBase, 64)
+		if err != nil {
+			c.violate(p.Entry, "generation monotonicity", "unparsable generation %q", data)
+		} else {
+			if generation < floor {
+				c.violate(p.Entry, "generation monotonicity", "read generation %d after %d", generation, floor)
+			}
+			if generation > e.floor {
+				e.floor = generation
+			}
+		}
+	}
+	return data, nil
+}
+
+// RemoveAll forwards to the subsystem and records the removal time.
+func (c *stressClient) RemoveAll(name string) error {
+	if err := c.r.RemoveAll(name); err != nil {
+		return err
+	}
+	if p, err := configfsi.ParseTsmPath(name); err == nil {
+		c.mu.Lock()
+		if e, ok := c.entries[p.Entry]; ok {
+			e.removedAt = c.tick()
+		}
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// liveEntry returns the path of a random entry that has not been removed, or "".
+func (c *stressClient) liveEntry(rnd *rand.Rand) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var live []string
+	for name, e := range c.entries {
+		if e.removedAt == 0 {
+			live = append(live, name)
+		}
+	}
+	if len(live) == 0 {
+		return ""
+	}
+	sort.Strings(live)
+	p := &configfsi.TsmPath{Subsystem: subsystemName, Entry: live[rnd.Intn(len(live))]}
+	return p.String()
+}
+
+// defaultOp requests a report on a fresh entry and reads another entry's generation.
+func (c *stressClient) defaultOp(client configfsi.Client, rnd *rand.Rand) error {
+	dir := (&configfsi.TsmPath{Subsystem: subsystemName}).String()
+	entry, err := client.MkdirTemp(dir, "stress")
+	if err != nil {
+		return err
+	}
+	err = stressReport(client, entry, rnd)
+	if other := c.liveEntry(rnd); other != "" {
+		// Other goroutines' entries may be removed at any time, so errors are expected.
+		_, _ = client.ReadFile(path.Join(other, "generation"))
+	}
+	return multierr.Combine(err, stressRemove(client, entry))
+}
+
+// stressReport writes a random inblob to the entry and reads its outblob.
+func stressReport(client configfsi.Client, entry string, rnd *rand.Rand) error {
+	inblob := make([]byte, 1+rnd.Intn(tsmInBlobSize))
+	rnd.Read(inblob)
+	if err := client.WriteFile(path.Join(entry, "inblob"), inblob); err != nil {
+		return err
+	}
+	_, err := client.ReadFile(path.Join(entry, "outblob"))
+	return err
+}
+
+// stressRemove removes the entry, retrying while another goroutine is reading it.
+func stressRemove(client configfsi.Client, entry string) error {
+	var err error
+	for i := 0; i < stressRemoveRetries; i++ {
+		if err = client.RemoveAll(entry); !configfsi.IsBusy(err) {
+			return err
+		}
+		time.Sleep(time.Microsecond)
+	}
+	return err
+}
+
+// finish returns the violations, including entries that were never removed.
+func (c *stressClient) finish() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	for name, e := range c.entries {
+		if e.removedAt == 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.violate(name, "no leaked entries", "entry was not removed")
+	}
+	return multierr.Combine(c.violations...)
+}
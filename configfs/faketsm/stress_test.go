@@ -0,0 +1,61 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+	"go.uber.org/multierr"
+)
+
+func TestStress(t *testing.T) {
+	result, err := Stress(Report611(0), &StressOptions{Goroutines: 4, Iterations: 50})
+	if err != nil {
+		t.Fatalf("Stress() = _, %v. Want nil", err)
+	}
+	if result.Operations != 200 || result.Failures != 0 {
+		t.Errorf("Stress() = %+v. Want 200 operations and no failures", result)
+	}
+}
+
+func TestStressReportGet(t *testing.T) {
+	op := func(client configfsi.Client, rnd *rand.Rand) error {
+		_, err := report.Get(client, &report.Request{InBlob: makeNonce(uint(rnd.Intn(100)))})
+		return err
+	}
+	if _, err := Stress(Report611(0), &StressOptions{Iterations: 20, Op: op}); err != nil {
+		t.Errorf("Stress(report.Get) = _, %v. Want nil", err)
+	}
+}
+
+func TestStressLeak(t *testing.T) {
+	op := func(client configfsi.Client, rnd *rand.Rand) error {
+		_, err := report.CreateOpenReport(client)
+		return err
+	}
+	_, err := Stress(Report611(0), &StressOptions{Goroutines: 2, Iterations: 2, Op: op})
+	errs := multierr.Errors(err)
+	if len(errs) != 4 {
+		t.Fatalf("Stress(leaking op) = _, %v. Want 4 violations", err)
+	}
+	var invariantErr *InvariantError
+	if !errors.As(errs[0], &invariantErr) || invariantErr.Invariant != "no leaked entries" {
+		t.Errorf("Stress(leaking op) violation = %v. Want a leaked entry", errs[0])
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/internal/tdx"
+)
+
+// tdxIntelQeVendorID is the QE vendor ID of the Intel quoting enclave.
+var tdxIntelQeVendorID = [16]byte{0x93, 0x9a, 0x72, 0x33, 0xf7, 0x9c, 0x4c, 0xa9,
+	0x94, 0x0a, 0x0d, 0xb3, 0x95, 0x7f, 0x06, 0x07}
+
+// TdxOptions configures the fields of fake TDX quotes.
+type TdxOptions struct {
+	TeeTcbSvn      [16]byte
+	MrSeam         [48]byte
+	MrSignerSeam   [48]byte
+	SeamAttributes [8]byte
+	TdAttributes   [8]byte
+	Xfam           [8]byte
+	MrTd           [48]byte
+	MrConfigID     [48]byte
+	MrOwner        [48]byte
+	MrOwnerConfig  [48]byte
+	Rtmrs          [4][48]byte
+	// AttestationKey is a P-256 key that signs the quotes if not nil. Otherwise the
+	// signature is zero.
+	AttestationKey *ecdsa.PrivateKey
+	// PckCertChain is the PEM certificate chain in the quote's certification data.
+	PckCertChain []byte
+}
+
+// body returns the TD quote body with the report data.
+func (o *TdxOptions) body(reportData []byte) []byte {
+	b := make([]byte, 0, tdx.BodySize)
+	b = append(b, o.TeeTcbSvn[:]...)
+	b = append(b, o.MrSeam[:]...)
+	b = append(b, o.MrSignerSeam[:]...)
+	b = append(b, o.SeamAttributes[:]...)
+	b = append(b, o.TdAttributes[:]...)
+	b = append(b, o.Xfam[:]...)
+	b = append(b, o.MrTd[:]...)
+	b = append(b, o.MrConfigID[:]...)
+	b = append(b, o.MrOwner[:]...)
+	b = append(b, o.MrOwnerConfig[:]...)
+	for _, rtmr := range o.Rtmrs {
+		b = append(b, rtmr[:]...)
+	}
+	data := make([]byte, tsmInBlobSize)
+	copy(data, reportData)
+	return append(b, data...)
+}
+
+// render returns a version 4 quote of the report data.
+func (o *TdxOptions) render(reportData []byte) ([]byte, error) {
+	le := binary.LittleEndian
+	header := make([]byte, tdx.HeaderSize)
+	le.PutUint16(header[0:], tdx.QuoteVersion)
+	le.PutUint16(header[2:], tdx.AttestationKeyTypeEcdsaP256)
+	le.PutUint32(header[4:], tdx.TeeType)
+	copy(header[12:28], tdxIntelQeVendorID[:])
+	quote := append(header, o.body(reportData)...)
+
+	signature := make([]byte, tdx.SignatureSize)
+	attestationKey := make([]byte, tdx.SignatureSize)
+	if o.AttestationKey != nil {
+		digest := sha256.Sum256(quote)
+		r, s, err := ecdsa.Sign(rand.Reader, o.AttestationKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		o.AttestationKey.X.FillBytes(attestationKey[:32])
+		o.AttestationKey.Y.FillBytes(attestationKey[32:])
+	}
+	// The certification data is a QE report, its signature, empty QE authentication data,
+	// and the PCK certificate chain. The QE report and signature are zero.
+	pck := make([]byte, 6, 6+len(o.PckCertChain))
+	le.PutUint16(pck[0:], tdx.CertDataPckCertChain)
+	le.PutUint32(pck[2:], uint32(len(o.PckCertChain)))
+	pck = append(pck, o.PckCertChain...)
+	qe := make([]byte, tdx.QeReportSize+tdx.SignatureSize+2)
+	qe = append(qe, pck...)
+	cert := make([]byte, 6)
+	le.PutUint16(cert[0:], tdx.CertDataQeReport)
+	le.PutUint32(cert[2:], uint32(len(qe)))
+	cert = append(cert, qe...)
+
+	sigData := append(append(signature, attestationKey...), cert...)
+	sigLen := make([]byte, 4)
+	le.PutUint32(sigLen, uint32(len(sigData)))
+	quote = append(quote, sigLen...)
+	return append(quote, sigData...), nil
+}
+
+func readTdx(fallback func(*ReportEntry, string) ([]byte, error), reportOpts *ReportOptions, opts *TdxOptions) func(*ReportEntry, string) ([]byte, error) {
+	return func(e *ReportEntry, attr string) ([]byte, error) {
+		switch attr {
+		case "outblob":
+			inblob, err := reportOpts.inBlob(e)
+			if err != nil {
+				return nil, err
+			}
+			return opts.render(inblob)
+		}
+		return fallback(e, attr)
+	}
+}
+
+// ReportTdx returns an empty report subsystem like Report611's whose provider is
+// "tdx_guest" and whose outblob is a structurally valid version 4 TDX quote with the inblob
+// as REPORTDATA and the measurement fields from opts. A nil opts renders unsigned quotes
+// with zero fields.
+func ReportTdx(opts *TdxOptions) *ReportSubsystem {
+	if opts == nil {
+		opts = &TdxOptions{}
+	}
+	// Like the kernel's tdx_guest provider, require exactly TDX_REPORTDATA_LEN bytes.
+	reportOpts := &ReportOptions{Provider: "tdx_guest", InBlobSize: tsmInBlobSize}
+	r := NewReport(configfsi.Revision611, reportOpts)
+	r.ReadAttr = readTdx(r.ReadAttr, reportOpts, opts)
+	return r
+}
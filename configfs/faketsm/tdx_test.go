@@ -0,0 +1,76 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/internal/tdx"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestReportTdx(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &TdxOptions{AttestationKey: key, MrTd: [48]byte{0xd}, PckCertChain: []byte("chain")}
+	opts.Rtmrs[2] = [48]byte{0x2}
+	nonce := makeNonce(3)
+	resp, err := report.Get(ReportTdx(opts), &report.Request{InBlob: nonce})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	q := resp.OutBlob
+	if resp.Provider != "tdx_guest\n" {
+		t.Errorf("Provider = %q. Want tdx_guest", resp.Provider)
+	}
+	if v := binary.LittleEndian.Uint16(q[0:]); v != tdx.QuoteVersion {
+		t.Errorf("quote version = %d. Want %d", v, tdx.QuoteVersion)
+	}
+	body := q[tdx.HeaderSize : tdx.HeaderSize+tdx.BodySize]
+	// MRTD follows TEE_TCB_SVN, MRSEAM, MRSIGNERSEAM, and three 8-byte attribute fields.
+	const mrTdOffset = 16 + 48 + 48 + 3*8
+	if !bytes.Equal(body[mrTdOffset:mrTdOffset+48], opts.MrTd[:]) {
+		t.Errorf("MRTD = %v. Want %v", body[mrTdOffset:mrTdOffset+48], opts.MrTd)
+	}
+	const rtmr2Offset = mrTdOffset + 4*48 + 2*48
+	if !bytes.Equal(body[rtmr2Offset:rtmr2Offset+48], opts.Rtmrs[2][:]) {
+		t.Errorf("RTMR2 = %v. Want %v", body[rtmr2Offset:rtmr2Offset+48], opts.Rtmrs[2])
+	}
+	if !bytes.Equal(body[tdx.BodySize-64:], nonce) {
+		t.Errorf("REPORTDATA = %v. Want %v", body[tdx.BodySize-64:], nonce)
+	}
+	sigData := q[tdx.HeaderSize+tdx.BodySize+4:]
+	if got := binary.LittleEndian.Uint32(q[tdx.HeaderSize+tdx.BodySize:]); int(got) != len(sigData) {
+		t.Errorf("signature data length = %d. Want %d", got, len(sigData))
+	}
+	digest := sha256.Sum256(q[:tdx.HeaderSize+tdx.BodySize])
+	r := new(big.Int).SetBytes(sigData[:32])
+	s := new(big.Int).SetBytes(sigData[32:64])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("quote signature does not verify with the attestation key")
+	}
+	if !bytes.HasSuffix(q, []byte("chain")) {
+		t.Error("quote does not end with the PCK certificate chain")
+	}
+}
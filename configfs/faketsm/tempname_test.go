@@ -0,0 +1,36 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketsm
+
+import (
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestTempName(t *testing.T) {
+	r := Report611(0)
+	r.TempName = configfsi.SequentialNames()
+	for _, want := range []string{"entry0", "entry1"} {
+		resp, err := report.Get(r, &report.Request{InBlob: makeNonce(1)})
+		if err != nil {
+			t.Fatalf("report.Get() = _, %v. Want nil", err)
+		}
+		if resp.Entry != want {
+			t.Errorf("report.Get() entry = %q. Want %q", resp.Entry, want)
+		}
+	}
+}
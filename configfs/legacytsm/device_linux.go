@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// fileIoctl returns an ioctlFunc for f.
+func fileIoctl(f *os.File) ioctlFunc {
+	return func(cmd uintptr, arg unsafe.Pointer) error {
+		for {
+			_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), cmd, uintptr(arg))
+			if errno == syscall.EINTR {
+				continue
+			}
+			if errno != 0 {
+				return errno
+			}
+			return nil
+		}
+	}
+}
+
+// OpenSevGuest opens /dev/sev-guest as a Device.
+func OpenSevGuest() (Device, error) {
+	f, err := os.OpenFile(sevGuestPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &sevGuest{file: f, ioctl: fileIoctl(f)}, nil
+}
+
+// OpenTdxGuest opens /dev/tdx_guest as a Device.
+func OpenTdxGuest() (*TdxGuest, error) {
+	f, err := os.OpenFile(tdxGuestPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &TdxGuest{file: f, ioctl: fileIoctl(f)}, nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package legacytsm defines a configfsi.Client that emulates the configfs-tsm report
+// subsystem with a guest driver's ioctls, for kernels that predate configfs-tsm. Programs
+// that use the report package then have a single code path across kernel generations.
+package legacytsm
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+)
+
+const (
+	subsystemName = "report"
+	inBlobSize    = 64
+)
+
+// DeviceRequest is a report request to a guest device.
+type DeviceRequest struct {
+	// InBlob is the report data, zero-padded to 64 bytes.
+	InBlob [inBlobSize]byte
+	// Privilege is the privilege level written to privlevel, or 0.
+	Privilege uint
+	// AuxBlob is whether to also fetch the auxiliary blob, e.g., SEV-SNP certificates.
+	AuxBlob bool
+}
+
+// DeviceResponse is the result of a report request to a guest device.
+type DeviceResponse struct {
+	OutBlob []byte
+	AuxBlob []byte
+}
+
+// Device produces attestation reports through a guest driver.
+type Device interface {
+	// Provider returns the provider attribute value that configfs-tsm would show for the
+	// device's driver, e.g., "sev_guest".
+	Provider() string
+	// Privilege returns whether the device supports privlevel, and its privlevel_floor.
+	Privilege() (bool, uint)
+	// HasAuxBlob returns whether the device provides an auxblob.
+	HasAuxBlob() bool
+	// Report returns a report for the request.
+	Report(req *DeviceRequest) (*DeviceResponse, error)
+	// Close releases the device.
+	Close() error
+}
+
+// entry is an emulated report entry.
+type entry struct {
+	inblob     [inBlobSize]byte
+	privlevel  uint
+	generation uint64
+	// response caches the report for responseGeneration.
+	response           *DeviceResponse
+	responseGeneration uint64
+}
+
+// Client emulates the configfs-tsm report subsystem with a Device.
+type Client struct {
+	device Device
+	// Random is the source of randomness for MkdirTemp.
+	Random  io.Reader
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewClient returns a client that emulates the report subsystem with device.
+func NewClient(device Device) *Client {
+	return &Client{device: device, Random: rand.Reader, entries: make(map[string]*entry)}
+}
+
+// Close closes the client's device.
+func (c *Client) Close() error {
+	return c.device.Close()
+}
+
+// MakeClient returns a linuxtsm client if configfs-tsm reports are available and otherwise a
+// client for /dev/sev-guest. It returns the linuxtsm error if there is no guest device
+// either. /dev/tdx_guest is not used, since it produces TDREPORTs rather than quotes; use
+// NewClient with an OpenTdxGuest device whose Quote is set instead.
+func MakeClient() (configfsi.Client, error) {
+	client, err := linuxtsm.MakeClient()
+	if err == nil || !(errors.Is(err, linuxtsm.ErrConfigfsNotMounted) || errors.Is(err, linuxtsm.ErrReportUnsupported)) {
+		return client, err
+	}
+	for _, open := range devices {
+		device, derr := open()
+		if derr == nil {
+			return NewClient(device), nil
+		}
+		if !errors.Is(derr, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w; guest device fallback: %v", err, derr)
+		}
+	}
+	return nil, err
+}
+
+// devices are the guest device constructors that MakeClient tries in order.
+var devices = []func() (Device, error){
+	OpenSevGuest,
+}
+
+// parse returns the report entry path p of name, which must be in the report subsystem.
+func parse(name string) (*configfsi.TsmPath, error) {
+	p, err := configfsi.ParseTsmPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.Subsystem != subsystemName {
+		return nil, &os.PathError{Op: "legacytsm", Path: name, Err: syscall.ENOENT}
+	}
+	return p, nil
+}
+
+// attributes returns the names of the entry attributes that the device supports.
+func (c *Client) attributes() []string {
+	result := []string{"generation", "inblob", "outblob", "provider"}
+	if ok, _ := c.device.Privilege(); ok {
+		result = append(result, "privlevel", "privlevel_floor")
+	}
+	if c.device.HasAuxBlob() {
+		result = append(result, "auxblob")
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (c *Client) hasAttribute(attr string) bool {
+	for _, a := range c.attributes() {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the named entry. Called while holding mu.
+func (c *Client) lookup(name string, p *configfsi.TsmPath) (*entry, error) {
+	e, ok := c.entries[p.Entry]
+	if !ok {
+		return nil, &os.PathError{Op: "legacytsm", Path: name, Err: syscall.ENOENT}
+	}
+	return e, nil
+}
+
+// MkdirTemp creates a new report entry.
+func (c *Client) MkdirTemp(dir, pattern string) (string, error) {
+	p, err := parse(dir)
+	if err != nil {
+		return "", err
+	}
+	if p.Entry != "" {
+		return "", &os.PathError{Op: "mkdir", Path: dir, Err: syscall.EPERM}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name, err := configfsi.MakeTemp(c.Random, pattern, func(name string) error {
+		if _, ok := c.entries[name]; ok {
+			return os.ErrExist
+		}
+		c.entries[name] = &entry{}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, name), nil
+}
+
+// ReadDir lists the report entries or the attributes of an entry.
+func (c *Client) ReadDir(dirname string) ([]os.DirEntry, error) {
+	p, err := parse(dirname)
+	if err != nil {
+		return nil, err
+	}
+	if p.Attribute != "" {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: syscall.ENOTDIR}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var result []os.DirEntry
+	if p.Entry == "" {
+		for name := range c.entries {
+			result = append(result, &dirEntry{name: name, mode: fs.ModeDir | 0755})
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+		return result, nil
+	}
+	if _, err := c.lookup(dirname, p); err != nil {
+		return nil, err
+	}
+	schema := configfsi.Schema(subsystemName)
+	for _, name := range c.attributes() {
+		mode := fs.FileMode(0444)
+		if schema.Attribute(name).Access == configfsi.WriteOnly {
+			mode = 0200
+		}
+		result = append(result, &dirEntry{name: name, mode: mode})
+	}
+	return result, nil
+}
+
+// ReadFile reads a report entry attribute. Reading outblob or auxblob requests a report
+// from the device once per generation.
+func (c *Client) ReadFile(name string) ([]byte, error) {
+	p, err := parse(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.lookup(name, p)
+	if err != nil {
+		return nil, err
+	}
+	if !c.hasAttribute(p.Attribute) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+	switch p.Attribute {
+	case "generation":
+		return []byte(fmt.Sprintf("%d\n", e.generation)), nil
+	case "provider":
+		return []byte(c.device.Provider() + "\n"), nil
+	case "privlevel_floor":
+		_, floor := c.device.Privilege()
+		return []byte(fmt.Sprintf("%d\n", floor)), nil
+	case "outblob", "auxblob":
+		if e.response == nil || e.responseGeneration != e.generation {
+			resp, err := c.device.Report(&DeviceRequest{
+				InBlob:    e.inblob,
+				Privilege: e.privlevel,
+				AuxBlob:   c.device.HasAuxBlob(),
+			})
+			if err != nil {
+				return nil, &os.PathError{Op: "read", Path: name, Err: err}
+			}
+			e.response = resp
+			e.responseGeneration = e.generation
+		}
+		if p.Attribute == "auxblob" {
+			return e.response.AuxBlob, nil
+		}
+		return e.response.OutBlob, nil
+	}
+	return nil, &os.PathError{Op: "read", Path: name, Err: syscall.EACCES}
+}
+
+// WriteFile writes inblob or privlevel and advances the entry's generation.
+func (c *Client) WriteFile(name string, contents []byte) error {
+	p, err := parse(name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.lookup(name, p)
+	if err != nil {
+		return err
+	}
+	if !c.hasAttribute(p.Attribute) {
+		return &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+	switch p.Attribute {
+	case "inblob":
+		if len(contents) > inBlobSize {
+			return &os.PathError{Op: "write", Path: name, Err: syscall.EINVAL}
+		}
+		e.inblob = [inBlobSize]byte{}
+		copy(e.inblob[:], contents)
+	case "privlevel":
+		level, err := configfsi.Kstrtouint(contents, 10, 32)
+		_, floor := c.device.Privilege()
+		if err != nil || uint(level) < floor {
+			return &os.PathError{Op: "write", Path: name, Err: syscall.EINVAL}
+		}
+		e.privlevel = uint(level)
+	default:
+		return &os.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	}
+	e.generation++
+	return nil
+}
+
+// RemoveAll removes a report entry.
+func (c *Client) RemoveAll(name string) error {
+	p, err := parse(name)
+	if err != nil {
+		return err
+	}
+	if p.Entry == "" || p.Attribute != "" {
+		return &os.PathError{Op: "rmdir", Path: name, Err: syscall.EPERM}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, p.Entry)
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// fakeDevice echoes the request in its outblob.
+type fakeDevice struct {
+	requests int
+}
+
+func (*fakeDevice) Provider() string        { return "fake" }
+func (*fakeDevice) Privilege() (bool, uint) { return true, 1 }
+func (*fakeDevice) HasAuxBlob() bool        { return true }
+func (*fakeDevice) Close() error            { return nil }
+
+func (d *fakeDevice) Report(req *DeviceRequest) (*DeviceResponse, error) {
+	d.requests++
+	return &DeviceResponse{
+		OutBlob: append([]byte{byte(req.Privilege)}, req.InBlob[:]...),
+		AuxBlob: []byte("certs"),
+	}, nil
+}
+
+func TestGet(t *testing.T) {
+	device := &fakeDevice{}
+	client := NewClient(device)
+	inblob := bytes.Repeat([]byte{7}, 32)
+	resp, err := report.Get(client, &report.Request{
+		InBlob:     inblob,
+		Privilege:  &report.Privilege{Level: 2},
+		GetAuxBlob: true,
+	})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	want := append([]byte{2}, inblob...)
+	want = append(want, make([]byte, 32)...)
+	if !bytes.Equal(resp.OutBlob, want) {
+		t.Errorf("OutBlob = %v. Want %v", resp.OutBlob, want)
+	}
+	if string(resp.AuxBlob) != "certs" {
+		t.Errorf("AuxBlob = %q. Want %q", resp.AuxBlob, "certs")
+	}
+	if device.requests != 1 {
+		t.Errorf("device requests = %d. Want 1", device.requests)
+	}
+	if len(client.entries) != 0 {
+		t.Errorf("entries = %v. Want none after Get", client.entries)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	client := NewClient(&fakeDevice{})
+	entry, err := client.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		attr     string
+		contents []byte
+		want     error
+	}{
+		{attr: "inblob", contents: make([]byte, 65), want: syscall.EINVAL},
+		{attr: "privlevel", contents: []byte("0"), want: syscall.EINVAL},
+		{attr: "privlevel", contents: []byte("x"), want: syscall.EINVAL},
+		{attr: "outblob", contents: []byte("x"), want: syscall.EACCES},
+		{attr: "manifestblob", contents: []byte("x"), want: syscall.ENOENT},
+	}
+	for _, tc := range tcs {
+		if err := client.WriteFile(entry+"/"+tc.attr, tc.contents); !errors.Is(err, tc.want) {
+			t.Errorf("WriteFile(%q, %q) = %v. Want %v", tc.attr, tc.contents, err, tc.want)
+		}
+	}
+	if err := client.RemoveAll(entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadFile(entry + "/generation"); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("ReadFile after RemoveAll = _, %v. Want ENOENT", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"syscall"
+	"unsafe"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+)
+
+const (
+	// sevGuestPath is the AMD SEV-SNP guest driver's device.
+	sevGuestPath = "/dev/sev-guest"
+	// ProviderSevGuest is the provider attribute value of the SEV-SNP guest driver.
+	ProviderSevGuest = "sev_guest"
+
+	// snpGetReport is SNP_GET_REPORT, _IOWR('S', 0x0, struct snp_guest_request_ioctl).
+	snpGetReport = 0xc0205300
+	// snpGetExtReport is SNP_GET_EXT_REPORT, _IOWR('S', 0x2, struct snp_guest_request_ioctl).
+	snpGetExtReport = 0xc0205302
+	snpMsgVersion   = 1
+	// snpMaxVmpl is the highest VMPL, which privlevel selects.
+	snpMaxVmpl = 3
+	// snpReportRespHeaderSize is the size of the MSG_REPORT_RSP header before the report.
+	snpReportRespHeaderSize = 0x20
+	// snpCertsLen is the initial certificate buffer size, SEV_FW_BLOB_MAX_SIZE.
+	snpCertsLen = 0x4000
+	// snpVmmErrInvalidLen is SNP_GUEST_VMM_ERR_INVALID_LEN, the VMM error when the
+	// certificate buffer is too small. The kernel sets certs_len to the required size.
+	snpVmmErrInvalidLen = 1
+)
+
+// snpReportReq is struct snp_report_req.
+type snpReportReq struct {
+	UserData [inBlobSize]byte
+	Vmpl     uint32
+	_        [28]byte
+}
+
+// snpExtReportReq is struct snp_ext_report_req.
+type snpExtReportReq struct {
+	Data         snpReportReq
+	CertsAddress unsafe.Pointer
+	CertsLen     uint32
+	_            [4]byte
+}
+
+// snpReportResp is struct snp_report_resp.
+type snpReportResp struct {
+	Data [4000]byte
+}
+
+// snpGuestRequestIoctl is struct snp_guest_request_ioctl. The kernel's __u64 addresses are
+// pointers here so that the garbage collector keeps the buffers alive, which matches the
+// kernel layout on 64-bit guests, the only ones with SEV-SNP.
+type snpGuestRequestIoctl struct {
+	MsgVersion uint8
+	_          [7]byte
+	ReqData    unsafe.Pointer
+	RespData   *snpReportResp
+	// ExitInfo2 holds the firmware error in its low and the VMM error in its high 32 bits.
+	ExitInfo2 uint64
+}
+
+// SevGuestError is a failed SEV-SNP guest request.
+type SevGuestError struct {
+	// Errno is the ioctl error.
+	Errno syscall.Errno
+	// FirmwareError and VmmError are the error codes from the guest request's exitinfo2.
+	FirmwareError uint32
+	VmmError      uint32
+}
+
+// Error returns the errno and the firmware and VMM error codes.
+func (e *SevGuestError) Error() string {
+	return fmt.Sprintf("sev-guest request failed: %v (firmware error 0x%x, vmm error 0x%x)",
+		e.Errno, e.FirmwareError, e.VmmError)
+}
+
+// Unwrap returns the errno.
+func (e *SevGuestError) Unwrap() error {
+	return e.Errno
+}
+
+// ioctlFunc issues an ioctl with the given command and argument.
+type ioctlFunc func(cmd uintptr, arg unsafe.Pointer) error
+
+// sevGuest is a Device for /dev/sev-guest.
+type sevGuest struct {
+	file  io.Closer
+	ioctl ioctlFunc
+}
+
+// Provider returns "sev_guest".
+func (*sevGuest) Provider() string { return ProviderSevGuest }
+
+// Privilege returns that privlevel selects the VMPL. The floor is 0, since the guest's own
+// VMPL is not visible without configfs-tsm.
+func (*sevGuest) Privilege() (bool, uint) { return true, 0 }
+
+// HasAuxBlob returns true, since the auxblob is the certificate table.
+func (*sevGuest) HasAuxBlob() bool { return true }
+
+// Close closes the device.
+func (d *sevGuest) Close() error { return d.file.Close() }
+
+// request issues a guest request ioctl and returns its error as a *SevGuestError.
+func (d *sevGuest) request(cmd uintptr, req unsafe.Pointer, resp *snpReportResp) error {
+	arg := snpGuestRequestIoctl{
+		MsgVersion: snpMsgVersion,
+		ReqData:    req,
+		RespData:   resp,
+	}
+	err := d.ioctl(cmd, unsafe.Pointer(&arg))
+	if err == nil {
+		return nil
+	}
+	result := &SevGuestError{
+		FirmwareError: uint32(arg.ExitInfo2),
+		VmmError:      uint32(arg.ExitInfo2 >> 32),
+	}
+	if errno, ok := err.(syscall.Errno); ok {
+		result.Errno = errno
+	} else {
+		return err
+	}
+	return result
+}
+
+// Report returns the SEV-SNP attestation report and, for an auxblob, the certificate table.
+func (d *sevGuest) Report(req *DeviceRequest) (*DeviceResponse, error) {
+	if req.Privilege > snpMaxVmpl {
+		return nil, syscall.EINVAL
+	}
+	data := snpReportReq{UserData: req.InBlob, Vmpl: uint32(req.Privilege)}
+	resp := &snpReportResp{}
+	if !req.AuxBlob {
+		if err := d.request(snpGetReport, unsafe.Pointer(&data), resp); err != nil {
+			return nil, err
+		}
+		outblob, err := parseSnpReportResp(resp)
+		return &DeviceResponse{OutBlob: outblob}, err
+	}
+	certs := make([]byte, snpCertsLen)
+	for {
+		ext := snpExtReportReq{
+			Data:         data,
+			CertsAddress: unsafe.Pointer(&certs[0]),
+			CertsLen:     uint32(len(certs)),
+		}
+		err := d.request(snpGetExtReport, unsafe.Pointer(&ext), resp)
+		if gerr, ok := err.(*SevGuestError); ok && gerr.VmmError == snpVmmErrInvalidLen &&
+			int(ext.CertsLen) > len(certs) {
+			certs = make([]byte, ext.CertsLen)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		outblob, err := parseSnpReportResp(resp)
+		return &DeviceResponse{OutBlob: outblob, AuxBlob: trimCertTable(certs)}, err
+	}
+}
+
+// parseSnpReportResp returns the attestation report in a MSG_REPORT_RSP message.
+func parseSnpReportResp(resp *snpReportResp) ([]byte, error) {
+	status := binary.LittleEndian.Uint32(resp.Data[0:4])
+	size := binary.LittleEndian.Uint32(resp.Data[4:8])
+	if status != 0 {
+		return nil, fmt.Errorf("sev-guest report status 0x%x", status)
+	}
+	if int(size) > len(resp.Data)-snpReportRespHeaderSize {
+		return nil, fmt.Errorf("sev-guest report size %d exceeds the response", size)
+	}
+	return append([]byte(nil), resp.Data[snpReportRespHeaderSize:snpReportRespHeaderSize+size]...), nil
+}
+
+// trimCertTable returns the certificate table and certificates without the unused end of
+// the buffer. The table ends with an all-zero entry.
+func trimCertTable(certs []byte) []byte {
+	end := 0
+	for i := 0; i+snp.CertTableEntrySize <= len(certs); i += snp.CertTableEntrySize {
+		e := certs[i : i+snp.CertTableEntrySize]
+		offset := binary.LittleEndian.Uint32(e[16:20])
+		length := binary.LittleEndian.Uint32(e[20:24])
+		if offset == 0 && length == 0 {
+			if end < i+snp.CertTableEntrySize {
+				end = i + snp.CertTableEntrySize
+			}
+			if end == snp.CertTableEntrySize {
+				// There are no certificates.
+				return nil
+			}
+			return certs[:end]
+		}
+		if last := int(offset) + int(length); last > end && last <= len(certs) {
+			end = last
+		}
+	}
+	return certs
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+)
+
+func TestSnpLayout(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("SEV-SNP guests are 64-bit")
+	}
+	sizes := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"snp_report_req", unsafe.Sizeof(snpReportReq{}), 96},
+		{"snp_ext_report_req", unsafe.Sizeof(snpExtReportReq{}), 112},
+		{"snp_report_resp", unsafe.Sizeof(snpReportResp{}), 4000},
+		{"snp_guest_request_ioctl", unsafe.Sizeof(snpGuestRequestIoctl{}), 32},
+	}
+	for _, s := range sizes {
+		if s.got != s.want {
+			t.Errorf("sizeof(%s) = %d. Want %d", s.name, s.got, s.want)
+		}
+	}
+}
+
+// fakeSnp emulates the sev-guest driver for a certificate table of certsLen bytes.
+func fakeSnp(certsLen uint32) ioctlFunc {
+	return func(cmd uintptr, arg unsafe.Pointer) error {
+		ioctl := (*snpGuestRequestIoctl)(arg)
+		req := (*snpReportReq)(ioctl.ReqData)
+		resp := ioctl.RespData
+		if cmd == snpGetExtReport {
+			ext := (*snpExtReportReq)(ioctl.ReqData)
+			if ext.CertsLen < certsLen {
+				ext.CertsLen = certsLen
+				ioctl.ExitInfo2 = uint64(snpVmmErrInvalidLen) << 32
+				return syscall.EINVAL
+			}
+			certs := unsafe.Slice((*byte)(ext.CertsAddress), ext.CertsLen)
+			binary.LittleEndian.PutUint32(certs[16:20], 2*snp.CertTableEntrySize)
+			binary.LittleEndian.PutUint32(certs[20:24], certsLen-2*snp.CertTableEntrySize)
+			for i := 2 * snp.CertTableEntrySize; i < int(certsLen); i++ {
+				certs[i] = 0xc
+			}
+		} else if cmd != snpGetReport {
+			return syscall.ENOTTY
+		}
+		binary.LittleEndian.PutUint32(resp.Data[4:8], 0x4a0)
+		copy(resp.Data[snpReportRespHeaderSize:], req.UserData[:])
+		resp.Data[snpReportRespHeaderSize+inBlobSize] = byte(req.Vmpl)
+		return nil
+	}
+}
+
+func TestSevGuestReport(t *testing.T) {
+	for _, certsLen := range []uint32{0x1000, 0x5000} {
+		d := &sevGuest{file: io.NopCloser(nil), ioctl: fakeSnp(certsLen)}
+		req := &DeviceRequest{Privilege: 1, AuxBlob: true}
+		copy(req.InBlob[:], "nonce")
+		resp, err := d.Report(req)
+		if err != nil {
+			t.Fatalf("Report() = _, %v. Want nil", err)
+		}
+		if len(resp.OutBlob) != 0x4a0 || !bytes.HasPrefix(resp.OutBlob, []byte("nonce")) ||
+			resp.OutBlob[inBlobSize] != 1 {
+			t.Errorf("OutBlob = %v. Want a report of the request", resp.OutBlob)
+		}
+		if len(resp.AuxBlob) != int(certsLen) {
+			t.Errorf("len(AuxBlob) = %d. Want %d", len(resp.AuxBlob), certsLen)
+		}
+	}
+}
+
+func TestSevGuestError(t *testing.T) {
+	d := &sevGuest{file: io.NopCloser(nil), ioctl: func(cmd uintptr, arg unsafe.Pointer) error {
+		(*snpGuestRequestIoctl)(arg).ExitInfo2 = 0x2_00000016
+		return syscall.EIO
+	}}
+	_, err := d.Report(&DeviceRequest{})
+	var gerr *SevGuestError
+	if !errors.As(err, &gerr) || gerr.FirmwareError != 0x16 || gerr.VmmError != 2 {
+		t.Errorf("Report() = _, %v. Want a SevGuestError with firmware error 0x16 and vmm error 2", err)
+	}
+	if !errors.Is(err, syscall.EIO) {
+		t.Errorf("Report() = _, %v. Want EIO", err)
+	}
+	if _, err := d.Report(&DeviceRequest{Privilege: 4}); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Report(privilege 4) = _, %v. Want EINVAL", err)
+	}
+}
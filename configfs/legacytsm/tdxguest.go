@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"io"
+	"unsafe"
+)
+
+const (
+	// tdxGuestPath is the Intel TDX guest driver's device.
+	tdxGuestPath = "/dev/tdx_guest"
+	// ProviderTdxGuest is the provider attribute value of the TDX guest driver, whose
+	// outblob is a quote.
+	ProviderTdxGuest = "tdx_guest"
+	// ProviderTdxGuestReport is the provider of a TdxGuest without a Quote function, whose
+	// outblob is a TDREPORT. It differs from ProviderTdxGuest so that consumers that parse
+	// tdx_guest outblobs as quotes do not misparse a TDREPORT.
+	ProviderTdxGuestReport = "tdx_guest_report"
+
+	// tdxCmdGetReport0 is TDX_CMD_GET_REPORT0, _IOWR('T', 1, struct tdx_report_req).
+	tdxCmdGetReport0 = 0xc4405401
+	// tdReportSize is the size of a TDREPORT_STRUCT.
+	tdReportSize = 1024
+)
+
+// tdxReportReq is struct tdx_report_req.
+type tdxReportReq struct {
+	ReportData [inBlobSize]byte
+	TdReport   [tdReportSize]byte
+}
+
+// TdxGuest is a Device for /dev/tdx_guest.
+//
+// The device only produces a TDREPORT, which is not signed for remote verification.
+// configfs-tsm's outblob is instead a quote from the quote generation service, so for
+// identical Response semantics, set Quote to a function that has the TDREPORT quoted.
+// Without Quote, the provider is ProviderTdxGuestReport.
+type TdxGuest struct {
+	// Quote returns the quote for a TDREPORT. If nil, the outblob is the TDREPORT.
+	Quote func(tdreport []byte) ([]byte, error)
+	file  io.Closer
+	ioctl ioctlFunc
+}
+
+// Provider returns ProviderTdxGuest, or ProviderTdxGuestReport if the device has no Quote
+// function.
+func (d *TdxGuest) Provider() string {
+	if d.Quote == nil {
+		return ProviderTdxGuestReport
+	}
+	return ProviderTdxGuest
+}
+
+// Privilege returns false, since TDX has no privlevel.
+func (*TdxGuest) Privilege() (bool, uint) { return false, 0 }
+
+// HasAuxBlob returns false, since TDX has no auxblob.
+func (*TdxGuest) HasAuxBlob() bool { return false }
+
+// Close closes the device.
+func (d *TdxGuest) Close() error { return d.file.Close() }
+
+// Report returns the quote of a TDREPORT for the request, or the TDREPORT itself if the
+// device has no Quote function.
+func (d *TdxGuest) Report(req *DeviceRequest) (*DeviceResponse, error) {
+	arg := &tdxReportReq{ReportData: req.InBlob}
+	if err := d.ioctl(tdxCmdGetReport0, unsafe.Pointer(arg)); err != nil {
+		return nil, err
+	}
+	tdreport := append([]byte(nil), arg.TdReport[:]...)
+	if d.Quote == nil {
+		return &DeviceResponse{OutBlob: tdreport}, nil
+	}
+	quote, err := d.Quote(tdreport)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceResponse{OutBlob: quote}, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacytsm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func fakeTdx(cmd uintptr, arg unsafe.Pointer) error {
+	if cmd != tdxCmdGetReport0 {
+		return syscall.ENOTTY
+	}
+	req := (*tdxReportReq)(arg)
+	copy(req.TdReport[512:], req.ReportData[:])
+	return nil
+}
+
+func TestTdxGuestReport(t *testing.T) {
+	if got := unsafe.Sizeof(tdxReportReq{}); got != 1088 {
+		t.Errorf("sizeof(tdx_report_req) = %d. Want 1088", got)
+	}
+	d := &TdxGuest{file: io.NopCloser(nil), ioctl: fakeTdx}
+	req := &DeviceRequest{}
+	copy(req.InBlob[:], "nonce")
+	resp, err := d.Report(req)
+	if err != nil {
+		t.Fatalf("Report() = _, %v. Want nil", err)
+	}
+	if len(resp.OutBlob) != tdReportSize || !bytes.HasPrefix(resp.OutBlob[512:], []byte("nonce")) {
+		t.Errorf("OutBlob = %v. Want the TDREPORT", resp.OutBlob)
+	}
+	if got := d.Provider(); got != ProviderTdxGuestReport {
+		t.Errorf("Provider() without Quote = %q. Want %q", got, ProviderTdxGuestReport)
+	}
+
+	d.Quote = func(tdreport []byte) ([]byte, error) { return append([]byte("quote:"), tdreport...), nil }
+	resp, err = d.Report(req)
+	if err != nil || !bytes.HasPrefix(resp.OutBlob, []byte("quote:")) {
+		t.Errorf("Report() = %v, %v. Want a quote", resp, err)
+	}
+	if got := d.Provider(); got != ProviderTdxGuest {
+		t.Errorf("Provider() with Quote = %q. Want %q", got, ProviderTdxGuest)
+	}
+
+	errQuote := errors.New("no quote generation service")
+	d.Quote = func([]byte) ([]byte, error) { return nil, errQuote }
+	if _, err := d.Report(req); !errors.Is(err, errQuote) {
+		t.Errorf("Report() = _, %v. Want %v", err, errQuote)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// Access modes for access checks, as in access(2).
+const (
+	accessRead  = 0x4
+	accessWrite = 0x2
+	accessExec  = 0x1
+)
+
+// accessFunc returns an error if the process lacks the access mode to name.
+type accessFunc func(name string, mode uint32) error
+
+// entryAccess are the attributes that a report needs and the access it needs to them.
+var entryAccess = []struct {
+	attribute string
+	mode      uint32
+}{
+	{"inblob", accessWrite},
+	{"generation", accessRead},
+	{"provider", accessRead},
+	{"outblob", accessRead},
+}
+
+// AccessError is returned by CheckAccess when the process lacks access that reports need.
+// It matches ErrInsufficientPrivileges with errors.Is.
+type AccessError struct {
+	// Path is the file or directory that cannot be accessed.
+	Path string
+	// Need describes the missing access, e.g., "write".
+	Need string
+	// Remedy suggests how to grant the access.
+	Remedy string
+	Err    error
+}
+
+// Error returns the missing access and the remedy.
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("cannot %s %s: %v; %s", e.Need, e.Path, e.Err, e.Remedy)
+}
+
+// Unwrap returns the access check error.
+func (e *AccessError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns whether target is ErrInsufficientPrivileges.
+func (e *AccessError) Is(target error) bool {
+	return target == ErrInsufficientPrivileges
+}
+
+// CheckAccess returns an error if the process cannot request reports, before any report
+// request gets underway. It checks with faccessat(2) with the process's effective IDs that
+// it can create entries in the report directory and can write and read the attributes that
+// a report needs, which it checks on a temporary entry that it then removes. A missing
+// access is an *AccessError that suggests a remedy. If configfs-tsm is unavailable, the
+// error is a *ClientError as from MakeClient.
+func CheckAccess() error {
+	root, err := FindTsmRoot()
+	if err := checkReportSubsystem(root, err); err != nil {
+		return err
+	}
+	return checkAccessAt(root, access)
+}
+
+func checkAccessAt(root string, access accessFunc) error {
+	reportDir := path.Join(root, "report")
+	if err := access(reportDir, accessWrite|accessExec); err != nil {
+		return &AccessError{
+			Path:   reportDir,
+			Need:   "create report entries in",
+			Remedy: "run as root, or use a broker that creates entries on the process's behalf",
+			Err:    err,
+		}
+	}
+	entry, err := os.MkdirTemp(reportDir, "access")
+	if err != nil {
+		return &AccessError{
+			Path:   reportDir,
+			Need:   "create report entries in",
+			Remedy: "check that no security module denies mkdir in configfs",
+			Err:    err,
+		}
+	}
+	defer os.Remove(entry)
+	for _, a := range entryAccess {
+		name := path.Join(entry, a.attribute)
+		if err := access(name, a.mode); err != nil {
+			need := "read"
+			if a.mode == accessWrite {
+				need = "write"
+			}
+			return &AccessError{
+				Path: name,
+				Need: need,
+				Remedy: "configfs-tsm attributes are owned by root, so run as root or with " +
+					"CAP_DAC_OVERRIDE, or use a broker",
+				Err: err,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCheckAccessAt(t *testing.T) {
+	root := t.TempDir()
+	reportDir := path.Join(root, "report")
+	if err := os.Mkdir(reportDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		name     string
+		denied   string
+		wantNeed string
+	}{
+		{name: "allowed"},
+		{name: "report directory", denied: "report", wantNeed: "create report entries in"},
+		{name: "inblob", denied: "inblob", wantNeed: "write"},
+		{name: "outblob", denied: "outblob", wantNeed: "read"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkAccessAt(root, func(name string, mode uint32) error {
+				if tc.denied != "" && path.Base(name) == tc.denied {
+					return syscall.EACCES
+				}
+				return nil
+			})
+			if tc.denied == "" {
+				if err != nil {
+					t.Errorf("checkAccessAt() = %v. Want nil", err)
+				}
+				return
+			}
+			var aerr *AccessError
+			if !errors.As(err, &aerr) || aerr.Need != tc.wantNeed || !strings.HasSuffix(aerr.Path, tc.denied) {
+				t.Fatalf("checkAccessAt() = %v. Want an *AccessError to %s %s", err, tc.wantNeed, tc.denied)
+			}
+			if !errors.Is(err, ErrInsufficientPrivileges) || !errors.Is(err, syscall.EACCES) {
+				t.Errorf("checkAccessAt() = %v. Want ErrInsufficientPrivileges wrapping EACCES", err)
+			}
+			if aerr.Remedy == "" {
+				t.Error("Remedy is empty")
+			}
+		})
+	}
+	entries, err := os.ReadDir(reportDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("report entries = %v, %v. Want the probe entries removed", entries, err)
+	}
+}
@@ -19,20 +19,13 @@ package linuxtsm
 
 import (
 	"github.com/google/go-configfs-tsm/report"
-	"go.uber.org/multierr"
 )
 
-// GetReport returns a one-shot configfs-tsm report given a report request.
-func GetReport(req *report.Request) (*report.Response, error) {
-	var err error
+// GetReport returns a one-shot configfs-tsm report given a report request and options.
+func GetReport(req *report.Request, opts ...report.Option) (*report.Response, error) {
 	client, err := MakeClient()
 	if err != nil {
 		return nil, err
 	}
-	r, err := report.Create(client, req)
-	if err != nil {
-		return nil, err
-	}
-	response, err := r.Get()
-	return response, multierr.Combine(r.Destroy(), err)
+	return report.Get(client, req, opts...)
 }
@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"os"
+	"syscall"
+)
+
+// openFunc opens the named file with the given flags and returns its descriptor.
+type openFunc func(name string, flags int) (int, error)
+
+// openPath opens name relative to the working directory.
+func openPath(name string, flags int) (int, error) {
+	return syscall.Open(name, flags, 0)
+}
+
+// openRetry opens name with open, retrying EINTR.
+func openRetry(open openFunc, name string, flags int) (int, error) {
+	for {
+		fd, err := open(name, flags|syscall.O_CLOEXEC)
+		if err != syscall.EINTR {
+			if err != nil {
+				return -1, &os.PathError{Op: "open", Path: name, Err: err}
+			}
+			return fd, nil
+		}
+	}
+}
+
+// writeAttribute writes contents to an existing configfs attribute with one open and as
+// few writes as possible. Unlike os.WriteFile, it does not create or truncate the file,
+// which configfs attributes do not support. Errors wrap the raw errno, and write errors
+// are *TransferError values that report how many bytes were written.
+func writeAttribute(name string, contents []byte, observe TimingFunc) error {
+	return writeAttributeWith(openPath, name, contents, observe)
+}
+
+func writeAttributeWith(open openFunc, name string, contents []byte, observe TimingFunc) error {
+	var fd int
+	err := observe.timeErr("open", name, func() (err error) {
+		fd, err = openRetry(open, name, syscall.O_WRONLY)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// configfs stores binary attributes such as inblob when the file is released, and
+	// Linux discards the release error, so a failed store is not reported here. Only
+	// write errors, e.g., EFBIG past the attribute's maximum size, and close errors are.
+	written := 0
+	for written < len(contents) {
+		n, err := observe.time("write", name, func() (int, error) {
+			return syscall.Write(fd, contents[written:])
+		})
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == nil && n == 0 {
+			err = syscall.EIO
+		}
+		if err != nil {
+			syscall.Close(fd)
+			return &TransferError{Op: "write", Path: name, Done: written, Total: len(contents), Err: err}
+		}
+		written += n
+	}
+	if err := observe.timeErr("close", name, func() error { return syscall.Close(fd) }); err != nil {
+		return &os.PathError{Op: "close", Path: name, Err: err}
+	}
+	return nil
+}
+
+// readAttribute reads a configfs attribute until EOF, continuing after short reads and
+// EINTR. Errors wrap the raw errno, and read errors are *TransferError values that report
+// how many bytes were read.
+func readAttribute(name string, observe TimingFunc) ([]byte, error) {
+	return readAttributeWith(openPath, name, observe)
+}
+
+func readAttributeWith(open openFunc, name string, observe TimingFunc) ([]byte, error) {
+	var fd int
+	err := observe.timeErr("open", name, func() (err error) {
+		fd, err = openRetry(open, name, syscall.O_RDONLY)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+	data := make([]byte, 0, readChunkSize)
+	for {
+		if len(data) == cap(data) {
+			data = append(data, 0)[:len(data)]
+		}
+		n, err := observe.time("read", name, func() (int, error) {
+			return syscall.Read(fd, data[len(data):cap(data)])
+		})
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, &TransferError{Op: "read", Path: name, Done: len(data), Total: -1, Err: err}
+		}
+		if n == 0 {
+			return data, nil
+		}
+		data = data[:len(data)+n]
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+func TestReadAttribute(t *testing.T) {
+	dir := t.TempDir()
+	name := path.Join(dir, "outblob")
+	want := bytes.Repeat([]byte("0123456789abcdef"), 3*readChunkSize/16+5)
+	if err := os.WriteFile(name, want, 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readAttribute(name, nil)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("readAttribute(%d bytes) = %d bytes, %v, want the file", len(want), len(got), err)
+	}
+	_, err = readAttribute(dir, nil)
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) || transferErr.Op != "read" || !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("readAttribute(dir, nil) = _, %v, want *TransferError wrapping EISDIR", err)
+	}
+}
+
+func TestWriteAttributeReportsProgress(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full is not available")
+	}
+	err := writeAttribute("/dev/full", []byte("nonce"), nil)
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) || transferErr.Done != 0 || transferErr.Total != 5 || !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("writeAttribute(/dev/full) = %v, want *TransferError after 0 of 5 bytes wrapping ENOSPC", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package linuxtsm
+
+import "os"
+
+// writeAttribute writes contents to an existing attribute without creating or truncating it.
+func writeAttribute(name string, contents []byte, observe TimingFunc) error {
+	var f *os.File
+	if err := observe.timeErr("open", name, func() (err error) {
+		f, err = os.OpenFile(name, os.O_WRONLY, 0)
+		return err
+	}); err != nil {
+		return err
+	}
+	if _, err := observe.time("write", name, func() (int, error) { return f.Write(contents) }); err != nil {
+		f.Close()
+		return err
+	}
+	return observe.timeErr("close", name, f.Close)
+}
+
+// readAttribute reads an attribute in full. Its timing is reported as a single read.
+func readAttribute(name string, observe TimingFunc) ([]byte, error) {
+	var data []byte
+	_, err := observe.time("read", name, func() (n int, err error) {
+		data, err = os.ReadFile(name)
+		return len(data), err
+	})
+	return data, err
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package linuxtsm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+const (
+	// sysOpenat2 is the openat2 syscall number on all architectures but mips, which this
+	// file is not built for.
+	sysOpenat2 = 437
+	// resolveBeneath makes openat2 fail rather than resolve a path outside the dirfd.
+	resolveBeneath = 0x08
+	// atRemoveDir makes unlinkat remove a directory.
+	atRemoveDir = 0x200
+)
+
+// openHow is the struct open_how argument of openat2.
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// dirfdClient provides configfsi.Client for a pre-opened tsm directory descriptor.
+type dirfdClient struct {
+	dirfd int
+	// observe receives system call timings, if not nil.
+	observe TimingFunc
+}
+
+func (c *dirfdClient) withTiming(observe TimingFunc) configfsi.Client {
+	result := *c
+	result.observe = observe
+	return &result
+}
+
+// MakeDirfdClient returns a client that performs all operations relative to dirfd, an
+// open descriptor of the tsm directory, e.g., one inherited by a seccomp- or
+// landlock-confined process that cannot open /sys/kernel/config itself. The client takes
+// configfsi.TsmPrefix-based paths. Files, and the parents of directories that it creates
+// or removes, are opened with openat2 and RESOLVE_BENEATH so that no path can escape dirfd.
+// Operations fail on kernels without openat2 (before Linux 5.6) rather than resolve paths
+// unconfined. The caller keeps ownership of dirfd, which must stay open while the client is
+// in use.
+func MakeDirfdClient(dirfd int) (configfsi.Client, error) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(dirfd, &st); err != nil {
+		return nil, fmt.Errorf("fstat dirfd %d: %w", dirfd, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		return nil, fmt.Errorf("dirfd %d is not a directory", dirfd)
+	}
+	return &dirfdClient{dirfd: dirfd}, nil
+}
+
+// relPath returns name relative to the tsm directory.
+func (c *dirfdClient) relPath(name string) (string, error) {
+	p := path.Clean(name)
+	if p == configfsi.TsmPrefix {
+		return ".", nil
+	}
+	if !strings.HasPrefix(p, configfsi.TsmPrefix+"/") {
+		return "", &os.PathError{Op: "open", Path: name, Err: syscall.EXDEV}
+	}
+	return strings.TrimPrefix(p, configfsi.TsmPrefix+"/"), nil
+}
+
+// openat opens name beneath dirfd with openat2.
+func (c *dirfdClient) openat(name string, flags int) (int, error) {
+	rel, err := c.relPath(name)
+	if err != nil {
+		return -1, err
+	}
+	return c.openBeneath(rel, flags)
+}
+
+// openBeneath opens rel, a path relative to dirfd, with openat2 and RESOLVE_BENEATH.
+func (c *dirfdClient) openBeneath(rel string, flags int) (int, error) {
+	relPtr, err := syscall.BytePtrFromString(rel)
+	if err != nil {
+		return -1, err
+	}
+	how := openHow{flags: uint64(flags), resolve: resolveBeneath}
+	fd, _, errno := syscall.Syscall6(sysOpenat2, uintptr(c.dirfd), uintptr(unsafe.Pointer(relPtr)),
+		uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+	if errno == syscall.ENOSYS {
+		return -1, fmt.Errorf("openat2 is required to confine paths beneath dirfd: %w", errno)
+	}
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// inParent calls f with a descriptor of rel's parent directory, opened beneath dirfd, and
+// rel's final element.
+func (c *dirfdClient) inParent(rel string, f func(parentfd int, base string) error) error {
+	if rel == "." {
+		return syscall.EINVAL
+	}
+	parentfd, err := c.openBeneath(path.Dir(rel), syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(parentfd)
+	return f(parentfd, path.Base(rel))
+}
+
+// MkdirTemp creates a new temporary directory in the directory dir and returns the pathname
+// of the new directory. Pattern semantics follow os.MkdirTemp.
+func (c *dirfdClient) MkdirTemp(dir, pattern string) (string, error) {
+	rel, err := c.relPath(dir)
+	if err != nil {
+		return "", err
+	}
+	name, err := configfsi.MakeTemp(rand.Reader, pattern, func(name string) error {
+		if err := c.observe.timeErr("mkdir", path.Join(dir, name), func() error {
+			return c.inParent(path.Join(rel, name), func(parentfd int, base string) error {
+				return syscall.Mkdirat(parentfd, base, 0755)
+			})
+		}); err != nil {
+			return &os.PathError{Op: "mkdirat", Path: path.Join(dir, name), Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, name), nil
+}
+
+// Mkdir creates the named directory.
+func (c *dirfdClient) Mkdir(name string) error {
+	rel, err := c.relPath(name)
+	if err != nil {
+		return err
+	}
+	if err := c.observe.timeErr("mkdir", name, func() error {
+		return c.inParent(rel, func(parentfd int, base string) error {
+			return syscall.Mkdirat(parentfd, base, 0755)
+		})
+	}); err != nil {
+		return &os.PathError{Op: "mkdirat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// ReadFile reads the named file and returns the contents.
+func (c *dirfdClient) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := retryTransient(func() (err error) {
+		data, err = readAttributeWith(c.openat, name, c.observe)
+		return err
+	})
+	return data, err
+}
+
+// WriteFile writes data to the named attribute with a single open.
+func (c *dirfdClient) WriteFile(name string, contents []byte) error {
+	return retryTransient(func() error {
+		return writeAttributeWith(c.openat, name, contents, c.observe)
+	})
+}
+
+// ReadDir reads the directory named by dirname and returns a list of directory
+// entries sorted by filename.
+func (c *dirfdClient) ReadDir(dirname string) ([]os.DirEntry, error) {
+	fd, err := openRetry(c.openat, dirname, syscall.O_RDONLY|syscall.O_DIRECTORY)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), dirname)
+	defer f.Close()
+	entries, err := f.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// RemoveAll removes path and any children it contains, retrying while it is busy like
+// the path-based client.
+func (c *dirfdClient) RemoveAll(name string) error {
+	rel, err := c.relPath(name)
+	if err != nil {
+		return err
+	}
+	calls, err := retry(func() error {
+		return c.observe.timeErr("rmdir", name, func() error {
+			err := c.inParent(rel, func(parentfd int, base string) error {
+				basePtr, err := syscall.BytePtrFromString(base)
+				if err != nil {
+					return err
+				}
+				_, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(parentfd),
+					uintptr(unsafe.Pointer(basePtr)), atRemoveDir)
+				if errno != 0 {
+					return errno
+				}
+				return nil
+			})
+			if err != nil {
+				return &os.PathError{Op: "unlinkat", Path: name, Err: err}
+			}
+			return nil
+		})
+	}, configfsi.IsBusy, removeRetries, removeBackoff)
+	if configfsi.IsBusy(err) {
+		return &BusyError{Path: name, Attempts: calls, Err: err}
+	}
+	return err
+}
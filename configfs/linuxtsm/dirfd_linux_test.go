@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+func TestDirfdClient(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(path.Join(dir, "report"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dirfd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(dirfd)
+	c, err := MakeDirfdClient(dirfd)
+	if err != nil {
+		t.Fatalf("MakeDirfdClient() = _, %v, want nil", err)
+	}
+
+	entry, err := c.MkdirTemp("/sys/kernel/config/tsm/report", "entry")
+	if err != nil || !strings.HasPrefix(entry, "/sys/kernel/config/tsm/report/entry") {
+		t.Fatalf("MkdirTemp() = %q, %v, want a report entry", entry, err)
+	}
+	hostEntry := path.Join(dir, "report", path.Base(entry))
+	if err := os.WriteFile(path.Join(hostEntry, "inblob"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteFile(entry+"/inblob", []byte("nonce")); err != nil {
+		t.Fatalf("WriteFile() = %v, want nil", err)
+	}
+	if data, err := c.ReadFile(entry + "/inblob"); err != nil || string(data) != "nonce" {
+		t.Errorf("ReadFile() = %q, %v, want %q", data, err, "nonce")
+	}
+	entries, err := c.ReadDir(entry)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "inblob" {
+		t.Errorf("ReadDir() = %v, %v, want [inblob]", entries, err)
+	}
+	if _, err := c.ReadFile("/etc/hostname"); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("ReadFile(outside tsm) = _, %v, want EXDEV", err)
+	}
+	if err := os.Symlink("/etc", path.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadFile("/sys/kernel/config/tsm/escape/hostname"); err == nil {
+		t.Errorf("ReadFile(symlink escape) = _, nil, want an error")
+	}
+	outside := t.TempDir()
+	if err := os.Mkdir(path.Join(outside, "victim"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, path.Join(dir, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := configfsi.Mkdir(c, "/sys/kernel/config/tsm/outside/entry"); err == nil {
+		t.Errorf("Mkdir(symlink escape) = nil, want an error")
+	}
+	if _, err := os.Stat(path.Join(outside, "entry")); !os.IsNotExist(err) {
+		t.Errorf("Mkdir(symlink escape) created %s/entry: %v", outside, err)
+	}
+	if err := c.RemoveAll("/sys/kernel/config/tsm/outside/victim"); err == nil {
+		t.Errorf("RemoveAll(symlink escape) = nil, want an error")
+	}
+	if _, err := os.Stat(path.Join(outside, "victim")); err != nil {
+		t.Errorf("RemoveAll(symlink escape) removed %s/victim: %v", outside, err)
+	}
+	if err := os.Remove(path.Join(hostEntry, "inblob")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveAll(entry); err != nil {
+		t.Errorf("RemoveAll() = %v, want nil", err)
+	}
+	if _, err := os.Stat(hostEntry); !os.IsNotExist(err) {
+		t.Errorf("entry after RemoveAll: %v, want not exist", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+var (
+	// ErrConfigfsNotMounted means that no configfs filesystem is mounted.
+	ErrConfigfsNotMounted = errors.New("configfs is not mounted")
+	// ErrReportUnsupported means that configfs is mounted but the kernel does not provide
+	// the tsm report subsystem, e.g., because it predates Linux 6.7.
+	ErrReportUnsupported = errors.New("the kernel does not support configfs-tsm reports")
+	// ErrNoProvider means that the tsm report subsystem exists but no TSM provider, such as
+	// the sev_guest or tdx_guest driver, is registered.
+	ErrNoProvider = errors.New("no TSM provider is registered")
+	// ErrInsufficientPrivileges means that the process may not create report entries.
+	ErrInsufficientPrivileges = errors.New("insufficient privileges to use configfs-tsm")
+)
+
+// ClientError is returned by MakeClient, Provider, and the client's ReadFile when
+// configfs-tsm cannot be used. It matches its Kind with errors.Is.
+type ClientError struct {
+	// Kind is ErrConfigfsNotMounted, ErrReportUnsupported, ErrNoProvider, or
+	// ErrInsufficientPrivileges.
+	Kind error
+	// Path is the path that was checked.
+	Path string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the reason and the path that was checked.
+func (e *ClientError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v: %s", e.Kind, e.Path)
+	}
+	return fmt.Sprintf("%v: %s: %v", e.Kind, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ClientError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns whether target is the error's Kind.
+func (e *ClientError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// BusyError is returned by RemoveAll when an entry is still busy after all retries.
+type BusyError struct {
+	Path string
+	// Attempts is the number of removals tried.
+	Attempts int
+	// Err is the last removal error, which wraps EBUSY.
+	Err error
+}
+
+// Error returns the path and the number of attempts.
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s is still busy after %d removal attempts: %v", e.Path, e.Attempts, e.Err)
+}
+
+// Unwrap returns the last removal error.
+func (e *BusyError) Unwrap() error {
+	return e.Err
+}
+
+// isPermission returns whether err means that the process lacks privileges.
+func isPermission(err error) bool {
+	return errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// probeProvider returns the provider of the report subsystem under root. It creates a
+// temporary report entry to read the provider attribute and removes it before returning.
+func probeProvider(root string) (string, error) {
+	reportDir := path.Join(root, "report")
+	entry, err := os.MkdirTemp(reportDir, "probe")
+	if err != nil {
+		if isPermission(err) {
+			return "", &ClientError{Kind: ErrInsufficientPrivileges, Path: reportDir, Err: err}
+		}
+		return "", err
+	}
+	defer os.Remove(entry)
+	data, err := os.ReadFile(path.Join(entry, "provider"))
+	if err != nil {
+		if errors.Is(err, syscall.ENXIO) {
+			return "", &ClientError{Kind: ErrNoProvider, Path: reportDir, Err: err}
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// checkReportSubsystem returns a *ClientError if the report subsystem under root, as
+// returned by FindTsmRoot with findErr, does not exist or the process may not create
+// entries in it.
+func checkReportSubsystem(root string, findErr error) error {
+	if findErr != nil {
+		mounts, err := ConfigfsMounts()
+		if err == nil && len(mounts) == 0 {
+			return &ClientError{Kind: ErrConfigfsNotMounted, Path: mountInfoPath}
+		}
+		return &ClientError{Kind: ErrReportUnsupported, Path: configfsi.TsmPrefix, Err: findErr}
+	}
+	reportDir := path.Join(root, "report")
+	info, err := os.Stat(reportDir)
+	switch {
+	case isPermission(err):
+		return &ClientError{Kind: ErrInsufficientPrivileges, Path: reportDir, Err: err}
+	case err != nil:
+		return &ClientError{Kind: ErrReportUnsupported, Path: reportDir, Err: err}
+	case !info.IsDir():
+		return &ClientError{Kind: ErrReportUnsupported, Path: reportDir,
+			Err: fmt.Errorf("expected %s to be a directory", reportDir)}
+	}
+	// Check without creating an entry that the process may create entries.
+	if err := access(reportDir, accessWrite|accessExec); isPermission(err) {
+		return &ClientError{Kind: ErrInsufficientPrivileges, Path: reportDir, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+func TestCheckReportSubsystem(t *testing.T) {
+	root := t.TempDir()
+	err := checkReportSubsystem(root, nil)
+	var clientErr *ClientError
+	if !errors.Is(err, ErrReportUnsupported) || !errors.As(err, &clientErr) || clientErr.Path != path.Join(root, "report") {
+		t.Errorf("checkReportSubsystem(no report) = %v, want ErrReportUnsupported for %s/report", err, root)
+	}
+	if err := os.WriteFile(path.Join(root, "report"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkReportSubsystem(root, nil); !errors.Is(err, ErrReportUnsupported) {
+		t.Errorf("checkReportSubsystem(report file) = %v, want ErrReportUnsupported", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses permission checks")
+	}
+	root = t.TempDir()
+	if err := os.Mkdir(path.Join(root, "report"), 0555); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkReportSubsystem(root, nil); !errors.Is(err, ErrInsufficientPrivileges) {
+		t.Errorf("checkReportSubsystem(read-only report) = %v, want ErrInsufficientPrivileges", err)
+	}
+}
+
+func TestIsSupportedAt(t *testing.T) {
+	root := t.TempDir()
+	if isSupportedAt(root) {
+		t.Errorf("isSupportedAt(empty) = true, want false")
+	}
+	if err := os.Mkdir(path.Join(root, "rtmrs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !isSupportedAt(root) {
+		t.Errorf("isSupportedAt(rtmrs) = false, want true")
+	}
+}
+
+func TestProbeProviderLeavesNoEntry(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(path.Join(root, "report"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A plain directory has no provider attribute, so the probe fails after creating its
+	// entry.
+	if _, err := probeProvider(root); err == nil {
+		t.Fatalf("probeProvider() = _, nil, want an error")
+	}
+	entries, err := os.ReadDir(path.Join(root, "report"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("report entries after probe = %v, %v, want none", entries, err)
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	calls := 0
+	err := retryTransient(func() error {
+		calls++
+		if calls < 3 {
+			return &os.PathError{Op: "read", Path: "outblob", Err: syscall.EAGAIN}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryTransient(2 EAGAINs) = %v after %d calls, want nil after 3", err, calls)
+	}
+	calls = 0
+	err = retryTransient(func() error {
+		calls++
+		return syscall.EINTR
+	})
+	if !errors.Is(err, syscall.EINTR) || calls != transientRetries+1 {
+		t.Errorf("retryTransient(EINTR) = %v after %d calls, want EINTR after %d", err, calls, transientRetries+1)
+	}
+	calls = 0
+	if err := retryTransient(func() error { calls++; return syscall.EINVAL }); !errors.Is(err, syscall.EINVAL) || calls != 1 {
+		t.Errorf("retryTransient(EINVAL) = %v after %d calls, want EINVAL after 1", err, calls)
+	}
+}
+
+func TestRetryCountsCalls(t *testing.T) {
+	calls := 0
+	n, err := retry(func() error {
+		calls++
+		return syscall.EBUSY
+	}, func(err error) bool { return errors.Is(err, syscall.EBUSY) }, 3, 0)
+	if !errors.Is(err, syscall.EBUSY) || n != 4 || calls != 4 {
+		t.Errorf("retry(always EBUSY, 3) = %v, %d after %d calls, want EBUSY, 4", err, n, calls)
+	}
+	busy := &BusyError{Path: "/sys/kernel/config/tsm/report/entry", Attempts: n, Err: err}
+	if !errors.Is(busy, syscall.EBUSY) {
+		t.Errorf("errors.Is(%v, EBUSY) = false, want true", busy)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// osReleasePath holds the running kernel's release, as in uname -r.
+const osReleasePath = "/proc/sys/kernel/osrelease"
+
+// KernelVersion is a Linux kernel major and minor version.
+type KernelVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseKernelVersion returns the version of a kernel release string such as
+// "6.8.0-45-generic".
+func ParseKernelVersion(release string) (KernelVersion, error) {
+	fields := strings.SplitN(strings.TrimSpace(release), ".", 3)
+	if len(fields) < 2 {
+		return KernelVersion{}, fmt.Errorf("kernel release %q has no minor version", release)
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return KernelVersion{}, fmt.Errorf("kernel release %q: %w", release, err)
+	}
+	minor := fields[1]
+	if i := strings.IndexFunc(fields[1], func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = fields[1][:i]
+	}
+	minorVersion, err := strconv.Atoi(minor)
+	if err != nil {
+		return KernelVersion{}, fmt.Errorf("kernel release %q: %w", release, err)
+	}
+	return KernelVersion{Major: major, Minor: minorVersion}, nil
+}
+
+// AtLeast returns whether v is the same as or later than other.
+func (v KernelVersion) AtLeast(other KernelVersion) bool {
+	return v.Major > other.Major || (v.Major == other.Major && v.Minor >= other.Minor)
+}
+
+// String returns the version as "major.minor".
+func (v KernelVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// revisionKernels are the mainline kernels that introduced configfs-tsm revisions. The
+// rtmrs subsystem is not in a mainline kernel.
+var revisionKernels = map[configfsi.Revision]KernelVersion{
+	configfsi.RevisionV7:  {Major: 6, Minor: 7},
+	configfsi.Revision611: {Major: 6, Minor: 11},
+}
+
+// Feature describes whether a configfs-tsm feature is expected to work.
+type Feature struct {
+	// Name is "report", "rtmrs", or a report attribute such as "auxblob" or "manifestblob".
+	Name string
+	// MinKernel is the first mainline kernel with the feature, or zero if there is none.
+	MinKernel KernelVersion
+	// Available is whether the feature is expected to work.
+	Available bool
+	// Verified is whether Available was determined from the tsm directory rather than
+	// from the kernel version.
+	Verified bool
+	// Reason explains why the feature is unavailable, e.g., "manifestblob requires
+	// Linux >= 6.11 (running 6.8)", or why it could not be verified.
+	Reason string
+}
+
+// featureInputs are the facts that features are determined from.
+type featureInputs struct {
+	// root is the tsm directory, or "" if it was not found.
+	root string
+	// kernel is the running kernel version, if kernelErr is nil.
+	kernel    KernelVersion
+	kernelErr error
+	// attrs are the attributes of a report entry, if attrsErr is nil.
+	attrs    []string
+	attrsErr error
+}
+
+// Features returns which configfs-tsm features are expected to work with the running kernel,
+// judging from its version and the tsm directory. It creates and removes a temporary report
+// entry to list the report attributes if it can.
+func Features() []Feature {
+	in := &featureInputs{}
+	if root, err := FindTsmRoot(); err == nil {
+		in.root = root
+	}
+	release, err := os.ReadFile(osReleasePath)
+	in.kernelErr = err
+	if err == nil {
+		in.kernel, in.kernelErr = ParseKernelVersion(string(release))
+	}
+	if in.root != "" {
+		in.attrs, in.attrsErr = probeAttributes(in.root)
+	} else {
+		in.attrsErr = fmt.Errorf("no tsm directory found")
+	}
+	return in.features()
+}
+
+// probeAttributes returns the attribute names of a temporary report entry under root.
+func probeAttributes(root string) ([]string, error) {
+	entry, err := os.MkdirTemp(path.Join(root, "report"), "probe")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(entry)
+	entries, err := os.ReadDir(entry)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, nil
+}
+
+func (in *featureInputs) hasDir(subsystem string) bool {
+	if in.root == "" {
+		return false
+	}
+	info, err := os.Stat(path.Join(in.root, subsystem))
+	return err == nil && info.IsDir()
+}
+
+func (in *featureInputs) hasAttribute(name string) bool {
+	for _, a := range in.attrs {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// kernelReason returns why the running kernel lacks a feature of the given revision, or ""
+// if the kernel is new enough or its version is unknown.
+func (in *featureInputs) kernelReason(name string, rev configfsi.Revision) string {
+	minKernel, ok := revisionKernels[rev]
+	switch {
+	case !ok:
+		return fmt.Sprintf("%s requires a kernel with the configfs-tsm %s revision", name, rev)
+	case in.kernelErr == nil && !in.kernel.AtLeast(minKernel):
+		return fmt.Sprintf("%s requires Linux >= %s (running %s)", name, minKernel, in.kernel)
+	}
+	return ""
+}
+
+// unavailable returns the reason that a feature is missing from the tsm directory.
+func (in *featureInputs) unavailable(name string, rev configfsi.Revision, missing string) string {
+	if reason := in.kernelReason(name, rev); reason != "" {
+		return reason
+	}
+	return missing
+}
+
+func (in *featureInputs) features() []Feature {
+	report := in.subsystemFeature("report", configfsi.RevisionV7)
+	result := []Feature{report}
+	for _, name := range []string{"auxblob", "manifestblob"} {
+		rev := configfsi.Schema("report").Attribute(name).Since
+		f := Feature{Name: name, MinKernel: revisionKernels[rev]}
+		switch {
+		case !report.Available:
+			f.Verified = true
+			f.Reason = report.Reason
+		case in.attrsErr == nil:
+			f.Verified = true
+			f.Available = in.hasAttribute(name)
+			if !f.Available {
+				f.Reason = in.unavailable(name, rev, fmt.Sprintf("the report entry has no %s attribute", name))
+			}
+		default:
+			// Fall back to the kernel version.
+			f.Reason = in.kernelReason(name, rev)
+			f.Available = f.Reason == "" && in.kernelErr == nil
+			if f.Reason == "" {
+				f.Reason = fmt.Sprintf("could not list report attributes: %v", in.attrsErr)
+			}
+		}
+		result = append(result, f)
+	}
+	return append(result, in.subsystemFeature("rtmrs", configfsi.RevisionRtmr))
+}
+
+// subsystemFeature returns whether the tsm directory has the subsystem.
+func (in *featureInputs) subsystemFeature(name string, rev configfsi.Revision) Feature {
+	f := Feature{Name: name, MinKernel: revisionKernels[rev], Verified: true, Available: in.hasDir(name)}
+	switch {
+	case f.Available:
+	case in.root == "":
+		f.Reason = in.unavailable(name, rev, "configfs-tsm is not mounted")
+	default:
+		f.Reason = in.unavailable(name, rev, fmt.Sprintf("%s has no %s subsystem", in.root, name))
+	}
+	return f
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseKernelVersion(t *testing.T) {
+	tcs := []struct {
+		release string
+		want    KernelVersion
+		wantErr bool
+	}{
+		{release: "6.8.0-45-generic\n", want: KernelVersion{6, 8}},
+		{release: "6.11.0", want: KernelVersion{6, 11}},
+		{release: "6.12-rc1", want: KernelVersion{6, 12}},
+		{release: "5.15.153.1-microsoft-standard-WSL2", want: KernelVersion{5, 15}},
+		{release: "6", wantErr: true},
+		{release: "x.y", wantErr: true},
+	}
+	for _, tc := range tcs {
+		got, err := ParseKernelVersion(tc.release)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseKernelVersion(%q) = %v, %v. Want %v, error %v", tc.release, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func featureByName(t *testing.T, features []Feature, name string) Feature {
+	t.Helper()
+	for _, f := range features {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("no feature %q in %v", name, features)
+	return Feature{}
+}
+
+func TestFeatures(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(path.Join(root, "report"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		name           string
+		in             *featureInputs
+		wantAvailable  map[string]bool
+		wantReason     map[string]string
+		wantUnverified []string
+	}{
+		{
+			name:          "6.8 entry",
+			in:            &featureInputs{root: root, kernel: KernelVersion{6, 8}, attrs: []string{"auxblob", "outblob"}},
+			wantAvailable: map[string]bool{"report": true, "auxblob": true},
+			wantReason: map[string]string{
+				"manifestblob": "manifestblob requires Linux >= 6.11 (running 6.8)",
+				"rtmrs":        "rtmrs requires a kernel with the configfs-tsm rtmrs revision",
+			},
+		},
+		{
+			name:           "6.11 without a probe",
+			in:             &featureInputs{root: root, kernel: KernelVersion{6, 11}, attrsErr: os.ErrPermission},
+			wantAvailable:  map[string]bool{"report": true, "auxblob": true, "manifestblob": true},
+			wantUnverified: []string{"auxblob", "manifestblob"},
+		},
+		{
+			name:          "not mounted",
+			in:            &featureInputs{kernel: KernelVersion{6, 11}, attrsErr: errors.New("no tsm")},
+			wantAvailable: map[string]bool{},
+			wantReason: map[string]string{
+				"report":       "configfs-tsm is not mounted",
+				"manifestblob": "configfs-tsm is not mounted",
+			},
+		},
+		{
+			name:          "old kernel",
+			in:            &featureInputs{kernel: KernelVersion{6, 5}, attrsErr: errors.New("no tsm")},
+			wantAvailable: map[string]bool{},
+			wantReason:    map[string]string{"report": "report requires Linux >= 6.7 (running 6.5)"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.in.features()
+			for _, name := range []string{"report", "auxblob", "manifestblob", "rtmrs"} {
+				f := featureByName(t, features, name)
+				if f.Available != tc.wantAvailable[name] {
+					t.Errorf("%s Available = %v. Want %v (%s)", name, f.Available, tc.wantAvailable[name], f.Reason)
+				}
+				if want, ok := tc.wantReason[name]; ok && f.Reason != want {
+					t.Errorf("%s Reason = %q. Want %q", name, f.Reason, want)
+				}
+			}
+			for _, name := range tc.wantUnverified {
+				if f := featureByName(t, features, name); f.Verified {
+					t.Errorf("%s Verified = true. Want false", name)
+				}
+			}
+		})
+	}
+}
@@ -16,54 +16,189 @@
 package linuxtsm
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
 )
 
+const (
+	// transientRetries is how many more times ReadFile and WriteFile try after EINTR or
+	// EAGAIN, which long-running agents can see under signal load or a busy provider.
+	transientRetries = 5
+	// transientBackoff is the wait before the first retry. It doubles for each retry.
+	transientBackoff = time.Millisecond
+)
+
+const (
+	// removeRetries is how many more times RemoveAll tries while an entry is busy, e.g.,
+	// because the kernel is still reading a report for it.
+	removeRetries = 10
+	// removeBackoff is the wait before the first removal retry. It doubles for each retry.
+	removeBackoff = time.Millisecond
+)
+
+// retry calls f until retryable(err) is false or retries run out. It returns the number
+// of calls made and the last error.
+func retry(f func() error, retryable func(error) bool, retries int, backoff time.Duration) (int, error) {
+	wait := backoff
+	err := f()
+	calls := 1
+	for ; calls <= retries && retryable(err); calls++ {
+		time.Sleep(wait)
+		wait *= 2
+		err = f()
+	}
+	return calls, err
+}
+
+// retryTransient calls f until it does not return EINTR or EAGAIN, or retries run out.
+func retryTransient(f func() error) error {
+	_, err := retry(f, func(err error) bool {
+		return configfsi.IsInterrupted(err) || configfsi.IsWouldBlock(err)
+	}, transientRetries, transientBackoff)
+	return err
+}
+
 // client provides configfsi.Client for /sys/kernel/config/tsm file operations in Linux.
-type client struct{}
+type client struct {
+	// root is where the tsm directory is on the filesystem. Paths under
+	// configfsi.TsmPrefix are translated to paths under root.
+	root string
+	// observe receives system call timings, if not nil.
+	observe TimingFunc
+}
+
+func (c *client) withTiming(observe TimingFunc) configfsi.Client {
+	result := *c
+	result.observe = observe
+	return &result
+}
+
+// hostPath translates a configfsi.TsmPrefix-based path to the filesystem.
+func (c *client) hostPath(name string) string {
+	if c.root == "" || c.root == configfsi.TsmPrefix {
+		return name
+	}
+	if name == configfsi.TsmPrefix || strings.HasPrefix(name, configfsi.TsmPrefix+"/") {
+		return c.root + strings.TrimPrefix(name, configfsi.TsmPrefix)
+	}
+	return name
+}
+
+// tsmPath translates a filesystem path under root back to a configfsi.TsmPrefix-based path.
+func (c *client) tsmPath(name string) string {
+	if c.root == "" || c.root == configfsi.TsmPrefix {
+		return name
+	}
+	if name == c.root || strings.HasPrefix(name, c.root+"/") {
+		return configfsi.TsmPrefix + strings.TrimPrefix(name, c.root)
+	}
+	return name
+}
 
 // MkdirTemp creates a new temporary directory in the directory dir and returns the pathname
 // of the new directory. Pattern semantics follow os.MkdirTemp.
-func (*client) MkdirTemp(dir, pattern string) (string, error) {
-	return os.MkdirTemp(dir, pattern)
+func (c *client) MkdirTemp(dir, pattern string) (string, error) {
+	var name string
+	err := c.observe.timeErr("mkdir", dir, func() (err error) {
+		name, err = os.MkdirTemp(c.hostPath(dir), pattern)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.tsmPath(name), nil
+}
+
+// Mkdir creates the named directory.
+func (c *client) Mkdir(name string) error {
+	return c.observe.timeErr("mkdir", name, func() error { return os.Mkdir(c.hostPath(name), 0755) })
 }
 
 // ReadFile reads the named file and returns the contents.
-func (*client) ReadFile(name string) ([]byte, error) {
-	return os.ReadFile(name)
+func (c *client) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := retryTransient(func() (err error) {
+		data, err = readAttribute(c.hostPath(name), c.observe)
+		return err
+	})
+	if errors.Is(err, syscall.ENXIO) {
+		return nil, &ClientError{Kind: ErrNoProvider, Path: name, Err: err}
+	}
+	return data, err
 }
 
-// WriteFile writes data to the named file, creating it if necessary. The permissions
-// are implementation-defined.
-func (*client) WriteFile(name string, contents []byte) error {
-	return os.WriteFile(name, contents, 0220)
+// Open opens the named file for reading.
+func (c *client) Open(name string) (io.ReadCloser, error) {
+	return os.Open(c.hostPath(name))
 }
 
-// RemoveAll removes path and any children it contains.
-func (*client) RemoveAll(path string) error {
-	return os.Remove(path)
+// WriteFile writes data to the named attribute with a single open. Configfs attributes
+// always exist, so unlike os.WriteFile, it neither creates nor truncates the file.
+func (c *client) WriteFile(name string, contents []byte) error {
+	return retryTransient(func() error {
+		return writeAttribute(c.hostPath(name), contents, c.observe)
+	})
+}
+
+// RemoveAll removes path and any children it contains. Configfs removes an entry's
+// attributes with it. While the entry is busy, RemoveAll retries with backoff, and if the
+// entry stays busy, it returns a *BusyError.
+func (c *client) RemoveAll(path string) error {
+	calls, err := retry(func() error {
+		return c.observe.timeErr("rmdir", path, func() error { return os.Remove(c.hostPath(path)) })
+	}, configfsi.IsBusy, removeRetries, removeBackoff)
+	if configfsi.IsBusy(err) {
+		return &BusyError{Path: path, Attempts: calls, Err: err}
+	}
+	return err
 }
 
 // ReadDir reads the directory named by dirname and returns a list of directory
 // entries sorted by filename.
-func (*client) ReadDir(dirname string) ([]os.DirEntry, error) {
-	return os.ReadDir(dirname)
+func (c *client) ReadDir(dirname string) ([]os.DirEntry, error) {
+	return os.ReadDir(c.hostPath(dirname))
 }
 
-// MakeClient returns a "real" client for using configfs for TSM use.
+// Stat returns a FileInfo describing the named file.
+func (c *client) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(c.hostPath(name))
+}
+
+// MakeClient returns a "real" client for using configfs for TSM use. If configfs is not
+// mounted at /sys/kernel/config, the tsm directory is found with FindTsmRoot.
+//
+// MakeClient checks, without creating a report entry, that the process may create report
+// entries. If not, it returns a *ClientError that matches ErrConfigfsNotMounted,
+// ErrReportUnsupported, or ErrInsufficientPrivileges with errors.Is. Whether a provider is
+// registered is only known once an entry exists, so the client's ReadFile returns a
+// *ClientError that matches ErrNoProvider when an entry has none.
 func MakeClient() (configfsi.Client, error) {
+	root, err := FindTsmRoot()
+	return makeClient(root, err)
+}
+
+// MakeClientWithRoot returns a client like MakeClient's for the tsm directory at root,
+// e.g., "/run/tsm" for a container with a bind mount of /sys/kernel/config/tsm. The client
+// still takes configfsi.TsmPrefix-based paths and translates them to paths under root.
+func MakeClientWithRoot(root string) (configfsi.Client, error) {
+	if !path.IsAbs(root) {
+		return nil, fmt.Errorf("tsm root %q is not an absolute path", root)
+	}
+	return makeClient(path.Clean(root), nil)
+}
+
+func makeClient(root string, findErr error) (configfsi.Client, error) {
 	// Linux client expects just the "report" subsystem for now.
-	checkPath := path.Join(configfsi.TsmPrefix, "report")
-	info, err := os.Stat(checkPath)
-	if err != nil {
+	if err := checkReportSubsystem(root, findErr); err != nil {
 		return nil, err
 	}
-	if !info.IsDir() {
-		return nil, fmt.Errorf("expected %s to be a directory", checkPath)
-	}
-	return &client{}, nil
+	return &client{root: root}, nil
 }
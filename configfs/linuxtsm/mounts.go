@@ -0,0 +1,125 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+const mountInfoPath = "/proc/self/mountinfo"
+
+// ConfigfsMount is a mount of the configfs filesystem.
+type ConfigfsMount struct {
+	// Root is the directory within configfs that is mounted, e.g., "/" or "/tsm" for a
+	// bind mount of just the tsm tree.
+	Root string
+	// MountPoint is where Root is mounted.
+	MountPoint string
+}
+
+// TsmDir returns where the tsm directory would be under the mount, or "" if the mount
+// does not contain it.
+func (m *ConfigfsMount) TsmDir() string {
+	switch path.Clean(m.Root) {
+	case "/":
+		return path.Join(m.MountPoint, "tsm")
+	case "/tsm":
+		return m.MountPoint
+	}
+	return ""
+}
+
+// unescapeMountField undoes the octal escaping of spaces, tabs, newlines, and
+// backslashes in mountinfo fields.
+func unescapeMountField(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// parseConfigfsMounts returns the configfs mounts listed in mountinfo format.
+func parseConfigfsMounts(r io.Reader) ([]ConfigfsMount, error) {
+	var result []ConfigfsMount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Fields: id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) || fields[sep+1] != "configfs" {
+			continue
+		}
+		result = append(result, ConfigfsMount{
+			Root:       unescapeMountField(fields[3]),
+			MountPoint: unescapeMountField(fields[4]),
+		})
+	}
+	return result, scanner.Err()
+}
+
+// ConfigfsMounts returns the configfs mounts visible to this process.
+func ConfigfsMounts() ([]ConfigfsMount, error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseConfigfsMounts(f)
+}
+
+// FindTsmRoot returns the directory that holds the tsm subsystems, e.g.,
+// configfsi.TsmPrefix, or the tsm directory of another configfs mount if configfs is
+// mounted elsewhere or bind-mounted into a container.
+func FindTsmRoot() (string, error) {
+	if info, err := os.Stat(configfsi.TsmPrefix); err == nil && info.IsDir() {
+		return configfsi.TsmPrefix, nil
+	}
+	mounts, err := ConfigfsMounts()
+	if err != nil {
+		return "", fmt.Errorf("could not list configfs mounts: %w", err)
+	}
+	for _, m := range mounts {
+		dir := m.TsmDir()
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no configfs mount contains a tsm directory: %w", os.ErrNotExist)
+}
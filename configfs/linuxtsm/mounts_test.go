@@ -0,0 +1,93 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestParseConfigfsMounts(t *testing.T) {
+	mountinfo := `23 28 0:22 / /proc rw,relatime - proc proc rw
+36 24 0:31 / /sys/kernel/config rw,nosuid,nodev,noexec,relatime shared:15 - configfs configfs rw
+410 400 0:31 /tsm /run/tsm rw,relatime - configfs configfs rw
+411 400 0:31 / /run/config\040dir rw,relatime master:15 - configfs configfs rw
+412 400 0:31 /tsm/report /run/report rw,relatime - configfs configfs rw
+`
+	mounts, err := parseConfigfsMounts(strings.NewReader(mountinfo))
+	if err != nil {
+		t.Fatalf("parseConfigfsMounts() = _, %v, want nil", err)
+	}
+	want := []string{"/sys/kernel/config/tsm", "/run/tsm", "/run/config dir/tsm", ""}
+	if len(mounts) != len(want) {
+		t.Fatalf("parseConfigfsMounts() = %v, want %d mounts", mounts, len(want))
+	}
+	for i, m := range mounts {
+		if got := m.TsmDir(); got != want[i] {
+			t.Errorf("mount %+v TsmDir() = %q, want %q", m, got, want[i])
+		}
+	}
+}
+
+func TestClientTranslatesRoot(t *testing.T) {
+	c := &client{root: t.TempDir()}
+	entry, err := c.MkdirTemp("/sys/kernel/config/tsm", "entry")
+	if err != nil {
+		t.Fatalf("MkdirTemp() = _, %v, want nil", err)
+	}
+	if !strings.HasPrefix(entry, "/sys/kernel/config/tsm/entry") {
+		t.Errorf("MkdirTemp() = %q, want a path under /sys/kernel/config/tsm", entry)
+	}
+	// Configfs creates attributes with the entry, and WriteFile does not create them.
+	if err := c.WriteFile(entry+"/inblob", []byte("nonce")); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("WriteFile(missing) = %v, want ENOENT", err)
+	}
+	if err := os.WriteFile(c.hostPath(entry+"/inblob"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteFile(entry+"/inblob", []byte("nonce")); err != nil {
+		t.Fatalf("WriteFile() = %v, want nil", err)
+	}
+	entries, err := c.ReadDir(entry)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "inblob" {
+		t.Errorf("ReadDir(%q) = %v, %v, want [inblob]", entry, entries, err)
+	}
+	if data, err := c.ReadFile(entry + "/inblob"); err != nil || string(data) != "nonce" {
+		t.Errorf("ReadFile(inblob) = %q, %v, want %q", data, err, "nonce")
+	}
+}
+
+func TestMakeClientWithRoot(t *testing.T) {
+	if _, err := MakeClientWithRoot("run/tsm"); err == nil {
+		t.Errorf("MakeClientWithRoot(relative) = _, nil, want an error")
+	}
+	if _, err := MakeClientWithRoot(t.TempDir()); !errors.Is(err, ErrReportUnsupported) {
+		t.Errorf("MakeClientWithRoot(empty) = _, %v, want ErrReportUnsupported", err)
+	}
+	root := t.TempDir()
+	if err := os.Mkdir(path.Join(root, "report"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MakeClientWithRoot(root); err != nil {
+		t.Fatalf("MakeClientWithRoot(%q) = _, %v, want nil", root, err)
+	}
+	if entries, err := os.ReadDir(path.Join(root, "report")); err != nil || len(entries) != 0 {
+		t.Errorf("report entries after MakeClientWithRoot = %v, %v, want none", entries, err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// isSupportedAt returns whether the tsm directory root has a report or rtmrs subsystem.
+func isSupportedAt(root string) bool {
+	for _, subsystem := range []string{"report", "rtmrs"} {
+		if info, err := os.Stat(path.Join(root, subsystem)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupported returns whether the kernel exposes the configfs-tsm report or rtmrs
+// subsystem. It does not check for a provider or create entries.
+func IsSupported() bool {
+	root, err := FindTsmRoot()
+	return err == nil && isSupportedAt(root)
+}
+
+// Provider returns the name of the TSM provider that backs the report subsystem, e.g.,
+// "sev_guest" or "tdx_guest", without a trailing newline. It creates a temporary report
+// entry to read the provider and removes it before returning, even on error.
+func Provider() (string, error) {
+	root, err := FindTsmRoot()
+	if err := checkReportSubsystem(root, err); err != nil {
+		return "", err
+	}
+	provider, err := probeProvider(root)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(provider, "\n"), nil
+}
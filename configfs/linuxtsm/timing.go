@@ -0,0 +1,71 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// SyscallTiming is the duration of one system call that a client made.
+type SyscallTiming struct {
+	// Op is "open", "read", "write", "close", "mkdir", or "rmdir".
+	Op   string
+	Path string
+	// N is the number of bytes that a read or write transferred.
+	N        int
+	Duration time.Duration
+	Err      error
+}
+
+// TimingFunc receives the timing of each system call. It is called synchronously on the
+// calling goroutine, so it should return quickly.
+type TimingFunc func(SyscallTiming)
+
+// time calls call and reports its duration to f, if f is not nil.
+func (f TimingFunc) time(op, path string, call func() (int, error)) (int, error) {
+	if f == nil {
+		return call()
+	}
+	start := time.Now()
+	n, err := call()
+	f(SyscallTiming{Op: op, Path: path, N: n, Duration: time.Since(start), Err: err})
+	return n, err
+}
+
+// timeErr is time for calls that only return an error.
+func (f TimingFunc) timeErr(op, path string, call func() error) error {
+	_, err := f.time(op, path, func() (int, error) { return 0, call() })
+	return err
+}
+
+// timedClient is a linuxtsm client that can report system call timings.
+type timedClient interface {
+	withTiming(observe TimingFunc) configfsi.Client
+}
+
+// WithTiming returns a copy of a client from this package that reports the duration of its
+// open, read, write, close, mkdir, and rmdir system calls to observe, e.g., to track the
+// latency of the hardware attestation path in production. Retried calls are reported
+// individually.
+func WithTiming(client configfsi.Client, observe TimingFunc) (configfsi.Client, error) {
+	c, ok := client.(timedClient)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a linuxtsm client", client)
+	}
+	return c.withTiming(observe), nil
+}
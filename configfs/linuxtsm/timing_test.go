@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+func TestWithTiming(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(path.Join(root, "inblob"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var timings []SyscallTiming
+	c, err := WithTiming(&client{root: root}, func(s SyscallTiming) { timings = append(timings, s) })
+	if err != nil {
+		t.Fatalf("WithTiming() = _, %v. Want nil", err)
+	}
+	if err := c.WriteFile(configfsi.TsmPrefix+"/inblob", []byte("nonce")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadFile(configfsi.TsmPrefix + "/missing"); !errors.Is(err, syscall.ENOENT) {
+		t.Fatalf("ReadFile(missing) = _, %v. Want ENOENT", err)
+	}
+	want := []SyscallTiming{
+		{Op: "open", Path: root + "/inblob"},
+		{Op: "write", Path: root + "/inblob", N: 5},
+		{Op: "close", Path: root + "/inblob"},
+		{Op: "open", Path: root + "/missing", Err: syscall.ENOENT},
+	}
+	if len(timings) != len(want) {
+		t.Fatalf("timings = %+v. Want %d timings", timings, len(want))
+	}
+	for i, got := range timings {
+		w := want[i]
+		if got.Op != w.Op || got.Path != w.Path || got.N != w.N || !errors.Is(got.Err, w.Err) ||
+			(w.Err == nil && got.Err != nil) || got.Duration < 0 {
+			t.Errorf("timings[%d] = %+v. Want %+v", i, got, w)
+		}
+	}
+
+	wrapped := configfsi.Wrap(&client{root: root}, func(_, _ string, call func() error) error { return call() })
+	if _, err := WithTiming(wrapped, nil); err == nil {
+		t.Error("WithTiming(wrapped client) = _, nil. Want an error")
+	}
+}
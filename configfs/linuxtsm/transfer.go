@@ -0,0 +1,47 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import "fmt"
+
+// readChunkSize is the initial buffer size for reading attributes. Most attributes are
+// small, and an SNP report with certificates fits in a few pages.
+const readChunkSize = 4096
+
+// TransferError reports how much of an attribute read or write completed before it failed.
+type TransferError struct {
+	// Op is "read" or "write".
+	Op   string
+	Path string
+	// Done is the number of bytes transferred before the error.
+	Done int
+	// Total is the number of bytes to transfer, or -1 if unknown, as for reads.
+	Total int
+	// Err is the raw errno.
+	Err error
+}
+
+// Error returns the operation, path, error, and progress of the transfer.
+func (e *TransferError) Error() string {
+	if e.Total < 0 {
+		return fmt.Sprintf("%s %s: %v (after %d bytes)", e.Op, e.Path, e.Err, e.Done)
+	}
+	return fmt.Sprintf("%s %s: %v (after %d of %d bytes)", e.Op, e.Path, e.Err, e.Done, e.Total)
+}
+
+// Unwrap returns the raw errno.
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxtsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"unsafe"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+const watchMask = syscall.IN_MODIFY | syscall.IN_DELETE_SELF
+
+// Watch returns a channel of inotify-backed events for writes to attributes of the named
+// entry directory.
+func (c *client) Watch(ctx context.Context, entry string) (<-chan configfsi.Event, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	if _, err := syscall.InotifyAddWatch(fd, c.hostPath(entry), watchMask); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("inotify_add_watch %s: %w", entry, err)
+	}
+	// A non-blocking file participates in the runtime poller, so Close unblocks Read.
+	f := os.NewFile(uintptr(fd), "inotify")
+	events := make(chan configfsi.Event)
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	go func() {
+		defer close(events)
+		buf := make([]byte, 4096)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				name := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(raw.Len)]
+				off += syscall.SizeofInotifyEvent + int(raw.Len)
+				var ev configfsi.Event
+				switch {
+				case raw.Mask&syscall.IN_DELETE_SELF != 0:
+					ev = configfsi.Event{Name: entry, Removed: true}
+				case raw.Mask&syscall.IN_MODIFY != 0:
+					ev = configfsi.Event{Name: path.Join(entry, trimNul(name))}
+				default:
+					continue
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+				if ev.Removed {
+					f.Close()
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// trimNul returns the inotify event name without its NUL padding.
+func trimNul(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && !386
+
+package vsockproxy
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// afVsock is the AF_VSOCK address family, which the syscall package does not define.
+	afVsock = 40
+	// listenBacklog is the listen(2) backlog of a listener.
+	listenBacklog = 128
+)
+
+// sockaddrVM is struct sockaddr_vm.
+type sockaddrVM struct {
+	family    uint16
+	reserved1 uint16
+	port      uint32
+	cid       uint32
+	flags     uint8
+	zero      [3]uint8
+}
+
+// socket returns a non-blocking vsock stream socket as a file in the runtime poller.
+func socket() (*os.File, error) {
+	fd, err := syscall.Socket(afVsock, syscall.SOCK_STREAM|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	return os.NewFile(uintptr(fd), "vsock"), nil
+}
+
+// sockaddrCall calls a socket system call that takes a sockaddr_vm and its length.
+func sockaddrCall(trap uintptr, fd uintptr, addr *Addr) syscall.Errno {
+	sa := sockaddrVM{family: afVsock, port: addr.Port, cid: addr.CID}
+	_, _, errno := syscall.Syscall(trap, fd, uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	return errno
+}
+
+// name returns the local or peer address of the socket.
+func name(trap uintptr, fd uintptr) (*Addr, error) {
+	var sa sockaddrVM
+	size := uint32(unsafe.Sizeof(sa))
+	if _, _, errno := syscall.Syscall(trap, fd, uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&size))); errno != 0 {
+		return nil, errno
+	}
+	return &Addr{CID: sa.cid, Port: sa.port}, nil
+}
+
+// control calls f with the file's descriptor.
+func control(f *os.File, fn func(fd uintptr) error) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := raw.Control(func(fd uintptr) { ferr = fn(fd) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// conn is a connected vsock socket.
+type conn struct {
+	*os.File
+	local, remote *Addr
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.local }
+func (c *conn) RemoteAddr() net.Addr { return c.remote }
+
+// newConn returns the connection of a connected socket file.
+func newConn(f *os.File) (*conn, error) {
+	c := &conn{File: f}
+	err := control(f, func(fd uintptr) (err error) {
+		if c.local, err = name(syscall.SYS_GETSOCKNAME, fd); err != nil {
+			return os.NewSyscallError("getsockname", err)
+		}
+		if c.remote, err = name(syscall.SYS_GETPEERNAME, fd); err != nil {
+			return os.NewSyscallError("getpeername", err)
+		}
+		return nil
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// listener is a listening vsock socket.
+type listener struct {
+	f    *os.File
+	addr *Addr
+}
+
+// Listen returns a listener for vsock connections to the given port of any of the
+// machine's context IDs.
+func Listen(port uint32) (net.Listener, error) {
+	return ListenAddr(&Addr{CID: CIDAny, Port: port})
+}
+
+// ListenAddr returns a listener for vsock connections to the given address.
+func ListenAddr(addr *Addr) (net.Listener, error) {
+	f, err := socket()
+	if err != nil {
+		return nil, err
+	}
+	l := &listener{f: f}
+	err = control(f, func(fd uintptr) error {
+		if errno := sockaddrCall(syscall.SYS_BIND, fd, addr); errno != 0 {
+			return os.NewSyscallError("bind", errno)
+		}
+		if err := syscall.Listen(int(fd), listenBacklog); err != nil {
+			return os.NewSyscallError("listen", err)
+		}
+		l.addr, err = name(syscall.SYS_GETSOCKNAME, fd)
+		return err
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Accept waits for and returns the next connection.
+func (l *listener) Accept() (net.Conn, error) {
+	raw, err := l.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var nfd uintptr
+	var errno syscall.Errno
+	err = raw.Read(func(fd uintptr) bool {
+		nfd, _, errno = syscall.Syscall6(syscall.SYS_ACCEPT4, fd, 0, 0,
+			syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, 0, 0)
+		return errno != syscall.EAGAIN
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errno != 0 {
+		return nil, os.NewSyscallError("accept4", errno)
+	}
+	return newConn(os.NewFile(nfd, "vsock"))
+}
+
+// Close stops listening. Blocked Accept calls return an error.
+func (l *listener) Close() error { return l.f.Close() }
+
+// Addr returns the listener's address.
+func (l *listener) Addr() net.Addr { return l.addr }
+
+// DialVsock connects to the given vsock address.
+func DialVsock(cid, port uint32) (net.Conn, error) {
+	return DialVsockTimeout(cid, port, 0)
+}
+
+// DialVsockTimeout connects to the given vsock address, failing after timeout if it is
+// positive.
+func DialVsockTimeout(cid, port uint32, timeout time.Duration) (net.Conn, error) {
+	f, err := socket()
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		f.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	raw, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	addr := &Addr{CID: cid, Port: port}
+	var errno syscall.Errno
+	err = raw.Write(func(fd uintptr) bool {
+		// Repeated connect calls report the progress of a non-blocking connect.
+		switch errno = sockaddrCall(syscall.SYS_CONNECT, fd, addr); errno {
+		case syscall.EINPROGRESS, syscall.EALREADY, syscall.EINTR:
+			return false
+		case syscall.EISCONN:
+			errno = 0
+		}
+		return true
+	})
+	if err == nil && errno != 0 {
+		err = os.NewSyscallError("connect", errno)
+	}
+	if err != nil {
+		f.Close()
+		return nil, &net.OpError{Op: "dial", Net: "vsock", Addr: addr, Err: err}
+	}
+	f.SetWriteDeadline(time.Time{})
+	return newConn(f)
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux || 386
+
+package vsockproxy
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+// errUnsupported is returned on platforms without AF_VSOCK support. On linux/386, socket
+// system calls go through socketcall, which this package does not implement.
+var errUnsupported = fmt.Errorf("vsock is not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+
+// Listen returns a listener for vsock connections to the given port of any of the
+// machine's context IDs.
+func Listen(port uint32) (net.Listener, error) {
+	return nil, errUnsupported
+}
+
+// ListenAddr returns a listener for vsock connections to the given address.
+func ListenAddr(addr *Addr) (net.Listener, error) {
+	return nil, errUnsupported
+}
+
+// DialVsock connects to the given vsock address.
+func DialVsock(cid, port uint32) (net.Conn, error) {
+	return nil, errUnsupported
+}
+
+// DialVsockTimeout connects to the given vsock address, failing after timeout if it is
+// positive.
+func DialVsockTimeout(cid, port uint32, timeout time.Duration) (net.Conn, error) {
+	return nil, errUnsupported
+}
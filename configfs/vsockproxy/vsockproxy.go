@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vsockproxy serves a configfsi.Client over AF_VSOCK, so that a component on the
+// other side of a virtual machine boundary, e.g., on the host or in a paravisor, can
+// perform report and rtmr operations on behalf of a guest.
+//
+// The proxy speaks the broker package's protocol. Peers are admitted by their vsock
+// context ID (CID) rather than by process credentials, and like with the broker, a
+// connection can only use the entries that it created, which the proxy removes when the
+// connection closes.
+package vsockproxy
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+const (
+	// CIDHypervisor is the well-known CID of the hypervisor.
+	CIDHypervisor = 0
+	// CIDLocal is the well-known CID for loopback connections within a machine.
+	CIDLocal = 1
+	// CIDHost is the well-known CID of the host.
+	CIDHost = 2
+	// CIDAny binds a listener to any CID of the machine.
+	CIDAny = 0xffffffff
+	// PortAny binds a listener to a free port.
+	PortAny = 0xffffffff
+)
+
+// Addr is a vsock address.
+type Addr struct {
+	CID  uint32
+	Port uint32
+}
+
+// Network returns "vsock".
+func (a *Addr) Network() string { return "vsock" }
+
+// String returns the address in CID:port form.
+func (a *Addr) String() string { return fmt.Sprintf("%d:%d", a.CID, a.Port) }
+
+// Policy decides whether a peer with the given context ID may use the proxy.
+type Policy func(cid uint32) error
+
+// AllowCIDs returns a Policy that admits only the given context IDs.
+func AllowCIDs(cids ...uint32) Policy {
+	return func(cid uint32) error {
+		for _, c := range cids {
+			if cid == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("vsock cid %d is not allowed to use the configfs proxy", cid)
+	}
+}
+
+// Server serves configfs operations backed by a client to vsock peers.
+type Server struct {
+	broker *broker.Server
+	policy Policy
+}
+
+// NewServer returns a proxy that serves operations on client to peers that policy admits.
+func NewServer(client configfsi.Client, policy Policy) *Server {
+	return &Server{broker: broker.NewServer(client, nil), policy: policy}
+}
+
+// Serve accepts connections on l, e.g., from Listen, until it is closed. Each connection
+// is served in its own goroutine.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection whose remote address is an *Addr, and removes the
+// entries it created when it closes. Connections from peers that the policy does not
+// admit are closed.
+func (s *Server) ServeConn(conn net.Conn) {
+	addr, ok := conn.RemoteAddr().(*Addr)
+	if !ok || s.policy(addr.CID) != nil {
+		conn.Close()
+		return
+	}
+	s.broker.ServeAdmitted(conn)
+}
+
+// Dial connects to the proxy listening on the given vsock address and returns a
+// configfsi.Client that forwards operations to it.
+func Dial(cid, port uint32) (*broker.Client, error) {
+	conn, err := DialVsock(cid, port)
+	if err != nil {
+		return nil, err
+	}
+	return broker.NewClient(conn), nil
+}
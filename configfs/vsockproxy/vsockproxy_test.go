@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsockproxy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// pipeConn is one end of a net.Pipe with a vsock remote address.
+type pipeConn struct {
+	net.Conn
+	remote *Addr
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remote }
+
+func newServer() *Server {
+	client := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.Report611(0)}}
+	return NewServer(client, AllowCIDs(3))
+}
+
+func connect(t *testing.T, s *Server, cid uint32) *broker.Client {
+	t.Helper()
+	server, client := net.Pipe()
+	go s.ServeConn(&pipeConn{Conn: server, remote: &Addr{CID: cid, Port: 1234}})
+	c := broker.NewClient(client)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestGetReport(t *testing.T) {
+	client := connect(t, newServer(), 3)
+	inblob := bytes.Repeat([]byte{2}, 64)
+	resp, err := report.Get(client, &report.Request{InBlob: inblob})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	if !bytes.Contains(resp.OutBlob, []byte(hex.EncodeToString(inblob))) {
+		t.Errorf("OutBlob = %v. Want it to contain the inblob", resp.OutBlob)
+	}
+}
+
+func TestPolicyDenied(t *testing.T) {
+	client := connect(t, newServer(), 4)
+	if _, err := report.Get(client, &report.Request{InBlob: make([]byte, 64)}); err == nil {
+		t.Error("report.Get() from a denied cid = _, nil. Want an error")
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	l, err := ListenAddr(&Addr{CID: CIDLocal, Port: PortAny})
+	if err != nil {
+		t.Skipf("vsock loopback is unavailable: %v", err)
+	}
+	defer l.Close()
+	s := newServer()
+	s.policy = AllowCIDs(CIDLocal)
+	go s.Serve(l)
+	client, err := Dial(CIDLocal, l.Addr().(*Addr).Port)
+	if err != nil {
+		t.Fatalf("Dial() = _, %v. Want nil", err)
+	}
+	defer client.Close()
+	if _, err := report.Get(client, &report.Request{InBlob: make([]byte, 64)}); err != nil {
+		t.Errorf("report.Get() over vsock = _, %v. Want nil", err)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cose signs serialized evidence as COSE_Sign1 (RFC 9052) objects with a caller's
+// key, e.g., an ephemeral key whose KeyBinding was the report's inblob, so that the signed
+// object and the report together are self-contained evidence.
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/google/go-configfs-tsm/internal/cbor"
+)
+
+// Algorithm identifiers from the IANA COSE Algorithms registry.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgES384 = -35
+	AlgES512 = -36
+	AlgPS256 = -37
+)
+
+const (
+	// TagSign1 is the CBOR tag of a COSE_Sign1 object.
+	TagSign1 = 18
+
+	headerAlg         = 1
+	headerContentType = 3
+	headerKid         = 4
+
+	sign1Context = "Signature1"
+)
+
+// ContentTypeEAT is the content type of an EAT claims set payload, e.g., from
+// eat.Token.MarshalCBOR.
+const ContentTypeEAT = "application/eat+cwt"
+
+// ErrVerification is returned when a COSE_Sign1 signature does not verify.
+var ErrVerification = errors.New("cose: signature verification failed")
+
+// algorithm describes how a key type signs.
+type algorithm struct {
+	id   int64
+	hash crypto.Hash
+	// size is the byte length of each of r and s of an ECDSA signature.
+	size int
+}
+
+// algorithmFor returns the algorithm for a public key.
+func algorithmFor(pub crypto.PublicKey) (*algorithm, error) {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return &algorithm{id: AlgES256, hash: crypto.SHA256, size: 32}, nil
+		case elliptic.P384():
+			return &algorithm{id: AlgES384, hash: crypto.SHA384, size: 48}, nil
+		case elliptic.P521():
+			return &algorithm{id: AlgES512, hash: crypto.SHA512, size: 66}, nil
+		}
+		return nil, fmt.Errorf("cose: unsupported curve %s", pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return &algorithm{id: AlgEdDSA}, nil
+	case *rsa.PublicKey:
+		return &algorithm{id: AlgPS256, hash: crypto.SHA256}, nil
+	}
+	return nil, fmt.Errorf("cose: unsupported key type %T", pub)
+}
+
+// KeyBinding returns the SHA-512 digest of the public key's PKIX encoding, which is as
+// large as a report's inblob. Placing it in the inblob binds the report to the key.
+func KeyBinding(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha512.Sum512(der)
+	return digest[:], nil
+}
+
+// Signer creates COSE_Sign1 objects.
+type Signer struct {
+	key crypto.Signer
+	alg *algorithm
+	// KeyID, if not empty, is the kid header of the objects.
+	KeyID []byte
+	// Rand is the source of randomness for signing. Nil means crypto/rand.Reader.
+	Rand io.Reader
+}
+
+// NewSigner returns a Signer for an ECDSA P-256, P-384, or P-521, Ed25519, or RSA key.
+// RSA keys sign with PS256.
+func NewSigner(key crypto.Signer) (*Signer, error) {
+	alg, err := algorithmFor(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, alg: alg}, nil
+}
+
+// sigStructure returns the Sig_structure that a COSE_Sign1 signature covers.
+func sigStructure(protected, externalAAD, payload []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	return cbor.Marshal([]any{sign1Context, protected, externalAAD, payload})
+}
+
+// Sign returns a tagged COSE_Sign1 object with the payload and, if not empty, its content
+// type. The signature also covers externalAAD, which may be nil.
+func (s *Signer) Sign(payload []byte, contentType string, externalAAD []byte) ([]byte, error) {
+	protectedMap := map[any]any{headerAlg: s.alg.id}
+	if contentType != "" {
+		protectedMap[headerContentType] = contentType
+	}
+	protected, err := cbor.Marshal(protectedMap)
+	if err != nil {
+		return nil, err
+	}
+	tbs, err := sigStructure(protected, externalAAD, payload)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.sign(tbs)
+	if err != nil {
+		return nil, err
+	}
+	unprotected := map[any]any{}
+	if len(s.KeyID) != 0 {
+		unprotected[headerKid] = s.KeyID
+	}
+	return cbor.Marshal(cbor.Tag{Number: TagSign1, Content: []any{protected, unprotected, payload, sig}})
+}
+
+// sign returns the COSE signature of tbs.
+func (s *Signer) sign(tbs []byte) ([]byte, error) {
+	random := s.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+	if s.alg.id == AlgEdDSA {
+		return s.key.Sign(random, tbs, crypto.Hash(0))
+	}
+	h := s.alg.hash.New()
+	h.Write(tbs)
+	digest := h.Sum(nil)
+	if s.alg.id == AlgPS256 {
+		return s.key.Sign(random, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: s.alg.hash})
+	}
+	der, err := s.key.Sign(random, digest, s.alg.hash)
+	if err != nil {
+		return nil, err
+	}
+	// COSE ECDSA signatures are r and s as fixed-size big-endian integers, not DER.
+	var rs struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &rs); err != nil {
+		return nil, fmt.Errorf("cose: could not parse ecdsa signature: %w", err)
+	}
+	sig := make([]byte, 2*s.alg.size)
+	rs.R.FillBytes(sig[:s.alg.size])
+	rs.S.FillBytes(sig[s.alg.size:])
+	return sig, nil
+}
+
+// Sign1 is a decoded COSE_Sign1 object.
+type Sign1 struct {
+	// Algorithm is the alg header.
+	Algorithm int64
+	// ContentType is the content type header, if any.
+	ContentType string
+	// KeyID is the kid header, if any.
+	KeyID   []byte
+	Payload []byte
+	// protected and signature are the encoded protected headers and the signature.
+	protected []byte
+	signature []byte
+}
+
+// Parse decodes a COSE_Sign1 object, tagged or not, without verifying its signature.
+func Parse(data []byte) (*Sign1, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := v.(cbor.Tag); ok {
+		if tag.Number != TagSign1 {
+			return nil, fmt.Errorf("cose: unexpected tag %d, want %d", tag.Number, TagSign1)
+		}
+		v = tag.Content
+	}
+	parts, ok := v.([]any)
+	if !ok || len(parts) != 4 {
+		return nil, errors.New("cose: COSE_Sign1 is not an array of 4 items")
+	}
+	protected, pok := parts[0].([]byte)
+	unprotected, uok := parts[1].(map[any]any)
+	payload, lok := parts[2].([]byte)
+	signature, sok := parts[3].([]byte)
+	if !pok || !uok || !lok || !sok {
+		return nil, errors.New("cose: COSE_Sign1 has items of the wrong types")
+	}
+	headers, err := cbor.Unmarshal(protected)
+	if err != nil {
+		return nil, fmt.Errorf("cose: protected headers: %w", err)
+	}
+	protectedMap, ok := headers.(map[any]any)
+	if !ok {
+		return nil, errors.New("cose: protected headers are not a map")
+	}
+	s := &Sign1{Payload: payload, protected: protected, signature: signature}
+	if s.Algorithm, ok = protectedMap[int64(headerAlg)].(int64); !ok {
+		return nil, errors.New("cose: missing protected alg header")
+	}
+	s.ContentType, _ = protectedMap[int64(headerContentType)].(string)
+	s.KeyID, _ = unprotected[int64(headerKid)].([]byte)
+	return s, nil
+}
+
+// Verify returns the payload of a COSE_Sign1 object if its signature with externalAAD
+// verifies with pub, or an error wrapping ErrVerification otherwise.
+func Verify(pub crypto.PublicKey, data, externalAAD []byte) ([]byte, error) {
+	s, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := algorithmFor(pub)
+	if err != nil {
+		return nil, err
+	}
+	if alg.id != s.Algorithm {
+		return nil, fmt.Errorf("%w: alg %d does not match the key's %d", ErrVerification, s.Algorithm, alg.id)
+	}
+	tbs, err := sigStructure(s.protected, externalAAD, s.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if !verify(pub, alg, tbs, s.signature) {
+		return nil, ErrVerification
+	}
+	return s.Payload, nil
+}
+
+func verify(pub crypto.PublicKey, alg *algorithm, tbs, sig []byte) bool {
+	if alg.id == AlgEdDSA {
+		return ed25519.Verify(pub.(ed25519.PublicKey), tbs, sig)
+	}
+	h := alg.hash.New()
+	h.Write(tbs)
+	digest := h.Sum(nil)
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPSS(pub, alg.hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+	case *ecdsa.PublicKey:
+		if len(sig) != 2*alg.size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:alg.size])
+		s := new(big.Int).SetBytes(sig[alg.size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"ES256": p256, "ES384": p384, "EdDSA": ed, "PS256": rsaKey}
+}
+
+func TestSignVerify(t *testing.T) {
+	payload := []byte("evidence")
+	aad := []byte("channel")
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSigner(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.KeyID = []byte("kid")
+			obj, err := s.Sign(payload, ContentTypeEAT, aad)
+			if err != nil {
+				t.Fatalf("Sign() = %v", err)
+			}
+			got, err := Verify(key.Public(), obj, aad)
+			if err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+			if string(got) != string(payload) {
+				t.Errorf("Verify() = %q, want %q", got, payload)
+			}
+			parsed, err := Parse(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.ContentType != ContentTypeEAT || string(parsed.KeyID) != "kid" {
+				t.Errorf("Parse() = %+v, want content type %q and kid \"kid\"", parsed, ContentTypeEAT)
+			}
+			if _, err := Verify(key.Public(), obj, []byte("other")); !errors.Is(err, ErrVerification) {
+				t.Errorf("Verify() with other aad = %v, want %v", err, ErrVerification)
+			}
+		})
+	}
+}
+
+func TestVerifyWrongKey(t *testing.T) {
+	keys := testKeys(t)
+	s, err := NewSigner(keys["ES256"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := s.Sign([]byte("evidence"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pub := range []crypto.PublicKey{other.Public(), keys["ES384"].Public()} {
+		if _, err := Verify(pub, obj, nil); !errors.Is(err, ErrVerification) {
+			t.Errorf("Verify() = %v, want %v", err, ErrVerification)
+		}
+	}
+	obj[len(obj)-1] ^= 1
+	if _, err := Verify(keys["ES256"].Public(), obj, nil); !errors.Is(err, ErrVerification) {
+		t.Errorf("Verify() of a tampered object = %v, want %v", err, ErrVerification)
+	}
+}
+
+func TestKeyBinding(t *testing.T) {
+	keys := testKeys(t)
+	a, err := KeyBinding(keys["ES256"].Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := KeyBinding(keys["EdDSA"].Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 64 || string(a) == string(b) {
+		t.Errorf("KeyBinding() = %x, %x, want distinct 64-byte digests", a, b)
+	}
+}
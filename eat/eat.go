@@ -0,0 +1,206 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eat wraps report evidence in an Entity Attestation Token (EAT, RFC 9711) claims
+// set encoded as CBOR, for verifiers built on the IETF RATS architecture.
+//
+// The standard nonce, iat, and eat_profile claims are used where they apply. The report
+// blobs, provider, and RTMR digests use private claim keys, which the profile names.
+package eat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-configfs-tsm/internal/cbor"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// Claim keys.
+const (
+	ClaimIat          = 6
+	ClaimNonce        = 10
+	ClaimProfile      = 265
+	ClaimProvider     = -70001
+	ClaimOutBlob      = -70002
+	ClaimAuxBlob      = -70003
+	ClaimManifestBlob = -70004
+	ClaimRtmrs        = -70005
+)
+
+const (
+	// Profile is the eat_profile claim value of tokens that this package creates, which
+	// defines the private claims.
+	Profile = "tag:github.com,2024:google/go-configfs-tsm/eat"
+	// TagUCCS is the CBOR tag of an unprotected CWT claims set.
+	TagUCCS = 601
+)
+
+// Token is the claims of an evidence token.
+type Token struct {
+	// Nonce is the verifier's nonce, which the report's inblob binds.
+	Nonce []byte
+	// IssuedAt is when the evidence was collected. It is encoded with second precision.
+	IssuedAt time.Time
+	// Provider is the report provider without a trailing newline, e.g., "tdx_guest".
+	Provider string
+	// OutBlob, AuxBlob, and ManifestBlob are the report's blobs. Empty blobs are omitted.
+	OutBlob      []byte
+	AuxBlob      []byte
+	ManifestBlob []byte
+	// Rtmrs are optional RTMR digests by index.
+	Rtmrs map[int][]byte
+}
+
+// FromResponse returns a token of a report response whose inblob was nonce, with optional
+// RTMR digests by index, issued now.
+func FromResponse(resp *report.Response, nonce []byte, rtmrs map[int][]byte) *Token {
+	return &Token{
+		Nonce:        nonce,
+		IssuedAt:     time.Now(),
+		Provider:     strings.TrimRight(resp.Provider, "\n"),
+		OutBlob:      resp.OutBlob,
+		AuxBlob:      resp.AuxBlob,
+		ManifestBlob: resp.ManifestBlob,
+		Rtmrs:        rtmrs,
+	}
+}
+
+// claims returns the token's claims map.
+func (t *Token) claims() map[any]any {
+	claims := map[any]any{
+		ClaimProfile:  Profile,
+		ClaimNonce:    t.Nonce,
+		ClaimIat:      t.IssuedAt.Unix(),
+		ClaimProvider: t.Provider,
+		ClaimOutBlob:  t.OutBlob,
+	}
+	for key, blob := range map[int][]byte{ClaimAuxBlob: t.AuxBlob, ClaimManifestBlob: t.ManifestBlob} {
+		if len(blob) != 0 {
+			claims[key] = blob
+		}
+	}
+	if len(t.Rtmrs) != 0 {
+		rtmrs := make(map[any]any, len(t.Rtmrs))
+		for index, digest := range t.Rtmrs {
+			rtmrs[index] = digest
+		}
+		claims[ClaimRtmrs] = rtmrs
+	}
+	return claims
+}
+
+// MarshalCBOR returns the deterministic CBOR encoding of the token's claims map, e.g., as
+// the payload of a signed CWT.
+func (t *Token) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(t.claims())
+}
+
+// MarshalUCCS returns the token as an unprotected CWT claims set, the claims map with the
+// UCCS tag, for transports that protect its integrity otherwise.
+func (t *Token) MarshalUCCS() ([]byte, error) {
+	return cbor.Marshal(cbor.Tag{Number: TagUCCS, Content: t.claims()})
+}
+
+// Unmarshal decodes a token from a claims map, with or without the UCCS tag. It returns an
+// error if the claims do not follow Profile.
+func Unmarshal(data []byte) (*Token, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := v.(cbor.Tag); ok {
+		if tag.Number != TagUCCS {
+			return nil, fmt.Errorf("unexpected cbor tag %d, want %d", tag.Number, TagUCCS)
+		}
+		v = tag.Content
+	}
+	claims, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("token is not a claims map")
+	}
+	if profile, _ := claims[int64(ClaimProfile)].(string); profile != Profile {
+		return nil, fmt.Errorf("token has eat_profile %q, want %q", profile, Profile)
+	}
+	t := &Token{}
+	var iat int64
+	fields := []struct {
+		key      int64
+		dst      any
+		required bool
+	}{
+		{key: ClaimNonce, dst: &t.Nonce, required: true},
+		{key: ClaimIat, dst: &iat, required: true},
+		{key: ClaimProvider, dst: &t.Provider, required: true},
+		{key: ClaimOutBlob, dst: &t.OutBlob, required: true},
+		{key: ClaimAuxBlob, dst: &t.AuxBlob},
+		{key: ClaimManifestBlob, dst: &t.ManifestBlob},
+	}
+	for _, f := range fields {
+		v, ok := claims[f.key]
+		if !ok {
+			if f.required {
+				return nil, fmt.Errorf("token is missing claim %d", f.key)
+			}
+			continue
+		}
+		if err := assign(f.dst, v); err != nil {
+			return nil, fmt.Errorf("claim %d: %w", f.key, err)
+		}
+	}
+	t.IssuedAt = time.Unix(iat, 0)
+	if v, ok := claims[int64(ClaimRtmrs)]; ok {
+		rtmrs, ok := v.(map[any]any)
+		if !ok {
+			return nil, fmt.Errorf("claim %d is %T, want a map", ClaimRtmrs, v)
+		}
+		t.Rtmrs = make(map[int][]byte, len(rtmrs))
+		for k, v := range rtmrs {
+			index, iok := k.(int64)
+			digest, dok := v.([]byte)
+			if !iok || !dok || index < 0 {
+				return nil, fmt.Errorf("claim %d has an invalid entry %v: %v", ClaimRtmrs, k, v)
+			}
+			t.Rtmrs[int(index)] = digest
+		}
+	}
+	return t, nil
+}
+
+// assign stores a decoded claim value in dst if the types match.
+func assign(dst, v any) error {
+	switch dst := dst.(type) {
+	case *[]byte:
+		b, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("got %T, want a byte string", v)
+		}
+		*dst = b
+	case *string:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("got %T, want a text string", v)
+		}
+		*dst = s
+	case *int64:
+		i, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("got %T, want an integer", v)
+		}
+		*dst = i
+	}
+	return nil
+}
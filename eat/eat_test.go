@@ -0,0 +1,86 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eat
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/internal/cbor"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestRoundTrip(t *testing.T) {
+	nonce := bytes.Repeat([]byte{3}, 64)
+	resp, err := report.Get(faketsm.Report611(0), &report.Request{InBlob: nonce, GetAuxBlob: true})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	token := FromResponse(resp, nonce, map[int][]byte{2: make([]byte, 48)})
+	token.IssuedAt = time.Unix(1700000000, 0)
+	for _, marshal := range []func() ([]byte, error){token.MarshalCBOR, token.MarshalUCCS} {
+		data, err := marshal()
+		if err != nil {
+			t.Fatalf("marshal() = _, %v. Want nil", err)
+		}
+		got, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("Unmarshal() = _, %v. Want nil", err)
+		}
+		if !reflect.DeepEqual(got, token) {
+			t.Errorf("Unmarshal() = %+v. Want %+v", got, token)
+		}
+	}
+	if token.Provider != "fake" {
+		t.Errorf("Provider = %q. Want %q without a newline", token.Provider, "fake")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	valid := (&Token{Nonce: []byte{1}, Provider: "fake", OutBlob: []byte{2}}).claims()
+	without := func(key int) map[any]any {
+		m := map[any]any{}
+		for k, v := range valid {
+			if k != key {
+				m[k] = v
+			}
+		}
+		return m
+	}
+	with := func(key int, v any) map[any]any {
+		m := without(key)
+		m[key] = v
+		return m
+	}
+	for name, v := range map[string]any{
+		"not a map":     []any{1},
+		"wrong tag":     cbor.Tag{Number: 61, Content: valid},
+		"wrong profile": with(ClaimProfile, "other"),
+		"no nonce":      without(ClaimNonce),
+		"text outblob":  with(ClaimOutBlob, "outblob"),
+		"bad rtmrs":     with(ClaimRtmrs, map[any]any{"2": []byte{}}),
+	} {
+		data, err := cbor.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Unmarshal(data); err == nil {
+			t.Errorf("Unmarshal(%s) = _, nil. Want an error", name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemeasure
+
+// Events selects the file accesses that Watch measures.
+type Events int
+
+const (
+	// OnExec measures files when they are opened for execution, e.g., by execve. Shared
+	// libraries are opened for reading by the dynamic loader, so need OnOpen.
+	OnExec Events = 1 << iota
+	// OnOpen measures files when they are opened for any purpose.
+	OnOpen
+)
@@ -0,0 +1,186 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filemeasure measures files into an RTMR as they are opened or executed, giving
+// confidential VMs without IMA a runtime integrity trail. Each measurement is a JSON record
+// of the file's path and SHA-384 digest, whose own SHA-384 digest is extended and which is
+// logged as the event content so that a verifier can replay the register.
+//
+// On Linux, Watch receives open and exec events from fanotify. Files are measured after
+// they are opened, so the trail records what ran rather than preventing it.
+package filemeasure
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+// ContentType is the event content type of file records.
+const ContentType = "file"
+
+// DefaultRtmr is the RTMR that files are measured into by default, the register for
+// runtime measurements of applications on TDX.
+const DefaultRtmr = 3
+
+var errUnsupported = errors.New("file measurement events are not supported on this platform")
+
+// Record is the measurement of a file.
+type Record struct {
+	// Path is the path the file was opened by.
+	Path string `json:"path"`
+	// SHA384 is the hex SHA-384 digest of the file's contents.
+	SHA384 string `json:"sha384"`
+}
+
+// Options configures a Measurer.
+type Options struct {
+	// Rtmr is the register to extend. Nil means DefaultRtmr.
+	Rtmr *int
+	// Include are the directories whose files are measured, e.g., "/usr/bin". Empty means
+	// all files.
+	Include []string
+	// Exclude are directories within Include whose files are not measured.
+	Exclude []string
+	// OnMeasure, if not nil, is called with each record after it is extended and logged,
+	// e.g., to persist the event log.
+	OnMeasure func(*Record)
+	// RtmrOptions are passed to each extend.
+	RtmrOptions []rtmr.Option
+}
+
+// Measurer measures files into an RTMR and an event log. It measures a file again only
+// when its version changes, like IMA.
+type Measurer struct {
+	client configfsi.Client
+	log    *rtmr.EventLog
+	opts   Options
+	mu     sync.Mutex
+	// rtmr is the register to extend.
+	rtmr int
+	// versions are the versions of the files last measured, by path.
+	versions map[string]string
+}
+
+// NewMeasurer returns a Measurer that extends through client and records events in log. A
+// nil opts measures all files into DefaultRtmr.
+func NewMeasurer(client configfsi.Client, log *rtmr.EventLog, opts *Options) *Measurer {
+	m := &Measurer{client: client, log: log, versions: make(map[string]string)}
+	if opts != nil {
+		m.opts = *opts
+	}
+	m.rtmr = DefaultRtmr
+	if m.opts.Rtmr != nil {
+		m.rtmr = *m.opts.Rtmr
+	}
+	return m
+}
+
+// within returns true if path is dir or inside it.
+func within(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// Matches returns true if files at path are measured.
+func (m *Measurer) Matches(path string) bool {
+	path = filepath.Clean(path)
+	for _, dir := range m.opts.Exclude {
+		if within(path, dir) {
+			return false
+		}
+	}
+	if len(m.opts.Include) == 0 {
+		return true
+	}
+	for _, dir := range m.opts.Include {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Measure extends the measurement of the file at path with contents r, unless the path is
+// not matched or the file was already measured at the same version. The version identifies
+// the file's contents, e.g., from its inode number, size, and change time; an empty version
+// is always measured. It returns whether the file was measured.
+func (m *Measurer) Measure(path, version string, r io.Reader) (bool, error) {
+	if !m.Matches(path) {
+		return false, nil
+	}
+	// Hold the lock across the extend so that a file is measured once per version.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if version != "" && m.versions[path] == version {
+		return false, nil
+	}
+	h := sha512.New384()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	rec := &Record{Path: path, SHA384: hex.EncodeToString(h.Sum(nil))}
+	record, err := json.Marshal(rec)
+	if err != nil {
+		return false, err
+	}
+	digest := sha512.Sum384(record)
+	if err := m.log.ExtendDigest(m.client, m.rtmr, digest[:], ContentType, record, m.opts.RtmrOptions...); err != nil {
+		return false, err
+	}
+	if version != "" {
+		m.versions[path] = version
+	}
+	if m.opts.OnMeasure != nil {
+		m.opts.OnMeasure(rec)
+	}
+	return true, nil
+}
+
+// ParseEvent returns the record of a file measurement event, checking that the event's
+// SHA-384 digest is of the record.
+func ParseEvent(e *rtmr.Event) (*Record, error) {
+	if e.ContentType != ContentType {
+		return nil, fmt.Errorf("event content type %q is not %q", e.ContentType, ContentType)
+	}
+	digest := sha512.Sum384(e.Content)
+	measured := false
+	for _, d := range e.Digests {
+		if d.HashAlg != "sha384" {
+			continue
+		}
+		if !bytes.Equal(d.Digest, digest[:]) {
+			return nil, fmt.Errorf("event %d digest is not of its file record", e.RecNum)
+		}
+		measured = true
+	}
+	if !measured {
+		return nil, fmt.Errorf("event %d has no sha384 digest", e.RecNum)
+	}
+	record := &Record{}
+	if err := json.Unmarshal(e.Content, record); err != nil {
+		return nil, fmt.Errorf("could not parse file record: %w", err)
+	}
+	return record, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemeasure
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+func TestMatches(t *testing.T) {
+	m := NewMeasurer(nil, nil, &Options{
+		Include: []string{"/usr/bin", "/opt/app/"},
+		Exclude: []string{"/opt/app/cache"},
+	})
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/usr/bin/env", true},
+		{"/usr/bin", true},
+		{"/usr/binary", false},
+		{"/opt/app/bin/server", true},
+		{"/opt/app/cache/blob", false},
+		{"/opt/app/../../etc/passwd", false},
+		{"/etc/passwd", false},
+	}
+	for _, tc := range tests {
+		if got := m.Matches(tc.path); got != tc.want {
+			t.Errorf("Matches(%q) = %v. Want %v", tc.path, got, tc.want)
+		}
+	}
+	if all := NewMeasurer(nil, nil, nil); !all.Matches("/etc/passwd") {
+		t.Error("Matches() with no includes = false. Want true")
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	log := rtmr.NewEventLog()
+	var measured []*Record
+	m := NewMeasurer(client, log, &Options{
+		Include:   []string{"/usr/bin"},
+		OnMeasure: func(r *Record) { measured = append(measured, r) },
+	})
+	steps := []struct {
+		path, version, content string
+		want                   bool
+	}{
+		{"/usr/bin/env", "1", "env v1", true},
+		{"/usr/bin/env", "1", "env v1", false},
+		{"/usr/bin/env", "2", "env v2", true},
+		{"/usr/bin/env", "", "env v2", true},
+		{"/etc/passwd", "1", "root", false},
+	}
+	for _, s := range steps {
+		got, err := m.Measure(s.path, s.version, strings.NewReader(s.content))
+		if err != nil || got != s.want {
+			t.Fatalf("Measure(%q, %q) = %v, %v. Want %v, nil", s.path, s.version, got, err, s.want)
+		}
+	}
+	events := log.Events()
+	if len(events) != 3 || len(measured) != 3 {
+		t.Fatalf("measured %d events and %d records. Want 3", len(events), len(measured))
+	}
+	results, err := rtmr.Verify(client, events)
+	if err != nil || len(results) != 1 || results[0].Index != DefaultRtmr || !results[0].Match {
+		t.Fatalf("Verify() = %v, %v. Want rtmr%d to match", results, err, DefaultRtmr)
+	}
+	got, err := ParseEvent(&events[1])
+	if err != nil {
+		t.Fatalf("ParseEvent() = _, %v. Want nil", err)
+	}
+	sum := sha512.Sum384([]byte("env v2"))
+	if want := (Record{Path: "/usr/bin/env", SHA384: hex.EncodeToString(sum[:])}); *got != want || *measured[1] != want {
+		t.Errorf("ParseEvent() = %+v. Want %+v", got, want)
+	}
+	events[1].Content = []byte(`{"path":"/usr/bin/env","sha384":"00"}`)
+	if _, err := ParseEvent(&events[1]); err == nil {
+		t.Error("ParseEvent() of altered content = _, nil. Want an error")
+	}
+}
+
+func TestMeasureRtmr(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	for _, index := range []int{0, 2} {
+		index := index
+		m := NewMeasurer(client, rtmr.NewEventLog(), &Options{Rtmr: &index})
+		_, err := m.Measure("/usr/bin/env", "1", strings.NewReader("env"))
+		// Firmware owns rtmr0, so an explicit 0 must not fall back to DefaultRtmr.
+		if wantErr := index == 0; (err != nil) != wantErr || (wantErr && !errors.Is(err, rtmr.ErrNotExtendable)) {
+			t.Errorf("Measure() into rtmr%d = _, %v. Want error %v", index, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && (amd64 || arm64)
+
+package filemeasure
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// fanotify constants from include/uapi/linux/fanotify.h. The 64-bit event mask is passed in
+// one register, so only 64-bit architectures are supported.
+const (
+	fanCloexec       = 0x1
+	fanNonblock      = 0x2
+	fanClassNotif    = 0x0
+	fanMarkAdd       = 0x1
+	fanMarkMount     = 0x10
+	fanOpen          = 0x20
+	fanOpenExec      = 0x1000
+	fanQOverflow     = 0x4000
+	fanNoFd          = -1
+	fanMetadataVers  = 3
+	fanMetadataSize  = 24
+	atFdcwd          = -100
+	eventBufferBytes = 4096
+)
+
+// Watch measures files on the mounts of the given paths as they are accessed until ctx is
+// done. It requires CAP_SYS_ADMIN and Linux 5.0 for OnExec. Errors measuring one file are
+// passed to onError, if not nil, and do not stop Watch.
+func Watch(ctx context.Context, m *Measurer, mounts []string, events Events, onError func(error)) error {
+	var mask uint64
+	if events&OnExec != 0 {
+		mask |= fanOpenExec
+	}
+	if events&OnOpen != 0 {
+		mask |= fanOpen
+	}
+	if mask == 0 {
+		return errors.New("no events to watch")
+	}
+	fd, _, errno := syscall.Syscall(syscall.SYS_FANOTIFY_INIT, fanClassNotif|fanCloexec|fanNonblock,
+		uintptr(os.O_RDONLY|syscall.O_LARGEFILE|syscall.O_CLOEXEC), 0)
+	if errno != 0 {
+		return os.NewSyscallError("fanotify_init", errno)
+	}
+	// A non-blocking descriptor uses the runtime poller, so Close interrupts Read.
+	f := os.NewFile(fd, "fanotify")
+	defer f.Close()
+	for _, mount := range mounts {
+		p, err := syscall.BytePtrFromString(mount)
+		if err != nil {
+			return err
+		}
+		dirfd := atFdcwd
+		if _, _, errno := syscall.Syscall6(syscall.SYS_FANOTIFY_MARK, fd, fanMarkAdd|fanMarkMount, uintptr(mask),
+			uintptr(dirfd), uintptr(unsafe.Pointer(p)), 0); errno != 0 {
+			return fmt.Errorf("fanotify_mark %s: %w", mount, os.NewSyscallError("fanotify_mark", errno))
+		}
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.Close()
+		case <-done:
+		}
+	}()
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+	buf := make([]byte, eventBufferBytes)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		// Both supported architectures are little-endian.
+		le := binary.LittleEndian
+		for b := buf[:n]; len(b) >= fanMetadataSize; {
+			eventLen := int(le.Uint32(b[0:]))
+			if b[4] != fanMetadataVers {
+				return fmt.Errorf("fanotify metadata version %d is not %d", b[4], fanMetadataVers)
+			}
+			if eventLen < fanMetadataSize || eventLen > len(b) {
+				return fmt.Errorf("fanotify event length %d is invalid", eventLen)
+			}
+			eventMask := le.Uint64(b[8:])
+			eventFd := int32(le.Uint32(b[16:]))
+			pid := int(int32(le.Uint32(b[20:])))
+			b = b[eventLen:]
+			if eventMask&fanQOverflow != 0 {
+				report(errors.New("fanotify event queue overflowed; some accesses were not measured"))
+			}
+			if eventFd == fanNoFd {
+				continue
+			}
+			// Skip the process's own accesses, e.g., persisting the event log.
+			if pid == os.Getpid() {
+				syscall.Close(int(eventFd))
+				continue
+			}
+			if err := measureFd(m, int(eventFd)); err != nil {
+				report(err)
+			}
+		}
+	}
+}
+
+// measureFd measures the file of an event's descriptor and closes it.
+func measureFd(m *Measurer, fd int) error {
+	file := os.NewFile(uintptr(fd), "")
+	defer file.Close()
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+	if err != nil {
+		return err
+	}
+	if !m.Matches(path) {
+		return nil
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFREG {
+		return nil
+	}
+	version := fmt.Sprintf("%d:%d:%d:%d.%d", st.Dev, st.Ino, st.Size, st.Ctim.Sec, st.Ctim.Nsec)
+	_, err = m.Measure(path, version, io.NewSectionReader(file, 0, st.Size))
+	return err
+}
@@ -0,0 +1,316 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cbor implements the subset of CBOR (RFC 8949) that attestation evidence formats
+// need: integers, byte and text strings, arrays, maps, tags, and simple values. Maps are
+// encoded with deterministically sorted keys.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	majorUint   = 0
+	majorNint   = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+
+	// maxDepth bounds the nesting of decoded items.
+	maxDepth = 32
+)
+
+// ErrTrailingData is returned by Unmarshal for data that continues after the first item.
+var ErrTrailingData = errors.New("cbor: trailing data after item")
+
+// Tag is a tagged data item.
+type Tag struct {
+	Number  uint64
+	Content any
+}
+
+// Marshal returns the encoding of v, which can be nil, a bool, an integer, a []byte, a
+// string, a []any, a map[any]any, a Tag, or a RawMessage, nested arbitrarily.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RawMessage is an already encoded data item that Marshal copies as is.
+type RawMessage []byte
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(m | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(m | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeInt(buf *bytes.Buffer, i int64) {
+	if i >= 0 {
+		writeHead(buf, majorUint, uint64(i))
+	} else {
+		writeHead(buf, majorNint, uint64(-(i + 1)))
+	}
+}
+
+func encode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if v {
+			buf.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			buf.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		writeInt(buf, int64(v))
+	case int32:
+		writeInt(buf, int64(v))
+	case int64:
+		writeInt(buf, v)
+	case uint:
+		writeHead(buf, majorUint, uint64(v))
+	case uint32:
+		writeHead(buf, majorUint, uint64(v))
+	case uint64:
+		writeHead(buf, majorUint, v)
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		writeHead(buf, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return encodeMap(buf, v)
+	case Tag:
+		writeHead(buf, majorTag, v.Number)
+		return encode(buf, v.Content)
+	case *Tag:
+		return encode(buf, *v)
+	case RawMessage:
+		buf.Write(v)
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", v)
+	}
+	return nil
+}
+
+// encodeMap writes a map with its keys sorted by their encodings, as in RFC 8949's core
+// deterministic encoding.
+func encodeMap(buf *bytes.Buffer, m map[any]any) error {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, len(m))
+	for k, v := range m {
+		key, err := Marshal(k)
+		if err != nil {
+			return err
+		}
+		value, err := Marshal(v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+	writeHead(buf, majorMap, uint64(len(entries)))
+	for _, e := range entries {
+		buf.Write(e.key)
+		buf.Write(e.value)
+	}
+	return nil
+}
+
+// Unmarshal decodes a single data item that makes up all of data. Unsigned and negative
+// integers decode as int64 if they fit and as uint64 otherwise, byte strings as []byte,
+// text strings as string, arrays as []any, maps as map[any]any, and tags as Tag.
+// Indefinite-length items and floating-point numbers are not supported.
+func Unmarshal(data []byte) (any, error) {
+	d := &decoder{data: data}
+	v, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, ErrTrailingData
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+var errTruncated = errors.New("cbor: truncated data")
+
+func (d *decoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errTruncated
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head returns the major type and argument of the next item.
+func (d *decoder) head() (byte, uint64, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	var size uint64
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case major == majorSimple && info > 24:
+		return 0, 0, errors.New("cbor: floating-point numbers are not supported")
+	case info <= 27:
+		size = 1 << (info - 24)
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	arg, err := d.take(size)
+	if err != nil {
+		return 0, 0, err
+	}
+	var n uint64
+	for _, c := range arg {
+		n = n<<8 | uint64(c)
+	}
+	return major, n, nil
+}
+
+func (d *decoder) item(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case majorNint:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer out of range")
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Clone(b), nil
+	case majorText:
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		// Each item takes at least one byte, which bounds allocations by the input size.
+		if n > uint64(len(d.data)-d.off) {
+			return nil, errTruncated
+		}
+		result := make([]any, n)
+		for i := range result {
+			if result[i], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case majorMap:
+		if n > uint64(len(d.data)-d.off) {
+			return nil, errTruncated
+		}
+		result := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, uint64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+			if _, ok := result[k]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", k)
+			}
+			if result[k], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case majorTag:
+		content, err := d.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: n, Content: content}, nil
+	default:
+		switch n {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	// Expected encodings are from RFC 8949 Appendix A.
+	tcs := []struct {
+		v    any
+		want string
+	}{
+		{v: 0, want: "00"},
+		{v: 23, want: "17"},
+		{v: 24, want: "1818"},
+		{v: 1000, want: "1903e8"},
+		{v: uint64(1000000000000), want: "1b000000e8d4a51000"},
+		{v: -1, want: "20"},
+		{v: -1000, want: "3903e7"},
+		{v: false, want: "f4"},
+		{v: true, want: "f5"},
+		{v: nil, want: "f6"},
+		{v: []byte{1, 2, 3, 4}, want: "4401020304"},
+		{v: "IETF", want: "6449455446"},
+		{v: []any{1, []any{2, 3}}, want: "8201820203"},
+		{v: map[any]any{"b": []any{2, 3}, "a": 1}, want: "a26161016162820203"},
+		{v: map[any]any{10: 1, -1: 2, "a": 3}, want: "a30a012002616103"},
+		{v: Tag{Number: 1, Content: 1363896240}, want: "c11a514b67b0"},
+	}
+	for _, tc := range tcs {
+		got, err := Marshal(tc.v)
+		if err != nil {
+			t.Errorf("Marshal(%v) = _, %v. Want nil", tc.v, err)
+			continue
+		}
+		if hex.EncodeToString(got) != tc.want {
+			t.Errorf("Marshal(%v) = %x. Want %s", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := Tag{Number: 601, Content: map[any]any{
+		int64(10):     []byte("nonce"),
+		int64(-70000): "sev_guest",
+		"list":        []any{int64(1), uint64(1 << 63), true, nil},
+	}}
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() = _, %v. Want nil", err)
+	}
+	got, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() = _, %v. Want nil", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("Unmarshal(Marshal(%v)) = %v", v, got)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, in := range []string{
+		"",                   // empty
+		"1903",               // truncated argument
+		"44010203",           // truncated byte string
+		"9bffffffffffffffff", // huge array
+		"a2616101616102",     // duplicate key
+		"f97e00",             // half-precision float
+		"5f",                 // indefinite length
+		"a18000",             // array key
+	} {
+		data, _ := hex.DecodeString(in)
+		if _, err := Unmarshal(data); err == nil {
+			t.Errorf("Unmarshal(%s) = _, nil. Want an error", in)
+		}
+	}
+	if _, err := Unmarshal([]byte{0, 0}); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("Unmarshal(0000) = _, %v. Want ErrTrailingData", err)
+	}
+	deep := append(bytes.Repeat([]byte{0x81}, 100), 0)
+	if _, err := Unmarshal(deep); err == nil {
+		t.Error("Unmarshal(deeply nested) = _, nil. Want an error")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snp holds the layout of SEV-SNP attestation reports and certificate tables
+// shared by the packages that render, inspect, and verify them.
+package snp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ReportSize is the size of an SEV-SNP ATTESTATION_REPORT.
+	ReportSize = 0x4A0
+	// SignedSize is the size of the signed part of the report, before the signature.
+	SignedSize = 0x2A0
+	// CertTableEntrySize is the size of a certificate table entry: a GUID, offset, and
+	// length.
+	CertTableEntrySize = 24
+)
+
+// GUIDs of the certificates in an SEV-SNP certificate table.
+var (
+	GUIDArk  = uuid.MustParse("c0b406a4-a803-4952-9743-3fb6014cd0ae")
+	GUIDAsk  = uuid.MustParse("4ab7b379-bbac-4fe4-a02f-05aef327c782")
+	GUIDVcek = uuid.MustParse("63da758d-e664-4564-adc5-f4b93be8accd")
+	GUIDVlek = uuid.MustParse("a8074bc2-a25a-483e-aae6-39c045a0b8a1")
+)
+
+// CertTableEntry locates a certificate in the bytes of its certificate table.
+type CertTableEntry struct {
+	GUID           uuid.UUID
+	Offset, Length uint32
+}
+
+// ParseCertTable returns the entries of a certificate table and the size of the table
+// through its terminating all-zero entry. The entries are not checked against the table.
+func ParseCertTable(table []byte) (entries []CertTableEntry, end int, err error) {
+	le := binary.LittleEndian
+	for off := 0; off+CertTableEntrySize <= len(table); off += CertTableEntrySize {
+		var e CertTableEntry
+		copy(e.GUID[:], table[off:off+16])
+		e.Offset = le.Uint32(table[off+16:])
+		e.Length = le.Uint32(table[off+20:])
+		if e == (CertTableEntry{}) {
+			return entries, off + CertTableEntrySize, nil
+		}
+		entries = append(entries, e)
+	}
+	return nil, 0, fmt.Errorf("certificate table of %d bytes has no terminating zero entry", len(table))
+}
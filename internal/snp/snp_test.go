@@ -0,0 +1,38 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseCertTable(t *testing.T) {
+	table := make([]byte, 2*CertTableEntrySize+3)
+	copy(table, GUIDVcek[:])
+	binary.LittleEndian.PutUint32(table[16:], 2*CertTableEntrySize)
+	binary.LittleEndian.PutUint32(table[20:], 3)
+	entries, end, err := ParseCertTable(table)
+	if err != nil {
+		t.Fatalf("ParseCertTable() = _, _, %v. Want nil", err)
+	}
+	want := CertTableEntry{GUID: GUIDVcek, Offset: 2 * CertTableEntrySize, Length: 3}
+	if len(entries) != 1 || entries[0] != want || end != 2*CertTableEntrySize {
+		t.Errorf("ParseCertTable() = %v, %d, nil. Want [%v], %d, nil", entries, end, want, 2*CertTableEntrySize)
+	}
+	if _, _, err := ParseCertTable(table[:CertTableEntrySize]); err == nil {
+		t.Error("ParseCertTable() of a table without a terminating entry = _, _, nil. Want an error")
+	}
+}
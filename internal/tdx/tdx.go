@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tdx holds the layout of version 4 TDX quotes shared by the packages that
+// render, inspect, and verify them.
+package tdx
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// QuoteVersion is the quote version whose layout is described.
+	QuoteVersion = 4
+	// AttestationKeyTypeEcdsaP256 is the ECDSA-256-with-P-256 attestation key type.
+	AttestationKeyTypeEcdsaP256 = 2
+	// TeeType is the TEE type of TDX.
+	TeeType = 0x81
+	// HeaderSize and BodySize are the sizes of the quote header and TD quote body.
+	HeaderSize = 48
+	BodySize   = 584
+	// SignatureDataOffset is the offset of the signature data after the header, body, and
+	// 4-byte signature data length.
+	SignatureDataOffset = HeaderSize + BodySize + 4
+	// ReportDataOffset and ReportDataSize locate REPORTDATA in the quote.
+	ReportDataOffset = HeaderSize + BodySize - ReportDataSize
+	ReportDataSize   = 64
+	// RtmrsOffset locates RTMR0 in the quote, and RtmrCount RTMRs of RtmrSize bytes each
+	// follow it.
+	RtmrsOffset = HeaderSize + 328
+	RtmrCount   = 4
+	RtmrSize    = 48
+	// SignatureSize is the size of a raw ECDSA P-256 signature or public key.
+	SignatureSize = 64
+	// CertDataQeReport and CertDataPckCertChain are certification data types.
+	CertDataQeReport     = 6
+	CertDataPckCertChain = 5
+	// QeReportSize is the size of the QE report in the certification data.
+	QeReportSize = 384
+)
+
+// CertData splits certification data into its type, its data, and the bytes after it.
+func CertData(b []byte) (typ uint16, data, rest []byte, err error) {
+	if len(b) < 6 {
+		return 0, nil, nil, fmt.Errorf("%d bytes is too short for a certification data header", len(b))
+	}
+	typ = binary.LittleEndian.Uint16(b[0:])
+	size := uint64(binary.LittleEndian.Uint32(b[2:]))
+	if size > uint64(len(b)-6) {
+		return 0, nil, nil, fmt.Errorf("certification data size %d exceeds the %d bytes remaining", size, len(b)-6)
+	}
+	return typ, b[6 : 6+size], b[6+size:], nil
+}
+
+// QeCertData returns the type and data of the certification data nested in QE report
+// certification data, after the QE report, its signature, and the QE authentication data.
+func QeCertData(data []byte) (typ uint16, inner []byte, err error) {
+	if len(data) < QeReportSize+SignatureSize+2 {
+		return 0, nil, fmt.Errorf("QE report certification data of %d bytes is too short", len(data))
+	}
+	data = data[QeReportSize+SignatureSize:]
+	authLen := int(binary.LittleEndian.Uint16(data))
+	if authLen > len(data)-2 {
+		return 0, nil, fmt.Errorf("QE authentication data size %d exceeds the %d bytes remaining", authLen, len(data)-2)
+	}
+	typ, inner, _, err = CertData(data[2+authLen:])
+	if err != nil {
+		return 0, nil, fmt.Errorf("QE certification data: %w", err)
+	}
+	return typ, inner, nil
+}
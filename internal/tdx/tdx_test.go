@@ -0,0 +1,45 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// certData returns certification data of the given type.
+func certData(typ uint16, data []byte) []byte {
+	b := make([]byte, 6, 6+len(data))
+	binary.LittleEndian.PutUint16(b[0:], typ)
+	binary.LittleEndian.PutUint32(b[2:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func TestQeCertData(t *testing.T) {
+	qe := make([]byte, QeReportSize+SignatureSize+2)
+	qe = append(qe, certData(CertDataPckCertChain, []byte("chain"))...)
+	typ, data, rest, err := CertData(append(certData(CertDataQeReport, qe), 0))
+	if err != nil || typ != CertDataQeReport || len(rest) != 1 {
+		t.Fatalf("CertData() = %d, _, %v, %v. Want %d, _, [0], nil", typ, rest, err, CertDataQeReport)
+	}
+	typ, data, err = QeCertData(data)
+	if err != nil || typ != CertDataPckCertChain || !bytes.Equal(data, []byte("chain")) {
+		t.Errorf("QeCertData() = %d, %q, %v. Want %d, \"chain\", nil", typ, data, err, CertDataPckCertChain)
+	}
+	if _, _, err := QeCertData(qe[:QeReportSize]); err == nil {
+		t.Error("QeCertData() of a truncated QE report = _, _, nil. Want an error")
+	}
+}
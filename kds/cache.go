@@ -0,0 +1,93 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache caches KDS responses by URL. KDS responses for a URL do not change, so entries do
+// not expire.
+type Cache interface {
+	// Get returns the cached data for the key and true, or false if none is cached.
+	Get(key string) ([]byte, bool)
+	// Put caches the data for the key.
+	Put(key string, data []byte)
+}
+
+// MemoryCache is a Cache in memory.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// Get returns the cached data for the key.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[key]
+	return bytes.Clone(data), ok
+}
+
+// Put caches the data for the key.
+func (c *MemoryCache) Put(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string][]byte)
+	}
+	c.entries[key] = bytes.Clone(data)
+}
+
+// DirCache is a Cache of files in a directory, named by the SHA-256 digest of their key,
+// that persists across processes. Errors are treated as misses.
+type DirCache string
+
+func (c DirCache) path(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return filepath.Join(string(c), hex.EncodeToString(digest[:]))
+}
+
+// Get returns the contents of the key's file.
+func (c DirCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key))
+	return data, err == nil
+}
+
+// Put atomically replaces the key's file with the data.
+func (c DirCache) Put(key string, data []byte) {
+	if err := os.MkdirAll(string(c), 0700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(string(c), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
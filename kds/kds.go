@@ -0,0 +1,317 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kds completes the certificate chain of SEV-SNP reports from the AMD Key
+// Distribution Service when the auxblob certificate table lacks some of it. The VCEK is
+// requested for the chip ID and reported TCB of the report, and responses are cached since
+// they do not change for a given URL.
+package kds
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/internal/httputil"
+	"github.com/google/go-configfs-tsm/internal/snp"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/uuid"
+)
+
+// BaseURL is the AMD KDS URL that Fetcher uses by default.
+const BaseURL = "https://kdsintf.amd.com"
+
+// Products that KDS issues certificates for.
+const (
+	ProductMilan = "Milan"
+	ProductGenoa = "Genoa"
+	ProductTurin = "Turin"
+)
+
+// GUIDs of the certificates in an SEV-SNP certificate table.
+var (
+	GUIDArk  = snp.GUIDArk
+	GUIDAsk  = snp.GUIDAsk
+	GUIDVcek = snp.GUIDVcek
+	GUIDVlek = snp.GUIDVlek
+)
+
+// HTTPError is returned when KDS responds with a status other than 200 OK. KDS responds
+// with 429 Too Many Requests when rate limited.
+type HTTPError = httputil.StatusError
+
+// Getter fetches the body of a URL.
+type Getter interface {
+	Get(url string) ([]byte, error)
+}
+
+// HTTPGetter is a Getter that uses an HTTP client.
+type HTTPGetter struct {
+	// Client is the client to use. Nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// Get returns the body of a 200 OK response to a GET of the URL.
+func (g *HTTPGetter) Get(url string) ([]byte, error) {
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Chain is the DER certificate chain of an SEV-SNP report's signing key.
+type Chain struct {
+	// Vcek is the VCEK certificate, or nil if Vlek signs the report.
+	Vcek []byte
+	// Vlek is the VLEK certificate from the auxblob, if any.
+	Vlek []byte
+	Ask  []byte
+	Ark  []byte
+}
+
+// CertTable returns the chain as an SEV-SNP certificate table, e.g., to replace an
+// incomplete auxblob.
+func (c *Chain) CertTable() []byte {
+	var entries []struct {
+		guid uuid.UUID
+		der  []byte
+	}
+	for _, e := range []struct {
+		guid uuid.UUID
+		der  []byte
+	}{{GUIDArk, c.Ark}, {GUIDAsk, c.Ask}, {GUIDVcek, c.Vcek}, {GUIDVlek, c.Vlek}} {
+		if len(e.der) != 0 {
+			entries = append(entries, e)
+		}
+	}
+	offset := (len(entries) + 1) * snp.CertTableEntrySize
+	table := make([]byte, offset)
+	for i, e := range entries {
+		entry := table[i*snp.CertTableEntrySize:]
+		copy(entry[0:16], e.guid[:])
+		binary.LittleEndian.PutUint32(entry[16:], uint32(len(table)))
+		binary.LittleEndian.PutUint32(entry[20:], uint32(len(e.der)))
+		table = append(table, e.der...)
+	}
+	return table
+}
+
+// ParseCertTable returns the certificates of an SEV-SNP certificate table by GUID.
+func ParseCertTable(table []byte) (map[uuid.UUID][]byte, error) {
+	entries, _, err := snp.ParseCertTable(table)
+	if err != nil {
+		return nil, err
+	}
+	certs := make(map[uuid.UUID][]byte)
+	for _, e := range entries {
+		offset, length := uint64(e.Offset), uint64(e.Length)
+		if offset+length > uint64(len(table)) {
+			return nil, fmt.Errorf("certificate %s at [%d, %d) exceeds the %d-byte table", e.GUID, offset, offset+length, len(table))
+		}
+		certs[e.GUID] = table[offset : offset+length]
+	}
+	return certs, nil
+}
+
+// Tcb is the security patch levels of the TCB that a VCEK is derived from.
+type Tcb struct {
+	// Fmc is the firmware FMC patch level, which only Turin reports.
+	Fmc        uint8
+	Bootloader uint8
+	Tee        uint8
+	Snp        uint8
+	Microcode  uint8
+}
+
+// parseTcb decodes a TCB_VERSION, whose layout differs for Turin.
+func parseTcb(product string, tcb uint64) Tcb {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, tcb)
+	if product == ProductTurin {
+		return Tcb{Fmc: b[0], Bootloader: b[1], Tee: b[2], Snp: b[3], Microcode: b[7]}
+	}
+	return Tcb{Bootloader: b[0], Tee: b[1], Snp: b[6], Microcode: b[7]}
+}
+
+// productFromCpuid returns the product of a CPUID family and model, or "" if unknown.
+func productFromCpuid(family, model byte) string {
+	switch {
+	case family == 0x19 && model < 0x10:
+		return ProductMilan
+	case family == 0x19 && model >= 0x10 && model < 0x20:
+		return ProductGenoa
+	case family == 0x1A && model < 0x20:
+		return ProductTurin
+	}
+	return ""
+}
+
+// Fetcher fetches certificates from KDS.
+type Fetcher struct {
+	// BaseURL is the KDS URL. Empty means BaseURL.
+	BaseURL string
+	// Getter fetches URLs. Nil means an HTTPGetter with http.DefaultClient.
+	Getter Getter
+	// Cache caches fetched certificates if not nil.
+	Cache Cache
+	// Product is the product of the chip. If empty, it is determined from the CPUID fields
+	// of version 3 and later reports.
+	Product string
+}
+
+func (f *Fetcher) get(url string) ([]byte, error) {
+	if f.Cache != nil {
+		if data, ok := f.Cache.Get(url); ok {
+			return data, nil
+		}
+	}
+	getter := f.Getter
+	if getter == nil {
+		getter = &HTTPGetter{}
+	}
+	data, err := getter.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if f.Cache != nil {
+		f.Cache.Put(url, data)
+	}
+	return data, nil
+}
+
+func (f *Fetcher) baseURL() string {
+	if f.BaseURL == "" {
+		return BaseURL
+	}
+	return strings.TrimRight(f.BaseURL, "/")
+}
+
+// product returns the product of the chip that produced the report.
+func (f *Fetcher) product(outblob []byte) (string, error) {
+	if f.Product != "" {
+		return f.Product, nil
+	}
+	if len(outblob) < snp.ReportSize {
+		return "", fmt.Errorf("report of %d bytes is shorter than %d", len(outblob), snp.ReportSize)
+	}
+	if binary.LittleEndian.Uint32(outblob[0x00:]) >= 3 {
+		if product := productFromCpuid(outblob[0x188], outblob[0x189]); product != "" {
+			return product, nil
+		}
+	}
+	return "", errors.New("cannot determine the product of the report; set Fetcher.Product")
+}
+
+// VcekURL returns the KDS URL of the VCEK that signs an SEV-SNP report.
+func (f *Fetcher) VcekURL(outblob []byte) (string, error) {
+	product, err := f.product(outblob)
+	if err != nil {
+		return "", err
+	}
+	if len(outblob) < snp.ReportSize {
+		return "", fmt.Errorf("report of %d bytes is shorter than %d", len(outblob), snp.ReportSize)
+	}
+	tcb := parseTcb(product, binary.LittleEndian.Uint64(outblob[0x180:]))
+	chipID := outblob[0x1A0:0x1E0]
+	if product == ProductTurin {
+		// Turin VCEKs are identified by the first 8 bytes of the chip ID.
+		return fmt.Sprintf("%s/vcek/v1/%s/%s?fmcSPL=%02d&blSPL=%02d&teeSPL=%02d&snpSPL=%02d&ucodeSPL=%02d",
+			f.baseURL(), product, hex.EncodeToString(chipID[:8]), tcb.Fmc, tcb.Bootloader, tcb.Tee, tcb.Snp, tcb.Microcode), nil
+	}
+	return fmt.Sprintf("%s/vcek/v1/%s/%s?blSPL=%02d&teeSPL=%02d&snpSPL=%02d&ucodeSPL=%02d",
+		f.baseURL(), product, hex.EncodeToString(chipID), tcb.Bootloader, tcb.Tee, tcb.Snp, tcb.Microcode), nil
+}
+
+// certChain fetches the ASK and ARK of the product for the "vcek" or "vlek" key type.
+func (f *Fetcher) certChain(product, keyType string) (ask, ark []byte, err error) {
+	data, err := f.get(fmt.Sprintf("%s/%s/v1/%s/cert_chain", f.baseURL(), keyType, product))
+	if err != nil {
+		return nil, nil, err
+	}
+	var certs [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		certs = append(certs, block.Bytes)
+	}
+	if len(certs) != 2 {
+		return nil, nil, fmt.Errorf("cert_chain has %d certificates, want the ASK and ARK", len(certs))
+	}
+	return certs[0], certs[1], nil
+}
+
+// Complete returns the certificate chain of an SEV-SNP response, taking certificates from
+// its auxblob and fetching the missing ones from KDS. A VLEK cannot be fetched, so a
+// response without a VLEK in its auxblob is completed with a VCEK.
+func (f *Fetcher) Complete(resp *report.Response) (*Chain, error) {
+	if provider := strings.TrimRight(resp.Provider, "\n"); provider != report.ProviderSevGuest {
+		return nil, fmt.Errorf("provider %q is not %q", provider, report.ProviderSevGuest)
+	}
+	chain := &Chain{}
+	if len(resp.AuxBlob) != 0 {
+		certs, err := ParseCertTable(resp.AuxBlob)
+		if err != nil {
+			return nil, fmt.Errorf("auxblob: %w", err)
+		}
+		chain.Vcek, chain.Vlek, chain.Ask, chain.Ark = certs[GUIDVcek], certs[GUIDVlek], certs[GUIDAsk], certs[GUIDArk]
+	}
+	if len(chain.Vlek) == 0 && len(chain.Vcek) == 0 {
+		url, err := f.VcekURL(resp.OutBlob)
+		if err != nil {
+			return nil, err
+		}
+		if chain.Vcek, err = f.get(url); err != nil {
+			return nil, fmt.Errorf("could not fetch the VCEK: %w", err)
+		}
+	}
+	if len(chain.Ask) != 0 && len(chain.Ark) != 0 {
+		return chain, nil
+	}
+	product, err := f.product(resp.OutBlob)
+	if err != nil {
+		return nil, err
+	}
+	keyType := "vcek"
+	if len(chain.Vlek) != 0 {
+		keyType = "vlek"
+	}
+	ask, ark, err := f.certChain(product, keyType)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the %s certificate chain: %w", keyType, err)
+	}
+	if len(chain.Ask) == 0 {
+		chain.Ask = ask
+	}
+	if len(chain.Ark) == 0 {
+		chain.Ark = ark
+	}
+	return chain, nil
+}
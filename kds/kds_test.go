@@ -0,0 +1,168 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kds
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// fakeGetter serves fixed responses and counts the requests.
+type fakeGetter struct {
+	bodies   map[string][]byte
+	requests int
+}
+
+func (g *fakeGetter) Get(url string) ([]byte, error) {
+	g.requests++
+	body, ok := g.bodies[url]
+	if !ok {
+		return nil, &HTTPError{URL: url, StatusCode: http.StatusNotFound}
+	}
+	return body, nil
+}
+
+func testCert(t *testing.T) []byte {
+	t.Helper()
+	_, cert, err := faketsm.GenerateTestVcek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func milanResponse(t *testing.T, opts *faketsm.SnpOptions, aux bool) *report.Response {
+	t.Helper()
+	opts.Version = 3
+	opts.ReportedTcb = 0x7308000000000203
+	opts.ChipID = [64]byte{0xab, 0xcd}
+	resp, err := report.Get(faketsm.ReportSnp(0, opts), &report.Request{InBlob: make([]byte, 64), GetAuxBlob: aux})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	// Family 0x19, model 0x01 is Milan.
+	resp.OutBlob[0x188], resp.OutBlob[0x189] = 0x19, 0x01
+	return resp
+}
+
+const milanVcekURL = "https://kdsintf.amd.com/vcek/v1/Milan/abcd" +
+	"0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000" +
+	"?blSPL=03&teeSPL=02&snpSPL=08&ucodeSPL=115"
+
+func TestVcekURL(t *testing.T) {
+	resp := milanResponse(t, &faketsm.SnpOptions{}, false)
+	f := &Fetcher{}
+	url, err := f.VcekURL(resp.OutBlob)
+	if err != nil || url != milanVcekURL {
+		t.Errorf("VcekURL() = %q, %v. Want %q, nil", url, err, milanVcekURL)
+	}
+
+	// Turin has an 8-byte hardware ID and another TCB layout.
+	resp.OutBlob[0x188], resp.OutBlob[0x189] = 0x1A, 0x02
+	want := "https://kdsintf.amd.com/vcek/v1/Turin/abcd000000000000?fmcSPL=03&blSPL=02&teeSPL=00&snpSPL=00&ucodeSPL=115"
+	if url, err := f.VcekURL(resp.OutBlob); err != nil || url != want {
+		t.Errorf("VcekURL() = %q, %v. Want %q, nil", url, err, want)
+	}
+
+	// Version 2 reports have no CPUID fields.
+	resp.OutBlob[0] = 2
+	if _, err := f.VcekURL(resp.OutBlob); err == nil {
+		t.Error("VcekURL() of a version 2 report = _, nil. Want an error")
+	}
+	f.Product = ProductGenoa
+	if _, err := f.VcekURL(resp.OutBlob); err != nil {
+		t.Errorf("VcekURL() with Product = _, %v. Want nil", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	vcek, ask, ark := testCert(t), testCert(t), testCert(t)
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ask}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ark})...)
+	getter := &fakeGetter{bodies: map[string][]byte{
+		milanVcekURL: vcek,
+		"https://kdsintf.amd.com/vcek/v1/Milan/cert_chain": chain,
+	}}
+	f := &Fetcher{Getter: getter, Cache: &MemoryCache{}}
+	resp := milanResponse(t, &faketsm.SnpOptions{}, true)
+	for i := 0; i < 2; i++ {
+		got, err := f.Complete(resp)
+		if err != nil {
+			t.Fatalf("Complete() = _, %v. Want nil", err)
+		}
+		if !bytes.Equal(got.Vcek, vcek) || !bytes.Equal(got.Ask, ask) || !bytes.Equal(got.Ark, ark) {
+			t.Errorf("Complete() returned the wrong chain")
+		}
+		certs, err := ParseCertTable(got.CertTable())
+		if err != nil || len(certs) != 3 || !bytes.Equal(certs[GUIDVcek], vcek) {
+			t.Errorf("ParseCertTable(CertTable()) = %d certificates, %v. Want the chain", len(certs), err)
+		}
+	}
+	if getter.requests != 2 {
+		t.Errorf("Complete() twice made %d requests. Want 2", getter.requests)
+	}
+
+	// A VCEK in the auxblob is used rather than fetched.
+	getter.requests = 0
+	f.Cache = nil
+	resp = milanResponse(t, &faketsm.SnpOptions{VcekCert: vcek}, true)
+	if _, err := f.Complete(resp); err != nil || getter.requests != 1 {
+		t.Errorf("Complete() with a VCEK = _, %v after %d requests. Want nil after 1", err, getter.requests)
+	}
+
+	delete(getter.bodies, milanVcekURL)
+	resp = milanResponse(t, &faketsm.SnpOptions{}, false)
+	var httpErr *HTTPError
+	if _, err := f.Complete(resp); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Complete() = _, %v. Want a 404 HTTPError", err)
+	}
+}
+
+func TestHTTPGetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("cert"))
+	}))
+	defer server.Close()
+	g := &HTTPGetter{Client: server.Client()}
+	if body, err := g.Get(server.URL + "/ok"); err != nil || string(body) != "cert" {
+		t.Errorf("Get() = %q, %v. Want \"cert\", nil", body, err)
+	}
+	var httpErr *HTTPError
+	if _, err := g.Get(server.URL + "/limited"); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Get() = _, %v. Want a 429 HTTPError", err)
+	}
+}
+
+func TestDirCache(t *testing.T) {
+	c := DirCache(t.TempDir())
+	if _, ok := c.Get("key"); ok {
+		t.Error("Get() of an empty cache = _, true. Want false")
+	}
+	c.Put("key", []byte("value"))
+	if data, ok := DirCache(string(c)).Get("key"); !ok || string(data) != "value" {
+		t.Errorf("Get() = %q, %v. Want \"value\", true", data, ok)
+	}
+}
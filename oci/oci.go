@@ -0,0 +1,241 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci measures OCI container images into an RTMR for confidential-container
+// runtimes. An image is measured as a canonical JSON record of its manifest, config, and
+// layer digests, whose SHA-384 digest is extended and whose content is logged so that a
+// verifier can replay the register and learn which images started.
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+// ContentType is the event content type of image records.
+const ContentType = "oci-image"
+
+// Manifest media types that can be measured.
+const (
+	MediaTypeImageManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// Index media types, which must be resolved to a platform's manifest before measuring.
+const (
+	MediaTypeImageIndex         = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// ErrIndex is returned when a manifest is an image index rather than an image manifest.
+var ErrIndex = errors.New("manifest is an image index; measure the platform's image manifest")
+
+// DigestMismatchError is returned when content does not have its expected digest.
+type DigestMismatchError struct {
+	Content string
+	Got     string
+	Want    string
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("%s digest %s does not match %s", e.Content, e.Got, e.Want)
+}
+
+// Image is the record of an image that is measured. Its JSON encoding is canonical: the
+// fields are in a fixed order and the layers in manifest order.
+type Image struct {
+	// Reference is the name the image was pulled by, e.g., "registry.example/app:1.0". It
+	// is informational; the digests identify the image.
+	Reference string `json:"reference,omitempty"`
+	// MediaType is the media type of the manifest.
+	MediaType string `json:"media_type"`
+	// ManifestDigest is the digest of the manifest, e.g., "sha256:…".
+	ManifestDigest string `json:"manifest_digest"`
+	// ConfigDigest is the digest of the image config.
+	ConfigDigest string `json:"config_digest"`
+	// Layers are the digests of the layers, from the base layer up.
+	Layers []string `json:"layers"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// validateDigest returns an error if a digest is not a sha256 or sha512 digest in the
+// canonical lowercase hex encoding.
+func validateDigest(digest string) error {
+	alg, encoded, ok := strings.Cut(digest, ":")
+	if !ok {
+		return fmt.Errorf("digest %q has no algorithm", digest)
+	}
+	var size int
+	switch alg {
+	case "sha256":
+		size = sha256.Size
+	case "sha512":
+		size = sha512.Size
+	default:
+		return fmt.Errorf("digest %q has unsupported algorithm %q", digest, alg)
+	}
+	if len(encoded) != 2*size || strings.ToLower(encoded) != encoded {
+		return fmt.Errorf("digest %q is not %d lowercase hex bytes", digest, size)
+	}
+	if _, err := hex.DecodeString(encoded); err != nil {
+		return fmt.Errorf("digest %q: %w", digest, err)
+	}
+	return nil
+}
+
+// digestOf returns the digest of content with the algorithm of want.
+func digestOf(want string, content []byte) string {
+	if strings.HasPrefix(want, "sha512:") {
+		sum := sha512.Sum512(content)
+		return "sha512:" + hex.EncodeToString(sum[:])
+	}
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ParseManifest returns the record of the image with the manifest, as fetched from the
+// registry. The manifest digest is the SHA-256 digest of its bytes.
+func ParseManifest(reference string, data []byte) (*Image, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %w", err)
+	}
+	switch m.MediaType {
+	case MediaTypeImageIndex, MediaTypeDockerManifestList:
+		return nil, ErrIndex
+	case "":
+		// The mediaType of OCI manifests is optional.
+		if m.Manifests != nil {
+			return nil, ErrIndex
+		}
+		m.MediaType = MediaTypeImageManifest
+	case MediaTypeImageManifest, MediaTypeDockerManifest:
+	default:
+		return nil, fmt.Errorf("unsupported manifest media type %q", m.MediaType)
+	}
+	if m.SchemaVersion != 2 {
+		return nil, fmt.Errorf("manifest schema version %d is not 2", m.SchemaVersion)
+	}
+	if err := validateDigest(m.Config.Digest); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	img := &Image{
+		Reference:      reference,
+		MediaType:      m.MediaType,
+		ManifestDigest: digestOf("sha256:", data),
+		ConfigDigest:   m.Config.Digest,
+		Layers:         make([]string, len(m.Layers)),
+	}
+	for i, layer := range m.Layers {
+		if err := validateDigest(layer.Digest); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+		img.Layers[i] = layer.Digest
+	}
+	return img, nil
+}
+
+// VerifyManifestDigest returns an error if the image's manifest does not have the digest
+// it was pulled by, e.g., the digest in "registry.example/app@sha256:…".
+func (img *Image) VerifyManifestDigest(digest string) error {
+	if digest != img.ManifestDigest {
+		return &DigestMismatchError{Content: "manifest", Got: img.ManifestDigest, Want: digest}
+	}
+	return nil
+}
+
+// VerifyConfig returns an error if config is not the image config that the manifest
+// references.
+func (img *Image) VerifyConfig(config []byte) error {
+	if got := digestOf(img.ConfigDigest, config); got != img.ConfigDigest {
+		return &DigestMismatchError{Content: "config", Got: got, Want: img.ConfigDigest}
+	}
+	return nil
+}
+
+// Record returns the canonical JSON encoding of the image record.
+func (img *Image) Record() ([]byte, error) {
+	return json.Marshal(img)
+}
+
+// Digest returns the SHA-384 digest of the image record that Measure extends.
+func (img *Image) Digest() ([]byte, error) {
+	record, err := img.Record()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha512.Sum384(record)
+	return digest[:], nil
+}
+
+// Measure extends the digest of the image record into the RTMR and logs the record as the
+// event content with ContentType.
+func Measure(client configfsi.Client, log *rtmr.EventLog, index int, img *Image, opts ...rtmr.Option) error {
+	record, err := img.Record()
+	if err != nil {
+		return err
+	}
+	digest := sha512.Sum384(record)
+	return log.ExtendDigest(client, index, digest[:], ContentType, record, opts...)
+}
+
+// ParseEvent returns the image record of a measured event, checking that the event's
+// SHA-384 digest is of the record.
+func ParseEvent(e *rtmr.Event) (*Image, error) {
+	if e.ContentType != ContentType {
+		return nil, fmt.Errorf("event content type %q is not %q", e.ContentType, ContentType)
+	}
+	digest := sha512.Sum384(e.Content)
+	measured := false
+	for _, d := range e.Digests {
+		if d.HashAlg != "sha384" {
+			continue
+		}
+		if !bytes.Equal(d.Digest, digest[:]) {
+			return nil, fmt.Errorf("event %d digest is not of its image record", e.RecNum)
+		}
+		measured = true
+	}
+	if !measured {
+		return nil, fmt.Errorf("event %d has no sha384 digest", e.RecNum)
+	}
+	img := &Image{}
+	if err := json.Unmarshal(e.Content, img); err != nil {
+		return nil, fmt.Errorf("could not parse image record: %w", err)
+	}
+	return img, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+func sha256Digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+const config = `{"architecture":"amd64","os":"linux"}`
+
+func testManifest() string {
+	return fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.manifest.v1+json",
+  "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": %q, "size": %d},
+  "layers": [
+    {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": %q, "size": 1},
+    {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": %q, "size": 1}
+  ]
+}`, sha256Digest(config), len(config), sha256Digest("base"), sha256Digest("app"))
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest := testManifest()
+	img, err := ParseManifest("registry.example/app:1.0", []byte(manifest))
+	if err != nil {
+		t.Fatalf("ParseManifest() = _, %v. Want nil", err)
+	}
+	if len(img.Layers) != 2 || img.Layers[0] != sha256Digest("base") || img.Layers[1] != sha256Digest("app") {
+		t.Errorf("Layers = %v. Want the base and app layers in order", img.Layers)
+	}
+	if err := img.VerifyManifestDigest(sha256Digest(manifest)); err != nil {
+		t.Errorf("VerifyManifestDigest() = %v. Want nil", err)
+	}
+	if err := img.VerifyConfig([]byte(config)); err != nil {
+		t.Errorf("VerifyConfig() = %v. Want nil", err)
+	}
+	var mismatch *DigestMismatchError
+	if err := img.VerifyConfig([]byte("{}")); !errors.As(err, &mismatch) {
+		t.Errorf("VerifyConfig() of another config = %v. Want a DigestMismatchError", err)
+	}
+	want := `{"reference":"registry.example/app:1.0","media_type":"application/vnd.oci.image.manifest.v1+json",` +
+		`"manifest_digest":"` + sha256Digest(manifest) + `","config_digest":"` + sha256Digest(config) + `",` +
+		`"layers":["` + sha256Digest("base") + `","` + sha256Digest("app") + `"]}`
+	if record, err := img.Record(); err != nil || string(record) != want {
+		t.Errorf("Record() = %s, %v. Want %s", record, err, want)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  error
+	}{
+		{"index", `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`, ErrIndex},
+		{"untyped index", `{"schemaVersion":2,"manifests":[]}`, ErrIndex},
+		{"schema 1", `{"schemaVersion":1,"config":{"digest":"` + sha256Digest("") + `"}}`, nil},
+		{"bad config digest", `{"schemaVersion":2,"config":{"digest":"sha256:ABCD"}}`, nil},
+		{"bad layer digest", `{"schemaVersion":2,"config":{"digest":"` + sha256Digest("") + `"},"layers":[{"digest":"md5:00"}]}`, nil},
+		{"not json", `schemaVersion: 2`, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseManifest("", []byte(tc.manifest))
+			if err == nil || (tc.wantErr != nil && !errors.Is(err, tc.wantErr)) {
+				t.Errorf("ParseManifest() = _, %v. Want an error matching %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	img, err := ParseManifest("registry.example/app:1.0", []byte(testManifest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := rtmr.NewEventLog()
+	if err := Measure(client, log, 3, img); err != nil {
+		t.Fatalf("Measure() = %v. Want nil", err)
+	}
+	events := log.Events()
+	results, err := rtmr.Verify(client, events)
+	if err != nil || len(results) != 1 || !results[0].Match {
+		t.Fatalf("Verify() = %v, %v. Want rtmr3 to match", results, err)
+	}
+	got, err := ParseEvent(&events[0])
+	if err != nil {
+		t.Fatalf("ParseEvent() = _, %v. Want nil", err)
+	}
+	if got.ManifestDigest != img.ManifestDigest || len(got.Layers) != len(img.Layers) {
+		t.Errorf("ParseEvent() = %+v. Want %+v", got, img)
+	}
+	events[0].Content = []byte(`{"reference":"other"}`)
+	if _, err := ParseEvent(&events[0]); err == nil {
+		t.Error("ParseEvent() of altered content = _, nil. Want an error")
+	}
+}
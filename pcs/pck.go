@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcs
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/internal/tdx"
+)
+
+var (
+	// oidSgxExtensions is the PCK certificate extension of SGX platform information.
+	oidSgxExtensions = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	// oidFmspc is the FMSPC in oidSgxExtensions.
+	oidFmspc = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
+)
+
+// errNoPck is returned when a quote has no PCK certificate chain.
+var errNoPck = errors.New("quote has no PCK certificate chain; fetch collateral by FMSPC")
+
+// PckInfo is the platform information of a PCK certificate that selects its collateral.
+type PckInfo struct {
+	// Fmspc is the hex FMSPC.
+	Fmspc string
+	// Ca is the issuing CA type, CaPlatform or CaProcessor.
+	Ca string
+}
+
+// quotePckChain returns the PEM PCK certificate chain in a version 4 quote.
+func quotePckChain(quote []byte) ([]byte, error) {
+	if len(quote) < tdx.SignatureDataOffset+2*tdx.SignatureSize {
+		return nil, fmt.Errorf("quote of %d bytes is too short", len(quote))
+	}
+	if version := binary.LittleEndian.Uint16(quote); version != tdx.QuoteVersion {
+		return nil, fmt.Errorf("quote version %d is not %d", version, tdx.QuoteVersion)
+	}
+	typ, data, _, err := tdx.CertData(quote[tdx.SignatureDataOffset+2*tdx.SignatureSize:])
+	if err != nil {
+		return nil, err
+	}
+	if typ == tdx.CertDataQeReport {
+		if typ, data, err = tdx.QeCertData(data); err != nil {
+			return nil, err
+		}
+	}
+	if typ != tdx.CertDataPckCertChain {
+		return nil, fmt.Errorf("certification data type %d is not a PCK certificate chain", typ)
+	}
+	return data, nil
+}
+
+// QuotePckInfo returns the platform information of the PCK certificate in a version 4
+// quote's certification data.
+func QuotePckInfo(quote []byte) (*PckInfo, error) {
+	chain, err := quotePckChain(quote)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(chain)
+	if block == nil {
+		return nil, errNoPck
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PCK certificate: %w", err)
+	}
+	return ParsePckInfo(cert)
+}
+
+// ParsePckInfo returns the platform information of a PCK certificate.
+func ParsePckInfo(cert *x509.Certificate) (*PckInfo, error) {
+	info := &PckInfo{}
+	switch issuer := strings.ToLower(cert.Issuer.CommonName); {
+	case strings.Contains(issuer, "platform"):
+		info.Ca = CaPlatform
+	case strings.Contains(issuer, "processor"):
+		info.Ca = CaProcessor
+	default:
+		return nil, fmt.Errorf("PCK issuer %q is not a platform or processor CA", cert.Issuer.CommonName)
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSgxExtensions) {
+			continue
+		}
+		var entries []struct {
+			ID    asn1.ObjectIdentifier
+			Value asn1.RawValue
+		}
+		if _, err := asn1.Unmarshal(ext.Value, &entries); err != nil {
+			return nil, fmt.Errorf("could not parse SGX extensions: %w", err)
+		}
+		for _, e := range entries {
+			if e.ID.Equal(oidFmspc) {
+				info.Fmspc = hex.EncodeToString(e.Value.Bytes)
+				return info, nil
+			}
+		}
+	}
+	return nil, errors.New("PCK certificate has no FMSPC")
+}
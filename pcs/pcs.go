@@ -0,0 +1,199 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pcs retrieves the collateral that appraising a TDX quote requires, the TCB info,
+// QE identity, and PCK CRLs, from the Intel Provisioning Certification Service or a PCCS
+// caching service that mirrors it, e.g., in an air-gapped network.
+package pcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-configfs-tsm/internal/httputil"
+)
+
+// Endpoints locates a collateral service. Intel PCS and PCCS serve the same paths under
+// their base URLs.
+type Endpoints struct {
+	// BaseURL is the URL under which "/tdx/certification/v4" and "/sgx/certification/v4"
+	// are served, e.g., "https://pccs.example.com:8081".
+	BaseURL string
+	// RootCaCrlURL is the URL of the DER CRL of the Intel SGX Root CA. If empty, the root
+	// CA CRL is not fetched.
+	RootCaCrlURL string
+}
+
+// IntelPCS is the Intel Provisioning Certification Service.
+var IntelPCS = Endpoints{
+	BaseURL:      "https://api.trustedservices.intel.com",
+	RootCaCrlURL: "https://certificates.trustedservices.intel.com/IntelSGXRootCA.der",
+}
+
+// PCK CA types, which name the CA that issues a platform's PCK certificate.
+const (
+	CaPlatform  = "platform"
+	CaProcessor = "processor"
+)
+
+// Response headers that hold the URL-encoded PEM issuer chains of collateral.
+const (
+	headerTcbInfoIssuerChain    = "TCB-Info-Issuer-Chain"
+	headerQeIdentityIssuerChain = "SGX-Enclave-Identity-Issuer-Chain"
+	headerPckCrlIssuerChain     = "SGX-PCK-CRL-Issuer-Chain"
+)
+
+// HTTPError is returned when the service responds with a status other than 200 OK.
+type HTTPError = httputil.StatusError
+
+// Collateral is the collateral for appraising quotes of platforms with one FMSPC and PCK CA.
+type Collateral struct {
+	// Fmspc is the hex FMSPC of the platform.
+	Fmspc string
+	// Ca is the PCK CA type, CaPlatform or CaProcessor.
+	Ca string
+	// TcbInfo is the signed TDX TCB info JSON and TcbInfoIssuerChain its PEM issuer chain.
+	TcbInfo            []byte
+	TcbInfoIssuerChain []byte
+	// QeIdentity is the signed TD QE identity JSON and QeIdentityIssuerChain its PEM issuer
+	// chain.
+	QeIdentity            []byte
+	QeIdentityIssuerChain []byte
+	// PckCrl is the DER CRL of the PCK CA and PckCrlIssuerChain its PEM issuer chain.
+	PckCrl            []byte
+	PckCrlIssuerChain []byte
+	// RootCaCrl is the DER CRL of the Intel SGX Root CA, if fetched.
+	RootCaCrl []byte
+	// NextUpdate is the earliest nextUpdate of the TCB info and QE identity, after which the
+	// collateral should be fetched again.
+	NextUpdate time.Time
+}
+
+// Fetcher fetches collateral.
+type Fetcher struct {
+	// Endpoints locates the service. Zero means IntelPCS.
+	Endpoints Endpoints
+	// Client is the HTTP client to use. Nil means http.DefaultClient.
+	Client *http.Client
+}
+
+func (f *Fetcher) endpoints() Endpoints {
+	if f.Endpoints == (Endpoints{}) {
+		return IntelPCS
+	}
+	return f.Endpoints
+}
+
+// get returns the body and unescaped issuer chain header of a 200 OK response to the URL.
+func (f *Fetcher) get(u, chainHeader string) (body, chain []byte, err error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &HTTPError{URL: u, StatusCode: resp.StatusCode}
+	}
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return nil, nil, err
+	}
+	if chainHeader == "" {
+		return body, nil, nil
+	}
+	escaped := resp.Header.Get(chainHeader)
+	if escaped == "" {
+		return nil, nil, fmt.Errorf("GET %s: missing %s header", u, chainHeader)
+	}
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GET %s: %s header: %w", u, chainHeader, err)
+	}
+	return body, []byte(unescaped), nil
+}
+
+// nextUpdate returns the nextUpdate of the signed tcbInfo or enclaveIdentity JSON.
+func nextUpdate(signed []byte, field string) (time.Time, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(signed, &body); err != nil {
+		return time.Time{}, err
+	}
+	var info struct {
+		NextUpdate time.Time `json:"nextUpdate"`
+	}
+	raw, ok := body[field]
+	if !ok {
+		return time.Time{}, fmt.Errorf("missing %q", field)
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return info.NextUpdate, nil
+}
+
+// Fetch returns the collateral for platforms with the FMSPC and PCK CA type.
+func (f *Fetcher) Fetch(fmspc, ca string) (*Collateral, error) {
+	if ca != CaPlatform && ca != CaProcessor {
+		return nil, fmt.Errorf("PCK CA type %q is not %q or %q", ca, CaPlatform, CaProcessor)
+	}
+	ep := f.endpoints()
+	base := strings.TrimRight(ep.BaseURL, "/")
+	c := &Collateral{Fmspc: fmspc, Ca: ca}
+	var err error
+	if c.TcbInfo, c.TcbInfoIssuerChain, err = f.get(base+"/tdx/certification/v4/tcb?fmspc="+url.QueryEscape(fmspc), headerTcbInfoIssuerChain); err != nil {
+		return nil, fmt.Errorf("could not fetch TCB info: %w", err)
+	}
+	if c.QeIdentity, c.QeIdentityIssuerChain, err = f.get(base+"/tdx/certification/v4/qe/identity", headerQeIdentityIssuerChain); err != nil {
+		return nil, fmt.Errorf("could not fetch QE identity: %w", err)
+	}
+	if c.PckCrl, c.PckCrlIssuerChain, err = f.get(base+"/sgx/certification/v4/pckcrl?ca="+ca+"&encoding=der", headerPckCrlIssuerChain); err != nil {
+		return nil, fmt.Errorf("could not fetch PCK CRL: %w", err)
+	}
+	if ep.RootCaCrlURL != "" {
+		if c.RootCaCrl, _, err = f.get(ep.RootCaCrlURL, ""); err != nil {
+			return nil, fmt.Errorf("could not fetch root CA CRL: %w", err)
+		}
+	}
+	tcbNext, err := nextUpdate(c.TcbInfo, "tcbInfo")
+	if err != nil {
+		return nil, fmt.Errorf("TCB info: %w", err)
+	}
+	qeNext, err := nextUpdate(c.QeIdentity, "enclaveIdentity")
+	if err != nil {
+		return nil, fmt.Errorf("QE identity: %w", err)
+	}
+	c.NextUpdate = tcbNext
+	if qeNext.Before(tcbNext) {
+		c.NextUpdate = qeNext
+	}
+	return c, nil
+}
+
+// FetchForQuote returns the collateral for the platform whose PCK certificate is in the
+// quote's certification data, e.g., a tdx_guest report outblob.
+func (f *Fetcher) FetchForQuote(quote []byte) (*Collateral, error) {
+	info, err := QuotePckInfo(quote)
+	if err != nil {
+		return nil, err
+	}
+	return f.Fetch(info.Fmspc, info.Ca)
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+const testChain = "-----BEGIN CERTIFICATE-----\nissuer+chain/==\n-----END CERTIFICATE-----\n"
+
+// pckCert returns a PEM PCK certificate issued by a CA named issuer with the FMSPC.
+func pckCert(t *testing.T, issuer string, fmspc []byte) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sgx, err := asn1.Marshal([]struct {
+		ID    asn1.ObjectIdentifier
+		Value any
+	}{
+		{ID: asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 1}, Value: make([]byte, 16)},
+		{ID: oidFmspc, Value: fmspc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: issuer},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: oidSgxExtensions, Value: sgx}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func tdxQuote(t *testing.T, chain []byte) []byte {
+	t.Helper()
+	resp, err := report.Get(faketsm.ReportTdx(&faketsm.TdxOptions{PckCertChain: chain}), &report.Request{InBlob: make([]byte, 64)})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	return resp.OutBlob
+}
+
+func TestQuotePckInfo(t *testing.T) {
+	quote := tdxQuote(t, pckCert(t, "Intel SGX PCK Processor CA", []byte{0x00, 0x80, 0x6f, 0x05, 0x00, 0x00}))
+	info, err := QuotePckInfo(quote)
+	if err != nil {
+		t.Fatalf("QuotePckInfo() = _, %v. Want nil", err)
+	}
+	if info.Fmspc != "00806f050000" || info.Ca != CaProcessor {
+		t.Errorf("QuotePckInfo() = %+v. Want FMSPC 00806f050000 from the processor CA", info)
+	}
+	if _, err := QuotePckInfo(tdxQuote(t, nil)); !errors.Is(err, errNoPck) {
+		t.Errorf("QuotePckInfo() without a PCK chain = _, %v. Want %v", err, errNoPck)
+	}
+	if _, err := QuotePckInfo(quote[:100]); err == nil {
+		t.Error("QuotePckInfo() of a truncated quote = _, nil. Want an error")
+	}
+}
+
+// collateralServer serves collateral like a PCCS and counts the requests by path.
+func collateralServer(t *testing.T, requests map[string]int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		chain := url.PathEscape(testChain)
+		switch r.URL.Path {
+		case "/tdx/certification/v4/tcb":
+			if r.URL.Query().Get("fmspc") != "00806f050000" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set(headerTcbInfoIssuerChain, chain)
+			w.Write([]byte(`{"tcbInfo":{"id":"TDX","nextUpdate":"2030-01-02T00:00:00Z"},"signature":"00"}`))
+		case "/tdx/certification/v4/qe/identity":
+			w.Header().Set(headerQeIdentityIssuerChain, chain)
+			w.Write([]byte(`{"enclaveIdentity":{"id":"TD_QE","nextUpdate":"2030-01-01T00:00:00Z"},"signature":"00"}`))
+		case "/sgx/certification/v4/pckcrl":
+			if r.URL.Query().Get("ca") != CaPlatform || r.URL.Query().Get("encoding") != "der" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set(headerPckCrlIssuerChain, chain)
+			w.Write([]byte("pck crl"))
+		case "/root.der":
+			w.Write([]byte("root crl"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestFetchForQuote(t *testing.T) {
+	requests := make(map[string]int)
+	server := collateralServer(t, requests)
+	defer server.Close()
+	f := &Fetcher{
+		Endpoints: Endpoints{BaseURL: server.URL + "/", RootCaCrlURL: server.URL + "/root.der"},
+		Client:    server.Client(),
+	}
+	c, err := f.FetchForQuote(tdxQuote(t, pckCert(t, "Intel SGX PCK Platform CA", []byte{0x00, 0x80, 0x6f, 0x05, 0x00, 0x00})))
+	if err != nil {
+		t.Fatalf("FetchForQuote() = _, %v. Want nil", err)
+	}
+	if c.Fmspc != "00806f050000" || c.Ca != CaPlatform {
+		t.Errorf("FetchForQuote() = FMSPC %q, CA %q. Want 00806f050000, platform", c.Fmspc, c.Ca)
+	}
+	for name, chain := range map[string][]byte{"TCB info": c.TcbInfoIssuerChain, "QE identity": c.QeIdentityIssuerChain, "PCK CRL": c.PckCrlIssuerChain} {
+		if string(chain) != testChain {
+			t.Errorf("%s issuer chain = %q. Want %q", name, chain, testChain)
+		}
+	}
+	if string(c.PckCrl) != "pck crl" || string(c.RootCaCrl) != "root crl" {
+		t.Errorf("FetchForQuote() CRLs = %q, %q. Want the served CRLs", c.PckCrl, c.RootCaCrl)
+	}
+	if want := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC); !c.NextUpdate.Equal(want) {
+		t.Errorf("NextUpdate = %v. Want %v", c.NextUpdate, want)
+	}
+	if len(requests) != 4 {
+		t.Errorf("FetchForQuote() requested %v. Want each endpoint once", requests)
+	}
+
+	var httpErr *HTTPError
+	if _, err := f.Fetch("ffffffffffff", CaPlatform); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Fetch() of an unknown FMSPC = _, %v. Want a 404 HTTPError", err)
+	}
+	if _, err := f.Fetch("00806f050000", "other"); err == nil {
+		t.Error("Fetch() with an unknown CA type = _, nil. Want an error")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+)
+
+// Attribute describes a file in a report entry as exposed by the running kernel.
+type Attribute struct {
+	Name     string
+	Readable bool
+	Writable bool
+}
+
+// Attributes returns the attributes of the report entry sorted by name, classified by
+// their file permissions.
+func (r *OpenReport) Attributes() ([]Attribute, error) {
+	if r.entry == nil {
+		return nil, fmt.Errorf("report entry is destroyed")
+	}
+	entries, err := r.client.ReadDir(r.entry.String())
+	if err != nil {
+		return nil, fmt.Errorf("could not list report attributes: %w", err)
+	}
+	var result []Attribute
+	for _, d := range entries {
+		if d.IsDir() {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil, fmt.Errorf("could not get report attribute %q info: %w", d.Name(), err)
+		}
+		perm := info.Mode().Perm()
+		result = append(result, Attribute{
+			Name:     d.Name(),
+			Readable: perm&0444 != 0,
+			Writable: perm&0222 != 0,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"go.uber.org/multierr"
+)
+
+// defaultBatchWorkers is the number of concurrent reports GetMany requests when the
+// caller does not specify a limit.
+const defaultBatchWorkers = 4
+
+// BatchOptions configures GetMany.
+type BatchOptions struct {
+	// Workers is the maximum number of reports requested concurrently. Non-positive values
+	// use a small default.
+	Workers int
+	// Options are applied to every request in the batch.
+	Options []Option
+}
+
+// BatchError is returned by GetMany when at least one request failed.
+type BatchError struct {
+	// Errs has one entry per request given to GetMany. Successful requests have a nil error.
+	Errs []error
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *BatchError) Error() string {
+	var msgs []string
+	for i, err := range e.Errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("request %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d report requests failed: %s",
+		len(msgs), len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Is returns whether any per-request error matches target.
+func (e *BatchError) Is(target error) bool {
+	return errors.Is(multierr.Combine(e.Errs...), target)
+}
+
+// As finds the first per-request error that matches target, and if so, sets target to
+// that error value and returns true.
+func (e *BatchError) As(target any) bool {
+	return errors.As(multierr.Combine(e.Errs...), target)
+}
+
+// GetMany returns one-shot reports for each request, requesting at most opts.Workers
+// reports concurrently. The responses are in the same order as reqs, with nil for failed
+// requests. If any request fails, the error is a *BatchError.
+func GetMany(client configfsi.Client, reqs []*Request, opts *BatchOptions) ([]*Response, error) {
+	workers := defaultBatchWorkers
+	var reqOpts []Option
+	if opts != nil {
+		if opts.Workers > 0 {
+			workers = opts.Workers
+		}
+		reqOpts = opts.Options
+	}
+	responses := make([]*Response, len(reqs))
+	errs := make([]error, len(reqs))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(reqs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				responses[i], errs[i] = Get(client, reqs[i], reqOpts...)
+			}
+		}()
+	}
+	for i := range reqs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return responses, &BatchError{Errs: errs}
+		}
+	}
+	return responses, nil
+}
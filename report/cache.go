@@ -0,0 +1,139 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Cache returns previously fetched reports for identical requests until they are older
+// than a time-to-live. It is meant for callers that repeatedly attest the same state, and
+// should not be used when every report must be fresh. Failed requests are not cached.
+// Expired reports are dropped whenever a new report is cached, and the cache holds at
+// most maxEntries reports, evicting the one closest to expiry to make room.
+type Cache struct {
+	client     configfsi.Client
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	response *Response
+	expires  time.Time
+}
+
+// NewCache returns a Cache that fetches reports from client and keeps up to maxEntries of
+// them for ttl. A maxEntries of zero or less means DefaultCacheEntries.
+func NewCache(client configfsi.Client, ttl time.Duration, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheEntries
+	}
+	return &Cache{
+		client:     client,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*cacheEntry),
+	}
+}
+
+// DefaultCacheEntries is the number of reports a Cache holds when NewCache is not given a
+// bound.
+const DefaultCacheEntries = 64
+
+// cacheKey returns a string that uniquely identifies the report a request produces.
+func cacheKey(req *Request) string {
+	privilege := "-"
+	if req.Privilege != nil {
+		privilege = fmt.Sprintf("%d", req.Privilege.Level)
+	}
+	return fmt.Sprintf("%s|%s|%t|%q|%q|%q|%q|%d",
+		hex.EncodeToString(req.InBlob), privilege, req.GetAuxBlob, req.ServiceProvider,
+		req.ServiceGuid, req.ServiceManifestVersion, req.ExpectedProvider, req.FloorPolicy)
+}
+
+func cloneResponse(resp *Response) *Response {
+	result := *resp
+	result.OutBlob = bytes.Clone(resp.OutBlob)
+	result.AuxBlob = bytes.Clone(resp.AuxBlob)
+	result.ManifestBlob = bytes.Clone(resp.ManifestBlob)
+	if resp.Privilege != nil {
+		p := *resp.Privilege
+		result.Privilege = &p
+	}
+	return &result
+}
+
+// Get returns a cached report for the request and options if one has not expired, and
+// otherwise fetches and caches a new report.
+func (c *Cache) Get(req *Request, opts ...Option) (*Response, error) {
+	req = applyOptions(req, opts)
+	key := cacheKey(req)
+	now := c.now()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if now.Before(e.expires) {
+			c.mu.Unlock()
+			return cloneResponse(e.response), nil
+		}
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	resp, err := Get(c.client, req)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.put(key, &cacheEntry{response: cloneResponse(resp), expires: now.Add(c.ttl)}, now)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// put stores e under key after dropping expired entries and, if the cache is still full,
+// the entry that expires soonest. c.mu must be held.
+func (c *Cache) put(key string, e *cacheEntry, now time.Time) {
+	for k, old := range c.entries {
+		if !now.Before(old.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for k, old := range c.entries {
+			if oldest == "" || old.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = e
+}
+
+// Purge removes all cached reports.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.entries = make(map[string]*cacheEntry)
+	c.mu.Unlock()
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"go.uber.org/multierr"
+)
+
+// CapabilityReport describes the report attributes that the running kernel supports.
+type CapabilityReport struct {
+	// Provider is the provider attribute value without a trailing newline.
+	Provider string
+	// Attributes are all attributes of a report entry.
+	Attributes []Attribute
+	// Privilege is true if privlevel and privlevel_floor are supported.
+	Privilege bool
+	// AuxBlob is true if auxblob is supported.
+	AuxBlob bool
+	// Service is true if the service_provider, service_guid, service_manifest_version,
+	// and manifestblob attributes added in Linux 6.11 are supported.
+	Service bool
+}
+
+// Has returns whether the report entry has an attribute with the given name.
+func (c *CapabilityReport) Has(name string) bool {
+	for _, a := range c.Attributes {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the capabilities of the client's report subsystem. It creates and
+// removes a temporary report entry but does not request a report.
+func Capabilities(client configfsi.Client) (*CapabilityReport, error) {
+	r, err := CreateOpenReport(client)
+	if err != nil {
+		return nil, err
+	}
+	caps, err := r.capabilities()
+	return caps, multierr.Combine(r.Destroy(), err)
+}
+
+func (r *OpenReport) capabilities() (*CapabilityReport, error) {
+	attrs, err := r.Attributes()
+	if err != nil {
+		return nil, err
+	}
+	caps := &CapabilityReport{Attributes: attrs}
+	caps.Privilege = caps.Has("privlevel") && caps.Has("privlevel_floor")
+	caps.AuxBlob = caps.Has("auxblob")
+	caps.Service = true
+	for _, a := range configfsi.Schema(subsystem).AttributesAt(configfsi.Revision611) {
+		if a.Since == configfsi.Revision611 && !caps.Has(a.Name) {
+			caps.Service = false
+		}
+	}
+	provider, err := r.ReadOption("provider")
+	if err != nil {
+		return nil, err
+	}
+	caps.Provider = strings.TrimRight(string(provider), "\n")
+	return caps, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Metrics receives notifications of report operations so callers can export them to their
+// metrics system. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveGet is called when a one-shot Get completes with its total latency, the number
+	// of retries made, and the final error.
+	ObserveGet(latency time.Duration, retries int, err error)
+	// EntryCreated is called when a report entry is created.
+	EntryCreated()
+	// EntryDestroyed is called when a report entry is removed.
+	EntryDestroyed()
+}
+
+// Error classes returned by ErrorClass.
+const (
+	ErrorClassNone       = ""
+	ErrorClassGeneration = "generation"
+	ErrorClassProvider   = "provider"
+	ErrorClassPrivilege  = "privilege"
+	ErrorClassBusy       = "busy"
+	ErrorClassPermission = "permission"
+	ErrorClassInvalid    = "invalid"
+	ErrorClassOther      = "other"
+)
+
+// ErrorClass returns a short, low-cardinality label for err suitable for use as a metric
+// dimension.
+func ErrorClass(err error) string {
+	var providerErr *ProviderErr
+	var privilegeErr *PrivilegeErr
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case GetGenerationErr(err) != nil:
+		return ErrorClassGeneration
+	case errors.As(err, &providerErr):
+		return ErrorClassProvider
+	case errors.As(err, &privilegeErr):
+		return ErrorClassPrivilege
+	case configfsi.IsBusy(err), configfsi.IsWouldBlock(err):
+		return ErrorClassBusy
+	case errors.Is(err, os.ErrPermission):
+		return ErrorClassPermission
+	case errors.Is(err, syscall.EINVAL):
+		return ErrorClassInvalid
+	}
+	return ErrorClassOther
+}
+
+// WithMetrics sets the Metrics that is notified of the request's report operations.
+func WithMetrics(m Metrics) Option {
+	return func(r *Request) {
+		r.Metrics = m
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Option modifies a Request before it is used to create or get a report. Options let
+// callers request newer kernel attributes without depending on every Request field.
+type Option func(*Request)
+
+// NewRequest returns a Request for the given inblob with all options applied.
+func NewRequest(inblob []byte, opts ...Option) *Request {
+	return applyOptions(&Request{InBlob: inblob}, opts)
+}
+
+// applyOptions returns a copy of req with opts applied. The original request is not
+// modified. A nil req is treated as an empty request.
+func applyOptions(req *Request, opts []Option) *Request {
+	result := &Request{}
+	if req != nil {
+		*result = *req
+	}
+	for _, opt := range opts {
+		opt(result)
+	}
+	return result
+}
+
+// WithPrivilege sets the privilege level at which the report should be created.
+func WithPrivilege(level uint) Option {
+	return func(r *Request) {
+		r.Privilege = &Privilege{Level: level}
+	}
+}
+
+// WithAuxBlob requests that the auxblob attribute is read into the Response.
+func WithAuxBlob() Option {
+	return func(r *Request) {
+		r.GetAuxBlob = true
+	}
+}
+
+// WithManifest requests a service manifest from the given service provider. The guid
+// and version may be empty to use the provider's defaults.
+func WithManifest(serviceProvider, serviceGuid, manifestVersion string) Option {
+	return func(r *Request) {
+		r.ServiceProvider = serviceProvider
+		r.ServiceGuid = serviceGuid
+		r.ServiceManifestVersion = manifestVersion
+	}
+}
+
+// WithRetry sets the number of additional attempts Get makes with a fresh entry when a
+// report fails due to interference or a busy host.
+func WithRetry(retries int) Option {
+	return func(r *Request) {
+		r.Retries = retries
+	}
+}
+
+// WithProviderExpectation requires the report to come from the given provider, e.g.,
+// "sev_guest" or "tdx_guest".
+func WithProviderExpectation(provider string) Option {
+	return func(r *Request) {
+		r.ExpectedProvider = provider
+	}
+}
+
+// WithFloorPolicy sets how a requested privilege level below privlevel_floor is handled.
+func WithFloorPolicy(policy FloorPolicy) Option {
+	return func(r *Request) {
+		r.FloorPolicy = policy
+	}
+}
+
+// WithLogger sets the logger that receives debug logs of the request's report operations.
+func WithLogger(logger configfsi.Logger) Option {
+	return func(r *Request) {
+		r.Logger = logger
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"go.uber.org/multierr"
+)
+
+// Pool reuses configfs report entries across Get calls to avoid the cost of creating and
+// removing an entry for every report. Each pooled entry is used by at most one Get at a time.
+type Pool struct {
+	client  configfsi.Client
+	maxIdle int
+	mu      sync.Mutex
+	idle    []*OpenReport
+	closed  bool
+}
+
+// NewPool returns a Pool of report entries for client that keeps at most maxIdle unused
+// entries alive between calls.
+func NewPool(client configfsi.Client, maxIdle int) *Pool {
+	return &Pool{client: client, maxIdle: maxIdle}
+}
+
+// acquire returns an idle entry, or a new entry and true.
+func (p *Pool) acquire() (*OpenReport, bool, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, false, fmt.Errorf("report pool is closed")
+	}
+	var r *OpenReport
+	if n := len(p.idle); n > 0 {
+		r = p.idle[n-1]
+		p.idle = p.idle[:n-1]
+	}
+	p.mu.Unlock()
+	if r == nil {
+		r, err := CreateOpenReport(p.client)
+		return r, err == nil, err
+	}
+	return r, false, nil
+}
+
+// release returns r to the pool, or destroys it if it cannot be safely reused.
+func (p *Pool) release(r *OpenReport, reusable bool) error {
+	// Service attributes cannot be cleared once written, so such entries are not reused.
+	if reusable && !r.serviceWritten {
+		p.mu.Lock()
+		if !p.closed && len(p.idle) < p.maxIdle {
+			p.idle = append(p.idle, r)
+			p.mu.Unlock()
+			return nil
+		}
+		p.mu.Unlock()
+	}
+	return r.Destroy()
+}
+
+// Get returns a report for the request and options using a pooled entry. Entries that
+// observed an error are destroyed rather than reused.
+func (p *Pool) Get(req *Request, opts ...Option) (*Response, error) {
+	req = applyOptions(req, opts)
+	return getWithPolicy(req, func() (*Response, error) { return p.getOnce(req) })
+}
+
+func (p *Pool) getOnce(req *Request) (*Response, error) {
+	r, created, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		err := r.Reset(req)
+		if GetGenerationErr(err) != nil {
+			// Another process wrote to the idle entry, so replace it.
+			if err := p.release(r, false); err != nil {
+				return nil, err
+			}
+			if r, err = CreateOpenReport(p.client); err != nil {
+				return nil, err
+			}
+			created = true
+		} else if err != nil {
+			return nil, multierr.Combine(p.release(r, false), err)
+		}
+	}
+	if created {
+		r.setRequest(req)
+		if r.metrics != nil {
+			r.metrics.EntryCreated()
+		}
+	}
+	response, err := r.Get()
+	return response, multierr.Combine(p.release(r, err == nil), err)
+}
+
+// Close destroys all idle entries. Entries in use by an ongoing Get are destroyed when
+// that Get completes.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+	var err error
+	for _, r := range idle {
+		err = multierr.Append(err, r.Destroy())
+	}
+	return err
+}
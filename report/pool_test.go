@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+)
+
+func TestPoolReusesEntries(t *testing.T) {
+	sub := faketsm.ReportV7(0)
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": sub}}
+	p := NewPool(c, 1)
+	tcs := []struct {
+		req     *Request
+		wantOut string
+	}{
+		{
+			req:     &Request{InBlob: []byte("a"), Privilege: &Privilege{Level: 3}},
+			wantOut: "privlevel: 3\ninblob: 61",
+		},
+		{
+			req:     &Request{InBlob: []byte("b")},
+			wantOut: "privlevel: 0\ninblob: 62",
+		},
+		{
+			req:     &Request{InBlob: []byte("c"), Privilege: &Privilege{Level: 1}},
+			wantOut: "privlevel: 1\ninblob: 63",
+		},
+	}
+	for _, tc := range tcs {
+		resp, err := p.Get(tc.req)
+		if err != nil {
+			t.Fatalf("Get(%+v) = %+v, %v, want nil", tc.req, resp, err)
+		}
+		if !bytes.Equal(resp.OutBlob, []byte(tc.wantOut)) {
+			t.Errorf("OutBlob %q is not %q", resp.OutBlob, tc.wantOut)
+		}
+		if len(sub.Entries) != 1 {
+			t.Errorf("got %d report entries, want 1", len(sub.Entries))
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+	if len(sub.Entries) != 0 {
+		t.Errorf("got %d report entries after Close, want 0", len(sub.Entries))
+	}
+}
+
+func TestPoolReplacesTamperedEntries(t *testing.T) {
+	sub := faketsm.ReportV7(0)
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": sub}}
+	p := NewPool(c, 1)
+	defer p.Close()
+	if _, err := p.Get(&Request{InBlob: []byte("a")}); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	var tampered string
+	for name := range sub.Entries {
+		tampered = name
+	}
+	if err := c.WriteFile(path.Join(configfsi.TsmPrefix, "report", tampered, "inblob"), []byte("other")); err != nil {
+		t.Fatalf("interfering write failed: %v", err)
+	}
+	resp, err := p.Get(&Request{InBlob: []byte("b")})
+	if err != nil {
+		t.Fatalf("Get() after interference = _, %v, want nil", err)
+	}
+	if want := "privlevel: 0\ninblob: 62"; string(resp.OutBlob) != want {
+		t.Errorf("OutBlob = %q, want %q", resp.OutBlob, want)
+	}
+	if _, ok := sub.Entries[tampered]; ok || len(sub.Entries) != 1 {
+		t.Errorf("report entries = %v, want one entry other than the tampered %q", sub.Entries, tampered)
+	}
+}
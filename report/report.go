@@ -19,6 +19,8 @@ package report
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
 	"go.uber.org/multierr"
@@ -36,6 +38,19 @@ type Privilege struct {
 	Level uint
 }
 
+// FloorPolicy determines how a requested privilege level below the privlevel_floor
+// attribute is handled.
+type FloorPolicy int
+
+const (
+	// FloorKernel writes the requested privilege level as is and lets the kernel reject it.
+	FloorKernel FloorPolicy = iota
+	// FloorClamp raises the requested privilege level to privlevel_floor.
+	FloorClamp
+	// FloorError returns a *PrivilegeErr without writing the privilege level.
+	FloorError
+)
+
 // Request represents an open request for an attestation report.
 type Request struct {
 	InBlob                 []byte
@@ -44,6 +59,25 @@ type Request struct {
 	ServiceProvider        string
 	ServiceGuid            string
 	ServiceManifestVersion string
+	// ExpectedProvider, if non-empty, is the provider attribute value (without trailing
+	// newline) that the report must come from.
+	ExpectedProvider string
+	// Retries is the number of additional attempts Get makes when a report fails due to
+	// interference or a busy host.
+	Retries int
+	// FloorPolicy determines how a Privilege below privlevel_floor is handled.
+	FloorPolicy FloorPolicy
+	// Metrics, if non-nil, is notified of report operations.
+	Metrics Metrics `json:"-"`
+	// Logger, if non-nil, receives debug logs of entry names, attribute writes, and
+	// generation changes.
+	Logger configfsi.Logger `json:"-"`
+	// Timeout, if positive, bounds how long Get waits for a report. The report entry is
+	// still removed once the abandoned attempt completes.
+	Timeout time.Duration
+	// startSpan, if non-nil, starts the span of Get and returns a client whose operations
+	// are traced as its children. WithTracer sets it.
+	startSpan func(client configfsi.Client, name string, attrs ...any) (configfsi.Client, configfsi.Span)
 }
 
 // OpenReport represents a created tsm report subtree with internal expectations for the generation.
@@ -54,9 +88,18 @@ type OpenReport struct {
 	ServiceProvider        string
 	ServiceGuid            string
 	ServiceManifestVersion string
+	ExpectedProvider       string
+	FloorPolicy            FloorPolicy
 	entry                  *configfsi.TsmPath
 	expectedGeneration     uint64
 	client                 configfsi.Client
+	// privilegeWritten and serviceWritten record whether Get has changed the entry's
+	// privlevel or service attributes away from their initial values.
+	privilegeWritten bool
+	serviceWritten   bool
+	metrics          Metrics
+	logger           configfsi.Logger
+	created          time.Time
 }
 
 // Response represents a common case response for getting at attestation report to avoid
@@ -66,6 +109,19 @@ type Response struct {
 	OutBlob      []byte
 	AuxBlob      []byte
 	ManifestBlob []byte
+	// Privilege is the privilege level written for the report, or nil if none was written.
+	Privilege *Privilege
+	// PrivilegeClamped is true if the requested privilege level was raised to
+	// privlevel_floor.
+	PrivilegeClamped bool
+	// Entry is the name of the report entry the report was read from.
+	Entry string
+	// Generation is the entry's generation when the report was read.
+	Generation uint64
+	// Retries is the number of failed attempts before the report was read.
+	Retries int
+	// Duration is the wall-clock time taken to get the report, including retries.
+	Duration time.Duration
 }
 
 // GenerationErr is returned when an attribute's value is invalid due to mismatched expectations
@@ -74,12 +130,45 @@ type GenerationErr struct {
 	Got       uint64
 	Want      uint64
 	Attribute string
+	// Entry is the name of the report entry that observed the interference.
+	Entry string
+	// Age is the time between the entry's creation (or wrapping) and the interference
+	// being detected.
+	Age time.Duration
 }
 
 // Error returns the human-readable explanation for the error.
 func (e *GenerationErr) Error() string {
-	return fmt.Sprintf("report generation was %d when expecting %d while reading property %q",
+	msg := fmt.Sprintf("report generation was %d when expecting %d while reading property %q",
 		e.Got, e.Want, e.Attribute)
+	if e.Entry != "" {
+		msg += fmt.Sprintf(" of entry %q (%v after creation)", e.Entry, e.Age)
+	}
+	return msg
+}
+
+// PrivilegeErr is returned when the requested privilege level is below the
+// privlevel_floor attribute and the request's FloorPolicy is FloorError.
+type PrivilegeErr struct {
+	Requested uint
+	Floor     uint
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *PrivilegeErr) Error() string {
+	return fmt.Sprintf("requested privlevel %d is below privlevel_floor %d", e.Requested, e.Floor)
+}
+
+// ProviderErr is returned when a report's provider does not match the request's
+// expected provider.
+type ProviderErr struct {
+	Got  string
+	Want string
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *ProviderErr) Error() string {
+	return fmt.Sprintf("report provider is %q, want %q", e.Got, e.Want)
 }
 
 // GetGenerationErr returns the GenerationErr contained in an error with 0 or 1 wraps.
@@ -100,7 +189,7 @@ func (r *OpenReport) attribute(subtree string) string {
 func readUint64File(client configfsi.Client, p string) (uint64, error) {
 	data, err := client.ReadFile(p)
 	if err != nil {
-		return 0, fmt.Errorf("could not read %q: %v", p, err)
+		return 0, fmt.Errorf("could not read %q: %w", p, err)
 	}
 	return configfsi.Kstrtouint(data, numberAttributeBase, 64)
 }
@@ -110,7 +199,7 @@ func readUint64File(client configfsi.Client, p string) (uint64, error) {
 func CreateOpenReport(client configfsi.Client) (*OpenReport, error) {
 	entry, err := client.MkdirTemp(subsystemPath, "entry")
 	if err != nil {
-		return nil, fmt.Errorf("could not create report entry in configfs: %v", err)
+		return nil, fmt.Errorf("could not create report entry in configfs: %w", err)
 	}
 	return UnsafeWrap(client, entry)
 }
@@ -119,8 +208,9 @@ func CreateOpenReport(client configfsi.Client) (*OpenReport, error) {
 func UnsafeWrap(client configfsi.Client, entryPath string) (r *OpenReport, err error) {
 	p, _ := configfsi.ParseTsmPath(entryPath)
 	r = &OpenReport{
-		client: client,
-		entry:  &configfsi.TsmPath{Subsystem: subsystem, Entry: p.Entry},
+		client:  client,
+		entry:   &configfsi.TsmPath{Subsystem: subsystem, Entry: p.Entry},
+		created: time.Now(),
 	}
 	r.expectedGeneration, err = readUint64File(client, r.attribute("generation"))
 	if err != nil {
@@ -131,19 +221,38 @@ func UnsafeWrap(client configfsi.Client, entryPath string) (r *OpenReport, err e
 }
 
 // Create returns a newly-created entry in the configfs-tsm report subtree with common inputs
-// for the Get() method initialized from the request.
-func Create(client configfsi.Client, req *Request) (*OpenReport, error) {
+// for the Get() method initialized from the request and options.
+func Create(client configfsi.Client, req *Request, opts ...Option) (*OpenReport, error) {
+	req = applyOptions(req, opts)
 	r, err := CreateOpenReport(client)
 	if err != nil {
 		return nil, err
 	}
+	r.setRequest(req)
+	if r.metrics != nil {
+		r.metrics.EntryCreated()
+	}
+	r.debug("created report entry", "entry", r.entry.Entry, "generation", r.expectedGeneration)
+	return r, nil
+}
+
+func (r *OpenReport) setRequest(req *Request) {
 	r.InBlob = req.InBlob // InBlob is not a copy!
 	r.Privilege = req.Privilege
 	r.GetAuxBlob = req.GetAuxBlob
 	r.ServiceProvider = req.ServiceProvider
 	r.ServiceGuid = req.ServiceGuid
 	r.ServiceManifestVersion = req.ServiceManifestVersion
-	return r, nil
+	r.ExpectedProvider = req.ExpectedProvider
+	r.FloorPolicy = req.FloorPolicy
+	r.metrics = req.Metrics
+	r.logger = req.Logger
+}
+
+func (r *OpenReport) debug(msg string, args ...any) {
+	if r.logger != nil {
+		r.logger.Debug(msg, args...)
+	}
 }
 
 // Destroy returns an error if the configfs report subtree cannot be removed. Will not error for
@@ -153,7 +262,11 @@ func (r *OpenReport) Destroy() error {
 		if err := r.client.RemoveAll(r.entry.String()); err != nil {
 			return err
 		}
+		r.debug("destroyed report entry", "entry", r.entry.Entry)
 		r.entry = nil
+		if r.metrics != nil {
+			r.metrics.EntryDestroyed()
+		}
 	}
 	return nil
 }
@@ -166,18 +279,39 @@ func (r *OpenReport) PrivilegeLevelFloor() (uint, error) {
 	}
 	i, err := configfsi.Kstrtouint(data, numberAttributeBase, 32)
 	if err != nil {
-		return 0, fmt.Errorf("could not parse privlevel_floor data %v as int: %v", data, err)
+		return 0, fmt.Errorf("could not parse privlevel_floor data %v as int: %w", data, err)
 	}
 	return uint(i), nil
 }
 
+// negotiatePrivilege returns the privilege level to write for a requested level according
+// to the report's FloorPolicy, and whether the level was clamped.
+func (r *OpenReport) negotiatePrivilege(level uint) (uint, bool, error) {
+	if r.FloorPolicy == FloorKernel {
+		return level, false, nil
+	}
+	floor, err := r.PrivilegeLevelFloor()
+	if err != nil {
+		return 0, false, err
+	}
+	if level >= floor {
+		return level, false, nil
+	}
+	if r.FloorPolicy == FloorClamp {
+		return floor, true, nil
+	}
+	return 0, false, &PrivilegeErr{Requested: level, Floor: floor}
+}
+
 // WriteOption sets a configfs report option to the provided data and internally tracks
 // the generation that should be expected on the next ReadOption.
 func (r *OpenReport) WriteOption(subtree string, data []byte) error {
 	if err := r.client.WriteFile(r.attribute(subtree), data); err != nil {
-		return fmt.Errorf("could not write report %s: %v", subtree, err)
+		return fmt.Errorf("could not write report %s: %w", subtree, err)
 	}
 	r.expectedGeneration += 1
+	r.debug("wrote report attribute", "entry", r.entry.Entry, "attribute", subtree,
+		"size", len(data), "generation", r.expectedGeneration)
 	return nil
 }
 
@@ -186,16 +320,33 @@ func (r *OpenReport) WriteOption(subtree string, data []byte) error {
 func (r *OpenReport) ReadOption(subtree string) ([]byte, error) {
 	data, err := r.client.ReadFile(r.attribute(subtree))
 	if err != nil {
-		return nil, fmt.Errorf("could not read report property %q: %v", subtree, err)
+		return nil, fmt.Errorf("could not read report property %q: %w", subtree, err)
+	}
+	if err := r.checkGeneration(subtree); err != nil {
+		return nil, err
 	}
+	return data, nil
+}
+
+// checkGeneration returns an error if the entry's generation is not as expected after
+// reading subtree.
+func (r *OpenReport) checkGeneration(subtree string) error {
 	gotGeneration, err := readUint64File(r.client, r.attribute("generation"))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if gotGeneration != r.expectedGeneration {
-		return nil, &GenerationErr{Got: gotGeneration, Want: r.expectedGeneration, Attribute: subtree}
+		r.debug("report generation changed unexpectedly", "entry", r.entry.Entry,
+			"attribute", subtree, "got", gotGeneration, "want", r.expectedGeneration)
+		return &GenerationErr{
+			Got:       gotGeneration,
+			Want:      r.expectedGeneration,
+			Attribute: subtree,
+			Entry:     r.entry.Entry,
+			Age:       time.Since(r.created),
+		}
 	}
-	return data, nil
+	return nil
 }
 
 // Get returns the requested report data after initializing the context to the expected
@@ -203,30 +354,53 @@ func (r *OpenReport) ReadOption(subtree string) ([]byte, error) {
 // generation value.
 func (r *OpenReport) Get() (*Response, error) {
 	var err error
+	start := time.Now()
 	if err := r.WriteOption("inblob", r.InBlob); err != nil {
 		return nil, err
 	}
+	resp := &Response{Entry: r.entry.Entry}
 	if r.Privilege != nil {
-		if err := r.WriteOption("privlevel", []byte(fmt.Sprintf("%d", r.Privilege.Level))); err != nil {
+		level, clamped, err := r.negotiatePrivilege(r.Privilege.Level)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.WriteOption("privlevel", []byte(fmt.Sprintf("%d", level))); err != nil {
 			return nil, err
 		}
+		r.privilegeWritten = true
+		resp.Privilege = &Privilege{Level: level}
+		resp.PrivilegeClamped = clamped
 	}
 	if r.ServiceProvider != "" {
 		if err := r.WriteOption("service_provider", []byte(r.ServiceProvider)); err != nil {
 			return nil, err
 		}
+		r.serviceWritten = true
 	}
 	if r.ServiceGuid != "" {
 		if err := r.WriteOption("service_guid", []byte(r.ServiceGuid)); err != nil {
 			return nil, err
 		}
+		r.serviceWritten = true
 	}
 	if r.ServiceManifestVersion != "" {
 		if err := r.WriteOption("service_manifest_version", []byte(r.ServiceManifestVersion)); err != nil {
 			return nil, err
 		}
+		r.serviceWritten = true
+	}
+	// The provider is read before the report blobs so that a mismatch does not cost a
+	// hardware attestation.
+	providerData, err := r.ReadOption("provider")
+	if err != nil {
+		return nil, err
+	}
+	resp.Provider = string(providerData)
+	if r.ExpectedProvider != "" {
+		if got := strings.TrimRight(resp.Provider, "\n"); got != r.ExpectedProvider {
+			return nil, &ProviderErr{Got: got, Want: r.ExpectedProvider}
+		}
 	}
-	resp := &Response{}
 
 	if r.GetAuxBlob {
 		resp.AuxBlob, err = r.ReadOption("auxblob")
@@ -238,11 +412,9 @@ func (r *OpenReport) Get() (*Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not read report outblob: %w", err)
 	}
-	providerData, err := r.ReadOption("provider")
-	if err != nil {
+	if err := ValidateOutBlob(resp.Provider, resp.OutBlob); err != nil {
 		return nil, err
 	}
-	resp.Provider = string(providerData)
 	if r.ServiceProvider != "" {
 		manifest, err := r.ReadOption("manifestblob")
 		if err != nil {
@@ -250,12 +422,17 @@ func (r *OpenReport) Get() (*Response, error) {
 		}
 		resp.ManifestBlob = manifest
 	}
+	resp.Generation = r.expectedGeneration
+	resp.Duration = time.Since(start)
 	return resp, nil
 }
 
-// Get returns a one-shot configfs-tsm report given a report request.
-func Get(client configfsi.Client, req *Request) (*Response, error) {
-	var err error
+// retryable returns whether a failed report attempt may succeed on a fresh entry.
+func retryable(err error) bool {
+	return GetGenerationErr(err) != nil || configfsi.IsBusy(err)
+}
+
+func getOnce(client configfsi.Client, req *Request) (*Response, error) {
 	r, err := Create(client, req)
 	if err != nil {
 		return nil, err
@@ -263,3 +440,44 @@ func Get(client configfsi.Client, req *Request) (*Response, error) {
 	response, err := r.Get()
 	return response, multierr.Combine(r.Destroy(), err)
 }
+
+// Get returns a one-shot configfs-tsm report given a report request and options.
+func Get(client configfsi.Client, req *Request, opts ...Option) (resp *Response, err error) {
+	req = applyOptions(req, opts)
+	if req.startSpan != nil {
+		var span configfsi.Span
+		client, span = req.startSpan(client, "report.Get", "inblob_size", len(req.InBlob))
+		defer func() { endGetSpan(span, resp, err) }()
+	}
+	return getWithPolicy(req, func() (*Response, error) { return getOnce(client, req) })
+}
+
+// getWithPolicy calls once until it succeeds or the request's retries are exhausted,
+// subject to the request's timeout, and records the outcome in the response and metrics.
+func getWithPolicy(req *Request, once func() (*Response, error)) (*Response, error) {
+	start := time.Now()
+	attempts := func() (*Response, int, error) {
+		response, err := once()
+		retries := 0
+		for ; retries < req.Retries && err != nil && retryable(err); retries++ {
+			response, err = once()
+		}
+		return response, retries, err
+	}
+	var response *Response
+	var retries int
+	var err error
+	if req.Timeout > 0 {
+		response, retries, err = withTimeout(req, attempts)
+	} else {
+		response, retries, err = attempts()
+	}
+	if response != nil {
+		response.Retries = retries
+		response.Duration = time.Since(start)
+	}
+	if req.Metrics != nil {
+		req.Metrics.ObserveGet(time.Since(start), retries, err)
+	}
+	return response, err
+}
@@ -16,10 +16,19 @@ package report
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/configfsitest"
 	"github.com/google/go-configfs-tsm/configfs/faketsm"
 )
 
@@ -48,17 +57,19 @@ func TestGet(t *testing.T) {
 
 func TestGetErr(t *testing.T) {
 	tcs := []struct {
-		name    string
-		req     *Request
-		floor   uint
-		wantErr string
+		name      string
+		req       *Request
+		floor     uint
+		wantErr   string
+		wantErrno syscall.Errno
 	}{
 		{
 			name: "inblob too big",
 			req: &Request{
 				InBlob: make([]byte, 4096),
 			},
-			wantErr: "invalid argument",
+			wantErr:   "invalid argument",
+			wantErrno: syscall.EINVAL,
 		},
 		{
 			name: "privlevel too high",
@@ -69,9 +80,10 @@ func TestGetErr(t *testing.T) {
 			wantErr: "privlevel must be 0-3",
 		},
 		{
-			name:    "missing inblob",
-			req:     &Request{},
-			wantErr: "invalid argument",
+			name:      "missing inblob",
+			req:       &Request{},
+			wantErr:   "invalid argument",
+			wantErrno: syscall.EINVAL,
 		},
 		{
 			name: "privlevel too low",
@@ -99,6 +111,448 @@ func TestGetErr(t *testing.T) {
 			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
 				t.Fatalf("Get(%+v) = %+v, %v, want %q", tc.req, resp, err, tc.wantErr)
 			}
+			if tc.wantErrno != 0 && !errors.Is(err, tc.wantErrno) {
+				t.Errorf("Get(%+v) = %+v, %v, want errors.Is %v", tc.req, resp, err, tc.wantErrno)
+			}
+		})
+	}
+}
+
+func TestGetOptions(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.Report611(0)}}
+	req := NewRequest([]byte("nonce"),
+		WithPrivilege(2),
+		WithAuxBlob(),
+		WithManifest("svsm", "", ""),
+		WithRetry(2),
+		WithProviderExpectation("fake"))
+	resp, err := Get(c, req)
+	if err != nil {
+		t.Fatalf("Get(%+v) = %+v, %v, want nil", req, resp, err)
+	}
+	wantOut := "privlevel: 2\ninblob: 6e6f6e6365"
+	if !bytes.Equal(resp.OutBlob, []byte(wantOut)) {
+		t.Errorf("OutBlob %v is not %v", string(resp.OutBlob), wantOut)
+	}
+	if !bytes.Equal(resp.AuxBlob, []byte(`auxblob`)) {
+		t.Errorf("auxblob = %v, want %v", resp.AuxBlob, []byte(`auxblob`))
+	}
+	if !bytes.Equal(resp.ManifestBlob, []byte("fakemanifest\n")) {
+		t.Errorf("manifestblob = %q, want %q", resp.ManifestBlob, "fakemanifest\n")
+	}
+	if resp.Entry == "" || resp.Generation != 3 || resp.Retries != 0 || resp.Duration <= 0 {
+		t.Errorf("metadata = entry %q, generation %d, retries %d, duration %v, want 3 writes",
+			resp.Entry, resp.Generation, resp.Retries, resp.Duration)
+	}
+
+	_, err = Get(c, &Request{InBlob: []byte("nonce")}, WithProviderExpectation("sev_guest"))
+	var providerErr *ProviderErr
+	if !errors.As(err, &providerErr) || providerErr.Got != "fake" || providerErr.Want != "sev_guest" {
+		t.Errorf("Get(_, _, WithProviderExpectation(\"sev_guest\")) = _, %v, want *ProviderErr", err)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	reqs := []*Request{
+		{InBlob: []byte("a"), Privilege: &Privilege{Level: 1}},
+		{InBlob: make([]byte, 4096)},
+		{InBlob: []byte("c")},
+	}
+	resps, err := GetMany(c, reqs, &BatchOptions{Workers: 2})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("GetMany() = _, %v, want *BatchError", err)
+	}
+	if batchErr.Errs[0] != nil || batchErr.Errs[1] == nil || batchErr.Errs[2] != nil {
+		t.Errorf("GetMany() errors = %v, want only request 1 to fail", batchErr.Errs)
+	}
+	if !errors.Is(err, batchErr.Errs[1]) {
+		t.Errorf("errors.Is(%v, %v) = false, want true", err, batchErr.Errs[1])
+	}
+	if resps[1] != nil {
+		t.Errorf("GetMany() response 1 = %+v, want nil", resps[1])
+	}
+	wantOut := "privlevel: 1\ninblob: 61"
+	if resps[0] == nil || !bytes.Equal(resps[0].OutBlob, []byte(wantOut)) {
+		t.Errorf("GetMany() response 0 = %+v, want OutBlob %q", resps[0], wantOut)
+	}
+}
+
+func TestGetFloorPolicy(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(2)}}
+	resp, err := Get(c, NewRequest([]byte("nonce"), WithPrivilege(1), WithFloorPolicy(FloorClamp)))
+	if err != nil {
+		t.Fatalf("Get(FloorClamp) = _, %v, want nil", err)
+	}
+	if resp.Privilege == nil || resp.Privilege.Level != 2 || !resp.PrivilegeClamped {
+		t.Errorf("Get(FloorClamp) privilege = %+v, clamped %v, want level 2 clamped", resp.Privilege, resp.PrivilegeClamped)
+	}
+
+	_, err = Get(c, NewRequest([]byte("nonce"), WithPrivilege(1), WithFloorPolicy(FloorError)))
+	var privErr *PrivilegeErr
+	if !errors.As(err, &privErr) || privErr.Requested != 1 || privErr.Floor != 2 {
+		t.Errorf("Get(FloorError) = _, %v, want *PrivilegeErr{1, 2}", err)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.Report611(0)}}
+	r, err := CreateOpenReport(c)
+	if err != nil {
+		t.Fatalf("CreateOpenReport() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	attrs, err := r.Attributes()
+	if err != nil {
+		t.Fatalf("Attributes() = _, %v, want nil", err)
+	}
+	want := map[string]Attribute{
+		"inblob":       {Name: "inblob", Writable: true},
+		"outblob":      {Name: "outblob", Readable: true},
+		"manifestblob": {Name: "manifestblob", Readable: true},
+	}
+	for _, a := range attrs {
+		if w, ok := want[a.Name]; ok {
+			if a != w {
+				t.Errorf("Attributes() %q = %+v, want %+v", a.Name, a, w)
+			}
+			delete(want, a.Name)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("Attributes() = %v, missing %v", attrs, want)
+	}
+}
+
+func TestStatAttribute(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.Report611(0)}}
+	r, err := CreateOpenReport(c)
+	if err != nil {
+		t.Fatalf("CreateOpenReport() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	info, err := configfsi.Stat(c, r.attribute("inblob"))
+	if err != nil {
+		t.Fatalf("Stat(inblob) = _, %v, want nil", err)
+	}
+	if info.Mode().Perm()&0444 != 0 || info.Mode().Perm()&0200 == 0 {
+		t.Errorf("Stat(inblob).Mode() = %v, want write-only", info.Mode())
+	}
+	if _, err := configfsi.Stat(c, r.attribute("nonexistent")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(nonexistent) = _, %v, want fs.ErrNotExist", err)
+	}
+}
+
+type countingMetrics struct {
+	mu                       sync.Mutex
+	gets, created, destroyed int
+	lastErr                  error
+}
+
+func (m *countingMetrics) ObserveGet(_ time.Duration, _ int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	m.lastErr = err
+}
+
+func (m *countingMetrics) EntryCreated() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created++
+}
+
+func (m *countingMetrics) EntryDestroyed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.destroyed++
+}
+
+func (m *countingMetrics) counts() (gets, created, destroyed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gets, m.created, m.destroyed
+}
+
+func TestGetMetrics(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	m := &countingMetrics{}
+	if _, err := Get(c, NewRequest([]byte("nonce")), WithMetrics(m)); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	if _, err := Get(c, NewRequest([]byte("nonce")), WithMetrics(m), WithProviderExpectation("x")); err == nil {
+		t.Fatalf("Get() = _, nil, want error")
+	}
+	if m.gets != 2 || m.created != 2 || m.destroyed != 2 {
+		t.Errorf("metrics = %+v, want 2 gets, creates, and destroys", m)
+	}
+	if got := ErrorClass(m.lastErr); got != ErrorClassProvider {
+		t.Errorf("ErrorClass(%v) = %q, want %q", m.lastErr, got, ErrorClassProvider)
+	}
+}
+
+type recordingLogger struct {
+	msgs []string
+}
+
+func (l *recordingLogger) Debug(msg string, _ ...any) {
+	l.msgs = append(l.msgs, msg)
+}
+
+func TestGetLogger(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	l := &recordingLogger{}
+	if _, err := Get(c, NewRequest([]byte("nonce"), WithPrivilege(1)), WithLogger(l)); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	want := []string{
+		"created report entry",
+		"wrote report attribute",
+		"wrote report attribute",
+		"destroyed report entry",
+	}
+	if strings.Join(l.msgs, "|") != strings.Join(want, "|") {
+		t.Errorf("logged %q, want %q", l.msgs, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	sub := faketsm.ReportV7(0)
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": sub}}
+	now := time.Unix(0, 0)
+	cache := NewCache(c, time.Minute, 2)
+	cache.now = func() time.Time { return now }
+	m := &countingMetrics{}
+	get := func(inblob string) {
+		t.Helper()
+		if _, err := cache.Get(NewRequest([]byte(inblob)), WithMetrics(m)); err != nil {
+			t.Fatalf("Get(%q) = _, %v, want nil", inblob, err)
+		}
+	}
+	get("a")
+	get("a")
+	if m.gets != 1 {
+		t.Errorf("cached Get fetched %d reports, want 1", m.gets)
+	}
+	get("b")
+	if m.gets != 2 {
+		t.Errorf("Get with a different inblob fetched %d reports, want 2", m.gets)
+	}
+	now = now.Add(2 * time.Minute)
+	get("a")
+	if m.gets != 3 {
+		t.Errorf("Get after expiry fetched %d reports, want 3", m.gets)
+	}
+	// Caching "a" again swept the expired "b".
+	if len(cache.entries) != 1 {
+		t.Errorf("cache holds %d entries after expiry, want 1", len(cache.entries))
+	}
+	now = now.Add(time.Second)
+	get("b")
+	now = now.Add(time.Second)
+	get("c")
+	if len(cache.entries) != 2 {
+		t.Errorf("cache holds %d entries, want at most 2", len(cache.entries))
+	}
+	// "a" expired first, so it was evicted to make room for "c".
+	get("a")
+	if m.gets != 6 {
+		t.Errorf("Get of an evicted report fetched %d reports, want 6", m.gets)
+	}
+}
+
+func TestGenerationErrDiagnostics(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	r, err := CreateOpenReport(c)
+	if err != nil {
+		t.Fatalf("CreateOpenReport() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	// An interfering writer bumps the generation behind r's back.
+	if err := c.WriteFile(r.attribute("inblob"), []byte("other")); err != nil {
+		t.Fatalf("interfering write failed: %v", err)
+	}
+	_, err = r.ReadOption("outblob")
+	genErr := GetGenerationErr(err)
+	if genErr == nil {
+		t.Fatalf("ReadOption(\"outblob\") = _, %v, want *GenerationErr", err)
+	}
+	if genErr.Entry != r.entry.Entry || genErr.Attribute != "outblob" || genErr.Got != genErr.Want+1 {
+		t.Errorf("GenerationErr = %+v, want entry %q, attribute outblob, and one extra write",
+			genErr, r.entry.Entry)
+	}
+}
+
+func TestReadOptionReader(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	r, err := CreateOpenReport(c)
+	if err != nil {
+		t.Fatalf("CreateOpenReport() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	if err := r.WriteOption("inblob", []byte("nonce")); err != nil {
+		t.Fatalf("WriteOption(\"inblob\") = %v, want nil", err)
+	}
+	rc, err := r.ReadOptionReader("outblob")
+	if err != nil {
+		t.Fatalf("ReadOptionReader(\"outblob\") = _, %v, want nil", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(iotest.OneByteReader(rc))
+	if err != nil {
+		t.Fatalf("ReadAll() = _, %v, want nil", err)
+	}
+	wantOut := "privlevel: 0\ninblob: 6e6f6e6365"
+	if string(got) != wantOut {
+		t.Errorf("ReadAll() = %q, want %q", got, wantOut)
+	}
+}
+
+func TestValidateOutBlob(t *testing.T) {
+	tcs := []struct {
+		provider string
+		size     int
+		wantErr  bool
+	}{
+		{provider: "fake\n", size: 0},
+		{provider: "sev_guest\n", size: 1184},
+		{provider: "sev_guest\n", size: 1183, wantErr: true},
+		{provider: "tdx_guest", size: 5000},
+		{provider: "tdx_guest", size: 48, wantErr: true},
+	}
+	for _, tc := range tcs {
+		err := ValidateOutBlob(tc.provider, make([]byte, tc.size))
+		var outErr *OutBlobErr
+		if gotErr := errors.As(err, &outErr); gotErr != tc.wantErr {
+			t.Errorf("ValidateOutBlob(%q, [%d]) = %v, want error %v", tc.provider, tc.size, err, tc.wantErr)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	r, err := Create(c, NewRequest([]byte("a"), WithPrivilege(2)))
+	if err != nil {
+		t.Fatalf("Create() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	if _, err := r.Get(); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	if err := r.Reset(NewRequest([]byte("b"))); err != nil {
+		t.Fatalf("Reset() = %v, want nil", err)
+	}
+	resp, err := r.Get()
+	if err != nil {
+		t.Fatalf("Get() after Reset = _, %v, want nil", err)
+	}
+	wantOut := "privlevel: 0\ninblob: 62"
+	if string(resp.OutBlob) != wantOut {
+		t.Errorf("OutBlob = %q, want %q", resp.OutBlob, wantOut)
+	}
+	// Interference before Reset is not forgotten.
+	if err := c.WriteFile(r.attribute("inblob"), []byte("other")); err != nil {
+		t.Fatalf("interfering write failed: %v", err)
+	}
+	if err := r.Reset(NewRequest([]byte("c"))); GetGenerationErr(err) == nil {
+		t.Errorf("Reset() after interference = %v, want a GenerationErr", err)
+	}
+}
+
+func TestResetServiceAttributes(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.Report611(0)}}
+	r, err := Create(c, NewRequest([]byte("a"), WithManifest("svsm", "c8a3e6a5-5f3b-4e1c-8d3f-2a6b7c9d0e1f", "1")))
+	if err != nil {
+		t.Fatalf("Create() = _, %v, want nil", err)
+	}
+	defer r.Destroy()
+	if _, err := r.Get(); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	// Even a request for the same provider would inherit the stale guid and version.
+	if err := r.Reset(NewRequest([]byte("b"), WithManifest("svsm", "", ""))); err == nil {
+		t.Error("Reset() after writing service attributes = nil, want error")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tcs := []struct {
+		name        string
+		sub         *faketsm.ReportSubsystem
+		wantService bool
+	}{
+		{name: "v7", sub: faketsm.ReportV7(0)},
+		{name: "6.11", sub: faketsm.Report611(0), wantService: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": tc.sub}}
+			caps, err := Capabilities(c)
+			if err != nil {
+				t.Fatalf("Capabilities() = _, %v, want nil", err)
+			}
+			if caps.Provider != "fake" || !caps.AuxBlob || caps.Service != tc.wantService {
+				t.Errorf("Capabilities() = %+v, want provider fake, auxblob, and service %v", caps, tc.wantService)
+			}
+			if len(tc.sub.Entries) != 0 {
+				t.Errorf("Capabilities() left %d entries behind", len(tc.sub.Entries))
+			}
 		})
 	}
 }
+
+// slowClient delays every ReadFile to simulate slow hardware.
+type slowClient struct {
+	configfsi.Client
+	delay time.Duration
+}
+
+func (c *slowClient) ReadFile(name string) ([]byte, error) {
+	time.Sleep(c.delay)
+	return c.Client.ReadFile(name)
+}
+
+func TestGetTimeout(t *testing.T) {
+	sub := faketsm.ReportV7(0)
+	c := &slowClient{
+		Client: &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": sub}},
+		delay:  20 * time.Millisecond,
+	}
+	m := &countingMetrics{}
+	_, err := Get(c, NewRequest([]byte("nonce")), WithTimeout(time.Millisecond), WithMetrics(m))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() = _, %v, want context.DeadlineExceeded", err)
+	}
+	// The abandoned attempt still cleans up its entry.
+	deadline := time.Now().Add(5 * time.Second)
+	_, created, destroyed := m.counts()
+	for destroyed == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, created, destroyed = m.counts()
+	}
+	if created != 1 || destroyed != 1 {
+		t.Errorf("entries created %d, destroyed %d, want 1 and 1", created, destroyed)
+	}
+}
+
+func TestGetTracer(t *testing.T) {
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	tracer := &configfsitest.Tracer{}
+	ctx, caller := tracer.Start(context.Background(), "caller")
+	if _, err := Get(c, &Request{InBlob: []byte("nonce")}, WithTracer(ctx, tracer)); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	caller.End(nil)
+	spans := tracer.Spans()
+	if got := tracer.Names(0); len(got) != 1 || got[0] != "report.Get" {
+		t.Fatalf("children of caller = %v, want report.Get", got)
+	}
+	if get := spans[1]; !get.Ended || get.Err != nil || get.Attributes["provider"] != "fake" {
+		t.Errorf("report.Get span = %+v, want it ended with provider fake", get)
+	}
+	children := tracer.Names(1)
+	if len(children) == 0 || children[0] != "configfs.MkdirTemp" || children[len(children)-1] != "configfs.RemoveAll" {
+		t.Errorf("children of report.Get = %v, want entry creation through cleanup", children)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reset prepares the report entry for a new request without removing and recreating it. A
+// privilege level written by a previous Get is returned to privlevel_floor if the new
+// request does not set one.
+//
+// If the entry's generation changed since the last Get, another process wrote to it, so
+// Reset returns a *GenerationErr and the entry should be destroyed rather than reused.
+//
+// Service attributes cannot be cleared once written, and a new request that sets only some
+// of them would inherit the others, so Reset returns an error if a previous request set
+// them. Such entries should be destroyed rather than reused.
+func (r *OpenReport) Reset(req *Request, opts ...Option) error {
+	if r.entry == nil {
+		return fmt.Errorf("report entry is destroyed")
+	}
+	if r.serviceWritten {
+		return fmt.Errorf("cannot reset service attributes of report entry %q", r.entry.Entry)
+	}
+	req = applyOptions(req, opts)
+	generation, err := readUint64File(r.client, r.attribute("generation"))
+	if err != nil {
+		return err
+	}
+	if generation != r.expectedGeneration {
+		r.debug("report entry generation changed before reset", "entry", r.entry.Entry,
+			"got", generation, "want", r.expectedGeneration)
+		return &GenerationErr{
+			Got:       generation,
+			Want:      r.expectedGeneration,
+			Attribute: "generation",
+			Entry:     r.entry.Entry,
+			Age:       time.Since(r.created),
+		}
+	}
+	r.created = time.Now()
+	r.setRequest(req)
+	if r.Privilege == nil && r.privilegeWritten {
+		floor, err := r.PrivilegeLevelFloor()
+		if err != nil {
+			return err
+		}
+		if err := r.WriteOption("privlevel", []byte(fmt.Sprintf("%d", floor))); err != nil {
+			return err
+		}
+		r.privilegeWritten = false
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// optionReader streams an attribute and checks the entry's generation once the attribute
+// has been read in full.
+type optionReader struct {
+	r       *OpenReport
+	subtree string
+	rc      io.ReadCloser
+	done    bool
+}
+
+// Read reads the next chunk of the attribute. Short reads from configfs are returned as
+// is; io.EOF is only returned once the generation matches expectations.
+func (o *optionReader) Read(p []byte) (int, error) {
+	if o.done {
+		return 0, io.EOF
+	}
+	n, err := o.rc.Read(p)
+	if err == io.EOF {
+		o.done = true
+		if genErr := o.r.checkGeneration(o.subtree); genErr != nil {
+			return n, genErr
+		}
+		return n, io.EOF
+	}
+	if err != nil {
+		return n, fmt.Errorf("could not read report property %q: %w", o.subtree, err)
+	}
+	return n, nil
+}
+
+// Close closes the underlying attribute file.
+func (o *optionReader) Close() error {
+	return o.rc.Close()
+}
+
+// ReadOptionReader returns a reader for a readable attribute of a report, for attributes
+// too large to comfortably hold in memory. The reader returns an error instead of io.EOF
+// if any tampering to the ongoing request is detected, so the data must not be trusted
+// until the reader returns io.EOF.
+func (r *OpenReport) ReadOptionReader(subtree string) (io.ReadCloser, error) {
+	rc, err := configfsi.Open(r.client, r.attribute(subtree))
+	if err != nil {
+		return nil, fmt.Errorf("could not open report property %q: %w", subtree, err)
+	}
+	return &optionReader{r: r, subtree: subtree, rc: rc}, nil
+}
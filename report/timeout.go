@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type attemptResult struct {
+	response *Response
+	retries  int
+	err      error
+}
+
+// withTimeout runs attempts in a separate goroutine and returns an error wrapping
+// context.DeadlineExceeded if it does not finish within the request's timeout. Since
+// attempts always removes the entries it creates, abandoning it does not leak entries;
+// its eventual result is logged instead.
+func withTimeout(req *Request, attempts func() (*Response, int, error)) (*Response, int, error) {
+	done := make(chan attemptResult, 1)
+	go func() {
+		response, retries, err := attempts()
+		done <- attemptResult{response: response, retries: retries, err: err}
+	}()
+	timer := time.NewTimer(req.Timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.response, res.retries, res.err
+	case <-timer.C:
+		if req.Logger != nil {
+			go func() {
+				res := <-done
+				req.Logger.Debug("abandoned report request completed", "retries", res.retries, "error", res.err)
+			}()
+		}
+		return nil, 0, fmt.Errorf("report not ready after %v: %w", req.Timeout, context.DeadlineExceeded)
+	}
+}
+
+// WithTimeout bounds how long Get waits for a report.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Request) {
+		r.Timeout = timeout
+	}
+}
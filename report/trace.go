@@ -0,0 +1,44 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// WithTracer sets the tracer that traces Get as a "report.Get" span, a child of the span in
+// ctx, with child spans for entry creation, attribute writes and reads, and cleanup, so
+// that attestation latency shows up in the caller's distributed trace.
+func WithTracer(ctx context.Context, tracer configfsi.ContextTracer) Option {
+	return func(r *Request) {
+		r.startSpan = func(client configfsi.Client, name string, attrs ...any) (configfsi.Client, configfsi.Span) {
+			return configfsi.StartSpan(ctx, tracer, client, name, attrs...)
+		}
+	}
+}
+
+// endGetSpan sets the attributes of a Get's outcome on its span and ends it.
+func endGetSpan(span configfsi.Span, resp *Response, err error) {
+	if resp != nil {
+		span.SetAttributes("provider", strings.TrimRight(resp.Provider, "\n"), "entry", resp.Entry,
+			"generation", resp.Generation, "retries", resp.Retries)
+	} else if err != nil {
+		span.SetAttributes("error_class", ErrorClass(err))
+	}
+	span.End(err)
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+	"github.com/google/go-configfs-tsm/internal/tdx"
+)
+
+const (
+	// ProviderSevGuest is the provider attribute value for AMD SEV-SNP guests.
+	ProviderSevGuest = "sev_guest"
+	// ProviderTdxGuest is the provider attribute value for Intel TDX guests.
+	ProviderTdxGuest = "tdx_guest"
+)
+
+// OutBlobErr is returned when an outblob does not have the shape its provider produces.
+type OutBlobErr struct {
+	Provider string
+	Size     int
+	Reason   string
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *OutBlobErr) Error() string {
+	return fmt.Sprintf("%s outblob of %d bytes is malformed: %s", e.Provider, e.Size, e.Reason)
+}
+
+// ValidateOutBlob returns an error if the outblob is too short to be a report from the
+// given provider. Unknown providers are not validated.
+func ValidateOutBlob(provider string, outblob []byte) error {
+	provider = strings.TrimRight(provider, "\n")
+	var minSize int
+	switch provider {
+	case ProviderSevGuest:
+		minSize = snp.ReportSize
+	case ProviderTdxGuest:
+		minSize = tdx.SignatureDataOffset
+	default:
+		return nil
+	}
+	if len(outblob) < minSize {
+		return &OutBlobErr{
+			Provider: provider,
+			Size:     len(outblob),
+			Reason:   fmt.Sprintf("expected at least %d bytes", minSize),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Measurement is a SHA-384 digest to extend into an RTMR.
+type Measurement struct {
+	Index  int
+	Digest []byte
+}
+
+// ExtendMeasurements extends each measurement in order, looking up each register's entry
+// only once. All measurements are validated before any are extended. If an extend fails,
+// the measurements before it have been extended and the rest have not; the error reports
+// how many succeeded.
+func ExtendMeasurements(client configfsi.Client, measurements []Measurement, opts ...Option) error {
+	for i, m := range measurements {
+		if len(m.Digest) != crypto.SHA384.Size() {
+			return fmt.Errorf("measurement %d: the length of the digest must be %d bytes, the input is %d bytes: %w",
+				i, crypto.SHA384.Size(), len(m.Digest), ErrBadDigestLength)
+		}
+		if m.Index < 0 {
+			return fmt.Errorf("measurement %d: invalid rtmr index %d. Index can only be a non-negative number", i, m.Index)
+		}
+	}
+	o := makeOptions(opts)
+	registers := make(map[int]*Extend)
+	for i, m := range measurements {
+		r, ok := registers[m.Index]
+		if !ok {
+			var err error
+			r, err = getRtmrInterface(client, m.Index, o)
+			if err != nil {
+				return fmt.Errorf("extended %d of %d measurements: %w", i, len(measurements), err)
+			}
+			registers[m.Index] = r
+		}
+		if err := r.extendDigest(m.Digest); err != nil {
+			return fmt.Errorf("extended %d of %d measurements: %w", i, len(measurements), err)
+		}
+	}
+	return nil
+}
+
+// ExtendDigests extends each SHA-384 digest into the rtmr in order.
+func ExtendDigests(client configfsi.Client, rtmr int, digests [][]byte, opts ...Option) error {
+	measurements := make([]Measurement, len(digests))
+	for i, d := range digests {
+		measurements[i] = Measurement{Index: rtmr, Digest: d}
+	}
+	return ExtendMeasurements(client, measurements, opts...)
+}
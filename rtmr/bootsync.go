@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// CCELPath is where Linux exposes the firmware CC event log of the ACPI CCEL table.
+const CCELPath = "/sys/firmware/acpi/tables/data/CCEL"
+
+// BootLogMismatchError is returned when the firmware event log does not replay to the
+// current value of an RTMR, e.g., because the register was extended without logging.
+type BootLogMismatchError struct {
+	// Mismatches are the results of the registers that do not match.
+	Mismatches []*VerifyResult
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *BootLogMismatchError) Error() string {
+	indices := make([]int, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		indices[i] = m.Index
+	}
+	return fmt.Sprintf("firmware event log does not replay to rtmrs %v", indices)
+}
+
+// SyncBootLog parses a firmware CC event log, checks that it replays to the current value of
+// every RTMR it extends, and returns an EventLog that continues it. Runtime extends of RTMR
+// 2 and 3 through the returned log are recorded after the firmware events, so that Verify
+// of its Events checks firmware-time and runtime measurements as one chain.
+func SyncBootLog(client configfsi.Client, ccel []byte, opts ...Option) (*EventLog, error) {
+	events, err := ParseCCEventLog(ccel)
+	if err != nil {
+		return nil, err
+	}
+	mismatches, err := mismatches(client, events, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(mismatches) != 0 {
+		return nil, &BootLogMismatchError{Mismatches: mismatches}
+	}
+	return &EventLog{events: events, now: time.Now}, nil
+}
+
+// mismatches returns the results of the registers that events do not replay to.
+func mismatches(client configfsi.Client, events []Event, opts ...Option) ([]*VerifyResult, error) {
+	results, err := Verify(client, events, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []*VerifyResult
+	for _, result := range results {
+		if !result.Match {
+			mismatches = append(mismatches, result)
+		}
+	}
+	return mismatches, nil
+}
+
+// EventLogMismatchError is returned by ContinueEventLog when events do not replay to the
+// current value of an RTMR, e.g., because the register was extended without logging.
+type EventLogMismatchError struct {
+	// Mismatches are the results of the registers that do not match.
+	Mismatches []*VerifyResult
+}
+
+// Error returns the human-readable explanation for the error.
+func (e *EventLogMismatchError) Error() string {
+	indices := make([]int, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		indices[i] = m.Index
+	}
+	return fmt.Sprintf("event log does not replay to rtmrs %v", indices)
+}
+
+// ContinueEventLog checks that events, e.g., a log that an earlier process wrote with
+// MarshalJSON, replay to the current value of every RTMR they extend, and returns an
+// EventLog that continues them. Registers that events do not extend are not checked.
+func ContinueEventLog(client configfsi.Client, events []Event, opts ...Option) (*EventLog, error) {
+	mismatches, err := mismatches(client, events, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(mismatches) != 0 {
+		return nil, &EventLogMismatchError{Mismatches: mismatches}
+	}
+	return &EventLog{events: append([]Event(nil), events...), now: time.Now}, nil
+}
+
+// SyncBootLogFile is SyncBootLog of the event log in a file, e.g., CCELPath.
+func SyncBootLogFile(client configfsi.Client, path string, opts ...Option) (*EventLog, error) {
+	ccel, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return SyncBootLog(client, ccel, opts...)
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Client is a handle to the rtmr subsystem that remembers the entry for each register it
+// has used, so repeated extends and reads do not search configfs each time. It is safe for
+// concurrent use.
+type Client struct {
+	client  configfsi.Client
+	opts    *options
+	mu      sync.Mutex
+	entries map[int]*Extend
+}
+
+// NewClient returns a Client for the rtmr subsystem of client.
+func NewClient(client configfsi.Client, opts ...Option) *Client {
+	return &Client{
+		client:  client,
+		opts:    makeOptions(opts),
+		entries: make(map[int]*Extend),
+	}
+}
+
+// entry returns the cached entry for rtmr, locating or creating it if needed.
+func (c *Client) entry(rtmr int) (*Extend, error) {
+	if rtmr < 0 {
+		return nil, fmt.Errorf("invalid rtmr index %d. Index can only be a non-negative number", rtmr)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.entries[rtmr]; ok {
+		return r, nil
+	}
+	r, err := getRtmrInterface(c.client, rtmr, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[rtmr] = r
+	return r, nil
+}
+
+// forget drops the cached entry for rtmr if it is still r.
+func (c *Client) forget(r *Extend) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[r.RtmrIndex] == r {
+		delete(c.entries, r.RtmrIndex)
+	}
+}
+
+// withEntry calls f with the cached entry for rtmr, retrying once with a fresh entry if the
+// cached entry no longer exists.
+func (c *Client) withEntry(rtmr int, f func(*Extend) error) error {
+	r, err := c.entry(rtmr)
+	if err != nil {
+		return err
+	}
+	err = f(r)
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	c.forget(r)
+	if r, err = c.entry(rtmr); err != nil {
+		return err
+	}
+	return f(r)
+}
+
+// ExtendDigest extends the measurement to the rtmr with the given SHA-384 digest.
+func (c *Client) ExtendDigest(rtmr int, digest []byte) error {
+	return c.ExtendHashDigest(rtmr, crypto.SHA384, digest)
+}
+
+// ExtendHashDigest extends the measurement to the rtmr with the given digest, which must
+// have the size of the hash algorithm.
+func (c *Client) ExtendHashDigest(rtmr int, hash crypto.Hash, digest []byte) error {
+	if err := checkExtend(rtmr, hash, digest); err != nil {
+		return err
+	}
+	return c.withEntry(rtmr, func(r *Extend) error { return r.extendDigest(digest) })
+}
+
+// GetDigest returns the digest and the tcg map of a given rtmr index.
+func (c *Client) GetDigest(rtmr int) (*Response, error) {
+	var resp *Response
+	err := c.withEntry(rtmr, func(r *Extend) error {
+		var err error
+		resp, err = r.response()
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"context"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// withContext returns an Option that stops the operation between configfs interactions
+// once ctx is done.
+func withContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// contextOptions returns a copy of opts followed by withContext(ctx). It does not append
+// to opts, which the caller may share across concurrent calls.
+func contextOptions(ctx context.Context, opts []Option) []Option {
+	return append(append([]Option(nil), opts...), withContext(ctx))
+}
+
+// ExtendDigestContext is ExtendDigest, but returns ctx.Err() without extending if ctx is
+// done before the digest is written. A configfs operation that has already started is not
+// interrupted, so a digest is never partially written.
+func ExtendDigestContext(ctx context.Context, client configfsi.Client, rtmr int, digest []byte, opts ...Option) error {
+	return ExtendDigest(client, rtmr, digest, contextOptions(ctx, opts)...)
+}
+
+// GetDigestContext is GetDigest, but returns ctx.Err() if ctx is done before the register
+// is read.
+func GetDigestContext(ctx context.Context, client configfsi.Client, rtmr int, opts ...Option) (*Response, error) {
+	return GetDigest(client, rtmr, contextOptions(ctx, opts)...)
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"errors"
+	"os"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+var (
+	// ErrNotExtendable is returned when software may not extend an RTMR, e.g., RTMR 0 and 1
+	// on TDX.
+	ErrNotExtendable = errors.New("rtmr is not extendable")
+	// ErrPermission is returned when the process lacks the privileges to bind or extend an
+	// RTMR that software may extend.
+	ErrPermission = errors.New("insufficient privileges to use rtmr")
+	// ErrBusy is returned when an RTMR index is already bound to another entry or the
+	// register is otherwise busy.
+	ErrBusy = errors.New("rtmr is busy")
+	// ErrBadDigestLength is returned when a digest does not have the size of its hash
+	// algorithm.
+	ErrBadDigestLength = errors.New("bad digest length")
+
+	// errNoRtmr marks the kernel's rejection of an index past the last RTMR, which ends
+	// scans over all registers.
+	errNoRtmr = errors.New("no such rtmr")
+)
+
+// classifiedError is an error that also matches a sentinel error with errors.Is, while
+// still unwrapping to the underlying cause.
+type classifiedError struct {
+	sentinel error
+	err      error
+}
+
+// Error returns the underlying error's message.
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// Is returns whether target is the error's sentinel.
+func (e *classifiedError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// classify returns err annotated with the sentinel error it corresponds to, if any.
+func classify(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrPermission):
+		return &classifiedError{sentinel: ErrPermission, err: err}
+	case configfsi.IsBusy(err):
+		return &classifiedError{sentinel: ErrBusy, err: err}
+	}
+	return err
+}
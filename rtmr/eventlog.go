@@ -0,0 +1,141 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"crypto"
+	// Register the hash algorithms that Replay supports.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// Event is a record of a single digest extended into an RTMR. The JSON encoding follows
+// the record layout of the TCG Canonical Event Log (CEL) JSON encoding, with the RTMR index
+// in place of a PCR index.
+type Event struct {
+	// RecNum is the event's position in the log, starting from 0.
+	RecNum uint64 `json:"recnum"`
+	// Index is the RTMR the digest was extended into.
+	Index int `json:"rtmr"`
+	// Digests are the digests extended into the RTMR, keyed by hash algorithm name.
+	Digests []EventDigest `json:"digests"`
+	// ContentType describes the format of Content.
+	ContentType string `json:"content_type"`
+	// Content is the measured event data that the digest was computed over, or a
+	// description of it.
+	Content []byte `json:"content"`
+	// Timestamp is the time the digest was extended.
+	Timestamp time.Time `json:"timestamp"`
+	// EventType is the TCG event type for events converted from or to a TCG2 event log.
+	EventType uint32 `json:"event_type,omitempty"`
+}
+
+// EventDigest is a digest in an Event.
+type EventDigest struct {
+	HashAlg string `json:"hashAlg"`
+	Digest  []byte `json:"digest"`
+}
+
+// hashAlgName returns the CEL name of a hash algorithm.
+func hashAlgName(h crypto.Hash) string {
+	switch h {
+	case crypto.SHA256:
+		return "sha256"
+	case crypto.SHA384:
+		return "sha384"
+	case crypto.SHA512:
+		return "sha512"
+	}
+	return h.String()
+}
+
+// EventLog records every digest extended through it so that verifiers can replay the log
+// against the final RTMR values. It is safe for concurrent use.
+type EventLog struct {
+	mu     sync.Mutex
+	events []Event
+	now    func() time.Time
+}
+
+// NewEventLog returns an empty event log.
+func NewEventLog() *EventLog {
+	return &EventLog{now: time.Now}
+}
+
+// ExtendDigest extends the digest into the rtmr like the package-level ExtendDigest and
+// records it with the given content if the extend succeeds.
+func (l *EventLog) ExtendDigest(client configfsi.Client, rtmr int, digest []byte, contentType string, content []byte, opts ...Option) error {
+	return l.ExtendHashDigest(client, rtmr, crypto.SHA384, digest, contentType, content, opts...)
+}
+
+// ExtendHashDigest extends the digest into the rtmr like the package-level
+// ExtendHashDigest and records it with the given content if the extend succeeds.
+func (l *EventLog) ExtendHashDigest(client configfsi.Client, rtmr int, hash crypto.Hash, digest []byte, contentType string, content []byte, opts ...Option) error {
+	// Hold the lock across the extend so the log order matches the extend order.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := ExtendHashDigest(client, rtmr, hash, digest, opts...); err != nil {
+		return err
+	}
+	l.events = append(l.events, Event{
+		RecNum:      uint64(len(l.events)),
+		Index:       rtmr,
+		Digests:     []EventDigest{{HashAlg: hashAlgName(hash), Digest: append([]byte(nil), digest...)}},
+		ContentType: contentType,
+		Content:     append([]byte(nil), content...),
+		Timestamp:   l.now(),
+	})
+	return nil
+}
+
+// Events returns a copy of the recorded events in order.
+func (l *EventLog) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// MarshalJSON returns the events as a JSON array.
+func (l *EventLog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Events())
+}
+
+// Replay returns the expected value of each RTMR in events, assuming each register started
+// at all zeros and used the given hash algorithm.
+func Replay(events []Event, hash crypto.Hash) map[int][]byte {
+	result := make(map[int][]byte)
+	name := hashAlgName(hash)
+	for _, e := range events {
+		for _, d := range e.Digests {
+			if d.HashAlg != name {
+				continue
+			}
+			current, ok := result[e.Index]
+			if !ok {
+				current = make([]byte, hash.Size())
+			}
+			h := hash.New()
+			h.Write(current)
+			h.Write(d.Digest)
+			result[e.Index] = h.Sum(nil)
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// firmwarePcrs is the number of low PCRs that the TCG PC Client specification reserves for
+// firmware measurements. RTMRs mapped to these PCRs are not extendable by software.
+const firmwarePcrs = 8
+
+// Entry describes an existing rtmr entry in configfs.
+type Entry struct {
+	// Name is the entry's directory name.
+	Name string
+	// Index is the RTMR index the entry is bound to, or -1 if it has not been bound.
+	Index int
+	// TcgMap is the set of PCRs the RTMR is mapped to.
+	TcgMap TcgMap
+	// Extendable is whether software may extend the RTMR. RTMRs that map to PCRs
+	// reserved for firmware are not extendable.
+	Extendable bool
+}
+
+// extendable returns whether an RTMR with the given mapping is expected to accept extends
+// from software.
+func extendable(m TcgMap) bool {
+	for _, pcr := range m.pcrs {
+		if pcr < firmwarePcrs {
+			return false
+		}
+	}
+	return true
+}
+
+// ListRtmrs returns the existing rtmr entries sorted by index. It does not create entries.
+func ListRtmrs(client configfsi.Client) ([]*Entry, error) {
+	dirs, err := client.ReadDir(tsmRtmrPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not list rtmr entries: %w", err)
+	}
+	var result []*Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		r := &Extend{
+			RtmrIndex: -1,
+			entry:     &configfsi.TsmPath{Subsystem: rtmrSubsystem, Entry: d.Name()},
+			client:    client,
+		}
+		e := &Entry{Name: d.Name(), Index: -1}
+		result = append(result, e)
+		indexBytes, err := client.ReadFile(r.attribute(tsmPathIndex))
+		if err != nil {
+			return nil, fmt.Errorf("could not read rtmr entry %q index: %w", d.Name(), err)
+		}
+		index, err := configfsi.Kstrtouint(indexBytes, 10, 31)
+		if err != nil {
+			// The entry's index has not been set yet.
+			continue
+		}
+		e.Index = int(index)
+		tcgmapData, err := r.getTcgMap()
+		if err != nil {
+			return nil, fmt.Errorf("could not read rtmr entry %q tcg_map: %w", d.Name(), err)
+		}
+		e.TcgMap, err = ParseTcgMap(tcgmapData)
+		if err != nil {
+			return nil, err
+		}
+		e.Extendable = extendable(e.TcgMap)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result, nil
+}
+
+// GetAllDigests returns the digest and tcg map of every RTMR the kernel supports, in index
+// order. Registers without an entry are bound to new entries, which are left in place. The
+// scan ends when the kernel rejects an index as past the last RTMR.
+func GetAllDigests(client configfsi.Client, opts ...Option) ([]*Response, error) {
+	o := makeOptions(opts)
+	var result []*Response
+	for index := 0; index < maxRtmrs; index++ {
+		r, err := getRtmrInterface(client, index, o)
+		if index > 0 && errors.Is(err, errNoRtmr) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp, err := r.response()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp)
+	}
+	return result, nil
+}
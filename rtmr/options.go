@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// options holds the optional configuration of rtmr operations.
+type options struct {
+	logger    configfsi.Logger
+	entryName func(index int) string
+	// ctx is checked between configfs operations. It is nil when there is no context.
+	ctx context.Context
+	// retries is the number of additional attempts to find or create an entry when the
+	// index is busy.
+	retries int
+	backoff time.Duration
+	// tracer traces operations as children of the span in traceCtx if not nil.
+	tracer   configfsi.ContextTracer
+	traceCtx context.Context
+}
+
+// Option configures an rtmr operation.
+type Option func(*options)
+
+func makeOptions(opts []Option) *options {
+	result := &options{}
+	for _, opt := range opts {
+		opt(result)
+	}
+	return result
+}
+
+// WithLogger sets the logger that receives debug logs of rtmr entry names and attribute
+// writes.
+func WithLogger(logger configfsi.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// err returns the context's error, if any.
+func (o *options) err() error {
+	if o.ctx == nil {
+		return nil
+	}
+	return o.ctx.Err()
+}
+
+func (o *options) debug(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+// WithEntryName sets how rtmr entries are named when they must be created. Cooperating
+// tools that use the same naming can find each other's entries predictably. Without
+// this option, entries are given a random name with an "rtmr<index>-" prefix.
+func WithEntryName(name func(index int) string) Option {
+	return func(o *options) {
+		o.entryName = name
+	}
+}
+
+// WithRetry sets the number of additional attempts to find or create an rtmr entry when
+// binding its index fails with EBUSY, e.g., because another process registered the index
+// concurrently. The wait before each retry starts at backoff and doubles after each attempt.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// sleep waits for d or until the context is done.
+func (o *options) sleep(d time.Duration) error {
+	if o.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-o.ctx.Done():
+		return o.ctx.Err()
+	}
+}
+
+// WithTracer sets the tracer that traces ExtendDigest and GetDigest as "rtmr.ExtendDigest"
+// and "rtmr.GetDigest" spans, children of the span in ctx, with child spans for entry
+// creation and attribute writes and reads.
+func WithTracer(ctx context.Context, tracer configfsi.ContextTracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+		o.traceCtx = ctx
+	}
+}
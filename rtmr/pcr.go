@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// maxRtmrs bounds the number of RTMR indices probed when looking for a PCR's register.
+const maxRtmrs = 32
+
+// RtmrForPCR returns the index of the RTMR whose tcg_map contains the given PCR.
+// Registers that have no entry yet are bound to entries until one is found or the kernel
+// rejects an index as past the last RTMR. Those entries are left in place, as ExtendDigest
+// leaves the entries it binds, so later calls find them with ListRtmrs.
+func RtmrForPCR(client configfsi.Client, pcr int, opts ...Option) (*Entry, error) {
+	if pcr < 0 {
+		return nil, fmt.Errorf("invalid PCR index %d. Index can only be a non-negative number", pcr)
+	}
+	entries, err := ListRtmrs(client)
+	if err != nil {
+		return nil, err
+	}
+	bound := make(map[int]bool)
+	for _, e := range entries {
+		if e.TcgMap.Contains(pcr) {
+			return e, nil
+		}
+		bound[e.Index] = true
+	}
+	o := makeOptions(opts)
+	for index := 0; index < maxRtmrs; index++ {
+		if bound[index] {
+			continue
+		}
+		r, err := getRtmrInterface(client, index, o)
+		if errors.Is(err, errNoRtmr) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.getTcgMap()
+		if err != nil {
+			return nil, err
+		}
+		m, err := ParseTcgMap(data)
+		if err != nil {
+			return nil, err
+		}
+		if m.Contains(pcr) {
+			return &Entry{Name: r.entry.Entry, Index: index, TcgMap: m, Extendable: extendable(m)}, nil
+		}
+	}
+	return nil, fmt.Errorf("no rtmr is mapped to PCR %d", pcr)
+}
+
+// ExtendPCR extends the SHA-384 digest into the RTMR that the given TPM PCR maps to. PCRs
+// that map to registers which software cannot extend are refused.
+func ExtendPCR(client configfsi.Client, pcr int, digest []byte, opts ...Option) error {
+	e, err := RtmrForPCR(client, pcr, opts...)
+	if err != nil {
+		return err
+	}
+	if !e.Extendable {
+		return fmt.Errorf("PCR %d maps to rtmr%d: %w", pcr, e.Index, ErrNotExtendable)
+	}
+	return ExtendDigest(client, e.Index, digest, opts...)
+}
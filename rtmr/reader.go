@@ -0,0 +1,59 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// hashReader returns the SHA-384 digest of everything read from r.
+func hashReader(r io.Reader) ([]byte, error) {
+	h := sha512.New384()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("could not hash measured content: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// ExtendReader hashes everything read from r with SHA-384 without holding it in memory,
+// extends the digest into the rtmr, and returns the digest for inclusion in an event log.
+// Nothing is extended if reading fails.
+func ExtendReader(client configfsi.Client, rtmr int, r io.Reader, opts ...Option) ([]byte, error) {
+	digest, err := hashReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := ExtendDigest(client, rtmr, digest, opts...); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// ExtendReader hashes everything read from r with SHA-384, extends the digest into the
+// rtmr, and records it with the given content. It returns the digest.
+func (l *EventLog) ExtendReader(client configfsi.Client, rtmr int, r io.Reader, contentType string, content []byte, opts ...Option) ([]byte, error) {
+	digest, err := hashReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.ExtendDigest(client, rtmr, digest, contentType, content, opts...); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
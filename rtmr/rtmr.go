@@ -18,8 +18,12 @@ package rtmr
 
 import (
 	"crypto"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"syscall"
 
 	"github.com/google/go-configfs-tsm/configfs/configfsi"
 )
@@ -40,13 +44,24 @@ type Extend struct {
 	RtmrIndex int
 	entry     *configfsi.TsmPath
 	client    configfsi.Client
+	opts      *options
 }
 
 // Response is a struct that represents the response of reading a rtmr entry in the configfs.
 type Response struct {
 	RtmrIndex int
 	Digest    []byte
-	TcgMap    []byte
+	TcgMap    TcgMap
+}
+
+// DigestHex returns the digest as a lowercase hex string.
+func (r *Response) DigestHex() string {
+	return hex.EncodeToString(r.Digest)
+}
+
+// String returns a human-readable description of the register for logs.
+func (r *Response) String() string {
+	return fmt.Sprintf("rtmr%d digest=%s tcg_map=%q", r.RtmrIndex, r.DigestHex(), r.TcgMap.String())
 }
 
 func (r *Extend) attribute(subtree string) string {
@@ -57,12 +72,32 @@ func (r *Extend) attribute(subtree string) string {
 
 // extendDigest extends the measurement to the rtmr with the given hash.
 func (r *Extend) extendDigest(hash []byte) error {
+	if err := r.opts.err(); err != nil {
+		return err
+	}
 	if err := r.client.WriteFile(r.attribute(tsmRtmrDigest), hash); err != nil {
-		return fmt.Errorf("could not write digest to rmtr%d: %v", r.RtmrIndex, err)
+		err = fmt.Errorf("could not write digest to rmtr%d: %w", r.RtmrIndex, err)
+		if errors.Is(err, os.ErrPermission) && !r.extendable() {
+			// The kernel refuses to extend registers that firmware owns.
+			return &classifiedError{sentinel: ErrNotExtendable, err: err}
+		}
+		return classify(err)
 	}
+	r.opts.debug("extended rtmr", "index", r.RtmrIndex, "entry", r.entry.Entry, "digest", hex.EncodeToString(hash))
 	return nil
 }
 
+// extendable returns whether the rtmr's tcg_map allows software to extend it. It returns
+// true if the tcg_map cannot be read, so that other failures are not mistaken for it.
+func (r *Extend) extendable() bool {
+	data, err := r.getTcgMap()
+	if err != nil {
+		return true
+	}
+	m, err := ParseTcgMap(data)
+	return err != nil || extendable(m)
+}
+
 // getDigest returns the digest of the rtmr.
 func (r *Extend) getDigest() ([]byte, error) {
 	return r.client.ReadFile(r.attribute(tsmRtmrDigest))
@@ -98,26 +133,32 @@ func (r *Extend) setRtmrIndex() error {
 	indexBytes := []byte(strconv.Itoa(r.RtmrIndex)) // Convert index to []byte
 	indexPath := r.attribute(tsmPathIndex)
 	if err := r.client.WriteFile(indexPath, indexBytes); err != nil {
-		return fmt.Errorf("could not write index %s: %v", indexPath, err)
+		return classify(fmt.Errorf("could not write index %s: %w", indexPath, err))
 	}
+	r.opts.debug("set rtmr entry index", "index", r.RtmrIndex, "entry", r.entry.Entry)
 	return nil
 }
 
 // searchRtmrInterface searches for an rtmr entry in the configfs.
-func searchRtmrInterface(client configfsi.Client, index int) *Extend {
+func searchRtmrInterface(client configfsi.Client, index int, opts *options) *Extend {
 	root := tsmRtmrPrefix
 	entries, err := client.ReadDir(root)
 	if err != nil {
 		return nil
 	}
 	for _, d := range entries {
+		if opts.err() != nil {
+			return nil
+		}
 		if d.IsDir() {
 			r := &Extend{
 				RtmrIndex: index,
 				entry:     &configfsi.TsmPath{Subsystem: rtmrSubsystem, Entry: d.Name()},
 				client:    client,
+				opts:      opts,
 			}
 			if r.validateIndex() {
+				opts.debug("found rtmr entry", "index", index, "entry", d.Name())
 				return r
 			}
 		}
@@ -126,46 +167,109 @@ func searchRtmrInterface(client configfsi.Client, index int) *Extend {
 }
 
 // createRtmrInterface creates a new rtmr entry in the configfs.
-func createRtmrInterface(client configfsi.Client, index int) (*Extend, error) {
-	entryPath, err := client.MkdirTemp(tsmRtmrPrefix, fmt.Sprintf("rtmr%d-", index))
+func createRtmrInterface(client configfsi.Client, index int, opts *options) (*Extend, error) {
+	var entryPath string
+	var err error
+	if opts.entryName != nil {
+		p := &configfsi.TsmPath{Subsystem: rtmrSubsystem, Entry: opts.entryName(index)}
+		if p.Entry == "" || p.Validate() != nil {
+			return nil, fmt.Errorf("invalid rtmr entry name %q", p.Entry)
+		}
+		entryPath = p.String()
+		err = configfsi.Mkdir(client, entryPath)
+	} else {
+		entryPath, err = client.MkdirTemp(tsmRtmrPrefix, fmt.Sprintf("rtmr%d-", index))
+	}
 	if err != nil {
 		return nil, err
 	}
 	p, _ := configfsi.ParseTsmPath(entryPath)
+	opts.debug("created rtmr entry", "index", index, "entry", p.Entry)
 
 	r := &Extend{
 		RtmrIndex: index,
 		entry:     &configfsi.TsmPath{Subsystem: rtmrSubsystem, Entry: p.Entry},
 		client:    client,
+		opts:      opts,
 	}
 
 	if err := r.setRtmrIndex(); err != nil {
-		return nil, fmt.Errorf("could not set rtmr index %d: %v", index, err)
+		// Best effort: don't leave an unbound entry behind.
+		_ = client.RemoveAll(entryPath)
+		err = fmt.Errorf("could not set rtmr index %d: %w", index, err)
+		if errors.Is(err, syscall.EINVAL) {
+			// The kernel rejects indices past the last RTMR with EINVAL.
+			err = &classifiedError{sentinel: errNoRtmr, err: err}
+		}
+		return nil, err
 	}
 	return r, nil
 }
 
 // getRtmrInterface returns the rtmr entry in the configfs.
-func getRtmrInterface(client configfsi.Client, index int) (*Extend, error) {
+func getRtmrInterface(client configfsi.Client, index int, opts *options) (*Extend, error) {
 	// The configfs-tsm interface only allows one rtmr entry for a given index.
 	// If the rtmr entry already exists, we should extend the digest to it.
-	var err error
-	r := searchRtmrInterface(client, index)
-	if r == nil {
-		r, err = createRtmrInterface(client, index)
+	// If another process binds the index between the search and the creation, the kernel
+	// reports EBUSY and a retry finds that process's entry.
+	backoff := opts.backoff
+	for attempt := 0; ; attempt++ {
+		if err := opts.err(); err != nil {
+			return nil, err
+		}
+		r := searchRtmrInterface(client, index, opts)
+		if r != nil {
+			return r, nil
+		}
+		if err := opts.err(); err != nil {
+			return nil, err
+		}
+		r, err := createRtmrInterface(client, index, opts)
+		if err == nil || !errors.Is(err, ErrBusy) || attempt >= opts.retries {
+			return r, err
+		}
+		opts.debug("rtmr index busy, retrying", "index", index, "attempt", attempt+1)
+		if err := opts.sleep(backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
 	}
-	return r, err
 }
 
-// ExtendDigest extends the measurement to the rtmr with the given digest.
-func ExtendDigest(client configfsi.Client, rtmr int, digest []byte) error {
-	if len(digest) != crypto.SHA384.Size() {
-		return fmt.Errorf("the length of the digest must be %d bytes, the input is %d bytes", crypto.SHA384.Size(), len(digest))
+// checkExtend returns an error if digest cannot be extended into rtmr with hash.
+func checkExtend(rtmr int, hash crypto.Hash, digest []byte) error {
+	if hash < crypto.MD4 || hash > crypto.BLAKE2b_512 {
+		return fmt.Errorf("unsupported hash algorithm %v", hash)
+	}
+	if len(digest) != hash.Size() {
+		return fmt.Errorf("the length of the digest must be %d bytes, the input is %d bytes: %w",
+			hash.Size(), len(digest), ErrBadDigestLength)
 	}
 	if rtmr < 0 {
 		return fmt.Errorf("invalid rtmr index %d. Index can only be a non-negative number", rtmr)
 	}
-	r, err := getRtmrInterface(client, rtmr)
+	return nil
+}
+
+// ExtendDigest extends the measurement to the rtmr with the given SHA-384 digest.
+func ExtendDigest(client configfsi.Client, rtmr int, digest []byte, opts ...Option) error {
+	return ExtendHashDigest(client, rtmr, crypto.SHA384, digest, opts...)
+}
+
+// ExtendHashDigest extends the measurement to the rtmr with the given digest, which must
+// have the size of the hash algorithm. The algorithm must match the register's bank, which
+// the kernel enforces.
+func ExtendHashDigest(client configfsi.Client, rtmr int, hash crypto.Hash, digest []byte, opts ...Option) (err error) {
+	if err := checkExtend(rtmr, hash, digest); err != nil {
+		return err
+	}
+	o := makeOptions(opts)
+	if o.tracer != nil {
+		var span configfsi.Span
+		client, span = configfsi.StartSpan(o.traceCtx, o.tracer, client, "rtmr.ExtendDigest", "rtmr", rtmr, "hash", hashAlgName(hash))
+		defer func() { span.End(err) }()
+	}
+	r, err := getRtmrInterface(client, rtmr, o)
 	if err != nil {
 		return err
 	}
@@ -173,25 +277,43 @@ func ExtendDigest(client configfsi.Client, rtmr int, digest []byte) error {
 }
 
 // GetDigest returns the digest and the tcg map of a given rtmr index.
-func GetDigest(client configfsi.Client, rtmr int) (*Response, error) {
+func GetDigest(client configfsi.Client, rtmr int, opts ...Option) (resp *Response, err error) {
 	if rtmr < 0 {
 		return nil, fmt.Errorf("invalid rtmr index %d. Index can only be a non-negative number", rtmr)
 	}
-	r, err := getRtmrInterface(client, rtmr)
+	o := makeOptions(opts)
+	if o.tracer != nil {
+		var span configfsi.Span
+		client, span = configfsi.StartSpan(o.traceCtx, o.tracer, client, "rtmr.GetDigest", "rtmr", rtmr)
+		defer func() { span.End(err) }()
+	}
+	r, err := getRtmrInterface(client, rtmr, o)
 	if err != nil {
 		return nil, err
 	}
+	return r.response()
+}
+
+// response returns the current digest and tcg map of the rtmr.
+func (r *Extend) response() (*Response, error) {
+	if err := r.opts.err(); err != nil {
+		return nil, err
+	}
 	digest, err := r.getDigest()
 	if err != nil {
 		return nil, err
 	}
-	tcgmap, err := r.getTcgMap()
+	tcgmapData, err := r.getTcgMap()
+	if err != nil {
+		return nil, err
+	}
+	tcgmap, err := ParseTcgMap(tcgmapData)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Response{
-		RtmrIndex: rtmr,
+		RtmrIndex: r.RtmrIndex,
 		Digest:    digest,
 		TcgMap:    tcgmap,
 	}, nil
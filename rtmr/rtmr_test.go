@@ -16,9 +16,22 @@ package rtmr
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/configfsitest"
 	"github.com/google/go-configfs-tsm/configfs/fakertmr"
 )
 
@@ -85,14 +98,14 @@ func TestGetDigestOk(t *testing.T) {
 	tcsOk := []struct {
 		rtmr   int
 		digest []byte
-		tcgMap []byte
+		tcgMap string
 	}{
-		{rtmr: 0, digest: sha384Hash[:], tcgMap: []byte("1,7\n")},
-		{rtmr: 1, digest: sha384Hash[:], tcgMap: []byte("2-6\n")},
-		{rtmr: 2, digest: sha384Hash[:], tcgMap: []byte("8-15\n")},
-		{rtmr: 3, digest: sha384Hash[:], tcgMap: []byte("\n")},
+		{rtmr: 0, digest: sha384Hash[:], tcgMap: "1,7"},
+		{rtmr: 1, digest: sha384Hash[:], tcgMap: "2-6"},
+		{rtmr: 2, digest: sha384Hash[:], tcgMap: "8-15"},
+		{rtmr: 3, digest: sha384Hash[:], tcgMap: ""},
 		// Test the same rtmr index with an existing entry.
-		{rtmr: 2, digest: sha384Hash[:], tcgMap: []byte("8-15\n")},
+		{rtmr: 2, digest: sha384Hash[:], tcgMap: "8-15"},
 	}
 	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
 	for _, tc := range tcsOk {
@@ -103,7 +116,7 @@ func TestGetDigestOk(t *testing.T) {
 		if r.RtmrIndex != tc.rtmr {
 			t.Fatalf("GetDigestRtmr(%d) failed: got %d, want %d", tc.rtmr, r.RtmrIndex, tc.rtmr)
 		}
-		if !bytes.Equal(r.TcgMap, tc.tcgMap) {
+		if r.TcgMap.String() != tc.tcgMap {
 			t.Fatalf("GetDigestRtmr(%d) failed: got %q, want %q", tc.rtmr, r.TcgMap, tc.tcgMap)
 		}
 	}
@@ -114,8 +127,8 @@ func TestGetRtmrDigestAndExtendDigest(t *testing.T) {
 	sha384Hash[0] = 0x01
 	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
 	rtmrIndex := 3
-	initRtmrValue := []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
-	extendRtmrValue := []byte("ܮ\x87\xd5n\xa6\x12\x15\xf3#\xa6&0`\xb6\x96(\x02\xb2Po\x80\xbe*\x92\xcb\fJ\x1f\x06\x80\xf0\x9c\x14\xee\xaan\x82\xc9\xfa\x9a\xec\xf9ROeś")
+	initRtmrValue := fakertmr.FoldSHA384()
+	extendRtmrValue := fakertmr.FoldSHA384(sha384Hash[:])
 	// GetDigest
 	digest1, err := GetDigest(client, rtmrIndex)
 	if err != nil {
@@ -137,4 +150,627 @@ func TestGetRtmrDigestAndExtendDigest(t *testing.T) {
 	if !bytes.Equal(digest2.Digest, extendRtmrValue) {
 		t.Fatalf("rtmr%q does not match the expected value: got %q, want %q", rtmrIndex, digest2.Digest, extendRtmrValue)
 	}
+	if err := client.CheckFolded(rtmrIndex, sha384Hash[:]); err != nil {
+		t.Errorf("CheckFolded(%d) = %v. Want nil", rtmrIndex, err)
+	}
+}
+
+func TestParseTcgMap(t *testing.T) {
+	tcs := []struct {
+		input    string
+		wantPCRs []int
+		wantStr  string
+		wantErr  string
+	}{
+		{input: "\n", wantStr: ""},
+		{input: "1,7\n", wantPCRs: []int{1, 7}, wantStr: "1,7"},
+		{input: "2-6", wantPCRs: []int{2, 3, 4, 5, 6}, wantStr: "2-6"},
+		{input: "0,2-3,9", wantPCRs: []int{0, 2, 3, 9}, wantStr: "0,2-3,9"},
+		{input: "6-2", wantErr: "invalid tcg_map range"},
+		{input: "a", wantErr: "invalid tcg_map PCR"},
+	}
+	for _, tc := range tcs {
+		m, err := ParseTcgMap([]byte(tc.input))
+		if (err == nil) != (tc.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("ParseTcgMap(%q) = _, %v, want %q", tc.input, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := m.PCRs(); fmt.Sprint(got) != fmt.Sprint(tc.wantPCRs) && !(len(got) == 0 && len(tc.wantPCRs) == 0) {
+			t.Errorf("ParseTcgMap(%q).PCRs() = %v, want %v", tc.input, got, tc.wantPCRs)
+		}
+		if got := m.String(); got != tc.wantStr {
+			t.Errorf("ParseTcgMap(%q).String() = %q, want %q", tc.input, got, tc.wantStr)
+		}
+		for _, pcr := range tc.wantPCRs {
+			if !m.Contains(pcr) {
+				t.Errorf("ParseTcgMap(%q).Contains(%d) = false, want true", tc.input, pcr)
+			}
+		}
+	}
+}
+
+func TestEventLogReplay(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	log := NewEventLog()
+	digests := [][]byte{
+		bytes.Repeat([]byte{1}, 48),
+		bytes.Repeat([]byte{2}, 48),
+		bytes.Repeat([]byte{3}, 48),
+	}
+	for i, d := range digests {
+		if err := log.ExtendDigest(client, 2+i%2, d, "test", []byte(fmt.Sprintf("event %d", i))); err != nil {
+			t.Fatalf("ExtendDigest(%d) = %v, want nil", i, err)
+		}
+	}
+	if err := log.ExtendDigest(client, 0, digests[0], "test", nil); err == nil {
+		t.Fatalf("ExtendDigest(0) = nil, want error")
+	}
+	events := log.Events()
+	if len(events) != len(digests) {
+		t.Fatalf("Events() has %d events, want %d", len(events), len(digests))
+	}
+	replayed := Replay(events, crypto.SHA384)
+	for _, index := range []int{2, 3} {
+		got, err := GetDigest(client, index)
+		if err != nil {
+			t.Fatalf("GetDigest(%d) = _, %v, want nil", index, err)
+		}
+		if !bytes.Equal(got.Digest, replayed[index]) {
+			t.Errorf("rtmr%d = %x, replayed %x", index, got.Digest, replayed[index])
+		}
+	}
+}
+
+func TestExtendHashDigestErr(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	err := ExtendHashDigest(client, 2, crypto.SHA256, make([]byte, 48))
+	if err == nil || !strings.Contains(err.Error(), "the length of the digest must be 32 bytes") {
+		t.Errorf("ExtendHashDigest(SHA256, [48]) = %v, want digest length error", err)
+	}
+	// The fake only models SHA-384 registers.
+	if err := ExtendHashDigest(client, 2, crypto.SHA256, make([]byte, 32)); err == nil {
+		t.Errorf("ExtendHashDigest(SHA256, [32]) = nil, want error from the SHA-384 bank")
+	}
+	if err := ExtendHashDigest(client, 2, crypto.SHA384, make([]byte, 48)); err != nil {
+		t.Errorf("ExtendHashDigest(SHA384, [48]) = %v, want nil", err)
+	}
+}
+
+func TestListRtmrs(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	for _, index := range []int{3, 0, 2} {
+		if _, err := GetDigest(client, index); err != nil {
+			t.Fatalf("GetDigest(%d) = _, %v, want nil", index, err)
+		}
+	}
+	entries, err := ListRtmrs(client)
+	if err != nil {
+		t.Fatalf("ListRtmrs() = _, %v, want nil", err)
+	}
+	want := []struct {
+		index      int
+		extendable bool
+	}{{0, false}, {2, true}, {3, true}}
+	if len(entries) != len(want) {
+		t.Fatalf("ListRtmrs() = %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		if entries[i].Index != w.index || entries[i].Extendable != w.extendable {
+			t.Errorf("ListRtmrs()[%d] = %+v, want index %d extendable %v", i, entries[i], w.index, w.extendable)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	log := NewEventLog()
+	if err := log.ExtendDigest(client, 2, bytes.Repeat([]byte{1}, 48), "test", nil); err != nil {
+		t.Fatalf("ExtendDigest(2) = %v, want nil", err)
+	}
+	if err := log.ExtendDigest(client, 3, bytes.Repeat([]byte{2}, 48), "test", nil); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	// An unlogged extend makes rtmr3 diverge from the log.
+	if err := ExtendDigest(client, 3, bytes.Repeat([]byte{3}, 48)); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	results, err := Verify(client, log.Events())
+	if err != nil {
+		t.Fatalf("Verify() = _, %v, want nil", err)
+	}
+	if len(results) != 2 || results[0].Index != 2 || !results[0].Match || results[1].Index != 3 || results[1].Match {
+		t.Errorf("Verify() = %+v, %+v, want rtmr2 to match and rtmr3 to mismatch", results[0], results[1])
+	}
+}
+
+func TestExtendPCR(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	digest := bytes.Repeat([]byte{1}, 48)
+	if err := ExtendPCR(client, 10, digest); err != nil {
+		t.Fatalf("ExtendPCR(10) = %v, want nil", err)
+	}
+	resp, err := GetDigest(client, 2)
+	if err != nil {
+		t.Fatalf("GetDigest(2) = _, %v, want nil", err)
+	}
+	if want := Replay([]Event{{Index: 2, Digests: []EventDigest{{HashAlg: "sha384", Digest: digest}}}}, crypto.SHA384)[2]; !bytes.Equal(resp.Digest, want) {
+		t.Errorf("rtmr2 = %x, want %x", resp.Digest, want)
+	}
+	if err := ExtendPCR(client, 4, digest); err == nil || !strings.Contains(err.Error(), "not extendable") {
+		t.Errorf("ExtendPCR(4) = %v, want not extendable error", err)
+	}
+	if err := ExtendPCR(client, 20, digest); err == nil || !strings.Contains(err.Error(), "no rtmr is mapped") {
+		t.Errorf("ExtendPCR(20) = %v, want unmapped error", err)
+	}
+}
+
+// mkdirFailingClient fails to create entries with err.
+type mkdirFailingClient struct {
+	configfsi.Client
+	err error
+}
+
+func (c *mkdirFailingClient) MkdirTemp(string, string) (string, error) {
+	return "", c.err
+}
+
+func TestScanErrors(t *testing.T) {
+	fake := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	if _, err := GetDigest(fake, 0); err != nil {
+		t.Fatalf("GetDigest(0) = _, %v, want nil", err)
+	}
+	// rtmr0 is bound, so the scans fail creating an entry for rtmr1.
+	client := &mkdirFailingClient{Client: fake, err: syscall.EACCES}
+	// Only the kernel's rejection of an index past the last RTMR ends the scan quietly.
+	if _, err := RtmrForPCR(client, 20); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("RtmrForPCR(20) = _, %v, want EACCES", err)
+	}
+	if _, err := GetAllDigests(client); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("GetAllDigests() = _, %v, want EACCES", err)
+	}
+}
+
+func TestExtendMeasurements(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	var events []Event
+	var measurements []Measurement
+	for i := 0; i < 10; i++ {
+		m := Measurement{Index: 2 + i%2, Digest: bytes.Repeat([]byte{byte(i)}, 48)}
+		measurements = append(measurements, m)
+		events = append(events, Event{Index: m.Index, Digests: []EventDigest{{HashAlg: "sha384", Digest: m.Digest}}})
+	}
+	if err := ExtendMeasurements(client, measurements); err != nil {
+		t.Fatalf("ExtendMeasurements() = %v, want nil", err)
+	}
+	results, err := Verify(client, events)
+	if err != nil {
+		t.Fatalf("Verify() = _, %v, want nil", err)
+	}
+	for _, r := range results {
+		if !r.Match {
+			t.Errorf("rtmr%d = %x, want %x", r.Index, r.Actual, r.Expected)
+		}
+	}
+	err = ExtendDigests(client, 3, [][]byte{make([]byte, 48), make([]byte, 2)})
+	if err == nil || !strings.Contains(err.Error(), "measurement 1") {
+		t.Errorf("ExtendDigests(3, bad digest) = %v, want measurement 1 error", err)
+	}
+}
+
+// readDirCounter counts ReadDir calls on a client.
+type readDirCounter struct {
+	configfsi.Client
+	readDirs int
+}
+
+func (c *readDirCounter) ReadDir(dirname string) ([]os.DirEntry, error) {
+	c.readDirs++
+	return c.Client.ReadDir(dirname)
+}
+
+func TestClientCachesEntries(t *testing.T) {
+	counter := &readDirCounter{Client: fakertmr.CreateRtmrSubsystem(t.TempDir())}
+	c := NewClient(counter)
+	for i := 0; i < 5; i++ {
+		if err := c.ExtendDigest(2, bytes.Repeat([]byte{byte(i)}, 48)); err != nil {
+			t.Fatalf("ExtendDigest(2) = %v, want nil", err)
+		}
+	}
+	resp, err := c.GetDigest(2)
+	if err != nil {
+		t.Fatalf("GetDigest(2) = _, %v, want nil", err)
+	}
+	if resp.TcgMap.String() != "8-15" {
+		t.Errorf("GetDigest(2).TcgMap = %q, want %q", resp.TcgMap, "8-15")
+	}
+	if counter.readDirs != 1 {
+		t.Errorf("Client searched configfs %d times, want 1", counter.readDirs)
+	}
+}
+
+// writeFailingClient fails to write digests with err.
+type writeFailingClient struct {
+	configfsi.Client
+	err error
+}
+
+func (c *writeFailingClient) WriteFile(name string, content []byte) error {
+	if strings.HasSuffix(name, "/"+tsmRtmrDigest) {
+		return c.err
+	}
+	return c.Client.WriteFile(name, content)
+}
+
+func TestErrorValues(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	if err := ExtendDigest(client, 1, make([]byte, 48)); !errors.Is(err, ErrNotExtendable) {
+		t.Errorf("ExtendDigest(1) = %v, want ErrNotExtendable", err)
+	}
+	if err := ExtendDigest(client, 2, make([]byte, 4)); !errors.Is(err, ErrBadDigestLength) {
+		t.Errorf("ExtendDigest(2, [4]) = %v, want ErrBadDigestLength", err)
+	}
+	if err := ExtendPCR(client, 7, make([]byte, 48)); !errors.Is(err, ErrNotExtendable) {
+		t.Errorf("ExtendPCR(7) = %v, want ErrNotExtendable", err)
+	}
+	// Permission errors on registers that software may extend are not ErrNotExtendable.
+	denied := &writeFailingClient{Client: client, err: syscall.EACCES}
+	if err := ExtendDigest(denied, 2, make([]byte, 48)); !errors.Is(err, ErrPermission) || errors.Is(err, ErrNotExtendable) {
+		t.Errorf("ExtendDigest(2) without privileges = %v, want ErrPermission", err)
+	}
+	// Binding a second entry to an already bound index is busy.
+	if _, err := GetDigest(client, 2); err != nil {
+		t.Fatalf("GetDigest(2) = _, %v, want nil", err)
+	}
+	r, err := createRtmrInterface(client, 2, &options{})
+	if !errors.Is(err, ErrBusy) {
+		t.Errorf("createRtmrInterface(2) = %v, %v, want ErrBusy", r, err)
+	}
+}
+
+func TestGetAllDigests(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	digest := bytes.Repeat([]byte{1}, 48)
+	if err := ExtendDigest(client, 3, digest); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	resps, err := GetAllDigests(client)
+	if err != nil {
+		t.Fatalf("GetAllDigests() = _, %v, want nil", err)
+	}
+	if len(resps) != 4 {
+		t.Fatalf("GetAllDigests() returned %d registers, want 4", len(resps))
+	}
+	want := Replay([]Event{{Index: 3, Digests: []EventDigest{{HashAlg: "sha384", Digest: digest}}}}, crypto.SHA384)
+	for i, resp := range resps {
+		if resp.RtmrIndex != i {
+			t.Errorf("GetAllDigests()[%d].RtmrIndex = %d", i, resp.RtmrIndex)
+		}
+		wantDigest, ok := want[i]
+		if !ok {
+			wantDigest = make([]byte, 48)
+		}
+		if !bytes.Equal(resp.Digest, wantDigest) {
+			t.Errorf("GetAllDigests()[%d].Digest = %x, want %x", i, resp.Digest, wantDigest)
+		}
+	}
+}
+
+func TestWithEntryName(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	name := WithEntryName(func(index int) string { return fmt.Sprintf("shared-rtmr%d", index) })
+	if err := ExtendDigest(client, 2, make([]byte, 48), name); err != nil {
+		t.Fatalf("ExtendDigest(2) = %v, want nil", err)
+	}
+	entries, err := ListRtmrs(client)
+	if err != nil {
+		t.Fatalf("ListRtmrs() = _, %v, want nil", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "shared-rtmr2" {
+		t.Errorf("ListRtmrs() = %v, want a single entry named shared-rtmr2", entries)
+	}
+	bad := WithEntryName(func(int) string { return "../escape" })
+	if err := ExtendDigest(client, 3, make([]byte, 48), bad); err == nil {
+		t.Errorf("ExtendDigest(3) with entry name ../escape = nil, want error")
+	}
+}
+
+func TestResponseString(t *testing.T) {
+	r := &Response{RtmrIndex: 2, Digest: []byte{0xab, 0x01}}
+	r.TcgMap, _ = ParseTcgMap([]byte("8-15\n"))
+	want := `rtmr2 digest=ab01 tcg_map="8-15"`
+	if got := r.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCCEventsRoundTrip(t *testing.T) {
+	events := []Event{
+		{Index: 2, Digests: []EventDigest{{HashAlg: "sha384", Digest: bytes.Repeat([]byte{1}, 48)}}, Content: []byte("one")},
+		{RecNum: 1, Index: 3, Digests: []EventDigest{{HashAlg: "sha384", Digest: bytes.Repeat([]byte{2}, 48)}}, Content: []byte("two")},
+	}
+	data, err := MarshalCCEvents(events)
+	if err != nil {
+		t.Fatalf("MarshalCCEvents() = _, %v, want nil", err)
+	}
+	got, err := ParseCCEventLog(data)
+	if err != nil {
+		t.Fatalf("ParseCCEventLog() = _, %v, want nil", err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("ParseCCEventLog() returned %d events, want %d", len(got), len(events))
+	}
+	for i := range events {
+		if got[i].Index != events[i].Index || got[i].RecNum != events[i].RecNum ||
+			got[i].EventType != EventTypeEventTag || !bytes.Equal(got[i].Content, events[i].Content) ||
+			!bytes.Equal(got[i].Digests[0].Digest, events[i].Digests[0].Digest) {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], events[i])
+		}
+	}
+	if err := func() error { _, err := ParseCCEventLog(data[:len(data)-1]); return err }(); err == nil {
+		t.Errorf("ParseCCEventLog(truncated) = _, nil, want error")
+	}
+
+	// A firmware log starts with a Spec ID event declaring its digest algorithms.
+	var specID bytes.Buffer
+	specID.WriteString("Spec ID Event03\x00")
+	binary.Write(&specID, binary.LittleEndian, []byte{0, 0, 0, 0, 0, 2, 0, 2})
+	binary.Write(&specID, binary.LittleEndian, []uint32{1})
+	binary.Write(&specID, binary.LittleEndian, []uint16{0x000C, 48})
+	specID.WriteByte(0)
+	var log bytes.Buffer
+	binary.Write(&log, binary.LittleEndian, []uint32{0, EventTypeNoAction})
+	log.Write(make([]byte, 20))
+	binary.Write(&log, binary.LittleEndian, uint32(specID.Len()))
+	log.Write(specID.Bytes())
+	log.Write(data)
+	got, err = ParseCCEventLog(log.Bytes())
+	if err != nil || len(got) != len(events) {
+		t.Errorf("ParseCCEventLog(with Spec ID) = %d events, %v, want %d events", len(got), err, len(events))
+	}
+}
+
+// specIDLog returns a log that starts with a Spec ID event of the given contents whose
+// header declares size bytes.
+func specIDLog(event []byte, size uint32) []byte {
+	var log bytes.Buffer
+	binary.Write(&log, binary.LittleEndian, []uint32{0, EventTypeNoAction})
+	log.Write(make([]byte, 20))
+	binary.Write(&log, binary.LittleEndian, size)
+	log.Write(event)
+	return log.Bytes()
+}
+
+func TestParseCCEventLogBadSpecID(t *testing.T) {
+	signature := []byte("Spec ID Event03\x00")
+	header := append(append([]byte{}, signature...), 0, 0, 0, 0, 0, 2, 0, 2)
+	tests := []struct {
+		name  string
+		event []byte
+		size  uint32
+	}{
+		{"signature only", signature, uint32(len(signature))},
+		{"no algorithm count", header, uint32(len(header))},
+		{"short algorithm count", append(header, 1, 0), uint32(len(header) + 2)},
+		{"truncated", append(header, 1, 0, 0, 0), 0xFFFFFFF0},
+		{"missing algorithms", append(header, 2, 0, 0, 0, 0x0C, 0), uint32(len(header) + 6)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseCCEventLog(specIDLog(tc.event, tc.size)); err == nil {
+				t.Error("ParseCCEventLog() = _, nil, want error")
+			}
+		})
+	}
+}
+
+func FuzzParseCCEventLog(f *testing.F) {
+	data, _ := MarshalCCEvents([]Event{{Index: 2, Digests: []EventDigest{{HashAlg: "sha384", Digest: make([]byte, 48)}}, Content: []byte("one")}})
+	f.Add(data)
+	f.Add(specIDLog([]byte("Spec ID Event03\x00"), 20))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseCCEventLog(data)
+	})
+}
+
+func TestExtendReader(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	content := strings.Repeat("layer data ", 100000)
+	digest, err := ExtendReader(client, 2, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ExtendReader(2) = _, %v, want nil", err)
+	}
+	want := sha512.Sum384([]byte(content))
+	if !bytes.Equal(digest, want[:]) {
+		t.Errorf("ExtendReader(2) = %x, want %x", digest, want)
+	}
+	if _, err := ExtendReader(client, 2, iotest.ErrReader(errors.New("boom"))); err == nil {
+		t.Errorf("ExtendReader(2, failing reader) = _, nil, want error")
+	}
+}
+
+func TestCompareQuoteRtmrs(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	if err := ExtendDigest(client, 2, bytes.Repeat([]byte{1}, 48)); err != nil {
+		t.Fatalf("ExtendDigest(2) = %v, want nil", err)
+	}
+	live, err := GetAllDigests(client)
+	if err != nil {
+		t.Fatalf("GetAllDigests() = _, %v, want nil", err)
+	}
+	quote := make([]byte, 1024)
+	for i, resp := range live {
+		copy(quote[48+328+48*i:], resp.Digest)
+	}
+	// Extending after the quote makes rtmr3 diverge.
+	if err := ExtendDigest(client, 3, bytes.Repeat([]byte{2}, 48)); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	results, err := CompareQuoteRtmrs(client, quote)
+	if err != nil {
+		t.Fatalf("CompareQuoteRtmrs() = _, %v, want nil", err)
+	}
+	for _, r := range results {
+		if wantMatch := r.Index != 3; r.Match != wantMatch {
+			t.Errorf("rtmr%d match = %v, want %v", r.Index, r.Match, wantMatch)
+		}
+	}
+	if _, err := CompareQuoteRtmrs(client, quote[:100]); err == nil {
+		t.Errorf("CompareQuoteRtmrs(short quote) = _, nil, want error")
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := ExtendDigestContext(ctx, client, 2, make([]byte, 48)); err != nil {
+		t.Fatalf("ExtendDigestContext(2) = %v, want nil", err)
+	}
+	cancel()
+	if err := ExtendDigestContext(ctx, client, 2, make([]byte, 48)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtendDigestContext(canceled) = %v, want context.Canceled", err)
+	}
+	if _, err := GetDigestContext(ctx, client, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetDigestContext(canceled) = _, %v, want context.Canceled", err)
+	}
+}
+
+func TestContextOptionsCopies(t *testing.T) {
+	opts := make([]Option, 0, 1)
+	if _, err := GetDigestContext(context.Background(), fakertmr.CreateRtmrSubsystem(t.TempDir()), 2, opts...); err != nil {
+		t.Fatalf("GetDigestContext(2) = _, %v, want nil", err)
+	}
+	if spare := opts[:1]; spare[0] != nil {
+		t.Error("GetDigestContext() wrote its context option into the caller's options slice")
+	}
+}
+
+// racingClient hides existing entries from the first search, as if another process bound
+// the index concurrently.
+type racingClient struct {
+	configfsi.Client
+	hidden int
+}
+
+func (c *racingClient) ReadDir(dirname string) ([]os.DirEntry, error) {
+	if c.hidden > 0 {
+		c.hidden--
+		return nil, nil
+	}
+	return c.Client.ReadDir(dirname)
+}
+
+func TestExtendRetriesBusyIndex(t *testing.T) {
+	fake := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	if _, err := GetDigest(fake, 2); err != nil {
+		t.Fatalf("GetDigest(2) = _, %v, want nil", err)
+	}
+	if err := ExtendDigest(&racingClient{Client: fake, hidden: 1}, 2, make([]byte, 48)); !errors.Is(err, ErrBusy) {
+		t.Errorf("ExtendDigest(racing) = %v, want ErrBusy", err)
+	}
+	client := &racingClient{Client: fake, hidden: 1}
+	if err := ExtendDigest(client, 2, make([]byte, 48), WithRetry(2, time.Millisecond)); err != nil {
+		t.Errorf("ExtendDigest(racing, WithRetry) = %v, want nil", err)
+	}
+}
+
+func TestSyncBootLog(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	// Stand in for firmware measuring the kernel command line and an initrd.
+	firmware := []Event{
+		{Index: 2, Digests: []EventDigest{{HashAlg: "sha384", Digest: bytes.Repeat([]byte{1}, 48)}}, Content: []byte("cmdline")},
+		{RecNum: 1, Index: 3, Digests: []EventDigest{{HashAlg: "sha384", Digest: bytes.Repeat([]byte{2}, 48)}}, Content: []byte("initrd")},
+	}
+	for _, e := range firmware {
+		if err := ExtendDigest(client, e.Index, e.Digests[0].Digest); err != nil {
+			t.Fatalf("ExtendDigest(%d) = %v, want nil", e.Index, err)
+		}
+	}
+	ccel, err := MarshalCCEvents(firmware)
+	if err != nil {
+		t.Fatalf("MarshalCCEvents() = _, %v, want nil", err)
+	}
+	log, err := SyncBootLog(client, ccel)
+	if err != nil {
+		t.Fatalf("SyncBootLog() = _, %v, want nil", err)
+	}
+	if err := log.ExtendDigest(client, 2, bytes.Repeat([]byte{3}, 48), "test", []byte("runtime")); err != nil {
+		t.Fatalf("ExtendDigest(2) = %v, want nil", err)
+	}
+	events := log.Events()
+	if len(events) != 3 || events[2].RecNum != 2 {
+		t.Fatalf("Events() = %+v, want the firmware events followed by recnum 2", events)
+	}
+	results, err := Verify(client, events)
+	if err != nil {
+		t.Fatalf("Verify() = _, %v, want nil", err)
+	}
+	for _, result := range results {
+		if !result.Match {
+			t.Errorf("Verify() rtmr%d = %x, want %x", result.Index, result.Actual, result.Expected)
+		}
+	}
+
+	// The runtime extend is not in the firmware log, so rtmr2 no longer replays from it.
+	var mismatch *BootLogMismatchError
+	if _, err := SyncBootLog(client, ccel); !errors.As(err, &mismatch) || len(mismatch.Mismatches) != 1 || mismatch.Mismatches[0].Index != 2 {
+		t.Errorf("SyncBootLog() after an unlogged extend = _, %v, want a mismatch of rtmr2", err)
+	}
+}
+
+func TestContinueEventLog(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	log := NewEventLog()
+	if err := log.ExtendDigest(client, 3, bytes.Repeat([]byte{1}, 48), "test", []byte("first")); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	data, err := log.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() = _, %v, want nil", err)
+	}
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		t.Fatalf("json.Unmarshal() = %v, want nil", err)
+	}
+	continued, err := ContinueEventLog(client, events)
+	if err != nil {
+		t.Fatalf("ContinueEventLog() = _, %v, want nil", err)
+	}
+	if err := continued.ExtendDigest(client, 3, bytes.Repeat([]byte{2}, 48), "test", []byte("second")); err != nil {
+		t.Fatalf("ExtendDigest(3) = %v, want nil", err)
+	}
+	if got := continued.Events(); len(got) != 2 || got[1].RecNum != 1 {
+		t.Errorf("Events() = %+v, want the first event followed by recnum 1", got)
+	}
+	// The first log lacks the second extend.
+	var mismatch *EventLogMismatchError
+	if _, err := ContinueEventLog(client, events); !errors.As(err, &mismatch) || mismatch.Mismatches[0].Index != 3 {
+		t.Errorf("ContinueEventLog(stale log) = _, %v, want a mismatch of rtmr3", err)
+	}
+}
+
+func TestExtendDigestTracer(t *testing.T) {
+	client := fakertmr.CreateRtmrSubsystem(t.TempDir())
+	tracer := &configfsitest.Tracer{}
+	if err := ExtendDigest(client, 2, bytes.Repeat([]byte{1}, 48), WithTracer(context.Background(), tracer)); err != nil {
+		t.Fatalf("ExtendDigest() = %v, want nil", err)
+	}
+	if err := ExtendDigest(client, 0, bytes.Repeat([]byte{1}, 48), WithTracer(context.Background(), tracer)); err == nil {
+		t.Fatalf("ExtendDigest(0) = nil, want error")
+	}
+	spans := tracer.Spans()
+	roots := tracer.Names(-1)
+	if len(roots) != 2 || roots[0] != "rtmr.ExtendDigest" || spans[0].Attributes["rtmr"] != 2 {
+		t.Fatalf("root spans = %v, want two rtmr.ExtendDigest spans", roots)
+	}
+	if children := tracer.Names(0); len(children) == 0 || children[len(children)-1] != "configfs.WriteFile" {
+		t.Errorf("children of the first extend = %v, want the digest write last", children)
+	}
+	for i, s := range spans {
+		if s.Name == "rtmr.ExtendDigest" && i != 0 && s.Err == nil {
+			t.Errorf("second rtmr.ExtendDigest span = %+v, want its error", s)
+		}
+	}
 }
@@ -0,0 +1,196 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TCG event types used in conversions. See the TCG PC Client Platform Firmware Profile.
+const (
+	// EventTypeNoAction marks events that are not extended, such as the Spec ID event.
+	EventTypeNoAction uint32 = 0x00000003
+	// EventTypeEventTag is the default type for events recorded by this package.
+	EventTypeEventTag uint32 = 0x00000006
+)
+
+// TPM algorithm identifiers of the hash algorithms an event digest may use.
+var tpmAlgIDs = map[string]uint16{
+	"sha1":   0x0004,
+	"sha256": 0x000B,
+	"sha384": 0x000C,
+	"sha512": 0x000D,
+}
+
+func tpmAlgName(id uint16) (string, bool) {
+	for name, algID := range tpmAlgIDs {
+		if algID == id {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// specIDSignature is the signature of the Spec ID event that starts a crypto agile log.
+var specIDSignature = []byte("Spec ID Event03\x00")
+
+// specIDMinSize is the size of a Spec ID event up to its algorithm count: the signature,
+// platformClass (4), spec versions (3), uintnSize (1), and numberOfAlgorithms (4).
+var specIDMinSize = len(specIDSignature) + 8 + 4
+
+// MarshalCCEvents encodes events as TCG_PCR_EVENT2 records in the format of the UEFI
+// confidential computing (CC) event log, so that they can be appended to a firmware log.
+// The CC measurement register index of an RTMR is its index plus one, since index 0 is
+// the build-time measurement (MRTD on TDX).
+func MarshalCCEvents(events []Event) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, e := range events {
+		eventType := e.EventType
+		if eventType == 0 {
+			eventType = EventTypeEventTag
+		}
+		binary.Write(&buf, binary.LittleEndian, uint32(e.Index+1))
+		binary.Write(&buf, binary.LittleEndian, eventType)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(e.Digests)))
+		for _, d := range e.Digests {
+			id, ok := tpmAlgIDs[d.HashAlg]
+			if !ok {
+				return nil, fmt.Errorf("event %d: unsupported hash algorithm %q", e.RecNum, d.HashAlg)
+			}
+			binary.Write(&buf, binary.LittleEndian, id)
+			buf.Write(d.Digest)
+		}
+		binary.Write(&buf, binary.LittleEndian, uint32(len(e.Content)))
+		buf.Write(e.Content)
+	}
+	return buf.Bytes(), nil
+}
+
+// readSpecID reads the legacy-format Spec ID event that starts a crypto agile event log
+// and returns the digest size of each algorithm the log uses.
+func readSpecID(r *bytes.Reader) (map[uint16]int, error) {
+	var header struct {
+		MrIndex   uint32
+		EventType uint32
+		Digest    [20]byte
+		EventSize uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("could not read event log header: %w", err)
+	}
+	if header.EventType != EventTypeNoAction {
+		return nil, fmt.Errorf("event log does not start with a Spec ID event")
+	}
+	// The event is untrusted input, so check its size before allocating or slicing it.
+	if header.EventSize < uint32(specIDMinSize) {
+		return nil, fmt.Errorf("event log Spec ID event is %d bytes, want at least %d", header.EventSize, specIDMinSize)
+	}
+	if int64(header.EventSize) > int64(r.Len()) {
+		return nil, errors.New("event log Spec ID event is truncated")
+	}
+	event := make([]byte, header.EventSize)
+	if _, err := io.ReadFull(r, event); err != nil {
+		return nil, fmt.Errorf("could not read Spec ID event: %w", err)
+	}
+	if !bytes.HasPrefix(event, specIDSignature) {
+		return nil, fmt.Errorf("event log Spec ID event has an unexpected signature")
+	}
+	// Skip platformClass (4), spec versions (3), and uintnSize (1).
+	er := bytes.NewReader(event[len(specIDSignature)+8:])
+	var count uint32
+	if err := binary.Read(er, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("could not read Spec ID algorithm count: %w", err)
+	}
+	sizes := make(map[uint16]int)
+	for i := uint32(0); i < count; i++ {
+		var alg struct {
+			ID   uint16
+			Size uint16
+		}
+		if err := binary.Read(er, binary.LittleEndian, &alg); err != nil {
+			return nil, fmt.Errorf("could not read Spec ID algorithm: %w", err)
+		}
+		sizes[alg.ID] = int(alg.Size)
+	}
+	return sizes, nil
+}
+
+// ParseCCEventLog decodes a UEFI CC event log, such as /sys/firmware/acpi/tables/data/CCEL,
+// into events for RTMRs. Events for measurement register 0 (MRTD) and events that are not
+// extended are skipped. Digests with algorithms this package does not know are dropped.
+// If the log does not begin with a Spec ID event, it is treated as a sequence of
+// SHA-384-only records as produced by MarshalCCEvents.
+func ParseCCEventLog(data []byte) ([]Event, error) {
+	r := bytes.NewReader(data)
+	sizes := map[uint16]int{tpmAlgIDs["sha384"]: 48}
+	if len(data) >= 32+len(specIDSignature) && bytes.HasPrefix(data[32:], specIDSignature) {
+		var err error
+		if sizes, err = readSpecID(r); err != nil {
+			return nil, err
+		}
+	}
+	var events []Event
+	for r.Len() > 0 {
+		var header struct {
+			MrIndex   uint32
+			EventType uint32
+			Count     uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+			return nil, fmt.Errorf("could not read event %d: %w", len(events), err)
+		}
+		if header.MrIndex == 0xFFFFFFFF && header.EventType == 0xFFFFFFFF {
+			// Unused space at the end of a preallocated log.
+			break
+		}
+		e := Event{Index: int(header.MrIndex) - 1, EventType: header.EventType, ContentType: "tcg2"}
+		for i := uint32(0); i < header.Count; i++ {
+			var id uint16
+			if err := binary.Read(r, binary.LittleEndian, &id); err != nil {
+				return nil, fmt.Errorf("could not read event %d digest: %w", len(events), err)
+			}
+			size, ok := sizes[id]
+			if !ok {
+				return nil, fmt.Errorf("event %d uses algorithm %#x not declared by the log", len(events), id)
+			}
+			digest := make([]byte, size)
+			if _, err := io.ReadFull(r, digest); err != nil {
+				return nil, fmt.Errorf("could not read event %d digest: %w", len(events), err)
+			}
+			if name, ok := tpmAlgName(id); ok {
+				e.Digests = append(e.Digests, EventDigest{HashAlg: name, Digest: digest})
+			}
+		}
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("could not read event %d size: %w", len(events), err)
+		}
+		if int64(size) > int64(r.Len()) {
+			return nil, errors.New("event log is truncated")
+		}
+		e.Content = make([]byte, size)
+		io.ReadFull(r, e.Content)
+		if header.MrIndex == 0 || header.EventType == EventTypeNoAction {
+			continue
+		}
+		e.RecNum = uint64(len(events))
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TcgMap is the set of TCG TPM PCR indices that an RTMR is architecturally mapped to.
+type TcgMap struct {
+	pcrs []int
+}
+
+// ParseTcgMap parses the kernel's tcg_map attribute format, which is a comma-separated list
+// of PCR indices and inclusive ranges such as "1,7" or "2-6", with an optional trailing
+// newline. An empty list means the RTMR maps to no PCRs.
+func ParseTcgMap(data []byte) (TcgMap, error) {
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return TcgMap{}, nil
+	}
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(text, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.ParseUint(lo, 10, 31)
+		if err != nil {
+			return TcgMap{}, fmt.Errorf("invalid tcg_map PCR %q in %q: %v", lo, text, err)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.ParseUint(hi, 10, 31)
+			if err != nil {
+				return TcgMap{}, fmt.Errorf("invalid tcg_map PCR %q in %q: %v", hi, text, err)
+			}
+			if last < first {
+				return TcgMap{}, fmt.Errorf("invalid tcg_map range %q in %q", part, text)
+			}
+		}
+		for pcr := first; pcr <= last; pcr++ {
+			seen[int(pcr)] = true
+		}
+	}
+	result := TcgMap{}
+	for pcr := range seen {
+		result.pcrs = append(result.pcrs, pcr)
+	}
+	sort.Ints(result.pcrs)
+	return result, nil
+}
+
+// PCRs returns the mapped PCR indices in increasing order.
+func (m TcgMap) PCRs() []int {
+	return append([]int(nil), m.pcrs...)
+}
+
+// Contains returns whether the given PCR is mapped.
+func (m TcgMap) Contains(pcr int) bool {
+	i := sort.SearchInts(m.pcrs, pcr)
+	return i < len(m.pcrs) && m.pcrs[i] == pcr
+}
+
+// Empty returns whether no PCRs are mapped.
+func (m TcgMap) Empty() bool {
+	return len(m.pcrs) == 0
+}
+
+// String returns the map in the kernel's list format without a trailing newline.
+func (m TcgMap) String() string {
+	var parts []string
+	for i := 0; i < len(m.pcrs); {
+		j := i
+		for j+1 < len(m.pcrs) && m.pcrs[j+1] == m.pcrs[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(m.pcrs[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", m.pcrs[i], m.pcrs[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/internal/tdx"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// TdxQuoteRtmrs returns the RTMR values in the TD report body of a TDX quote.
+func TdxQuoteRtmrs(quote []byte) ([][]byte, error) {
+	end := tdx.RtmrsOffset + tdx.RtmrCount*tdx.RtmrSize
+	if len(quote) < end {
+		return nil, fmt.Errorf("TDX quote of %d bytes is too short to contain RTMRs", len(quote))
+	}
+	var result [][]byte
+	for i := 0; i < tdx.RtmrCount; i++ {
+		offset := tdx.RtmrsOffset + i*tdx.RtmrSize
+		result = append(result, bytes.Clone(quote[offset:offset+tdx.RtmrSize]))
+	}
+	return result, nil
+}
+
+// CompareQuoteRtmrs compares the RTMR values in a TDX quote against the digests currently
+// readable through configfs. Each result's Expected value is from the quote. A mismatch
+// means the interfaces diverge, or that a register was extended after the quote was
+// generated.
+func CompareQuoteRtmrs(client configfsi.Client, quote []byte, opts ...Option) ([]*VerifyResult, error) {
+	quoted, err := TdxQuoteRtmrs(quote)
+	if err != nil {
+		return nil, err
+	}
+	var results []*VerifyResult
+	for index, value := range quoted {
+		resp, err := GetDigest(client, index, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &VerifyResult{
+			Index:    index,
+			Expected: value,
+			Actual:   resp.Digest,
+			Match:    bytes.Equal(value, resp.Digest),
+		})
+	}
+	return results, nil
+}
+
+// CheckTdxQuote fetches a TDX quote for inblob through the report subsystem and compares
+// its RTMR values against the digests readable through the rtmr subsystem.
+func CheckTdxQuote(client configfsi.Client, inblob []byte, opts ...Option) (*report.Response, []*VerifyResult, error) {
+	resp, err := report.Get(client, report.NewRequest(inblob,
+		report.WithProviderExpectation(report.ProviderTdxGuest)))
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := CompareQuoteRtmrs(client, resp.OutBlob, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, results, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtmr
+
+import (
+	"bytes"
+	"crypto"
+	"sort"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// VerifyResult is the outcome of comparing a replayed event log against one RTMR.
+type VerifyResult struct {
+	Index int
+	// Expected is the register value the event log replays to.
+	Expected []byte
+	// Actual is the register value read from configfs.
+	Actual []byte
+	// Match is true if Expected and Actual are equal.
+	Match bool
+}
+
+// Verify replays the SHA-384 digests in events and compares the results against the
+// current value of each RTMR that events extend. The results are sorted by index. An
+// error is only returned if a register cannot be read; mismatches are reported in the
+// results.
+//
+// Replay assumes registers start at all zeros, so events must include every extend since
+// the register was reset.
+func Verify(client configfsi.Client, events []Event, opts ...Option) ([]*VerifyResult, error) {
+	expected := Replay(events, crypto.SHA384)
+	var indices []int
+	for index := range expected {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	var results []*VerifyResult
+	for _, index := range indices {
+		resp, err := GetDigest(client, index, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &VerifyResult{
+			Index:    index,
+			Expected: expected[index],
+			Actual:   resp.Digest,
+			Match:    bytes.Equal(expected[index], resp.Digest),
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcserver serves the TSM service of tsmpb/tsm.proto over gRPC, so that
+// unprivileged workloads in a confidential VM can get reports and extend RTMRs through a
+// single privileged agent. Like the server package's net/rpc service, each connection's
+// peer is authorized by its SO_PEERCRED credentials against a server.Policy.
+//
+// It is a separate module so that the core module does not depend on gRPC.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/rtmr"
+	"github.com/google/go-configfs-tsm/server"
+	"github.com/google/go-configfs-tsm/server/grpcserver/tsmpb"
+)
+
+// AuthInfo is the credentials.AuthInfo of a peer connected by a Unix socket.
+type AuthInfo struct {
+	credentials.CommonAuthInfo
+	// Cred is the peer's SO_PEERCRED credentials.
+	Cred *broker.Cred
+}
+
+// AuthType returns "peercred".
+func (*AuthInfo) AuthType() string { return "peercred" }
+
+// peerCredentials are transport credentials that record the SO_PEERCRED credentials of
+// Unix socket peers. The transport itself is not encrypted, since it does not leave the
+// machine.
+type peerCredentials struct {
+	credentials.TransportCredentials
+}
+
+// PeerCredentials returns server transport credentials that record the SO_PEERCRED
+// credentials of each peer for authorization.
+func PeerCredentials() credentials.TransportCredentials {
+	return &peerCredentials{TransportCredentials: insecure.NewCredentials()}
+}
+
+// ServerHandshake records the peer's credentials. Peers not connected by a Unix socket
+// have no credentials.
+func (c *peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	info := &AuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}
+	if uc, ok := conn.(*net.UnixConn); ok {
+		cred, err := broker.PeerCred(uc)
+		if err != nil {
+			return nil, nil, err
+		}
+		info.Cred = cred
+	}
+	return conn, info, nil
+}
+
+// Clone returns a copy of the credentials.
+func (c *peerCredentials) Clone() credentials.TransportCredentials {
+	return PeerCredentials()
+}
+
+// Service implements tsmpb.TSMServer against a configfsi.Client.
+type Service struct {
+	tsmpb.UnimplementedTSMServer
+	client configfsi.Client
+	policy server.Policy
+}
+
+// NewService returns a Service backed by client that authorizes peers with policy. A nil
+// policy admits only root.
+func NewService(client configfsi.Client, policy server.Policy) *Service {
+	if policy == nil {
+		policy = server.AllowUIDs(nil, nil)
+	}
+	return &Service{client: client, policy: policy}
+}
+
+// authorize checks the policy for the peer of ctx.
+func (s *Service) authorize(ctx context.Context, method string) error {
+	var cred *broker.Cred
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(*AuthInfo); ok {
+			cred = info.Cred
+		}
+	}
+	if err := s.policy(cred, method); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// GetReport is the RPC for report.Get.
+func (s *Service) GetReport(ctx context.Context, req *tsmpb.GetReportRequest) (*tsmpb.GetReportResponse, error) {
+	if err := s.authorize(ctx, server.MethodGetReport); err != nil {
+		return nil, err
+	}
+	r := &report.Request{
+		InBlob:                 req.GetInblob(),
+		GetAuxBlob:             req.GetGetAuxblob(),
+		ServiceProvider:        req.GetServiceProvider(),
+		ServiceGuid:            req.GetServiceGuid(),
+		ServiceManifestVersion: req.GetServiceManifestVersion(),
+		ExpectedProvider:       req.GetExpectedProvider(),
+	}
+	if req.Privilege != nil {
+		r.Privilege = &report.Privilege{Level: uint(req.GetPrivilege())}
+	}
+	resp, err := report.Get(s.client, server.PeerRequest(r))
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	out := &tsmpb.GetReportResponse{
+		Provider:         resp.Provider,
+		Outblob:          resp.OutBlob,
+		Auxblob:          resp.AuxBlob,
+		Manifestblob:     resp.ManifestBlob,
+		PrivilegeClamped: resp.PrivilegeClamped,
+		Generation:       resp.Generation,
+	}
+	if resp.Privilege != nil {
+		level := uint32(resp.Privilege.Level)
+		out.Privilege = &level
+	}
+	return out, nil
+}
+
+// ExtendRtmr is the RPC for rtmr.ExtendDigest.
+func (s *Service) ExtendRtmr(ctx context.Context, req *tsmpb.ExtendRtmrRequest) (*tsmpb.ExtendRtmrResponse, error) {
+	if err := s.authorize(ctx, server.MethodExtendRtmr); err != nil {
+		return nil, err
+	}
+	if err := rtmr.ExtendDigest(s.client, int(req.GetIndex()), req.GetDigest()); err != nil {
+		return nil, errorStatus(err)
+	}
+	return &tsmpb.ExtendRtmrResponse{}, nil
+}
+
+// errorStatus returns err as a status with the closest code.
+func errorStatus(err error) error {
+	var privErr *report.PrivilegeErr
+	switch {
+	case errors.As(err, &privErr), errors.Is(err, rtmr.ErrBadDigestLength):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, rtmr.ErrNotExtendable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, rtmr.ErrPermission):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, rtmr.ErrBusy):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// NewServer returns a gRPC server with the TSM service backed by client, which authorizes
+// peers with policy. A nil policy admits only root.
+func NewServer(client configfsi.Client, policy server.Policy, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(PeerCredentials())}, opts...)...)
+	tsmpb.RegisterTSMServer(s, NewService(client, policy))
+	return s
+}
+
+// Dial returns a connection to the TSM service on the Unix socket at socketPath. Use
+// tsmpb.NewTSMClient to call it.
+func Dial(socketPath string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return grpc.NewClient("unix:"+socketPath, append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)...)
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/server"
+	"github.com/google/go-configfs-tsm/server/grpcserver/tsmpb"
+)
+
+func serve(t *testing.T, policy server.Policy) tsmpb.TSMClient {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "tsm.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.ReportV7(0),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	s := NewServer(fake, policy)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	conn, err := Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return tsmpb.NewTSMClient(conn)
+}
+
+func TestGetReport(t *testing.T) {
+	c := serve(t, server.AllowUIDs([]uint32{uint32(os.Getuid())}, nil))
+	level := uint32(1)
+	resp, err := c.GetReport(context.Background(), &tsmpb.GetReportRequest{Inblob: []byte("nonce"), Privilege: &level, GetAuxblob: true})
+	if err != nil {
+		t.Fatalf("GetReport() = _, %v. Want nil", err)
+	}
+	if want := "privlevel: 1\ninblob: 6e6f6e6365"; string(resp.GetOutblob()) != want || string(resp.GetAuxblob()) != "auxblob" {
+		t.Errorf("GetReport() = %v. Want outblob %q and auxblob %q", resp, want, "auxblob")
+	}
+	if resp.GetPrivilege() != 1 {
+		t.Errorf("GetReport() privilege = %d. Want 1", resp.GetPrivilege())
+	}
+}
+
+func TestExtendRtmr(t *testing.T) {
+	var gotCred *broker.Cred
+	policy := func(cred *broker.Cred, method string) error {
+		gotCred = cred
+		if method == server.MethodExtendRtmr && cred.UID != uint32(os.Getuid()) {
+			return errors.New("denied")
+		}
+		return nil
+	}
+	c := serve(t, policy)
+	if _, err := c.ExtendRtmr(context.Background(), &tsmpb.ExtendRtmrRequest{Index: 2, Digest: make([]byte, 48)}); err != nil {
+		t.Fatalf("ExtendRtmr() = _, %v. Want nil", err)
+	}
+	if gotCred == nil || gotCred.UID != uint32(os.Getuid()) {
+		t.Errorf("policy credentials = %+v. Want uid %d", gotCred, os.Getuid())
+	}
+	_, err := c.ExtendRtmr(context.Background(), &tsmpb.ExtendRtmrRequest{Index: 2, Digest: make([]byte, 3)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ExtendRtmr() with a short digest = _, %v. Want InvalidArgument", err)
+	}
+}
+
+func TestPolicyDenied(t *testing.T) {
+	c := serve(t, func(*broker.Cred, string) error { return errors.New("denied") })
+	if _, err := c.GetReport(context.Background(), &tsmpb.GetReportRequest{Inblob: []byte("nonce")}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("GetReport() = _, %v. Want PermissionDenied", err)
+	}
+	if _, err := c.ExtendRtmr(context.Background(), &tsmpb.ExtendRtmrRequest{Index: 2, Digest: make([]byte, 48)}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("ExtendRtmr() = _, %v. Want PermissionDenied", err)
+	}
+}
@@ -0,0 +1,388 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: tsm.proto
+
+package tsmpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetReportRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Inblob                 []byte                 `protobuf:"bytes,1,opt,name=inblob,proto3" json:"inblob,omitempty"`
+	Privilege              *uint32                `protobuf:"varint,2,opt,name=privilege,proto3,oneof" json:"privilege,omitempty"`
+	GetAuxblob             bool                   `protobuf:"varint,3,opt,name=get_auxblob,json=getAuxblob,proto3" json:"get_auxblob,omitempty"`
+	ServiceProvider        string                 `protobuf:"bytes,4,opt,name=service_provider,json=serviceProvider,proto3" json:"service_provider,omitempty"`
+	ServiceGuid            string                 `protobuf:"bytes,5,opt,name=service_guid,json=serviceGuid,proto3" json:"service_guid,omitempty"`
+	ServiceManifestVersion string                 `protobuf:"bytes,6,opt,name=service_manifest_version,json=serviceManifestVersion,proto3" json:"service_manifest_version,omitempty"`
+	ExpectedProvider       string                 `protobuf:"bytes,7,opt,name=expected_provider,json=expectedProvider,proto3" json:"expected_provider,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *GetReportRequest) Reset() {
+	*x = GetReportRequest{}
+	mi := &file_tsm_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReportRequest) ProtoMessage() {}
+
+func (x *GetReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tsm_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReportRequest.ProtoReflect.Descriptor instead.
+func (*GetReportRequest) Descriptor() ([]byte, []int) {
+	return file_tsm_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetReportRequest) GetInblob() []byte {
+	if x != nil {
+		return x.Inblob
+	}
+	return nil
+}
+
+func (x *GetReportRequest) GetPrivilege() uint32 {
+	if x != nil && x.Privilege != nil {
+		return *x.Privilege
+	}
+	return 0
+}
+
+func (x *GetReportRequest) GetGetAuxblob() bool {
+	if x != nil {
+		return x.GetAuxblob
+	}
+	return false
+}
+
+func (x *GetReportRequest) GetServiceProvider() string {
+	if x != nil {
+		return x.ServiceProvider
+	}
+	return ""
+}
+
+func (x *GetReportRequest) GetServiceGuid() string {
+	if x != nil {
+		return x.ServiceGuid
+	}
+	return ""
+}
+
+func (x *GetReportRequest) GetServiceManifestVersion() string {
+	if x != nil {
+		return x.ServiceManifestVersion
+	}
+	return ""
+}
+
+func (x *GetReportRequest) GetExpectedProvider() string {
+	if x != nil {
+		return x.ExpectedProvider
+	}
+	return ""
+}
+
+type GetReportResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Provider         string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Outblob          []byte                 `protobuf:"bytes,2,opt,name=outblob,proto3" json:"outblob,omitempty"`
+	Auxblob          []byte                 `protobuf:"bytes,3,opt,name=auxblob,proto3" json:"auxblob,omitempty"`
+	Manifestblob     []byte                 `protobuf:"bytes,4,opt,name=manifestblob,proto3" json:"manifestblob,omitempty"`
+	Privilege        *uint32                `protobuf:"varint,5,opt,name=privilege,proto3,oneof" json:"privilege,omitempty"`
+	PrivilegeClamped bool                   `protobuf:"varint,6,opt,name=privilege_clamped,json=privilegeClamped,proto3" json:"privilege_clamped,omitempty"`
+	Generation       uint64                 `protobuf:"varint,7,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GetReportResponse) Reset() {
+	*x = GetReportResponse{}
+	mi := &file_tsm_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReportResponse) ProtoMessage() {}
+
+func (x *GetReportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tsm_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReportResponse.ProtoReflect.Descriptor instead.
+func (*GetReportResponse) Descriptor() ([]byte, []int) {
+	return file_tsm_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetReportResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *GetReportResponse) GetOutblob() []byte {
+	if x != nil {
+		return x.Outblob
+	}
+	return nil
+}
+
+func (x *GetReportResponse) GetAuxblob() []byte {
+	if x != nil {
+		return x.Auxblob
+	}
+	return nil
+}
+
+func (x *GetReportResponse) GetManifestblob() []byte {
+	if x != nil {
+		return x.Manifestblob
+	}
+	return nil
+}
+
+func (x *GetReportResponse) GetPrivilege() uint32 {
+	if x != nil && x.Privilege != nil {
+		return *x.Privilege
+	}
+	return 0
+}
+
+func (x *GetReportResponse) GetPrivilegeClamped() bool {
+	if x != nil {
+		return x.PrivilegeClamped
+	}
+	return false
+}
+
+func (x *GetReportResponse) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+type ExtendRtmrRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Digest        []byte                 `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendRtmrRequest) Reset() {
+	*x = ExtendRtmrRequest{}
+	mi := &file_tsm_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendRtmrRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendRtmrRequest) ProtoMessage() {}
+
+func (x *ExtendRtmrRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tsm_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendRtmrRequest.ProtoReflect.Descriptor instead.
+func (*ExtendRtmrRequest) Descriptor() ([]byte, []int) {
+	return file_tsm_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExtendRtmrRequest) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ExtendRtmrRequest) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+type ExtendRtmrResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendRtmrResponse) Reset() {
+	*x = ExtendRtmrResponse{}
+	mi := &file_tsm_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendRtmrResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendRtmrResponse) ProtoMessage() {}
+
+func (x *ExtendRtmrResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tsm_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendRtmrResponse.ProtoReflect.Descriptor instead.
+func (*ExtendRtmrResponse) Descriptor() ([]byte, []int) {
+	return file_tsm_proto_rawDescGZIP(), []int{3}
+}
+
+var File_tsm_proto protoreflect.FileDescriptor
+
+const file_tsm_proto_rawDesc = "" +
+	"\n" +
+	"\ttsm.proto\x12\x0econfigfstsm.v1\"\xb1\x02\n" +
+	"\x10GetReportRequest\x12\x16\n" +
+	"\x06inblob\x18\x01 \x01(\fR\x06inblob\x12!\n" +
+	"\tprivilege\x18\x02 \x01(\rH\x00R\tprivilege\x88\x01\x01\x12\x1f\n" +
+	"\vget_auxblob\x18\x03 \x01(\bR\n" +
+	"getAuxblob\x12)\n" +
+	"\x10service_provider\x18\x04 \x01(\tR\x0fserviceProvider\x12!\n" +
+	"\fservice_guid\x18\x05 \x01(\tR\vserviceGuid\x128\n" +
+	"\x18service_manifest_version\x18\x06 \x01(\tR\x16serviceManifestVersion\x12+\n" +
+	"\x11expected_provider\x18\a \x01(\tR\x10expectedProviderB\f\n" +
+	"\n" +
+	"_privilege\"\x85\x02\n" +
+	"\x11GetReportResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x18\n" +
+	"\aoutblob\x18\x02 \x01(\fR\aoutblob\x12\x18\n" +
+	"\aauxblob\x18\x03 \x01(\fR\aauxblob\x12\"\n" +
+	"\fmanifestblob\x18\x04 \x01(\fR\fmanifestblob\x12!\n" +
+	"\tprivilege\x18\x05 \x01(\rH\x00R\tprivilege\x88\x01\x01\x12+\n" +
+	"\x11privilege_clamped\x18\x06 \x01(\bR\x10privilegeClamped\x12\x1e\n" +
+	"\n" +
+	"generation\x18\a \x01(\x04R\n" +
+	"generationB\f\n" +
+	"\n" +
+	"_privilege\"A\n" +
+	"\x11ExtendRtmrRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06digest\x18\x02 \x01(\fR\x06digest\"\x14\n" +
+	"\x12ExtendRtmrResponse2\xac\x01\n" +
+	"\x03TSM\x12P\n" +
+	"\tGetReport\x12 .configfstsm.v1.GetReportRequest\x1a!.configfstsm.v1.GetReportResponse\x12S\n" +
+	"\n" +
+	"ExtendRtmr\x12!.configfstsm.v1.ExtendRtmrRequest\x1a\".configfstsm.v1.ExtendRtmrResponseB;Z9github.com/google/go-configfs-tsm/server/grpcserver/tsmpbb\x06proto3"
+
+var (
+	file_tsm_proto_rawDescOnce sync.Once
+	file_tsm_proto_rawDescData []byte
+)
+
+func file_tsm_proto_rawDescGZIP() []byte {
+	file_tsm_proto_rawDescOnce.Do(func() {
+		file_tsm_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tsm_proto_rawDesc), len(file_tsm_proto_rawDesc)))
+	})
+	return file_tsm_proto_rawDescData
+}
+
+var file_tsm_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_tsm_proto_goTypes = []any{
+	(*GetReportRequest)(nil),   // 0: configfstsm.v1.GetReportRequest
+	(*GetReportResponse)(nil),  // 1: configfstsm.v1.GetReportResponse
+	(*ExtendRtmrRequest)(nil),  // 2: configfstsm.v1.ExtendRtmrRequest
+	(*ExtendRtmrResponse)(nil), // 3: configfstsm.v1.ExtendRtmrResponse
+}
+var file_tsm_proto_depIdxs = []int32{
+	0, // 0: configfstsm.v1.TSM.GetReport:input_type -> configfstsm.v1.GetReportRequest
+	2, // 1: configfstsm.v1.TSM.ExtendRtmr:input_type -> configfstsm.v1.ExtendRtmrRequest
+	1, // 2: configfstsm.v1.TSM.GetReport:output_type -> configfstsm.v1.GetReportResponse
+	3, // 3: configfstsm.v1.TSM.ExtendRtmr:output_type -> configfstsm.v1.ExtendRtmrResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_tsm_proto_init() }
+func file_tsm_proto_init() {
+	if File_tsm_proto != nil {
+		return
+	}
+	file_tsm_proto_msgTypes[0].OneofWrappers = []any{}
+	file_tsm_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tsm_proto_rawDesc), len(file_tsm_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tsm_proto_goTypes,
+		DependencyIndexes: file_tsm_proto_depIdxs,
+		MessageInfos:      file_tsm_proto_msgTypes,
+	}.Build()
+	File_tsm_proto = out.File
+	file_tsm_proto_goTypes = nil
+	file_tsm_proto_depIdxs = nil
+}
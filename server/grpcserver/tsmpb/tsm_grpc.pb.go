@@ -0,0 +1,159 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: tsm.proto
+
+package tsmpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TSM_GetReport_FullMethodName  = "/configfstsm.v1.TSM/GetReport"
+	TSM_ExtendRtmr_FullMethodName = "/configfstsm.v1.TSM/ExtendRtmr"
+)
+
+// TSMClient is the client API for TSM service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TSMClient interface {
+	GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*GetReportResponse, error)
+	ExtendRtmr(ctx context.Context, in *ExtendRtmrRequest, opts ...grpc.CallOption) (*ExtendRtmrResponse, error)
+}
+
+type tSMClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTSMClient(cc grpc.ClientConnInterface) TSMClient {
+	return &tSMClient{cc}
+}
+
+func (c *tSMClient) GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*GetReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReportResponse)
+	err := c.cc.Invoke(ctx, TSM_GetReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tSMClient) ExtendRtmr(ctx context.Context, in *ExtendRtmrRequest, opts ...grpc.CallOption) (*ExtendRtmrResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExtendRtmrResponse)
+	err := c.cc.Invoke(ctx, TSM_ExtendRtmr_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TSMServer is the server API for TSM service.
+// All implementations must embed UnimplementedTSMServer
+// for forward compatibility.
+type TSMServer interface {
+	GetReport(context.Context, *GetReportRequest) (*GetReportResponse, error)
+	ExtendRtmr(context.Context, *ExtendRtmrRequest) (*ExtendRtmrResponse, error)
+	mustEmbedUnimplementedTSMServer()
+}
+
+// UnimplementedTSMServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTSMServer struct{}
+
+func (UnimplementedTSMServer) GetReport(context.Context, *GetReportRequest) (*GetReportResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetReport not implemented")
+}
+func (UnimplementedTSMServer) ExtendRtmr(context.Context, *ExtendRtmrRequest) (*ExtendRtmrResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExtendRtmr not implemented")
+}
+func (UnimplementedTSMServer) mustEmbedUnimplementedTSMServer() {}
+func (UnimplementedTSMServer) testEmbeddedByValue()             {}
+
+// UnsafeTSMServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TSMServer will
+// result in compilation errors.
+type UnsafeTSMServer interface {
+	mustEmbedUnimplementedTSMServer()
+}
+
+func RegisterTSMServer(s grpc.ServiceRegistrar, srv TSMServer) {
+	// If the following call panics, it indicates UnimplementedTSMServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TSM_ServiceDesc, srv)
+}
+
+func _TSM_GetReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TSMServer).GetReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TSM_GetReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TSMServer).GetReport(ctx, req.(*GetReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TSM_ExtendRtmr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendRtmrRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TSMServer).ExtendRtmr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TSM_ExtendRtmr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TSMServer).ExtendRtmr(ctx, req.(*ExtendRtmrRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TSM_ServiceDesc is the grpc.ServiceDesc for TSM service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TSM_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "configfstsm.v1.TSM",
+	HandlerType: (*TSMServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetReport",
+			Handler:    _TSM_GetReport_Handler,
+		},
+		{
+			MethodName: "ExtendRtmr",
+			Handler:    _TSM_ExtendRtmr_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "tsm.proto",
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"syscall"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+const binaryContentType = "application/octet-stream"
+
+// reportHandler serves report.Get over HTTP.
+type reportHandler struct {
+	client configfsi.Client
+}
+
+// NewHTTPHandler returns an http.Handler that serves reports backed by client.
+//
+// Requests pass the hex-encoded inblob in the "nonce" query parameter, and optionally
+// "privlevel" and "auxblob=true". The report.Response is returned as JSON unless the
+// request's Accept header is application/octet-stream, in which case only the outblob is
+// returned.
+func NewHTTPHandler(client configfsi.Client) http.Handler {
+	return &reportHandler{client: client}
+}
+
+func parseReportRequest(r *http.Request) (*report.Request, error) {
+	q := r.URL.Query()
+	nonce, err := hex.DecodeString(q.Get("nonce"))
+	if err != nil {
+		return nil, fmt.Errorf("nonce is not hex: %v", err)
+	}
+	req := &report.Request{InBlob: nonce}
+	if p := q.Get("privlevel"); p != "" {
+		level, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privlevel %q: %v", p, err)
+		}
+		req.Privilege = &report.Privilege{Level: uint(level)}
+	}
+	if a := q.Get("auxblob"); a != "" {
+		req.GetAuxBlob, err = strconv.ParseBool(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auxblob %q: %v", a, err)
+		}
+	}
+	return req, nil
+}
+
+// httpStatus returns the HTTP status that best describes a report.Get error. The kernel
+// rejects an oversized inblob or an invalid privlevel with EINVAL, which are the caller's
+// errors.
+func httpStatus(err error) int {
+	var privErr *report.PrivilegeErr
+	switch {
+	case errors.As(err, &privErr), errors.Is(err, syscall.EINVAL):
+		return http.StatusBadRequest
+	case configfsi.IsBusy(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ServeHTTP responds to a report request.
+func (h *reportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := parseReportRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := report.Get(h.client, req)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	if r.Header.Get("Accept") == binaryContentType {
+		w.Header().Set("Content-Type", binaryContentType)
+		w.Write(resp.OutBlob)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,253 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server provides an attestation agent that serves report and rtmr operations to
+// unprivileged processes over a Unix socket, so only the agent needs access to configfs.
+//
+// The RPCs use the standard library's net/rpc encoding. The service is named "TSM" and
+// has the methods GetReport and ExtendRtmr. Each connection's peer is authorized by its
+// SO_PEERCRED credentials against a Policy, since extending an RTMR cannot be undone.
+//
+// The grpcserver module serves the same methods over gRPC, as defined by
+// grpcserver/tsmpb/tsm.proto, with the same policies.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/rtmr"
+)
+
+// serviceName is the net/rpc service name that the methods of Service are registered under.
+const serviceName = "TSM"
+
+// The methods of the service, as passed to a Policy.
+const (
+	MethodGetReport  = "GetReport"
+	MethodExtendRtmr = "ExtendRtmr"
+)
+
+const (
+	// maxRetries bounds the retries a peer can request for a report.
+	maxRetries = 3
+	// maxTimeout bounds how long a peer's report request can wait, and is the timeout of
+	// requests that set none.
+	maxTimeout = 30 * time.Second
+)
+
+// Policy decides whether a peer may call a method, MethodGetReport or MethodExtendRtmr.
+// The credentials are nil if the peer is not connected by a Unix socket.
+type Policy func(cred *broker.Cred, method string) error
+
+// AllowUIDs returns a Policy that admits root to every method, reportUIDs to GetReport,
+// and extendUIDs to every method. Peers without credentials are denied.
+func AllowUIDs(reportUIDs, extendUIDs []uint32) Policy {
+	contains := func(uids []uint32, uid uint32) bool {
+		for _, u := range uids {
+			if u == uid {
+				return true
+			}
+		}
+		return false
+	}
+	return func(cred *broker.Cred, method string) error {
+		if cred == nil {
+			return errors.New("peer credentials are unknown")
+		}
+		if cred.UID == 0 || contains(extendUIDs, cred.UID) ||
+			(method == MethodGetReport && contains(reportUIDs, cred.UID)) {
+			return nil
+		}
+		return fmt.Errorf("uid %d is not allowed to call %s", cred.UID, method)
+	}
+}
+
+// ExtendRtmrRequest is the argument to the ExtendRtmr RPC.
+type ExtendRtmrRequest struct {
+	Index  int
+	Digest []byte
+}
+
+// ExtendRtmrResponse is the result of the ExtendRtmr RPC.
+type ExtendRtmrResponse struct{}
+
+// Service implements the RPCs against a configfsi.Client for one peer.
+type Service struct {
+	client configfsi.Client
+	policy Policy
+	cred   *broker.Cred
+}
+
+// newService returns a Service for the peer with the given credentials.
+func newService(client configfsi.Client, policy Policy, cred *broker.Cred) *Service {
+	if policy == nil {
+		policy = AllowUIDs(nil, nil)
+	}
+	return &Service{client: client, policy: policy, cred: cred}
+}
+
+// PeerRequest returns the fields of a peer's request that servers honor. Retries and
+// Timeout are bounded so that one peer cannot hold the TSM indefinitely.
+func PeerRequest(req *report.Request) *report.Request {
+	r := &report.Request{
+		InBlob:                 req.InBlob,
+		Privilege:              req.Privilege,
+		GetAuxBlob:             req.GetAuxBlob,
+		ServiceProvider:        req.ServiceProvider,
+		ServiceGuid:            req.ServiceGuid,
+		ServiceManifestVersion: req.ServiceManifestVersion,
+		ExpectedProvider:       req.ExpectedProvider,
+		FloorPolicy:            req.FloorPolicy,
+		Retries:                req.Retries,
+		Timeout:                req.Timeout,
+	}
+	if r.Retries < 0 {
+		r.Retries = 0
+	}
+	if r.Retries > maxRetries {
+		r.Retries = maxRetries
+	}
+	if r.Timeout <= 0 || r.Timeout > maxTimeout {
+		r.Timeout = maxTimeout
+	}
+	return r
+}
+
+// GetReport is the RPC for report.Get.
+func (s *Service) GetReport(req *report.Request, resp *report.Response) error {
+	if err := s.policy(s.cred, MethodGetReport); err != nil {
+		return err
+	}
+	r, err := report.Get(s.client, PeerRequest(req))
+	if err != nil {
+		return err
+	}
+	*resp = *r
+	return nil
+}
+
+// ExtendRtmr is the RPC for rtmr.ExtendDigest.
+func (s *Service) ExtendRtmr(req *ExtendRtmrRequest, _ *ExtendRtmrResponse) error {
+	if err := s.policy(s.cred, MethodExtendRtmr); err != nil {
+		return err
+	}
+	return rtmr.ExtendDigest(s.client, req.Index, req.Digest)
+}
+
+// ReportService is a Service without ExtendRtmr, for agents that only serve reports.
+type ReportService struct {
+	s *Service
+}
+
+// GetReport is the RPC for report.Get.
+func (r *ReportService) GetReport(req *report.Request, resp *report.Response) error {
+	return r.s.GetReport(req, resp)
+}
+
+// peerCred returns the credentials of conn's peer, or nil if it is not connected by a Unix
+// socket.
+func peerCred(conn net.Conn) (*broker.Cred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, nil
+	}
+	return broker.PeerCred(uc)
+}
+
+// connServer returns an RPC server for a connection whose service is authorized by the
+// peer's credentials.
+func connServer(client configfsi.Client, opts *ServeOptions, cred *broker.Cred) (*rpc.Server, error) {
+	var service any = newService(client, opts.Policy, cred)
+	if opts.ReportOnly {
+		service = &ReportService{s: service.(*Service)}
+	}
+	s := rpc.NewServer()
+	if err := s.RegisterName(serviceName, service); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Serve accepts connections on l and serves the RPCs backed by client to the peers that
+// policy admits until l is closed. A nil policy admits only root.
+func Serve(l net.Listener, client configfsi.Client, policy Policy) error {
+	return ServeWithOptions(l, client, &ServeOptions{Policy: policy})
+}
+
+// ListenAndServe serves the RPCs backed by linuxtsm on a Unix socket at socketPath to the
+// peers that policy admits. The socket is accessible to the owner and group of the
+// process, so other users must also be kept out by its permissions.
+func ListenAndServe(socketPath string, policy Policy) error {
+	client, err := linuxtsm.MakeClient()
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	if err := os.Chmod(socketPath, 0660); err != nil {
+		return fmt.Errorf("could not set permissions on %q: %v", socketPath, err)
+	}
+	return Serve(l, client, policy)
+}
+
+// Client calls the RPCs of a server.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// NewClient returns a Client that communicates over conn.
+func NewClient(conn net.Conn) *Client {
+	return &Client{rpc: rpc.NewClient(conn)}
+}
+
+// Dial returns a Client connected to the server's Unix socket at socketPath.
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// GetReport returns a one-shot report from the server.
+func (c *Client) GetReport(req *report.Request) (*report.Response, error) {
+	resp := &report.Response{}
+	if err := c.rpc.Call(serviceName+".GetReport", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExtendRtmr extends the digest into the given RTMR on the server.
+func (c *Client) ExtendRtmr(index int, digest []byte) error {
+	return c.rpc.Call(serviceName+".ExtendRtmr", &ExtendRtmrRequest{Index: index, Digest: digest},
+		&ExtendRtmrResponse{})
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestServer(t *testing.T) {
+	socket := path.Join(t.TempDir(), "tsm.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.ReportV7(0),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	go Serve(l, fake, AllowUIDs(nil, []uint32{uint32(os.Getuid())}))
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial(%q) = _, %v, want nil", socket, err)
+	}
+	defer c.Close()
+	resp, err := c.GetReport(&report.Request{InBlob: []byte("nonce")})
+	if err != nil {
+		t.Fatalf("GetReport() = _, %v, want nil", err)
+	}
+	wantOut := "privlevel: 0\ninblob: 6e6f6e6365"
+	if !bytes.Equal(resp.OutBlob, []byte(wantOut)) {
+		t.Errorf("OutBlob = %q, want %q", resp.OutBlob, wantOut)
+	}
+	if err := c.ExtendRtmr(2, make([]byte, 48)); err != nil {
+		t.Errorf("ExtendRtmr(2, _) = %v, want nil", err)
+	}
+	if err := c.ExtendRtmr(0, make([]byte, 48)); err == nil {
+		t.Errorf("ExtendRtmr(0, _) = nil, want error")
+	}
+}
+
+func TestServerPolicy(t *testing.T) {
+	socket := path.Join(t.TempDir(), "tsm.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.ReportV7(0),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	var gotCred *broker.Cred
+	reportOnly := func(cred *broker.Cred, method string) error {
+		gotCred = cred
+		if method != MethodGetReport {
+			return errors.New("denied")
+		}
+		return nil
+	}
+	go Serve(l, fake, reportOnly)
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.GetReport(&report.Request{InBlob: []byte("nonce")}); err != nil {
+		t.Errorf("GetReport() = _, %v, want nil", err)
+	}
+	if gotCred == nil || gotCred.UID != uint32(os.Getuid()) {
+		t.Errorf("policy credentials = %+v, want uid %d", gotCred, os.Getuid())
+	}
+	if err := c.ExtendRtmr(2, make([]byte, 48)); err == nil {
+		t.Errorf("ExtendRtmr() for a report-only peer = nil, want error")
+	}
+}
+
+func TestAllowUIDs(t *testing.T) {
+	policy := AllowUIDs([]uint32{1000}, []uint32{1001})
+	tests := []struct {
+		cred   *broker.Cred
+		method string
+		allow  bool
+	}{
+		{&broker.Cred{UID: 0}, MethodExtendRtmr, true},
+		{&broker.Cred{UID: 1000}, MethodGetReport, true},
+		{&broker.Cred{UID: 1000}, MethodExtendRtmr, false},
+		{&broker.Cred{UID: 1001}, MethodExtendRtmr, true},
+		{&broker.Cred{UID: 1002}, MethodGetReport, false},
+		{nil, MethodGetReport, false},
+	}
+	for _, tc := range tests {
+		if err := policy(tc.cred, tc.method); (err == nil) != tc.allow {
+			t.Errorf("AllowUIDs()(%+v, %q) = %v, want allowed %v", tc.cred, tc.method, err, tc.allow)
+		}
+	}
+}
+
+func TestPeerRequest(t *testing.T) {
+	req := PeerRequest(&report.Request{InBlob: []byte("nonce"), Retries: 1 << 20, Timeout: time.Hour})
+	if req.Retries != maxRetries || req.Timeout != maxTimeout || string(req.InBlob) != "nonce" {
+		t.Errorf("PeerRequest() = %+v, want %d retries and a %v timeout", req, maxRetries, maxTimeout)
+	}
+	if req := PeerRequest(&report.Request{Retries: -1}); req.Retries != 0 || req.Timeout != maxTimeout {
+		t.Errorf("PeerRequest() = %+v, want no retries and a %v timeout", req, maxTimeout)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	h := NewHTTPHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?nonce=6e6f6e6365&privlevel=1&auxblob=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d (%s), want %d", rec.Code, rec.Body, http.StatusOK)
+	}
+	var resp report.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response %s: %v", rec.Body, err)
+	}
+	wantOut := "privlevel: 1\ninblob: 6e6f6e6365"
+	if !bytes.Equal(resp.OutBlob, []byte(wantOut)) || !bytes.Equal(resp.AuxBlob, []byte("auxblob")) {
+		t.Errorf("ServeHTTP() response = %+v, want OutBlob %q and AuxBlob %q", resp, wantOut, "auxblob")
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/?nonce=6e6f6e6365", nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	h.ServeHTTP(rec, req)
+	if wantOut := "privlevel: 0\ninblob: 6e6f6e6365"; rec.Body.String() != wantOut {
+		t.Errorf("ServeHTTP() binary body = %q, want %q", rec.Body, wantOut)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?nonce=zz", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP(nonce=zz) status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?nonce="+strings.Repeat("00", 65), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP(65-byte nonce) status = %d (%s), want %d", rec.Code, rec.Body, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+// listenFDsStart is the first file descriptor that systemd passes to socket-activated
+// services.
+const listenFDsStart = 3
+
+// ErrNotActivated is returned by ServeActivated when the process was not passed sockets.
+var ErrNotActivated = errors.New("no sockets were passed by the service manager")
+
+// ServeOptions configures ServeWithOptions.
+type ServeOptions struct {
+	// MaxRequests is the number of RPCs served on a connection before it is closed, so that
+	// long-lived connections are recycled. Clients can reconnect, so it does not limit
+	// use of the TSM; MaxConns and MaxConnsPerUID do. Zero means no limit.
+	MaxRequests int
+	// MaxConns is the number of connections served at once. Connections beyond it are
+	// closed when accepted. Zero means no limit.
+	MaxConns int
+	// MaxConnsPerUID is the number of connections served at once for each peer user ID,
+	// so that one user cannot take all of MaxConns. Zero means no limit.
+	MaxConnsPerUID int
+	// Policy authorizes each connection's peer. Nil admits only root.
+	Policy Policy
+	// ReportOnly serves ReportService instead of Service, so that no peer can extend
+	// RTMRs regardless of Policy.
+	ReportOnly bool
+}
+
+// connLimiter counts the connections being served against ServeOptions' limits.
+type connLimiter struct {
+	opts  *ServeOptions
+	mu    sync.Mutex
+	total int
+	byUID map[uint32]int
+}
+
+// acquire returns false if serving another connection for the peer would exceed a limit.
+// Peers without credentials count only against MaxConns.
+func (l *connLimiter) acquire(cred *broker.Cred) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.opts.MaxConns > 0 && l.total >= l.opts.MaxConns {
+		return false
+	}
+	if cred != nil {
+		if l.opts.MaxConnsPerUID > 0 && l.byUID[cred.UID] >= l.opts.MaxConnsPerUID {
+			return false
+		}
+		l.byUID[cred.UID]++
+	}
+	l.total++
+	return true
+}
+
+// release ends the count of a connection that acquire admitted.
+func (l *connLimiter) release(cred *broker.Cred) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if cred != nil {
+		if l.byUID[cred.UID]--; l.byUID[cred.UID] == 0 {
+			delete(l.byUID, cred.UID)
+		}
+	}
+}
+
+// limitCodec is the gob rpc.ServerCodec of net/rpc that ends the connection after a number
+// of requests.
+type limitCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	// remaining is the number of requests left to read, or negative for no limit.
+	remaining int
+	closeOnce sync.Once
+}
+
+func newLimitCodec(conn io.ReadWriteCloser, maxRequests int) *limitCodec {
+	buf := bufio.NewWriter(conn)
+	remaining := maxRequests
+	if maxRequests == 0 {
+		remaining = -1
+	}
+	return &limitCodec{rwc: conn, dec: gob.NewDecoder(conn), enc: gob.NewEncoder(buf), encBuf: buf, remaining: remaining}
+}
+
+// ReadRequestHeader reads the next request header, or returns io.EOF once the limit is
+// reached so that the server stops reading and closes the connection.
+func (c *limitCodec) ReadRequestHeader(r *rpc.Request) error {
+	if c.remaining == 0 {
+		return io.EOF
+	}
+	if c.remaining > 0 {
+		c.remaining--
+	}
+	return c.dec.Decode(r)
+}
+
+// ReadRequestBody reads the body of the request.
+func (c *limitCodec) ReadRequestBody(body any) error {
+	return c.dec.Decode(body)
+}
+
+// WriteResponse writes a response and closes the connection if it cannot be encoded.
+func (c *limitCodec) WriteResponse(r *rpc.Response, body any) error {
+	err := c.enc.Encode(r)
+	if err == nil {
+		err = c.enc.Encode(body)
+	}
+	if err == nil {
+		err = c.encBuf.Flush()
+	}
+	if err != nil {
+		c.Close()
+	}
+	return err
+}
+
+// Close closes the connection.
+func (c *limitCodec) Close() error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() { err = c.rwc.Close() })
+	return err
+}
+
+// ServeWithOptions is Serve with options. It returns nil when l is closed.
+func ServeWithOptions(l net.Listener, client configfsi.Client, opts *ServeOptions) error {
+	if opts == nil {
+		opts = &ServeOptions{}
+	}
+	limiter := &connLimiter{opts: opts, byUID: make(map[uint32]int)}
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go func() {
+			cred, err := peerCred(conn)
+			if err != nil || !limiter.acquire(cred) {
+				conn.Close()
+				return
+			}
+			defer limiter.release(cred)
+			s, err := connServer(client, opts, cred)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			s.ServeCodec(newLimitCodec(conn, opts.MaxRequests))
+		}()
+	}
+}
+
+// ListenFDs returns listeners for the sockets that systemd passed to the process by
+// socket activation, in the order of the socket unit's Listen directives, and unsets the
+// environment variables that passed them. It returns no listeners if none were passed.
+func ListenFDs() ([]net.Listener, error) {
+	return listenFDs(listenFDsStart)
+}
+
+func listenFDs(start int) ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	var listeners []net.Listener
+	for i := 0; i < count; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(start+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(start+i), name)
+		l, err := net.FileListener(f)
+		// FileListener duplicates the descriptor.
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket %q: %w", name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// Notify sends a state, e.g., "READY=1", to the systemd notification socket. It returns
+// false if there is no notification socket, i.e., the service is not of Type=notify.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading "@" names an abstract socket, which the net package handles.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ServeActivated serves the RPCs backed by client on the sockets passed by systemd socket
+// activation, notifies systemd that the service is ready, and serves until ctx is done.
+func ServeActivated(ctx context.Context, client configfsi.Client, opts *ServeOptions) error {
+	listeners, err := ListenFDs()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return ErrNotActivated
+	}
+	return ServeListeners(ctx, listeners, client, opts)
+}
+
+// ServeListeners serves the RPCs backed by client on each listener, notifies systemd that
+// the service is ready, and serves until ctx is done or a listener fails. The listeners
+// are closed when it returns.
+func ServeListeners(ctx context.Context, listeners []net.Listener, client configfsi.Client, opts *ServeOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			err := ServeWithOptions(l, client, opts)
+			cancel()
+			errs <- err
+		}(l)
+	}
+	_, notifyErr := Notify("READY=1")
+	if notifyErr != nil {
+		cancel()
+	}
+	<-ctx.Done()
+	Notify("STOPPING=1")
+	for _, l := range listeners {
+		l.Close()
+	}
+	var firstErr error
+	for range listeners {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil && notifyErr != nil {
+		return fmt.Errorf("could not notify readiness: %w", notifyErr)
+	}
+	return firstErr
+}
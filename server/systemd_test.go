@@ -0,0 +1,199 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/broker"
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/fakertmr"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestServeMaxRequests(t *testing.T) {
+	l, err := net.Listen("unix", path.Join(t.TempDir(), "tsm.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	done := make(chan error)
+	go func() {
+		done <- ServeWithOptions(l, fake, &ServeOptions{MaxRequests: 2, Policy: AllowUIDs([]uint32{uint32(os.Getuid())}, nil)})
+	}()
+
+	c, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetReport(&report.Request{InBlob: []byte("nonce")}); err != nil {
+			t.Fatalf("GetReport() %d = _, %v, want nil", i, err)
+		}
+	}
+	if _, err := c.GetReport(&report.Request{InBlob: []byte("nonce")}); err == nil {
+		t.Errorf("GetReport() past the limit = _, nil, want error")
+	}
+	l.Close()
+	if err := <-done; err != nil {
+		t.Errorf("ServeWithOptions() = %v after Close, want nil", err)
+	}
+}
+
+func TestServeReportOnly(t *testing.T) {
+	l, err := net.Listen("unix", path.Join(t.TempDir(), "tsm.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{
+		"report": faketsm.ReportV7(0),
+		"rtmrs":  fakertmr.CreateRtmrSubsystem(t.TempDir()),
+	}}
+	allowAll := func(*broker.Cred, string) error { return nil }
+	go ServeWithOptions(l, fake, &ServeOptions{Policy: allowAll, ReportOnly: true})
+
+	c, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.GetReport(&report.Request{InBlob: []byte("nonce")}); err != nil {
+		t.Errorf("GetReport() = _, %v, want nil", err)
+	}
+	if err := c.ExtendRtmr(2, make([]byte, 48)); err == nil {
+		t.Errorf("ExtendRtmr() on a report-only service = nil, want error")
+	}
+}
+
+func TestServeMaxConnsPerUID(t *testing.T) {
+	l, err := net.Listen("unix", path.Join(t.TempDir(), "tsm.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fake := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	allowAll := func(*broker.Cred, string) error { return nil }
+	go ServeWithOptions(l, fake, &ServeOptions{Policy: allowAll, MaxConnsPerUID: 1})
+
+	first, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.GetReport(&report.Request{InBlob: []byte("nonce")}); err != nil {
+		t.Fatalf("GetReport() = _, %v, want nil", err)
+	}
+	second, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if _, err := second.GetReport(&report.Request{InBlob: []byte("nonce")}); err == nil {
+		t.Errorf("GetReport() on a second connection of the uid = _, nil, want error")
+	}
+	first.Close()
+	// The first connection's slot is released once the server sees it close.
+	for i := 0; ; i++ {
+		third, err := Dial(l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = third.GetReport(&report.Request{InBlob: []byte("nonce")})
+		third.Close()
+		if err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("GetReport() after the first connection closed = _, %v, want nil", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListenFDs(t *testing.T) {
+	l, err := net.Listen("unix", path.Join(t.TempDir(), "tsm.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	if listeners, err := listenFDs(int(f.Fd())); err != nil || len(listeners) != 0 {
+		t.Errorf("listenFDs() for another pid = %v, %v, want none", listeners, err)
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "tsm")
+	listeners, err := listenFDs(int(f.Fd()))
+	if err != nil || len(listeners) != 1 {
+		t.Fatalf("listenFDs() = %v, %v, want one listener", listeners, err)
+	}
+	defer listeners[0].Close()
+	if listeners[0].Addr().String() != l.Addr().String() {
+		t.Errorf("listenFDs() address = %v, want %v", listeners[0].Addr(), l.Addr())
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Errorf("listenFDs() left LISTEN_FDS set")
+	}
+}
+
+func TestServeListenersNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram sockets are not supported")
+	}
+	dir := t.TempDir()
+	notify, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path.Join(dir, "notify"), Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notify.Close()
+	t.Setenv("NOTIFY_SOCKET", path.Join(dir, "notify"))
+	l, err := net.Listen("unix", path.Join(dir, "tsm.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- ServeListeners(ctx, []net.Listener{l}, &faketsm.Client{}, nil) }()
+	buf := make([]byte, 64)
+	for _, want := range []string{"READY=1", "STOPPING=1"} {
+		n, err := notify.Read(buf)
+		if err != nil || string(buf[:n]) != want {
+			t.Errorf("notification = %q, %v, want %q", buf[:n], err, want)
+		}
+		cancel()
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ServeListeners() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// PayloadOrChallengeResponse is a message from the agent attestor. Exactly one field is
+// set.
+type PayloadOrChallengeResponse struct {
+	Payload           []byte
+	ChallengeResponse []byte
+}
+
+// AgentStream is the agent attestor's stream to the server attestor.
+type AgentStream interface {
+	// Send sends a message to the server.
+	Send(*PayloadOrChallengeResponse) error
+	// Recv returns the server's next challenge.
+	Recv() ([]byte, error)
+}
+
+// AgentAttestor is the agent side of the node attestor.
+type AgentAttestor struct {
+	client configfsi.Client
+	opts   []report.Option
+}
+
+// NewAgentAttestor returns an agent attestor that gets reports from client with the given
+// options, e.g., report.WithAuxBlob() so that the server gets the signing certificates.
+func NewAgentAttestor(client configfsi.Client, opts ...report.Option) *AgentAttestor {
+	return &AgentAttestor{client: client, opts: opts}
+}
+
+// AidAttestation sends the payload and answers the server's challenges until the stream
+// ends.
+func (a *AgentAttestor) AidAttestation(stream AgentStream) error {
+	payload, err := json.Marshal(&Payload{Version: payloadVersion})
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&PayloadOrChallengeResponse{Payload: payload}); err != nil {
+		return err
+	}
+	for {
+		challenge, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			// SPIRE ends the stream once the server has attested the agent.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(challenge) != nonceSize {
+			return fmt.Errorf("challenge is %d bytes, want %d", len(challenge), nonceSize)
+		}
+		resp, err := report.Get(a.client, report.NewRequest(challenge, a.opts...))
+		if err != nil {
+			return fmt.Errorf("could not get report: %w", err)
+		}
+		evidence, err := json.Marshal(&Evidence{Provider: resp.Provider, OutBlob: resp.OutBlob, AuxBlob: resp.AuxBlob})
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&PayloadOrChallengeResponse{ChallengeResponse: evidence}); err != nil {
+			return err
+		}
+	}
+}
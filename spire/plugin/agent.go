@@ -0,0 +1,113 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/linuxtsm"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/spire"
+)
+
+// AgentConfig is the agent plugin's HCL configuration.
+type AgentConfig struct {
+	// TsmRoot is the tsm directory, e.g., a bind mount in a container. Empty means the
+	// directory under the configfs mount.
+	TsmRoot string `hcl:"tsm_root"`
+}
+
+// Agent is the SPIRE agent's NodeAttestor plugin.
+type Agent struct {
+	nodeattestorv1.UnimplementedNodeAttestorServer
+	configv1.UnimplementedConfigServer
+
+	// Client is the configfs client that reports are requested from. If nil, Configure
+	// makes a linuxtsm client for the configured TsmRoot.
+	Client configfsi.Client
+
+	mu       sync.RWMutex
+	attestor *spire.AgentAttestor
+}
+
+// Configure configures the plugin with its AgentConfig.
+func (a *Agent) Configure(_ context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := &AgentConfig{}
+	if err := hcl.Decode(config, req.GetHclConfiguration()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not decode configuration: %v", err)
+	}
+	client := a.Client
+	if client == nil {
+		var err error
+		if config.TsmRoot != "" {
+			client, err = linuxtsm.MakeClientWithRoot(config.TsmRoot)
+		} else {
+			client, err = linuxtsm.MakeClient()
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "configfs-tsm is unavailable: %v", err)
+		}
+	}
+	a.mu.Lock()
+	// The server completes missing SEV-SNP certificates from KDS, but the auxblob is the
+	// only source of a VLEK.
+	a.attestor = spire.NewAgentAttestor(client, report.WithAuxBlob())
+	a.mu.Unlock()
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// agentStream adapts the SDK's stream to spire.AgentStream.
+type agentStream struct {
+	stream nodeattestorv1.NodeAttestor_AidAttestationServer
+}
+
+func (s *agentStream) Send(m *spire.PayloadOrChallengeResponse) error {
+	if m.Payload != nil {
+		return s.stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+			Data: &nodeattestorv1.PayloadOrChallengeResponse_Payload{Payload: m.Payload},
+		})
+	}
+	return s.stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_ChallengeResponse{ChallengeResponse: m.ChallengeResponse},
+	})
+}
+
+func (s *agentStream) Recv() ([]byte, error) {
+	challenge, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return challenge.GetChallenge(), nil
+}
+
+// AidAttestation sends the agent's payload and answers the server's challenges with TSM
+// reports.
+func (a *Agent) AidAttestation(stream nodeattestorv1.NodeAttestor_AidAttestationServer) error {
+	a.mu.RLock()
+	attestor := a.attestor
+	a.mu.RUnlock()
+	if attestor == nil {
+		return status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return attestor.AidAttestation(&agentStream{stream: stream})
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tsm-agent-attestor is the SPIRE agent's "tsm" NodeAttestor plugin. It must run
+// as a user that may create configfs-tsm report entries. Configure it in the agent's
+// plugins block:
+//
+//	NodeAttestor "tsm" {
+//		plugin_cmd = "/usr/local/bin/tsm-agent-attestor"
+//		plugin_data {}
+//	}
+package main
+
+import (
+	"github.com/spiffe/spire-plugin-sdk/pluginmain"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+
+	"github.com/google/go-configfs-tsm/spire/plugin"
+)
+
+func main() {
+	p := &plugin.Agent{}
+	pluginmain.Serve(
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
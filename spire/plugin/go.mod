@@ -0,0 +1,35 @@
+module github.com/google/go-configfs-tsm/spire/plugin
+
+go 1.25.0
+
+replace github.com/google/go-configfs-tsm => ../..
+
+require (
+	github.com/google/go-configfs-tsm v0.3.2
+	github.com/google/go-sev-guest v0.14.0
+	github.com/google/go-tdx-guest v0.3.2-0.20241009005452-097ee70d0843
+	github.com/hashicorp/hcl v1.0.0
+	github.com/spiffe/spire-plugin-sdk v1.9.6
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	github.com/fatih/color v1.7.0 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/logger v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/go-hclog v0.15.0 // indirect
+	github.com/hashicorp/go-plugin v1.4.0 // indirect
+	github.com/hashicorp/yamux v0.0.0-20180604194846-3520598351bb // indirect
+	github.com/mattn/go-colorable v0.1.4 // indirect
+	github.com/mattn/go-isatty v0.0.10 // indirect
+	github.com/mitchellh/go-testing-interface v0.0.0-20171004221916-a61a99592b77 // indirect
+	github.com/oklog/run v1.0.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/crypto v0.54.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260819154853-08b0e4226688 // indirect
+	google.golang.org/protobuf v1.36.12 // indirect
+)
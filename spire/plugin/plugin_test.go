@@ -0,0 +1,143 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire-plugin-sdk/pluginsdk"
+	"github.com/spiffe/spire-plugin-sdk/plugintest"
+	agentnodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	servernodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func serveServer(t *testing.T, s *Server, trustDomain string) *servernodeattestorv1.NodeAttestorPluginClient {
+	t.Helper()
+	client := new(servernodeattestorv1.NodeAttestorPluginClient)
+	config := new(configv1.ConfigServiceClient)
+	plugintest.ServeInBackground(t, plugintest.Config{
+		PluginServer:   servernodeattestorv1.NodeAttestorPluginServer(s),
+		PluginClient:   client,
+		ServiceServers: []pluginsdk.ServiceServer{configv1.ConfigServiceServer(s)},
+		ServiceClients: []pluginsdk.ServiceClient{config},
+	})
+	if _, err := config.Configure(context.Background(), &configv1.ConfigureRequest{
+		CoreConfiguration: &configv1.CoreConfiguration{TrustDomain: trustDomain},
+		HclConfiguration:  `check_revocations = true`,
+	}); err != nil {
+		t.Fatalf("server Configure() = %v. Want nil", err)
+	}
+	return client
+}
+
+func serveAgent(t *testing.T, a *Agent) *agentnodeattestorv1.NodeAttestorPluginClient {
+	t.Helper()
+	client := new(agentnodeattestorv1.NodeAttestorPluginClient)
+	config := new(configv1.ConfigServiceClient)
+	plugintest.ServeInBackground(t, plugintest.Config{
+		PluginServer:   agentnodeattestorv1.NodeAttestorPluginServer(a),
+		PluginClient:   client,
+		ServiceServers: []pluginsdk.ServiceServer{configv1.ConfigServiceServer(a)},
+		ServiceClients: []pluginsdk.ServiceClient{config},
+	})
+	if _, err := config.Configure(context.Background(), &configv1.ConfigureRequest{
+		CoreConfiguration: &configv1.CoreConfiguration{TrustDomain: "example.org"},
+	}); err != nil {
+		t.Fatalf("agent Configure() = %v. Want nil", err)
+	}
+	return client
+}
+
+// attest relays messages between the plugins like SPIRE does.
+func attest(t *testing.T, server *servernodeattestorv1.NodeAttestorPluginClient, agent *agentnodeattestorv1.NodeAttestorPluginClient) (*servernodeattestorv1.AgentAttributes, error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agentStream, err := agent.AidAttestation(ctx)
+	if err != nil {
+		t.Fatalf("AidAttestation() = _, %v. Want nil", err)
+	}
+	serverStream, err := server.Attest(ctx)
+	if err != nil {
+		t.Fatalf("Attest() = _, %v. Want nil", err)
+	}
+	for {
+		msg, err := agentStream.Recv()
+		if err != nil {
+			t.Fatalf("agent Recv() = _, %v. Want nil", err)
+		}
+		req := &servernodeattestorv1.AttestRequest{}
+		if payload := msg.GetPayload(); payload != nil {
+			req.Request = &servernodeattestorv1.AttestRequest_Payload{Payload: payload}
+		} else {
+			req.Request = &servernodeattestorv1.AttestRequest_ChallengeResponse{ChallengeResponse: msg.GetChallengeResponse()}
+		}
+		if err := serverStream.Send(req); err != nil {
+			t.Fatalf("server Send() = %v. Want nil", err)
+		}
+		resp, err := serverStream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if attrs := resp.GetAgentAttributes(); attrs != nil {
+			if err := agentStream.CloseSend(); err != nil {
+				t.Fatalf("agent CloseSend() = %v. Want nil", err)
+			}
+			return attrs, nil
+		}
+		if err := agentStream.Send(&agentnodeattestorv1.Challenge{Challenge: resp.GetChallenge()}); err != nil {
+			t.Fatalf("agent Send() = %v. Want nil", err)
+		}
+	}
+}
+
+func TestAttest(t *testing.T) {
+	var verified *report.Response
+	server := serveServer(t, &Server{Verify: func(resp *report.Response) error {
+		verified = resp
+		return nil
+	}}, "example.org")
+	agent := serveAgent(t, &Agent{Client: faketsm.ReportSnp(0, &faketsm.SnpOptions{Measurement: [48]byte{1}})})
+	attrs, err := attest(t, server, agent)
+	if err != nil {
+		t.Fatalf("attest() = _, %v. Want nil", err)
+	}
+	if verified == nil {
+		t.Error("Attest() did not verify the report")
+	}
+	if !strings.HasPrefix(attrs.GetSpiffeId(), "spiffe://example.org/spire/agent/tsm/sev_guest/") {
+		t.Errorf("Attest() SPIFFE ID = %q. Want a sev_guest agent", attrs.GetSpiffeId())
+	}
+	selectors := strings.Join(attrs.GetSelectorValues(), " ")
+	if !strings.Contains(selectors, "measurement:01"+strings.Repeat("00", 47)) {
+		t.Errorf("Attest() selectors = %v. Want the measurement", attrs.GetSelectorValues())
+	}
+}
+
+func TestAttestUntrusted(t *testing.T) {
+	// The default verifier rejects the fake's unsigned report without network access,
+	// because no fake sev_guest report identifies its product.
+	server := serveServer(t, &Server{}, "example.org")
+	agent := serveAgent(t, &Agent{Client: faketsm.ReportSnp(0, &faketsm.SnpOptions{})})
+	if attrs, err := attest(t, server, agent); err == nil {
+		t.Errorf("attest() = %v, nil. Want an error", attrs)
+	}
+}
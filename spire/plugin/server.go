@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin adapts the spire package's node attestor to SPIRE's plugin SDK, as
+// NodeAttestor plugins named "tsm" for the SPIRE server and agent. It is a separate module
+// so that the go-configfs-tsm module does not depend on the SDK.
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/spire"
+)
+
+// ServerConfig is the server plugin's HCL configuration.
+type ServerConfig struct {
+	// KdsBaseURL is the AMD KDS URL that SEV-SNP certificates missing from agents' auxblobs
+	// are fetched from. Empty means kds.BaseURL.
+	KdsBaseURL string `hcl:"kds_base_url"`
+	// Product is the SEV-SNP product, e.g., "Milan", for reports that predate version 3 and
+	// do not identify it.
+	Product string `hcl:"product"`
+	// CheckRevocations makes verification fetch CRLs and reject revoked certificates.
+	CheckRevocations bool `hcl:"check_revocations"`
+}
+
+// Server is the SPIRE server's NodeAttestor plugin.
+type Server struct {
+	nodeattestorv1.UnimplementedNodeAttestorServer
+	configv1.UnimplementedConfigServer
+
+	// Verify checks the signature of a report and that its platform is trusted. If nil,
+	// reports are verified with VerifyReport and the configured options.
+	Verify func(*report.Response) error
+
+	mu       sync.RWMutex
+	attestor *spire.ServerAttestor
+}
+
+// Configure configures the plugin with the server's trust domain and the plugin's
+// ServerConfig.
+func (s *Server) Configure(_ context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := &ServerConfig{}
+	if err := hcl.Decode(config, req.GetHclConfiguration()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not decode configuration: %v", err)
+	}
+	trustDomain := req.GetCoreConfiguration().GetTrustDomain()
+	if trustDomain == "" {
+		return nil, status.Error(codes.InvalidArgument, "trust domain is required")
+	}
+	verify := s.Verify
+	if verify == nil {
+		opts := &VerifyOptions{KdsBaseURL: config.KdsBaseURL, Product: config.Product, CheckRevocations: config.CheckRevocations}
+		verify = func(resp *report.Response) error { return VerifyReport(resp, opts) }
+	}
+	s.mu.Lock()
+	s.attestor = &spire.ServerAttestor{TrustDomain: trustDomain, Verify: verify}
+	s.mu.Unlock()
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// serverStream adapts the SDK's stream to spire.ServerStream.
+type serverStream struct {
+	stream nodeattestorv1.NodeAttestor_AttestServer
+}
+
+func (s *serverStream) Recv() (*spire.AttestRequest, error) {
+	req, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return &spire.AttestRequest{Payload: req.GetPayload(), ChallengeResponse: req.GetChallengeResponse()}, nil
+}
+
+func (s *serverStream) Send(resp *spire.AttestResponse) error {
+	if attrs := resp.AgentAttributes; attrs != nil {
+		return s.stream.Send(&nodeattestorv1.AttestResponse{
+			Response: &nodeattestorv1.AttestResponse_AgentAttributes{
+				AgentAttributes: &nodeattestorv1.AgentAttributes{
+					SpiffeId:       attrs.SpiffeID,
+					SelectorValues: attrs.SelectorValues,
+					CanReattest:    attrs.CanReattest,
+				},
+			},
+		})
+	}
+	return s.stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_Challenge{Challenge: resp.Challenge},
+	})
+}
+
+// Attest attests an agent with a TSM report.
+func (s *Server) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
+	s.mu.RLock()
+	attestor := s.attestor
+	s.mu.RUnlock()
+	if attestor == nil {
+		return status.Error(codes.FailedPrecondition, "not configured")
+	}
+	if err := attestor.Attest(&serverStream{stream: stream}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.PermissionDenied, "tsm attestation failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sevabi "github.com/google/go-sev-guest/abi"
+	sevverify "github.com/google/go-sev-guest/verify"
+	tdxabi "github.com/google/go-tdx-guest/abi"
+	tdxverify "github.com/google/go-tdx-guest/verify"
+
+	"github.com/google/go-configfs-tsm/kds"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// VerifyOptions are options for VerifyReport.
+type VerifyOptions struct {
+	// KdsBaseURL is the AMD KDS URL. Empty means kds.BaseURL.
+	KdsBaseURL string
+	// Product is the SEV-SNP product for reports that do not identify it.
+	Product string
+	// CheckRevocations makes verification fetch CRLs and reject revoked certificates.
+	CheckRevocations bool
+	// Now is the time at which certificates must be valid. Zero means the current time.
+	Now time.Time
+}
+
+// VerifyReport checks the signature of a sev_guest report or tdx_guest quote up to the
+// vendor's root of trust. SEV-SNP certificates missing from the auxblob are fetched from
+// KDS, and TDX collateral from Intel PCS.
+func VerifyReport(resp *report.Response, opts *VerifyOptions) error {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	switch provider := strings.TrimRight(resp.Provider, "\n"); provider {
+	case report.ProviderSevGuest:
+		if len(resp.OutBlob) < sevabi.ReportSize {
+			return fmt.Errorf("sev_guest report is %d bytes, want %d", len(resp.OutBlob), sevabi.ReportSize)
+		}
+		fetcher := &kds.Fetcher{BaseURL: opts.KdsBaseURL, Product: opts.Product}
+		chain, err := fetcher.Complete(resp)
+		if err != nil {
+			return err
+		}
+		data := append(append([]byte(nil), resp.OutBlob[:sevabi.ReportSize]...), chain.CertTable()...)
+		attestation, err := sevabi.ReportCertsToProto(data)
+		if err != nil {
+			return fmt.Errorf("could not parse sev_guest report: %w", err)
+		}
+		options := sevverify.DefaultOptions()
+		options.CheckRevocations = opts.CheckRevocations
+		options.Now = now
+		return sevverify.SnpAttestation(attestation, options)
+	case report.ProviderTdxGuest:
+		quote, err := tdxabi.QuoteToProto(resp.OutBlob)
+		if err != nil {
+			return fmt.Errorf("could not parse tdx_guest quote: %w", err)
+		}
+		options := tdxverify.DefaultOptions()
+		options.GetCollateral = true
+		options.CheckRevocations = opts.CheckRevocations
+		options.Now = now
+		return tdxverify.TdxQuote(quote, options)
+	default:
+		return fmt.Errorf("cannot verify reports of provider %q", provider)
+	}
+}
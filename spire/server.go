@@ -0,0 +1,164 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spire
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/verify"
+)
+
+// AttestRequest is a message from the agent to the server attestor. Exactly one field is
+// set.
+type AttestRequest struct {
+	Payload           []byte
+	ChallengeResponse []byte
+}
+
+// AgentAttributes are the attributes of an attested agent.
+type AgentAttributes struct {
+	// SpiffeID is the agent's SPIFFE ID.
+	SpiffeID string
+	// SelectorValues are the agent's selectors without the PluginName type.
+	SelectorValues []string
+	// CanReattest is true if the agent can attest again with the same SPIFFE ID.
+	CanReattest bool
+}
+
+// AttestResponse is a message from the server attestor. Exactly one field is set.
+type AttestResponse struct {
+	Challenge       []byte
+	AgentAttributes *AgentAttributes
+}
+
+// ServerStream is the server attestor's stream to the agent attestor.
+type ServerStream interface {
+	// Recv returns the agent's next message.
+	Recv() (*AttestRequest, error)
+	// Send sends a message to the agent.
+	Send(*AttestResponse) error
+}
+
+// ErrNoVerifier is returned by Attest when the server attestor has no Verify function.
+var ErrNoVerifier = errors.New("no report verifier is configured")
+
+var (
+	trustDomainRegexp = regexp.MustCompile(`^[a-z0-9._-]+$`)
+	agentPathRegexp   = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+)
+
+// ServerAttestor is the server side of the node attestor.
+type ServerAttestor struct {
+	// TrustDomain is the name of the trust domain that agents join, e.g., "example.org".
+	TrustDomain string
+	// Verify checks the signature of a report and that its platform is trusted, e.g., with
+	// collateral from the kds or pcs packages. It is called after the report's structure
+	// and nonce are checked, and is required: an unsigned report proves nothing.
+	Verify func(*report.Response) error
+	// AgentPath returns the path of an agent's SPIFFE ID under
+	// "/spire/agent/tsm/<provider>/". Agents can then reattest, so the path must identify
+	// the same node across attestations, e.g., from a registry of expected measurements. If
+	// nil, the path is the hex SHA-256 digest of the report, which is unique to each
+	// attestation, so agents cannot reattest.
+	AgentPath func(*report.Response) (string, error)
+	// Rand is the source of challenges. If nil, crypto/rand.Reader is used.
+	Rand io.Reader
+}
+
+func (s *ServerAttestor) recv(stream ServerStream) (*AttestRequest, error) {
+	req, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("could not receive from agent: %w", err)
+	}
+	return req, nil
+}
+
+// Attest attests an agent and sends its attributes.
+func (s *ServerAttestor) Attest(stream ServerStream) error {
+	if s.Verify == nil {
+		return ErrNoVerifier
+	}
+	if !trustDomainRegexp.MatchString(s.TrustDomain) {
+		return fmt.Errorf("trust domain %q is invalid", s.TrustDomain)
+	}
+	req, err := s.recv(stream)
+	if err != nil {
+		return err
+	}
+	payload := &Payload{}
+	if err := json.Unmarshal(req.Payload, payload); err != nil {
+		return fmt.Errorf("could not parse payload: %w", err)
+	}
+	if payload.Version != payloadVersion {
+		return fmt.Errorf("payload version %d is not %d", payload.Version, payloadVersion)
+	}
+	r := s.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return fmt.Errorf("could not generate challenge: %w", err)
+	}
+	if err := stream.Send(&AttestResponse{Challenge: nonce}); err != nil {
+		return err
+	}
+	if req, err = s.recv(stream); err != nil {
+		return err
+	}
+	evidence := &Evidence{}
+	if err := json.Unmarshal(req.ChallengeResponse, evidence); err != nil {
+		return fmt.Errorf("could not parse challenge response: %w", err)
+	}
+	resp := evidence.Response()
+	if err := verify.Response(resp, &verify.Options{Nonce: nonce}).Err(); err != nil {
+		return fmt.Errorf("report is invalid: %w", err)
+	}
+	selectors, err := Selectors(resp)
+	if err != nil {
+		return err
+	}
+	if err := s.Verify(resp); err != nil {
+		return fmt.Errorf("report is not trusted: %w", err)
+	}
+	attrs := &AgentAttributes{SelectorValues: selectors}
+	var path string
+	if s.AgentPath != nil {
+		if path, err = s.AgentPath(resp); err != nil {
+			return fmt.Errorf("could not get agent path: %w", err)
+		}
+		if segments := "/" + path + "/"; !agentPathRegexp.MatchString(path) ||
+			strings.Contains(segments, "/./") || strings.Contains(segments, "/../") {
+			return fmt.Errorf("agent path %q is invalid", path)
+		}
+		attrs.CanReattest = true
+	} else {
+		digest := sha256.Sum256(resp.OutBlob)
+		path = hex.EncodeToString(digest[:])
+	}
+	// Selectors checked that the provider is known, so it is a valid path segment.
+	provider := strings.TrimRight(resp.Provider, "\n")
+	attrs.SpiffeID = fmt.Sprintf("spiffe://%s/spire/agent/%s/%s/%s", s.TrustDomain, PluginName, provider, path)
+	return stream.Send(&AttestResponse{AgentAttributes: attrs})
+}
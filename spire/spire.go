@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spire implements a SPIRE node attestor backed by TSM reports, so that
+// confidential VMs can join a SPIFFE trust domain with configfs-tsm evidence.
+//
+// The agent and server attestors follow the message flow of SPIRE's NodeAttestor plugins:
+// the agent sends a payload, the server challenges it with a nonce, and the agent responds
+// with a report that binds the nonce. The server checks the report, then issues the agent's
+// SPIFFE ID and selectors. AgentStream and ServerStream mirror the plugin SDK's streams so
+// that a plugin binary adapts them with a few lines, without this module depending on the
+// SDK. The spire/plugin module is such an adapter, with plugin binaries for the SPIRE
+// server and agent.
+package spire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+	"github.com/google/go-configfs-tsm/internal/tdx"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// PluginName is the name of the node attestor plugins and the type of their selectors.
+const PluginName = "tsm"
+
+// payloadVersion is the version of the payload and evidence formats.
+const payloadVersion = 1
+
+// nonceSize is the size of the server's challenge, the largest inblob.
+const nonceSize = 64
+
+// Payload is the agent's first message.
+type Payload struct {
+	// Version is the version of the payload and evidence formats.
+	Version int `json:"version"`
+}
+
+// Evidence is the agent's response to a challenge.
+type Evidence struct {
+	// Provider is the provider that generated the report.
+	Provider string `json:"provider"`
+	// OutBlob is the report whose inblob is the challenge.
+	OutBlob []byte `json:"outblob"`
+	// AuxBlob is the report's auxiliary data, e.g., certificates.
+	AuxBlob []byte `json:"auxblob,omitempty"`
+}
+
+// Response returns the evidence as a report response.
+func (e *Evidence) Response() *report.Response {
+	return &report.Response{Provider: e.Provider, OutBlob: e.OutBlob, AuxBlob: e.AuxBlob}
+}
+
+// field is a report field that becomes a selector.
+type field struct {
+	name   string
+	offset int
+	size   int
+}
+
+var (
+	// snpFields are fields of an SEV-SNP attestation report.
+	snpFields = []field{
+		{"family_id", 0x10, 16},
+		{"image_id", 0x20, 16},
+		{"measurement", 0x90, 48},
+		{"host_data", 0xC0, 32},
+	}
+	// tdxFields are fields of a TDX quote's body, offset by the quote header.
+	tdxFields = []field{
+		{"td_attributes", 168, 8},
+		{"mrtd", 184, 48},
+		{"mrconfigid", 232, 48},
+		{"mrowner", 280, 48},
+		{"mrownerconfig", 328, 48},
+		{"rtmr0", tdx.RtmrsOffset, tdx.RtmrSize},
+		{"rtmr1", tdx.RtmrsOffset + tdx.RtmrSize, tdx.RtmrSize},
+		{"rtmr2", tdx.RtmrsOffset + 2*tdx.RtmrSize, tdx.RtmrSize},
+		{"rtmr3", tdx.RtmrsOffset + 3*tdx.RtmrSize, tdx.RtmrSize},
+	}
+)
+
+const (
+	snpPolicyOffset = 0x08
+	snpVmplOffset   = 0x30
+)
+
+// Selectors returns the selector values of a report, e.g., "provider:tdx_guest" and
+// "mrtd:<hex>", which registration entries match agents by. The report's structure must
+// already be checked.
+func Selectors(resp *report.Response) ([]string, error) {
+	provider := strings.TrimRight(resp.Provider, "\n")
+	selectors := []string{"provider:" + provider}
+	out := resp.OutBlob
+	var fields []field
+	switch provider {
+	case report.ProviderSevGuest:
+		if len(out) < snp.ReportSize {
+			return nil, fmt.Errorf("sev_guest report is %d bytes, want %d", len(out), snp.ReportSize)
+		}
+		le := binary.LittleEndian
+		selectors = append(selectors,
+			fmt.Sprintf("policy:%#x", le.Uint64(out[snpPolicyOffset:])),
+			fmt.Sprintf("vmpl:%d", le.Uint32(out[snpVmplOffset:])))
+		fields = snpFields
+	case report.ProviderTdxGuest:
+		if len(out) < tdx.HeaderSize+tdx.BodySize {
+			return nil, fmt.Errorf("tdx_guest quote is %d bytes, want at least %d", len(out), tdx.HeaderSize+tdx.BodySize)
+		}
+		fields = tdxFields
+	default:
+		return nil, fmt.Errorf("no selectors for provider %q", provider)
+	}
+	for _, f := range fields {
+		selectors = append(selectors, f.name+":"+hex.EncodeToString(out[f.offset:f.offset+f.size]))
+	}
+	return selectors, nil
+}
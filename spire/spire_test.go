@@ -0,0 +1,168 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+// agentStream and serverStream connect the attestors like SPIRE does, ending the agent's
+// stream when the server returns.
+type agentStream struct {
+	toServer   chan *PayloadOrChallengeResponse
+	fromServer chan []byte
+}
+
+func (a *agentStream) Send(m *PayloadOrChallengeResponse) error {
+	a.toServer <- m
+	return nil
+}
+
+func (a *agentStream) Recv() ([]byte, error) {
+	challenge, ok := <-a.fromServer
+	if !ok {
+		return nil, io.EOF
+	}
+	return challenge, nil
+}
+
+type serverStream struct {
+	agent *agentStream
+	attrs *AgentAttributes
+}
+
+func (s *serverStream) Recv() (*AttestRequest, error) {
+	m := <-s.agent.toServer
+	return &AttestRequest{Payload: m.Payload, ChallengeResponse: m.ChallengeResponse}, nil
+}
+
+func (s *serverStream) Send(m *AttestResponse) error {
+	if m.AgentAttributes != nil {
+		s.attrs = m.AgentAttributes
+		return nil
+	}
+	s.agent.fromServer <- m.Challenge
+	return nil
+}
+
+func attest(t *testing.T, client configfsi.Client, server *ServerAttestor) (*AgentAttributes, error) {
+	t.Helper()
+	agent := &agentStream{toServer: make(chan *PayloadOrChallengeResponse, 1), fromServer: make(chan []byte, 1)}
+	agentErr := make(chan error, 1)
+	go func() { agentErr <- NewAgentAttestor(client).AidAttestation(agent) }()
+	stream := &serverStream{agent: agent}
+	err := server.Attest(stream)
+	close(agent.fromServer)
+	if aerr := <-agentErr; aerr != nil {
+		t.Fatalf("AidAttestation() = %v. Want nil", aerr)
+	}
+	return stream.attrs, err
+}
+
+func acceptAll(*report.Response) error { return nil }
+
+func TestAttestSnp(t *testing.T) {
+	client := faketsm.ReportSnp(0, &faketsm.SnpOptions{Policy: 0x30000, Measurement: [48]byte{1, 2, 3}})
+	attrs, err := attest(t, client, &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll})
+	if err != nil {
+		t.Fatalf("Attest() = %v. Want nil", err)
+	}
+	if !strings.HasPrefix(attrs.SpiffeID, "spiffe://example.org/spire/agent/tsm/sev_guest/") || attrs.CanReattest {
+		t.Errorf("Attest() attributes = %+v. Want a sev_guest agent that cannot reattest", attrs)
+	}
+	want := []string{"provider:sev_guest", "policy:0x30000", "vmpl:0", "measurement:010203" + strings.Repeat("00", 45)}
+	for _, w := range want {
+		found := false
+		for _, got := range attrs.SelectorValues {
+			found = found || got == w
+		}
+		if !found {
+			t.Errorf("Attest() selectors = %v. Want %q", attrs.SelectorValues, w)
+		}
+	}
+}
+
+func TestAttestTdx(t *testing.T) {
+	opts := &faketsm.TdxOptions{MrTd: [48]byte{0xaa}}
+	opts.Rtmrs[3][0] = 0xbb
+	path := func(*report.Response) (string, error) { return "node-1", nil }
+	attrs, err := attest(t, faketsm.ReportTdx(opts), &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll, AgentPath: path})
+	if err != nil {
+		t.Fatalf("Attest() = %v. Want nil", err)
+	}
+	if attrs.SpiffeID != "spiffe://example.org/spire/agent/tsm/tdx_guest/node-1" || !attrs.CanReattest {
+		t.Errorf("Attest() attributes = %+v. Want agent node-1 that can reattest", attrs)
+	}
+	selectors := strings.Join(attrs.SelectorValues, " ")
+	if !strings.Contains(selectors, "mrtd:aa"+strings.Repeat("00", 47)) || !strings.Contains(selectors, "rtmr3:bb"+strings.Repeat("00", 47)) {
+		t.Errorf("Attest() selectors = %v. Want mrtd and rtmr3", attrs.SelectorValues)
+	}
+}
+
+func TestAttestErrors(t *testing.T) {
+	errUntrusted := errors.New("untrusted")
+	badPath := func(*report.Response) (string, error) { return "../node", nil }
+	tests := []struct {
+		name    string
+		client  configfsi.Client
+		server  *ServerAttestor
+		wantErr error
+	}{
+		{"no verifier", faketsm.ReportTdx(nil), &ServerAttestor{TrustDomain: "example.org"}, ErrNoVerifier},
+		{"bad trust domain", faketsm.ReportTdx(nil), &ServerAttestor{TrustDomain: "Example.org", Verify: acceptAll}, nil},
+		{"untrusted", faketsm.ReportTdx(nil), &ServerAttestor{TrustDomain: "example.org",
+			Verify: func(*report.Response) error { return errUntrusted }}, errUntrusted},
+		{"unknown provider", faketsm.ReportV7(0), &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll}, nil},
+		{"bad agent path", faketsm.ReportTdx(nil), &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll, AgentPath: badPath}, nil},
+		{"short challenge", faketsm.ReportTdx(nil), &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll,
+			Rand: bytes.NewReader(make([]byte, nonceSize-1))}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs, err := attest(t, tc.client, tc.server)
+			if err == nil || attrs != nil || (tc.wantErr != nil && !errors.Is(err, tc.wantErr)) {
+				t.Errorf("Attest() = %+v, %v. Want an error matching %v", attrs, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttestReplayedEvidence(t *testing.T) {
+	client := faketsm.ReportTdx(nil)
+	resp, err := report.Get(client, report.NewRequest(make([]byte, nonceSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evidence, err := json.Marshal(&Evidence{Provider: resp.Provider, OutBlob: resp.OutBlob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &agentStream{toServer: make(chan *PayloadOrChallengeResponse, 2), fromServer: make(chan []byte, 1)}
+	agent.toServer <- &PayloadOrChallengeResponse{Payload: []byte(`{"version":1}`)}
+	agent.toServer <- &PayloadOrChallengeResponse{ChallengeResponse: evidence}
+	server := &ServerAttestor{TrustDomain: "example.org", Verify: acceptAll}
+	if err := server.Attest(&serverStream{agent: agent}); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Attest() of evidence for another challenge = %v. Want a nonce error", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tlsbind binds attestation reports to live TLS sessions. Both peers derive the
+// session's channel binding from its exporter (RFC 9266), so a report whose inblob is the
+// hash of the binding can only have been requested by a party to the session, and cannot
+// be relayed into another one.
+package tlsbind
+
+import (
+	"crypto/sha512"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/verify"
+)
+
+const (
+	// ExporterLabel is the TLS exporter label for channel bindings from RFC 9266.
+	ExporterLabel = "EXPORTER-Channel-Binding"
+	// ExporterLength is the size of the exported channel binding in bytes.
+	ExporterLength = 32
+)
+
+// Exporter returns the session's channel binding, the keying material exported with
+// ExporterLabel and no context. It requires TLS 1.3, since TLS 1.2 sessions without the
+// extended master secret do not have unique exporters.
+func Exporter(state *tls.ConnectionState) ([]byte, error) {
+	if !state.HandshakeComplete {
+		return nil, fmt.Errorf("tls handshake is not complete")
+	}
+	if state.Version != tls.VersionTLS13 {
+		return nil, fmt.Errorf("tls version 0x%04x does not have unique exporters, want tls 1.3", state.Version)
+	}
+	ekm, err := state.ExportKeyingMaterial(ExporterLabel, nil, ExporterLength)
+	if err != nil {
+		return nil, fmt.Errorf("could not export tls keying material: %w", err)
+	}
+	return ekm, nil
+}
+
+// InBlob returns the inblob that binds a report to the session, the SHA-512 digest of the
+// session's channel binding followed by data. Data is application-specific, e.g., a nonce
+// from the verifier or a hash of a public key, and may be nil.
+func InBlob(state *tls.ConnectionState, data []byte) ([]byte, error) {
+	ekm, err := Exporter(state)
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.New()
+	h.Write(ekm)
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Get returns a report bound to the session and data with the given options.
+func Get(client configfsi.Client, state *tls.ConnectionState, data []byte, opts ...report.Option) (*report.Response, error) {
+	inblob, err := InBlob(state, data)
+	if err != nil {
+		return nil, err
+	}
+	return report.Get(client, report.NewRequest(inblob, opts...))
+}
+
+// VerifyResponse checks the structure of a peer's response and that it is bound to the
+// session and data. Responses from providers whose report data is not known get an error
+// finding, since their binding cannot be checked. The report's signature and measurements are
+// not checked.
+func VerifyResponse(state *tls.ConnectionState, data []byte, resp *report.Response) (*verify.Result, error) {
+	inblob, err := InBlob(state, data)
+	if err != nil {
+		return nil, err
+	}
+	result := verify.Response(resp, &verify.Options{Nonce: inblob})
+	switch result.Provider {
+	case report.ProviderSevGuest, report.ProviderTdxGuest:
+	default:
+		result.Findings = append(result.Findings, verify.Finding{
+			Check:    verify.CheckNonce,
+			Severity: verify.SeverityError,
+			Field:    "outblob",
+			Message:  "channel binding cannot be checked for this provider",
+		})
+	}
+	return result, nil
+}
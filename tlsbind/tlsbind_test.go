@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsbind
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+	"github.com/google/go-configfs-tsm/verify"
+)
+
+func serverCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"cvm.example"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// session returns the client and server states of a new TLS session.
+func session(t *testing.T, maxVersion uint16) (client, server *tls.ConnectionState) {
+	t.Helper()
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+	serverConn := tls.Server(s, &tls.Config{Certificates: []tls.Certificate{serverCert(t)}, MaxVersion: maxVersion})
+	clientConn := tls.Client(c, &tls.Config{InsecureSkipVerify: true, MaxVersion: maxVersion})
+	errs := make(chan error, 1)
+	go func() { errs <- serverConn.Handshake() }()
+	if err := clientConn.Handshake(); err != nil {
+		t.Fatalf("client Handshake() = %v. Want nil", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("server Handshake() = %v. Want nil", err)
+	}
+	cs, ss := clientConn.ConnectionState(), serverConn.ConnectionState()
+	return &cs, &ss
+}
+
+func nonceErrors(result *verify.Result) []verify.Finding {
+	var findings []verify.Finding
+	for _, f := range result.Filter(verify.SeverityError) {
+		if f.Check == verify.CheckNonce {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func TestBinding(t *testing.T) {
+	clientState, serverState := session(t, 0)
+	clientBlob, err := InBlob(clientState, []byte("nonce"))
+	if err != nil {
+		t.Fatalf("InBlob() = _, %v. Want nil", err)
+	}
+	serverBlob, err := InBlob(serverState, []byte("nonce"))
+	if err != nil || string(clientBlob) != string(serverBlob) || len(clientBlob) != 64 {
+		t.Fatalf("InBlob() on the server = %x, %v. Want the client's 64-byte %x", serverBlob, err, clientBlob)
+	}
+
+	resp, err := Get(faketsm.ReportTdx(nil), clientState, []byte("nonce"))
+	if err != nil {
+		t.Fatalf("Get() = _, %v. Want nil", err)
+	}
+	result, err := VerifyResponse(serverState, []byte("nonce"), resp)
+	if err != nil || len(nonceErrors(result)) != 0 {
+		t.Errorf("VerifyResponse() = %v, %v. Want the binding to match", result.Findings, err)
+	}
+	result, err = VerifyResponse(serverState, []byte("other nonce"), resp)
+	if err != nil || len(nonceErrors(result)) == 0 {
+		t.Errorf("VerifyResponse() with other data = %v, %v. Want a nonce error", result.Findings, err)
+	}
+	_, otherState := session(t, 0)
+	result, err = VerifyResponse(otherState, []byte("nonce"), resp)
+	if err != nil || len(nonceErrors(result)) == 0 {
+		t.Errorf("VerifyResponse() in another session = %v, %v. Want a nonce error", result.Findings, err)
+	}
+}
+
+func TestVerifyUnknownProvider(t *testing.T) {
+	clientState, serverState := session(t, 0)
+	resp, err := Get(faketsm.ReportV7(0), clientState, nil)
+	if err != nil {
+		t.Fatalf("Get() = _, %v. Want nil", err)
+	}
+	result, err := VerifyResponse(serverState, nil, resp)
+	if err != nil || len(nonceErrors(result)) != 1 {
+		t.Errorf("VerifyResponse() of an unknown provider = %v, %v. Want a nonce error", result.Findings, err)
+	}
+}
+
+func TestExporterTLS12(t *testing.T) {
+	clientState, _ := session(t, tls.VersionTLS12)
+	if _, err := Exporter(clientState); err == nil {
+		t.Error("Exporter() of a TLS 1.2 session = _, nil. Want an error")
+	}
+	if _, err := Get(faketsm.ReportTdx(nil), clientState, nil, report.WithAuxBlob()); err == nil {
+		t.Error("Get() of a TLS 1.2 session = _, nil. Want an error")
+	}
+}
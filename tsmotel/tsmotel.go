@@ -0,0 +1,96 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tsmotel adapts an OpenTelemetry trace.Tracer to configfsi.ContextTracer, so that
+// report.WithTracer and rtmr.WithTracer add attestation spans to OpenTelemetry traces.
+//
+// It is a separate module so that the core module does not depend on OpenTelemetry.
+package tsmotel
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Tracer returns a configfsi.ContextTracer that starts spans with t.
+func Tracer(t trace.Tracer) configfsi.ContextTracer {
+	return &tracer{tracer: t}
+}
+
+// Start starts an OpenTelemetry span as a child of any span in ctx.
+func (t *tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, configfsi.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(keyValues(attrs)...))
+	return ctx, &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+// SetAttributes sets the span's attributes from alternating key-value pairs.
+func (s *span) SetAttributes(attrs ...any) {
+	s.span.SetAttributes(keyValues(attrs)...)
+}
+
+// End records err, if any, as the span's error status and ends the span.
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// keyValues converts alternating key-value pairs to attributes. Keys are formatted with
+// fmt.Sprint, and values of types without an attribute type become strings.
+func keyValues(attrs []any) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key := attribute.Key(fmt.Sprint(attrs[i]))
+		switch v := attrs[i+1].(type) {
+		case string:
+			result = append(result, key.String(v))
+		case bool:
+			result = append(result, key.Bool(v))
+		case int:
+			result = append(result, key.Int(v))
+		case int64:
+			result = append(result, key.Int64(v))
+		case uint64:
+			if v > math.MaxInt64 {
+				result = append(result, key.String(fmt.Sprint(v)))
+			} else {
+				result = append(result, key.Int64(int64(v)))
+			}
+		case uint32:
+			result = append(result, key.Int64(int64(v)))
+		case float64:
+			result = append(result, key.Float64(v))
+		default:
+			result = append(result, key.String(fmt.Sprint(v)))
+		}
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tsmotel
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/google/go-configfs-tsm/configfs/configfsi"
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func TestReportSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otelTracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	ctx, caller := otelTracer.Start(context.Background(), "caller")
+	c := &faketsm.Client{Subsystems: map[string]configfsi.Client{"report": faketsm.ReportV7(0)}}
+	if _, err := report.Get(c, report.NewRequest([]byte("nonce")), report.WithTracer(ctx, Tracer(otelTracer))); err != nil {
+		t.Fatalf("Get() = _, %v, want nil", err)
+	}
+	caller.End()
+
+	spans := recorder.Ended()
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	get, ok := byName["report.Get"]
+	if !ok {
+		t.Fatalf("ended spans %v, want report.Get", spans)
+	}
+	if get.Parent().SpanID() != caller.SpanContext().SpanID() {
+		t.Errorf("report.Get parent = %v, want the caller's span", get.Parent().SpanID())
+	}
+	want := map[attribute.Key]attribute.Value{
+		"inblob_size": attribute.IntValue(5),
+		"provider":    attribute.StringValue("fake"),
+	}
+	for _, kv := range get.Attributes() {
+		if w, ok := want[kv.Key]; ok {
+			if kv.Value != w {
+				t.Errorf("report.Get attribute %s = %v, want %v", kv.Key, kv.Value.Emit(), w.Emit())
+			}
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("report.Get attributes = %v, missing %v", get.Attributes(), want)
+	}
+	mkdir, ok := byName["configfs.MkdirTemp"]
+	if !ok || mkdir.Parent().SpanID() != get.SpanContext().SpanID() {
+		t.Errorf("configfs.MkdirTemp span = %v, want a child of report.Get", mkdir)
+	}
+}
+
+func TestEndError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := Tracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+	_, s := tracer.Start(context.Background(), "op")
+	s.End(context.Canceled)
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || len(spans[0].Events()) != 1 {
+		t.Errorf("ended spans = %v, want one span with an error status and event", spans)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/go-configfs-tsm/internal/snp"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+const (
+	// snpReportDataOffset and snpReportDataSize locate REPORT_DATA.
+	snpReportDataOffset = 0x50
+	snpReportDataSize   = 64
+	// snpMinVersion and snpMaxVersion bound the report versions that firmware produces.
+	snpMinVersion = 2
+	snpMaxVersion = 5
+	// snpSignatureAlgoEcdsaP384 is the SIGNATURE_ALGO of ECDSA P-384 with SHA-384.
+	snpSignatureAlgoEcdsaP384 = 1
+)
+
+func checkSnp(result *Result, resp *report.Response, opts *Options) {
+	out := resp.OutBlob
+	if len(out) != snp.ReportSize {
+		result.add(CheckSize, SeverityError, "outblob", "got %d bytes, want %d", len(out), snp.ReportSize)
+		if len(out) < snp.ReportSize {
+			// The remaining checks read fields of the report.
+			checkSnpCertTable(result, resp.AuxBlob)
+			return
+		}
+	}
+	le := binary.LittleEndian
+	if version := le.Uint32(out[0x00:]); version < snpMinVersion || version > snpMaxVersion {
+		result.add(CheckVersion, SeverityError, "outblob.version", "version %d is not in [%d, %d]", version, snpMinVersion, snpMaxVersion)
+	}
+	if algo := le.Uint32(out[0x34:]); algo != snpSignatureAlgoEcdsaP384 {
+		result.add(CheckVersion, SeverityError, "outblob.signature_algo", "got %d, want %d (ECDSA P-384 with SHA-384)", algo, snpSignatureAlgoEcdsaP384)
+	}
+	if resp.Privilege != nil {
+		if vmpl := le.Uint32(out[0x30:]); uint(vmpl) != resp.Privilege.Level {
+			result.add(CheckPrivilege, SeverityError, "outblob.vmpl", "got %d, want the requested privilege level %d", vmpl, resp.Privilege.Level)
+		}
+	}
+	checkNonce(result, "outblob.report_data", out[snpReportDataOffset:snpReportDataOffset+snpReportDataSize], opts)
+	checkSnpCertTable(result, resp.AuxBlob)
+}
+
+// checkSnpCertTable checks that an auxblob is a well-formed certificate table: entries
+// terminated by a zero entry, each locating an X.509 certificate after the table.
+func checkSnpCertTable(result *Result, aux []byte) {
+	if len(aux) == 0 {
+		result.add(CheckCertificates, SeverityInfo, "auxblob", "no certificate table")
+		return
+	}
+	entries, end, err := snp.ParseCertTable(aux)
+	if err != nil {
+		result.add(CheckCertificates, SeverityError, "auxblob", "%v", err)
+		return
+	}
+	signer := false
+	for i, e := range entries {
+		field := fmt.Sprintf("auxblob.entry[%d]", i)
+		if uint64(e.Offset) < uint64(end) || uint64(e.Offset)+uint64(e.Length) > uint64(len(aux)) {
+			result.add(CheckCertificates, SeverityError, field, "certificate at [%d, %d) is outside the %d bytes after the table", e.Offset, uint64(e.Offset)+uint64(e.Length), len(aux)-end)
+			continue
+		}
+		if e.GUID == snp.GUIDVcek || e.GUID == snp.GUIDVlek {
+			signer = true
+		}
+		if _, err := x509.ParseCertificate(aux[e.Offset : e.Offset+e.Length]); err != nil {
+			result.add(CheckCertificates, SeverityError, field, "certificate %s does not parse: %v", e.GUID, err)
+		}
+	}
+	if !signer {
+		result.add(CheckCertificates, SeverityInfo, "auxblob", "no VCEK or VLEK certificate; verifiers must fetch it")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+
+	"github.com/google/go-configfs-tsm/internal/tdx"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func checkTdx(result *Result, resp *report.Response, opts *Options) {
+	out := resp.OutBlob
+	if len(out) < tdx.SignatureDataOffset {
+		result.add(CheckSize, SeverityError, "outblob", "got %d bytes, want at least %d", len(out), tdx.SignatureDataOffset)
+		return
+	}
+	le := binary.LittleEndian
+	if version := le.Uint16(out[0:]); version != tdx.QuoteVersion {
+		result.add(CheckVersion, SeverityError, "outblob.header.version", "got %d, want %d", version, tdx.QuoteVersion)
+		// Other versions lay out the body differently.
+		return
+	}
+	if keyType := le.Uint16(out[2:]); keyType != tdx.AttestationKeyTypeEcdsaP256 {
+		result.add(CheckVersion, SeverityError, "outblob.header.attestation_key_type", "got %d, want %d (ECDSA P-256)", keyType, tdx.AttestationKeyTypeEcdsaP256)
+	}
+	if teeType := le.Uint32(out[4:]); teeType != tdx.TeeType {
+		result.add(CheckVersion, SeverityError, "outblob.header.tee_type", "got %#x, want %#x (TDX)", teeType, tdx.TeeType)
+	}
+	checkNonce(result, "outblob.body.report_data", out[tdx.ReportDataOffset:tdx.ReportDataOffset+tdx.ReportDataSize], opts)
+
+	sigOffset := tdx.SignatureDataOffset
+	sigLen := uint64(le.Uint32(out[sigOffset-4:]))
+	if uint64(len(out)-sigOffset) != sigLen {
+		result.add(CheckSize, SeverityError, "outblob.signature_data_len", "signature data length %d does not match the %d bytes after the body", sigLen, len(out)-sigOffset)
+		return
+	}
+	checkTdxSignatureData(result, out[sigOffset:])
+}
+
+// checkTdxSignatureData checks that the quote signature data holds well-formed
+// certification data whose PCK certificate chain, if any, parses.
+func checkTdxSignatureData(result *Result, sig []byte) {
+	const field = "outblob.signature_data.certification_data"
+	if len(sig) < 2*tdx.SignatureSize {
+		result.add(CheckSize, SeverityError, "outblob.signature_data", "got %d bytes, want at least %d", len(sig), 2*tdx.SignatureSize)
+		return
+	}
+	typ, data, rest, err := tdx.CertData(sig[2*tdx.SignatureSize:])
+	if err != nil {
+		result.add(CheckCertificates, SeverityError, field, "%v", err)
+		return
+	}
+	if len(rest) != 0 {
+		result.add(CheckSize, SeverityWarning, field, "%d bytes follow the certification data", len(rest))
+	}
+	if typ == tdx.CertDataQeReport {
+		if typ, data, err = tdx.QeCertData(data); err != nil {
+			result.add(CheckCertificates, SeverityError, field, "%v", err)
+			return
+		}
+	}
+	if typ != tdx.CertDataPckCertChain {
+		result.add(CheckCertificates, SeverityWarning, field, "certification data type %d is not a PCK certificate chain", typ)
+		return
+	}
+	checkPemChain(result, field+".pck_cert_chain", data)
+}
+
+// checkPemChain checks that data is a sequence of PEM certificates.
+func checkPemChain(result *Result, field string, data []byte) {
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			result.add(CheckCertificates, SeverityError, field, "PEM block %d is a %q, not a certificate", count, block.Type)
+		} else if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			result.add(CheckCertificates, SeverityError, field, "certificate %d does not parse: %v", count, err)
+		}
+		count++
+	}
+	if count == 0 {
+		result.add(CheckCertificates, SeverityInfo, field, "no PCK certificate chain; verifiers must fetch it")
+	}
+}
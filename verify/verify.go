@@ -0,0 +1,168 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify checks the structure of TSM report responses on behalf of a verifier:
+// blob sizes, version fields, nonce binding, and certificate table well-formedness. It
+// does not check signatures or endorsements, and it reports what it finds as Findings
+// rather than a single verdict so that callers can apply their own policy.
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-configfs-tsm/report"
+	"go.uber.org/multierr"
+)
+
+// Severity is how much a Finding matters.
+type Severity int
+
+const (
+	// SeverityInfo notes something that was or was not checked.
+	SeverityInfo Severity = iota
+	// SeverityWarning is a deviation that well-formed evidence may have.
+	SeverityWarning
+	// SeverityError is a deviation that makes the evidence malformed.
+	SeverityError
+)
+
+// String returns the lowercase name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Check names a structural check.
+type Check string
+
+const (
+	// CheckProvider checks that the provider is one with structural checks.
+	CheckProvider Check = "provider"
+	// CheckSize checks the size of a blob.
+	CheckSize Check = "size"
+	// CheckVersion checks version and type fields.
+	CheckVersion Check = "version"
+	// CheckNonce checks that the report data is the expected inblob.
+	CheckNonce Check = "nonce"
+	// CheckPrivilege checks that the report's privilege level is the requested one.
+	CheckPrivilege Check = "privilege"
+	// CheckCertificates checks the certificate table or certification data.
+	CheckCertificates Check = "certificates"
+)
+
+// Finding is the outcome of a check on one field.
+type Finding struct {
+	Check    Check
+	Severity Severity
+	// Field names the checked field, e.g., "outblob.version".
+	Field   string
+	Message string
+}
+
+// Error returns the finding as a message.
+func (f Finding) Error() string {
+	return fmt.Sprintf("%s: %s %s: %s", f.Severity, f.Check, f.Field, f.Message)
+}
+
+// Result holds the findings of checking a Response.
+type Result struct {
+	// Provider is the provider that the checks were dispatched on.
+	Provider string
+	Findings []Finding
+}
+
+func (r *Result) add(check Check, severity Severity, field, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{
+		Check:    check,
+		Severity: severity,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Filter returns the findings with at least the given severity.
+func (r *Result) Filter(min Severity) []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if f.Severity >= min {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// OK returns true if no finding is an error.
+func (r *Result) OK() bool {
+	return len(r.Filter(SeverityError)) == 0
+}
+
+// Err returns the error findings combined, or nil if there are none.
+func (r *Result) Err() error {
+	var err error
+	for _, f := range r.Filter(SeverityError) {
+		err = multierr.Append(err, f)
+	}
+	return err
+}
+
+// Options configures the checks.
+type Options struct {
+	// Nonce is the inblob that the report data must echo, zero-padded to the report data
+	// size. If nil, nonce binding is not checked.
+	Nonce []byte
+}
+
+// Response checks the structure of a response according to its provider. Unknown
+// providers get only a finding that nothing was checked. A nil opts checks no nonce.
+func Response(resp *report.Response, opts *Options) *Result {
+	if opts == nil {
+		opts = &Options{}
+	}
+	result := &Result{Provider: strings.TrimRight(resp.Provider, "\n")}
+	switch result.Provider {
+	case report.ProviderSevGuest:
+		checkSnp(result, resp, opts)
+	case report.ProviderTdxGuest:
+		checkTdx(result, resp, opts)
+	default:
+		result.add(CheckProvider, SeverityWarning, "provider", "no structural checks for provider %q", result.Provider)
+	}
+	return result
+}
+
+// checkNonce adds a finding for whether reportData echoes the nonce.
+func checkNonce(result *Result, field string, reportData []byte, opts *Options) {
+	if opts.Nonce == nil {
+		result.add(CheckNonce, SeverityInfo, field, "no nonce to check")
+		return
+	}
+	if len(opts.Nonce) > len(reportData) {
+		result.add(CheckNonce, SeverityError, field, "nonce of %d bytes is larger than the %d-byte report data", len(opts.Nonce), len(reportData))
+		return
+	}
+	want := make([]byte, len(reportData))
+	copy(want, opts.Nonce)
+	if !bytes.Equal(reportData, want) {
+		result.add(CheckNonce, SeverityError, field, "report data %x does not echo the nonce %x", reportData, opts.Nonce)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/google/go-configfs-tsm/configfs/faketsm"
+	"github.com/google/go-configfs-tsm/report"
+)
+
+func nonce() []byte {
+	return bytes.Repeat([]byte{0x5a}, 64)
+}
+
+// hasFinding returns true if the result has an error finding from the check.
+func hasFinding(result *Result, check Check) bool {
+	for _, f := range result.Filter(SeverityError) {
+		if f.Check == check {
+			return true
+		}
+	}
+	return false
+}
+
+func snpResponse(t *testing.T) *report.Response {
+	t.Helper()
+	key, cert, err := faketsm.GenerateTestVcek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := report.Get(faketsm.ReportSnp(0, &faketsm.SnpOptions{Vcek: key, VcekCert: cert}), &report.Request{
+		InBlob:     nonce(),
+		Privilege:  &report.Privilege{Level: 1},
+		GetAuxBlob: true,
+	})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	return resp
+}
+
+func tdxResponse(t *testing.T, chain []byte) *report.Response {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := report.Get(faketsm.ReportTdx(&faketsm.TdxOptions{AttestationKey: key, PckCertChain: chain}), &report.Request{InBlob: nonce()})
+	if err != nil {
+		t.Fatalf("report.Get() = _, %v. Want nil", err)
+	}
+	return resp
+}
+
+func TestSnp(t *testing.T) {
+	resp := snpResponse(t)
+	if result := Response(resp, &Options{Nonce: nonce()}); !result.OK() {
+		t.Fatalf("Response() = %v. Want no errors", result.Err())
+	}
+
+	if result := Response(resp, &Options{Nonce: []byte("other")}); !hasFinding(result, CheckNonce) {
+		t.Errorf("Response() with another nonce = %v. Want a nonce error", result.Findings)
+	}
+
+	resp.Privilege = &report.Privilege{Level: 3}
+	if result := Response(resp, nil); !hasFinding(result, CheckPrivilege) {
+		t.Errorf("Response() with another privilege = %v. Want a privilege error", result.Findings)
+	}
+
+	resp.OutBlob[0] = 9
+	if result := Response(resp, nil); !hasFinding(result, CheckVersion) {
+		t.Errorf("Response() of version 9 = %v. Want a version error", result.Findings)
+	}
+
+	resp.OutBlob = resp.OutBlob[:0x100]
+	if result := Response(resp, nil); !hasFinding(result, CheckSize) {
+		t.Errorf("Response() of a truncated outblob = %v. Want a size error", result.Findings)
+	}
+}
+
+func TestSnpCertTable(t *testing.T) {
+	resp := snpResponse(t)
+	tests := []struct {
+		name   string
+		mutate func(aux []byte) []byte
+	}{
+		{"unterminated", func(aux []byte) []byte { return aux[:24] }},
+		{"out of bounds", func(aux []byte) []byte { return aux[:len(aux)-1] }},
+		{"bad certificate", func(aux []byte) []byte { aux[48] ^= 0xff; return aux }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := *resp
+			r.AuxBlob = tc.mutate(bytes.Clone(resp.AuxBlob))
+			if result := Response(&r, nil); !hasFinding(result, CheckCertificates) {
+				t.Errorf("Response() = %v. Want a certificates error", result.Findings)
+			}
+		})
+	}
+}
+
+func TestTdx(t *testing.T) {
+	_, cert, err := faketsm.GenerateTestVcek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	resp := tdxResponse(t, chain)
+	result := Response(resp, &Options{Nonce: nonce()})
+	if !result.OK() {
+		t.Fatalf("Response() = %v. Want no errors", result.Err())
+	}
+	if len(result.Filter(SeverityInfo)) != 0 {
+		t.Errorf("Response() = %v. Want no findings", result.Findings)
+	}
+
+	if result := Response(resp, &Options{Nonce: make([]byte, 64)}); !hasFinding(result, CheckNonce) {
+		t.Errorf("Response() with another nonce = %v. Want a nonce error", result.Findings)
+	}
+
+	bad := tdxResponse(t, []byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"))
+	if result := Response(bad, nil); !hasFinding(result, CheckCertificates) {
+		t.Errorf("Response() with a bad PCK chain = %v. Want a certificates error", result.Findings)
+	}
+
+	resp.OutBlob = resp.OutBlob[:len(resp.OutBlob)-1]
+	if result := Response(resp, nil); !hasFinding(result, CheckSize) {
+		t.Errorf("Response() of a truncated outblob = %v. Want a size error", result.Findings)
+	}
+
+	resp.OutBlob[0] = 5
+	if result := Response(resp, nil); !hasFinding(result, CheckVersion) {
+		t.Errorf("Response() of version 5 = %v. Want a version error", result.Findings)
+	}
+}
+
+func TestUnknownProvider(t *testing.T) {
+	result := Response(&report.Response{Provider: "arm_cca_guest\n"}, nil)
+	if !result.OK() || result.Provider != "arm_cca_guest" || len(result.Filter(SeverityWarning)) != 1 {
+		t.Errorf("Response() = %+v. Want one provider warning", result)
+	}
+}